@@ -0,0 +1,131 @@
+package mailyak
+
+import (
+	"mime"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+)
+
+// ToWithName sets the To address to addr with the display name name, such as
+// "Jane Doe <jane@itsallbroken.com>":
+//
+//	mail.ToWithName("Jane Doe", "jane@itsallbroken.com")
+//
+// The name is quoted if it contains special characters, and encoded according
+// to RFC 2047 if it contains non-ASCII characters.
+func (m *MailYak) ToWithName(name, addr string) {
+	m.To(formatAddress(name, addr))
+}
+
+// CcWithName sets the CC address to addr with the display name name, as
+// ToWithName does for the To address.
+func (m *MailYak) CcWithName(name, addr string) {
+	m.Cc(formatAddress(name, addr))
+}
+
+// BccWithName sets the BCC address to addr with the display name name, as
+// ToWithName does for the To address.
+func (m *MailYak) BccWithName(name, addr string) {
+	m.Bcc(formatAddress(name, addr))
+}
+
+// FromWithName sets the sender email address and name, equivalent to calling
+// From and FromName.
+func (m *MailYak) FromWithName(name, addr string) {
+	m.From(addr)
+	m.FromName(name)
+}
+
+// ToAddresses sets the To addresses to addrs, including any display names,
+// such as the addresses returned by mail.ParseAddressList:
+//
+//	mail.ToAddresses(
+//		&mail.Address{Name: "Jane Doe", Address: "jane@itsallbroken.com"},
+//		&mail.Address{Name: "Jürgen Müller", Address: "jm@itsallbroken.com"},
+//	)
+func (m *MailYak) ToAddresses(addrs ...*mail.Address) {
+	m.To(formatAddresses(addrs)...)
+}
+
+// CcAddresses sets the CC addresses to addrs, including any display names.
+func (m *MailYak) CcAddresses(addrs ...*mail.Address) {
+	m.Cc(formatAddresses(addrs)...)
+}
+
+// BccAddresses sets the BCC addresses to addrs, including any display names.
+func (m *MailYak) BccAddresses(addrs ...*mail.Address) {
+	m.Bcc(formatAddresses(addrs)...)
+}
+
+// formatAddresses returns each of addrs formatted with formatAddress.
+func formatAddresses(addrs []*mail.Address) []string {
+	formatted := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		if a != nil {
+			formatted = append(formatted, formatAddress(a.Name, a.Address))
+		}
+	}
+	return formatted
+}
+
+// formatAddress returns addr with the display name name, or addr unchanged if
+// name is empty.
+func formatAddress(name, addr string) string {
+	addr = strings.TrimSpace(stripCRLF(addr))
+
+	name = encodeDisplayName(strings.TrimSpace(stripCRLF(name)))
+	if name == "" {
+		return addr
+	}
+
+	return quoteDisplayName(name) + " <" + addr + ">"
+}
+
+// displayNameSpecials are the characters that cannot appear in a display name
+// outside of a quoted-string (RFC 5322 section 3.2.3).
+const displayNameSpecials = "\"(),.:;<>@[\\]"
+
+// encodeDisplayName returns name encoded as an RFC 2047 encoded-word if it
+// contains non-ASCII characters.
+//
+// The Q encoding is used unless name contains ASCII characters that RFC 2047
+// section 5 does not permit in a Q encoded display name, which the B encoding
+// is used for instead.
+func encodeDisplayName(name string) string {
+	if isASCII(name) {
+		return name
+	}
+
+	restricted := strings.IndexFunc(name, func(r rune) bool {
+		if r >= utf8.RuneSelf || r == ' ' || strings.ContainsRune("!*+-/", r) {
+			return false
+		}
+		return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9')
+	}) >= 0
+
+	if restricted {
+		return mime.BEncoding.Encode("UTF-8", name)
+	}
+	return mime.QEncoding.Encode("UTF-8", name)
+}
+
+// quoteDisplayName returns the ASCII display name as a quoted-string if it
+// contains special characters, or unchanged otherwise.
+func quoteDisplayName(name string) string {
+	if !strings.ContainsAny(name, displayNameSpecials) {
+		return name
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(name); i++ {
+		if name[i] == '"' || name[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(name[i])
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
@@ -0,0 +1,90 @@
+package mailyak
+
+import (
+	"net/mail"
+	"reflect"
+	"testing"
+)
+
+func TestFormatAddress(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		displayName string
+		addr        string
+		// Want
+		want string
+	}{
+		{"Plain", "Jane Doe", "jane@example.com", "Jane Doe <jane@example.com>"},
+		{"No name", "", "jane@example.com", "jane@example.com"},
+		{"Whitespace name", "  ", "jane@example.com", "jane@example.com"},
+		{"Specials", "Doe, Jane", "jane@example.com", `"Doe, Jane" <jane@example.com>`},
+		{"Escaped", `Jane "JD" Doe`, "jane@example.com", `"Jane \"JD\" Doe" <jane@example.com>`},
+		{"Atext", "Jane O'Doe", "jane@example.com", "Jane O'Doe <jane@example.com>"},
+		{"Non-ASCII", "Jürgen", "jm@example.com", "=?UTF-8?q?J=C3=BCrgen?= <jm@example.com>"},
+		{"Non-ASCII specials", "Müller, J", "jm@example.com", "=?UTF-8?b?TcO8bGxlciwgSg==?= <jm@example.com>"},
+		{"CRLF", "Jane\r\nBcc: evil@example.com", "jane@example.com\r\n", `"JaneBcc: evil@example.com" <jane@example.com>`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := formatAddress(tt.displayName, tt.addr)
+			if got != tt.want {
+				t.Fatalf("formatAddress() = %q, want %q", got, tt.want)
+			}
+
+			// Ensure the display name survives a round trip
+			parsed, err := mail.ParseAddress(got)
+			if err != nil {
+				t.Fatalf("mail.ParseAddress(%q) error = %v", got, err)
+			}
+			if parsed.Address != tt.addr && parsed.Address+"\r\n" != tt.addr {
+				t.Errorf("mail.ParseAddress(%q) address = %q, want %q", got, parsed.Address, tt.addr)
+			}
+		})
+	}
+}
+
+// TestMailYakToAddresses ensures the address variants of the recipient setters
+// set the recipients with their display names.
+func TestMailYakToAddresses(t *testing.T) {
+	t.Parallel()
+
+	m := New("", nil)
+	m.ToAddresses(
+		&mail.Address{Name: "Jane Doe", Address: "jane@example.com"},
+		nil,
+		&mail.Address{Address: "bob@example.com"},
+	)
+	m.CcWithName("Doe, John", "john@example.com")
+	m.BccWithName("Jürgen", "jm@example.com")
+	m.FromWithName("Dom", "dom@itsallbroken.com")
+
+	wantTo := []string{"Jane Doe <jane@example.com>", "bob@example.com"}
+	if !reflect.DeepEqual(m.toAddrs, wantTo) {
+		t.Errorf("MailYak.ToAddresses() = %q, want %q", m.toAddrs, wantTo)
+	}
+
+	wantCc := []string{`"Doe, John" <john@example.com>`}
+	if !reflect.DeepEqual(m.ccAddrs, wantCc) {
+		t.Errorf("MailYak.CcWithName() = %q, want %q", m.ccAddrs, wantCc)
+	}
+
+	wantBcc := []string{"=?UTF-8?q?J=C3=BCrgen?= <jm@example.com>"}
+	if !reflect.DeepEqual(m.bccAddrs, wantBcc) {
+		t.Errorf("MailYak.BccWithName() = %q, want %q", m.bccAddrs, wantBcc)
+	}
+
+	if m.fromAddr != "dom@itsallbroken.com" || m.fromName != "Dom" {
+		t.Errorf("MailYak.FromWithName() = %q %q, want %q %q", m.fromName, m.fromAddr, "Dom", "dom@itsallbroken.com")
+	}
+
+	if err := m.Validate(); err != nil {
+		t.Errorf("MailYak.Validate() error = %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+package mailyak
+
+import "fmt"
+
+// APIError is returned by the email API transports, such as those in the ses
+// and sendgrid packages, when the API rejects a request.
+type APIError struct {
+	// Service is the name of the email API, such as "SES".
+	Service string
+
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Message describes the error, as returned by the API.
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("mailyak: %s API error (HTTP %d): %s", e.Service, e.StatusCode, e.Message)
+}
@@ -0,0 +1,51 @@
+package mailyak
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"sort"
+)
+
+// DeduplicateAttachments writes identical attachments once when true, so an
+// attachment added more than once, such as a logo attached by each of several
+// shared templates, is sent as a single part referenced by a single
+// Content-ID. Defaults to false.
+//
+// Attachments are identical when they have the same content, filename, MIME
+// type, disposition and part headers, so the parts written would be the same.
+// Content is compared by its SHA-256 hash, which requires each attachment to
+// be read into memory before it is written.
+func (m *MailYak) DeduplicateAttachments(enabled bool) {
+	m.dedupAttachments = enabled
+}
+
+// attachmentKey identifies the part written for an attachment with content
+// data.
+type attachmentKey [sha256.Size]byte
+
+// key returns the attachmentKey of a with content data.
+func (a attachment) key(data []byte) attachmentKey {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q %q %t\n", a.filename, a.mimeType, a.inline)
+	writeHeaderKey(h, a.header)
+	h.Write(data)
+
+	var key attachmentKey
+	h.Sum(key[:0])
+	return key
+}
+
+// writeHeaderKey writes header to h in a consistent order.
+func writeHeaderKey(h hash.Hash, header map[string][]string) {
+	names := make([]string, 0, len(header))
+	for k := range header {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		fmt.Fprintf(h, "%q: %q\n", k, header[k])
+	}
+	h.Write([]byte{'\n'})
+}
@@ -0,0 +1,126 @@
+package mailyak
+
+import (
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// TestMailYakDeduplicateAttachments ensures identical attachments are written
+// once when enabled.
+func TestMailYakDeduplicateAttachments(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		dedup  bool
+		attach func(m *MailYak)
+		// Want
+		wantNames []string
+	}{
+		{
+			name:  "Disabled",
+			dedup: false,
+			attach: func(m *MailYak) {
+				m.Attach("logo.png", strings.NewReader("png"))
+				m.Attach("logo.png", strings.NewReader("png"))
+			},
+			wantNames: []string{"logo.png", "logo.png"},
+		},
+		{
+			name:  "Identical",
+			dedup: true,
+			attach: func(m *MailYak) {
+				m.Attach("logo.png", strings.NewReader("png"))
+				m.Attach("report.pdf", strings.NewReader("pdf"))
+				m.Attach("logo.png", strings.NewReader("png"))
+				m.Attach("logo.png", strings.NewReader("png"))
+			},
+			wantNames: []string{"logo.png", "report.pdf"},
+		},
+		{
+			name:  "Identical inline",
+			dedup: true,
+			attach: func(m *MailYak) {
+				m.AttachInline("logo.png", strings.NewReader("png"))
+				m.AttachInline("logo.png", strings.NewReader("png"))
+			},
+			wantNames: []string{"logo.png"},
+		},
+		{
+			name:  "Different content",
+			dedup: true,
+			attach: func(m *MailYak) {
+				m.Attach("logo.png", strings.NewReader("png"))
+				m.Attach("logo.png", strings.NewReader("new png"))
+			},
+			wantNames: []string{"logo.png", "logo.png"},
+		},
+		{
+			name:  "Different filename",
+			dedup: true,
+			attach: func(m *MailYak) {
+				m.Attach("logo.png", strings.NewReader("png"))
+				m.Attach("copy.png", strings.NewReader("png"))
+			},
+			wantNames: []string{"logo.png", "copy.png"},
+		},
+		{
+			name:  "Different MIME type",
+			dedup: true,
+			attach: func(m *MailYak) {
+				m.AttachWithMimeType("logo", strings.NewReader("png"), "image/png")
+				m.AttachWithMimeType("logo", strings.NewReader("png"), "application/octet-stream")
+			},
+			wantNames: []string{"logo", "logo"},
+		},
+		{
+			name:  "Different disposition",
+			dedup: true,
+			attach: func(m *MailYak) {
+				m.Attach("logo.png", strings.NewReader("png"))
+				m.AttachInline("logo.png", strings.NewReader("png"))
+			},
+			wantNames: []string{"logo.png", "logo.png"},
+		},
+		{
+			name:  "Different headers",
+			dedup: true,
+			attach: func(m *MailYak) {
+				m.AttachPart("logo.png", strings.NewReader("png"), textproto.MIMEHeader{"Content-Id": {"<a>"}})
+				m.AttachPart("logo.png", strings.NewReader("png"), textproto.MIMEHeader{"Content-Id": {"<b>"}})
+				m.AttachPart("logo.png", strings.NewReader("png"), textproto.MIMEHeader{"Content-Id": {"<a>"}})
+			},
+			wantNames: []string{"logo.png", "logo.png"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := MailYak{}
+			m.DeduplicateAttachments(tt.dedup)
+			tt.attach(&m)
+
+			pc := testPartCreator{}
+			if err := m.writeAttachments(&pc, nopBuilder{}); err != nil {
+				t.Fatalf("MailYak.writeAttachments() error = %v", err)
+			}
+
+			if len(pc.attachments) != len(tt.wantNames) {
+				t.Fatalf("MailYak.writeAttachments() wrote %d attachments, want %d", len(pc.attachments), len(tt.wantNames))
+			}
+			for i, a := range pc.attachments {
+				if want := "filename=\"" + tt.wantNames[i] + "\""; !strings.Contains(a.disposition, want) {
+					t.Errorf("MailYak.writeAttachments() attachment %d disposition = %q, want %s", i, a.disposition, want)
+				}
+				if a.data.Len() == 0 {
+					t.Errorf("MailYak.writeAttachments() attachment %d has no content", i)
+				}
+			}
+		})
+	}
+}
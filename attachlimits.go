@@ -0,0 +1,186 @@
+package mailyak
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+var (
+	// ErrTooManyAttachments is wrapped by the *AttachmentLimitError returned
+	// when an email has more attachments than AttachmentLimits.MaxCount.
+	ErrTooManyAttachments = errors.New("mailyak: too many attachments")
+
+	// ErrAttachmentTooLarge is wrapped by the *AttachmentLimitError returned
+	// when an attachment is larger than AttachmentLimits.MaxSize.
+	ErrAttachmentTooLarge = errors.New("mailyak: attachment too large")
+
+	// ErrAttachmentsTooLarge is wrapped by the *AttachmentLimitError returned
+	// when the attachments of an email are larger in total than
+	// AttachmentLimits.MaxTotalSize.
+	ErrAttachmentsTooLarge = errors.New("mailyak: attachments too large")
+)
+
+// AttachmentLimits bounds the number and size of the attachments of an email,
+// such as when attaching files uploaded by users.
+//
+// Sizes are of the attachment content before encoding, in bytes. A zero value
+// disables the respective limit.
+type AttachmentLimits struct {
+	// MaxCount is the maximum number of attachments.
+	MaxCount int
+
+	// MaxSize is the maximum size of each attachment.
+	MaxSize int64
+
+	// MaxTotalSize is the maximum combined size of all attachments.
+	MaxTotalSize int64
+}
+
+// AttachmentLimitError is returned when the attachments of an email exceed
+// the configured AttachmentLimits, allowing a friendly message to be shown:
+//
+//	var limitErr *mailyak.AttachmentLimitError
+//	if errors.As(err, &limitErr) && errors.Is(err, mailyak.ErrAttachmentTooLarge) {
+//		return fmt.Errorf("%s is larger than %d MB", limitErr.Filename, limitErr.Limit>>20)
+//	}
+type AttachmentLimitError struct {
+	// Err is the limit exceeded - one of ErrTooManyAttachments,
+	// ErrAttachmentTooLarge or ErrAttachmentsTooLarge.
+	Err error
+
+	// Filename is the name of the attachment exceeding the limit, or empty
+	// for ErrTooManyAttachments.
+	Filename string
+
+	// Size is the number of attachments for ErrTooManyAttachments, or the
+	// size in bytes otherwise. When the limit is exceeded while reading an
+	// attachment, Size is the number of bytes read so far.
+	Size int64
+
+	// Limit is the limit exceeded.
+	Limit int64
+}
+
+func (e *AttachmentLimitError) Error() string {
+	if e.Err == ErrTooManyAttachments {
+		return fmt.Sprintf("%v: %d attachments, limit is %d", e.Err, e.Size, e.Limit)
+	}
+	return fmt.Sprintf("%v: %q exceeds limit of %d bytes", e.Err, e.Filename, e.Limit)
+}
+
+// Unwrap returns the limit exceeded.
+func (e *AttachmentLimitError) Unwrap() error { return e.Err }
+
+// AttachmentLimits sets the limits on the number and size of the attachments
+// of the email.
+//
+// The limits are checked by AttachFS and Validate (called by Send) for
+// attachments of a known size - files, and the content of a *bytes.Reader,
+// *bytes.Buffer or *strings.Reader. The size of other attachments is checked
+// as they are read when the email is built, failing with an
+// *AttachmentLimitError once exceeded.
+func (m *MailYak) AttachmentLimits(l AttachmentLimits) {
+	m.attachmentLimits = l
+}
+
+// checkAttachments returns an *AttachmentLimitError if attachments exceed the
+// limits of m, checking the size of attachments of a known size.
+func (m *MailYak) checkAttachments(attachments []attachment) error {
+	l := m.attachmentLimits
+
+	if l.MaxCount > 0 && len(attachments) > l.MaxCount {
+		return &AttachmentLimitError{Err: ErrTooManyAttachments, Size: int64(len(attachments)), Limit: int64(l.MaxCount)}
+	}
+
+	if l.MaxSize <= 0 && l.MaxTotalSize <= 0 {
+		return nil
+	}
+
+	var total int64
+	for _, a := range attachments {
+		size, ok := knownSize(a.content)
+		if !ok {
+			continue
+		}
+
+		if l.MaxSize > 0 && size > l.MaxSize {
+			return &AttachmentLimitError{Err: ErrAttachmentTooLarge, Filename: a.filename, Size: size, Limit: l.MaxSize}
+		}
+
+		total += size
+		if l.MaxTotalSize > 0 && total > l.MaxTotalSize {
+			return &AttachmentLimitError{Err: ErrAttachmentsTooLarge, Filename: a.filename, Size: total, Limit: l.MaxTotalSize}
+		}
+	}
+
+	return nil
+}
+
+// knownSize returns the number of bytes remaining to be read from r, if it can
+// be determined without reading it.
+func knownSize(r io.Reader) (int64, bool) {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len()), true
+
+	case *fsReader:
+		if r.file != nil || r.done {
+			return 0, false
+		}
+		info, err := fs.Stat(r.fsys, r.path)
+		if err != nil {
+			return 0, false
+		}
+		return info.Size(), true
+
+	case interface{ Stat() (fs.FileInfo, error) }:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0, false
+		}
+		return info.Size(), true
+	}
+
+	return 0, false
+}
+
+// limitedAttachment is an io.Reader failing with an *AttachmentLimitError once
+// more than the permitted size of an attachment has been read.
+type limitedAttachment struct {
+	r        io.Reader
+	filename string
+	limits   AttachmentLimits
+
+	// read is the number of bytes read from r, and total the number of bytes
+	// read from all attachments of the email
+	read  int64
+	total *int64
+}
+
+// limitAttachment returns the content of a limited to the attachment size
+// limits of m, adding the bytes read to total.
+func (m *MailYak) limitAttachment(a attachment, total *int64) io.Reader {
+	l := m.attachmentLimits
+	if l.MaxSize <= 0 && l.MaxTotalSize <= 0 {
+		return a.content
+	}
+
+	return &limitedAttachment{r: a.content, filename: a.filename, limits: l, total: total}
+}
+
+func (l *limitedAttachment) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	*l.total += int64(n)
+
+	if l.limits.MaxSize > 0 && l.read > l.limits.MaxSize {
+		return n, &AttachmentLimitError{Err: ErrAttachmentTooLarge, Filename: l.filename, Size: l.read, Limit: l.limits.MaxSize}
+	}
+	if l.limits.MaxTotalSize > 0 && *l.total > l.limits.MaxTotalSize {
+		return n, &AttachmentLimitError{Err: ErrAttachmentsTooLarge, Filename: l.filename, Size: *l.total, Limit: l.limits.MaxTotalSize}
+	}
+
+	return n, err
+}
@@ -0,0 +1,188 @@
+package mailyak
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// unknownSize hides the size of the content of r.
+func unknownSize(s string) io.Reader {
+	return io.MultiReader(strings.NewReader(s))
+}
+
+// TestMailYakAttachmentLimits ensures attachment limits are enforced when
+// validating attachments of a known size, and when building the email.
+func TestMailYakAttachmentLimits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		limits AttachmentLimits
+		attach map[string]io.Reader
+		// Want
+		wantValidate error
+		wantBuild    error
+		wantFilename string
+	}{
+		{
+			name:   "No limits",
+			attach: map[string]io.Reader{"a.txt": strings.NewReader(strings.Repeat("a", 1000))},
+		},
+		{
+			name:   "Within limits",
+			limits: AttachmentLimits{MaxCount: 1, MaxSize: 1000, MaxTotalSize: 1000},
+			attach: map[string]io.Reader{"a.txt": strings.NewReader(strings.Repeat("a", 1000))},
+		},
+		{
+			name:   "Within limits unknown size",
+			limits: AttachmentLimits{MaxSize: 1000, MaxTotalSize: 1000},
+			attach: map[string]io.Reader{"a.txt": unknownSize(strings.Repeat("a", 1000))},
+		},
+		{
+			name:   "Too many",
+			limits: AttachmentLimits{MaxCount: 1},
+			attach: map[string]io.Reader{
+				"a.txt": strings.NewReader("a"),
+				"b.txt": unknownSize("b"),
+			},
+			wantValidate: ErrTooManyAttachments,
+			wantBuild:    ErrTooManyAttachments,
+		},
+		{
+			name:         "Too large",
+			limits:       AttachmentLimits{MaxSize: 10},
+			attach:       map[string]io.Reader{"a.txt": strings.NewReader(strings.Repeat("a", 11))},
+			wantValidate: ErrAttachmentTooLarge,
+			wantBuild:    ErrAttachmentTooLarge,
+			wantFilename: "a.txt",
+		},
+		{
+			name:         "Too large unknown size",
+			limits:       AttachmentLimits{MaxSize: 1000},
+			attach:       map[string]io.Reader{"a.txt": unknownSize(strings.Repeat("a", 1001))},
+			wantBuild:    ErrAttachmentTooLarge,
+			wantFilename: "a.txt",
+		},
+		{
+			name:   "Total too large",
+			limits: AttachmentLimits{MaxSize: 10, MaxTotalSize: 15},
+			attach: map[string]io.Reader{
+				"a.txt": strings.NewReader(strings.Repeat("a", 10)),
+				"b.txt": strings.NewReader(strings.Repeat("b", 10)),
+			},
+			wantValidate: ErrAttachmentsTooLarge,
+			wantBuild:    ErrAttachmentsTooLarge,
+		},
+		{
+			name:   "Total too large unknown size",
+			limits: AttachmentLimits{MaxTotalSize: 1500},
+			attach: map[string]io.Reader{
+				"a.txt": unknownSize(strings.Repeat("a", 1000)),
+				"b.txt": unknownSize(strings.Repeat("b", 1000)),
+			},
+			wantBuild: ErrAttachmentsTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.AttachmentLimits(tt.limits)
+			m.From("from@example.org")
+			for name, r := range tt.attach {
+				m.Attach(name, r)
+			}
+
+			err := m.Validate()
+			if !errors.Is(err, tt.wantValidate) {
+				t.Fatalf("MailYak.Validate() error = %v, want %v", err, tt.wantValidate)
+			}
+
+			_, err = m.WriteTo(ioutil.Discard)
+			if !errors.Is(err, tt.wantBuild) {
+				t.Fatalf("MailYak.WriteTo() error = %v, want %v", err, tt.wantBuild)
+			}
+
+			if tt.wantFilename != "" {
+				var limitErr *AttachmentLimitError
+				if !errors.As(err, &limitErr) || limitErr.Filename != tt.wantFilename {
+					t.Errorf("MailYak.WriteTo() error = %v, want filename %q", err, tt.wantFilename)
+				}
+			}
+		})
+	}
+}
+
+// TestMailYakAttachFS_limits ensures AttachFS rejects files exceeding the
+// attachment limits without attaching any.
+func TestMailYakAttachFS_limits(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"small.txt": {Data: []byte("small")},
+		"large.txt": {Data: []byte(strings.Repeat("a", 100))},
+	}
+
+	m := New("", nil)
+	m.AttachmentLimits(AttachmentLimits{MaxSize: 50})
+
+	err := m.AttachFS(fsys, "small.txt", "large.txt")
+
+	var limitErr *AttachmentLimitError
+	if !errors.As(err, &limitErr) || !errors.Is(err, ErrAttachmentTooLarge) {
+		t.Fatalf("MailYak.AttachFS() error = %v, want %v", err, ErrAttachmentTooLarge)
+	}
+	if limitErr.Filename != "large.txt" || limitErr.Size != 100 || limitErr.Limit != 50 {
+		t.Errorf("MailYak.AttachFS() error = %+v, want large.txt of 100 bytes", limitErr)
+	}
+
+	if len(m.attachments) != 0 {
+		t.Errorf("MailYak.AttachFS() attached %d files, want 0", len(m.attachments))
+	}
+
+	if err := m.AttachFS(fsys, "small.txt"); err != nil {
+		t.Fatalf("MailYak.AttachFS() error = %v", err)
+	}
+}
+
+func TestAttachmentLimitError_Error(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		err *AttachmentLimitError
+		// Want
+		want string
+	}{
+		{
+			"Count",
+			&AttachmentLimitError{Err: ErrTooManyAttachments, Size: 3, Limit: 2},
+			"mailyak: too many attachments: 3 attachments, limit is 2",
+		},
+		{
+			"Size",
+			&AttachmentLimitError{Err: ErrAttachmentTooLarge, Filename: "a.pdf", Size: 3, Limit: 2},
+			`mailyak: attachment too large: "a.pdf" exceeds limit of 2 bytes`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("AttachmentLimitError.Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
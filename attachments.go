@@ -1,11 +1,17 @@
 package mailyak
 
 import (
+	"bytes"
 	"encoding/base64"
 	"fmt"
 	"io"
+	"io/fs"
+	"mime"
 	"net/http"
 	"net/textproto"
+	"path"
+	"strings"
+	"unicode/utf8"
 )
 
 // DetectContentType needs at most 512 bytes
@@ -24,6 +30,7 @@ type attachment struct {
 	content  io.Reader
 	inline   bool
 	mimeType string
+	header   textproto.MIMEHeader
 }
 
 // Attach adds the contents of r to the email as an attachment with name as the
@@ -92,17 +99,178 @@ func (m *MailYak) AttachInlineWithMimeType(name string, r io.Reader, mimeType st
 	})
 }
 
+// AttachPart adds the contents of r to the email as an attachment with name as
+// the filename, setting the MIME part headers in header.
+//
+// Headers in header are added to the part, replacing the default
+// Content-Type, Content-Disposition and Content-ID headers if set. This allows
+// setting a Content-Description, overriding the Content-Disposition
+// parameters, or adding provider specific headers:
+//
+//	mail.AttachPart("report.pdf", r, textproto.MIMEHeader{
+//		"Content-Description": {"Quarterly report"},
+//		"Content-Disposition": {`attachment; filename="report.pdf"; size=1024`},
+//		"X-Attachment-Id":     {"report"},
+//	})
+//
+// Attachments are base64 encoded (except message/rfc822 attachments) unless a
+// Content-Transfer-Encoding of "base64", "quoted-printable", "7bit" or "8bit"
+// is set in header. An unsupported encoding causes Send to return an error.
+//
+// r is not read until Send is called, and if no Content-Type is set in header
+// the MIME type will be detected using
+// https://golang.org/pkg/net/http/#DetectContentType
+func (m *MailYak) AttachPart(name string, r io.Reader, header textproto.MIMEHeader) {
+	m.attachments = append(m.attachments, attachment{
+		filename: name,
+		content:  r,
+		inline:   false,
+		header:   header,
+	})
+}
+
+// AttachFS adds the files at paths within fsys to the email as attachments,
+// such as assets embedded with go:embed:
+//
+//	//go:embed invoice.pdf logo.png
+//	var assets embed.FS
+//
+//	err := mail.AttachFS(assets, "invoice.pdf", "logo.png")
+//
+// Each attachment is named after the base name of its path, and the MIME type
+// is inferred from the file extension, falling back to detecting it from the
+// content.
+//
+// AttachFS returns an error if any path does not exist or is a directory, or
+// the files exceed the AttachmentLimits of the email, in which case no files
+// are attached. Files are not opened until Send is called.
+func (m *MailYak) AttachFS(fsys fs.FS, paths ...string) error {
+	var attachments []attachment
+	for _, p := range paths {
+		info, err := fs.Stat(fsys, p)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return fmt.Errorf("mailyak: cannot attach directory %q", p)
+		}
+
+		attachments = append(attachments, attachment{
+			filename: path.Base(p),
+			content:  &fsReader{fsys: fsys, path: p},
+			inline:   false,
+			mimeType: mime.TypeByExtension(path.Ext(p)),
+		})
+	}
+
+	attachments = append(m.attachments[:len(m.attachments):len(m.attachments)], attachments...)
+	if err := m.checkAttachments(attachments); err != nil {
+		return err
+	}
+
+	m.attachments = attachments
+	return nil
+}
+
+// fsReader reads the file at path within fsys, opening it on the first call to
+// Read and closing it once it has been read.
+type fsReader struct {
+	fsys fs.FS
+	path string
+	file fs.File
+	done bool
+}
+
+func (r *fsReader) Read(p []byte) (int, error) {
+	if r.done {
+		return 0, io.EOF
+	}
+
+	if r.file == nil {
+		f, err := r.fsys.Open(r.path)
+		if err != nil {
+			r.done = true
+			return 0, err
+		}
+		r.file = f
+	}
+
+	n, err := r.file.Read(p)
+	if err != nil {
+		r.done = true
+		r.file.Close()
+	}
+
+	return n, err
+}
+
+// AttachMessage adds the raw email read from r to the email as a
+// message/rfc822 attachment with name as the filename, typically ending in
+// ".eml". This is useful for forwarding an original message intact, such as in
+// bounce handlers or ticketing systems.
+//
+// The message is attached without being base64 encoded, as required by RFC
+// 2046, with any LF line endings converted to CRLF. r is not read until Send is
+// called.
+func (m *MailYak) AttachMessage(name string, r io.Reader) {
+	m.attachments = append(m.attachments, attachment{
+		filename: name,
+		content:  r,
+		inline:   false,
+		mimeType: "message/rfc822",
+	})
+}
+
 // ClearAttachments removes all current attachments.
 func (m *MailYak) ClearAttachments() {
 	m.attachments = []attachment{}
 }
 
 // writeAttachments loops over the attachments, guesses their content-type and
-// writes the data as a line-broken base64 string (using the splitter mutator).
+// writes the data with the transfer encoding of each attachment, typically as
+// a line-broken base64 string (using the splitter mutator).
+//
+// Attached messages are written unencoded, as required by RFC 2046.
 func (m *MailYak) writeAttachments(mixed partCreator, splitter writeWrapper) error {
+	if err := m.checkAttachments(m.attachments); err != nil {
+		return err
+	}
+
 	h := make([]byte, sniffLen)
 
+	// total is the number of bytes read from all attachments
+	var total int64
+
+	// written holds the attachments already written when deduplicating
+	var written map[attachmentKey]bool
+	if m.dedupAttachments {
+		written = make(map[attachmentKey]bool, len(m.attachments))
+	}
+
 	for _, item := range m.attachments {
+		// prevent the filename and MIME type injecting additional headers
+		item.filename = stripCRLF(item.filename)
+		item.mimeType = stripCRLF(item.mimeType)
+
+		// enforce the size limits on attachments of an unknown size
+		item.content = m.limitAttachment(item, &total)
+
+		if written != nil {
+			data, err := io.ReadAll(item.content)
+			if err != nil {
+				return err
+			}
+
+			key := item.key(data)
+			if written[key] {
+				continue
+			}
+			written[key] = true
+
+			item.content = bytes.NewReader(data)
+		}
+
 		hLen, err := io.ReadFull(item.content, h)
 		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 			return err
@@ -112,14 +280,25 @@ func (m *MailYak) writeAttachments(mixed partCreator, splitter writeWrapper) err
 			item.mimeType = http.DetectContentType(h[:hLen])
 		}
 
-		ctype := fmt.Sprintf("%s;\n\tfilename=%q", item.mimeType, item.filename)
+		ctype := fmt.Sprintf("%s;\r\n\t%s", item.mimeType, filenameParams(item.filename))
+
+		cte, err := item.transferEncoding()
+		if err != nil {
+			return err
+		}
 
-		part, err := mixed.CreatePart(getMIMEHeader(item, ctype))
+		part, err := mixed.CreatePart(getMIMEHeader(item, ctype, string(cte)))
 		if err != nil {
 			return err
 		}
 
-		encoder := base64.NewEncoder(base64.StdEncoding, splitter.new(part))
+		var encoder io.WriteCloser
+		if cte == EncodingBase64 {
+			encoder = base64.NewEncoder(base64.StdEncoding, splitter.new(part))
+		} else if encoder, err = cte.encoder(part, strings.HasPrefix(item.mimeType, "text/")); err != nil {
+			return err
+		}
+
 		if _, err := encoder.Write(h[:hLen]); err != nil {
 			return err
 		}
@@ -139,27 +318,151 @@ func (m *MailYak) writeAttachments(mixed partCreator, splitter writeWrapper) err
 	return nil
 }
 
-func getMIMEHeader(a attachment, ctype string) textproto.MIMEHeader {
+// isMessageType returns true if mimeType is message/rfc822, ignoring any
+// parameters.
+func isMessageType(mimeType string) bool {
+	mediaType := strings.SplitN(mimeType, ";", 2)[0]
+	return strings.EqualFold(strings.TrimSpace(mediaType), "message/rfc822")
+}
+
+// transferEncoding returns the Content-Transfer-Encoding of the attachment,
+// either set in the part headers or selected automatically.
+//
+// Attached messages must not be base64 or quoted-printable encoded, as required
+// by RFC 2046.
+func (a attachment) transferEncoding() (TransferEncoding, error) {
+	cte := EncodingBase64
+	if isMessageType(a.mimeType) {
+		cte = Encoding8Bit
+	}
+
+	for k, v := range a.header {
+		if !strings.EqualFold(k, "Content-Transfer-Encoding") || len(v) == 0 {
+			continue
+		}
+
+		var err error
+		if cte, err = parseTransferEncoding(v[0]); err != nil {
+			return "", err
+		}
+	}
+
+	if isMessageType(a.mimeType) && cte != Encoding7Bit && cte != Encoding8Bit {
+		return "", fmt.Errorf("mailyak: %s attachment %q cannot be %s encoded", a.mimeType, a.filename, cte)
+	}
+
+	return cte, nil
+}
+
+// nopWriteCloser wraps an io.Writer with a no-op Close method.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func getMIMEHeader(a attachment, ctype, cte string) textproto.MIMEHeader {
 	var disp string
 	var header textproto.MIMEHeader
 
 	if a.inline {
-		disp = fmt.Sprintf("inline;\n\tfilename=%q", a.filename)
+		disp = fmt.Sprintf("inline;\r\n\t%s", filenameParams(a.filename))
 		header = textproto.MIMEHeader{
 			"Content-Type":              {ctype},
 			"Content-Disposition":       {disp},
-			"Content-Transfer-Encoding": {"base64"},
+			"Content-Transfer-Encoding": {cte},
 		}
 	} else {
-		disp = fmt.Sprintf("attachment;\n\tfilename=%q", a.filename)
+		disp = fmt.Sprintf("attachment;\r\n\t%s", filenameParams(a.filename))
 		cid := fmt.Sprintf("<%s>", a.filename)
 		header = textproto.MIMEHeader{
 			"Content-Type":              {ctype},
 			"Content-Disposition":       {disp},
-			"Content-Transfer-Encoding": {"base64"},
+			"Content-Transfer-Encoding": {cte},
 			"Content-ID":                {cid},
 		}
 	}
 
+	for k, v := range a.header {
+		if strings.EqualFold(k, "Content-Transfer-Encoding") {
+			continue
+		}
+
+		// Replace any default header, regardless of case
+		for existing := range header {
+			if strings.EqualFold(existing, k) {
+				delete(header, existing)
+			}
+		}
+		values := make([]string, len(v))
+		for i := range v {
+			values[i] = stripCRLF(v[i])
+		}
+		header[textproto.CanonicalMIMEHeaderKey(headerName(k))] = values
+	}
+
 	return header
 }
+
+// stripCRLF returns s with any CR and LF characters removed, preventing a
+// value written to a header from starting a new header.
+func stripCRLF(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// filenameParams returns the filename parameter for the Content-Type and
+// Content-Disposition headers.
+//
+// Non-ASCII filenames are encoded as an RFC 2231 filename* parameter, preceded
+// by an RFC 2047 encoded-word filename for clients that do not support it.
+func filenameParams(name string) string {
+	if isASCII(name) {
+		return fmt.Sprintf("filename=%q", name)
+	}
+
+	return fmt.Sprintf(
+		"filename=\"%s\";\r\n\tfilename*=UTF-8''%s",
+		mime.QEncoding.Encode("UTF-8", name),
+		percentEncode(name),
+	)
+}
+
+// isASCII returns true if s contains only ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// percentEncode encodes s as an RFC 2231 extended parameter value, escaping
+// all bytes other than the attribute-char set.
+func percentEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isAttributeChar(c) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// isAttributeChar returns true if c may appear unescaped in an RFC 2231
+// parameter value.
+func isAttributeChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
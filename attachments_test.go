@@ -5,13 +5,16 @@ import (
 	"encoding/base64"
 	"io"
 	"net/textproto"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 type testAttachment struct {
 	contentType string
 	disposition string
+	header      textproto.MIMEHeader
 	data        bytes.Buffer
 }
 
@@ -24,6 +27,7 @@ func (t *testPartCreator) CreatePart(header textproto.MIMEHeader) (io.Writer, er
 	a := &testAttachment{
 		contentType: header.Get("Content-Type"),
 		disposition: header.Get("Content-Disposition"),
+		header:      header,
 	}
 
 	t.attachments = append(t.attachments, a)
@@ -71,7 +75,7 @@ func TestMailYakAttach(t *testing.T) {
 		},
 		{
 			"From one",
-			[]attachment{{"Existing", &bytes.Buffer{}, false, ""}},
+			[]attachment{{"Existing", &bytes.Buffer{}, false, "", nil}},
 			"test",
 			&bytes.Buffer{},
 			2,
@@ -117,7 +121,7 @@ func TestMailYakAttachInline(t *testing.T) {
 		},
 		{
 			"From one",
-			[]attachment{{"Existing", &bytes.Buffer{}, false, ""}},
+			[]attachment{{"Existing", &bytes.Buffer{}, false, "", nil}},
 			"test",
 			&bytes.Buffer{},
 			2,
@@ -165,7 +169,7 @@ func TestMailYakAttachWithMimeType(t *testing.T) {
 		},
 		{
 			"From one",
-			[]attachment{{"Existing", &bytes.Buffer{}, false, "text/csv; charset=utf-8"}},
+			[]attachment{{"Existing", &bytes.Buffer{}, false, "text/csv; charset=utf-8", nil}},
 			"test",
 			&bytes.Buffer{},
 			"text/csv; charset=utf-8",
@@ -214,7 +218,7 @@ func TestMailYakAttachInlineWithMimeType(t *testing.T) {
 		},
 		{
 			"From one",
-			[]attachment{{"Existing", &bytes.Buffer{}, false, "text/csv; charset=utf-8"}},
+			[]attachment{{"Existing", &bytes.Buffer{}, false, "text/csv; charset=utf-8", nil}},
 			"test",
 			&bytes.Buffer{},
 			"text/csv; charset=utf-8",
@@ -254,33 +258,33 @@ func TestMailYakWriteAttachments(t *testing.T) {
 	}{
 		{
 			"Empty",
-			[]attachment{{"Empty", &bytes.Buffer{}, false, ""}},
-			"text/plain; charset=utf-8;\n\tfilename=\"Empty\"",
-			"attachment;\n\tfilename=\"Empty\"",
+			[]attachment{{"Empty", &bytes.Buffer{}, false, "", nil}},
+			"text/plain; charset=utf-8;\r\n\tfilename=\"Empty\"",
+			"attachment;\r\n\tfilename=\"Empty\"",
 			"",
 			false,
 		},
 		{
 			"Short string",
-			[]attachment{{"advice", strings.NewReader("Don't Panic"), false, ""}},
-			"text/plain; charset=utf-8;\n\tfilename=\"advice\"",
-			"attachment;\n\tfilename=\"advice\"",
+			[]attachment{{"advice", strings.NewReader("Don't Panic"), false, "", nil}},
+			"text/plain; charset=utf-8;\r\n\tfilename=\"advice\"",
+			"attachment;\r\n\tfilename=\"advice\"",
 			"RG9uJ3QgUGFuaWM=",
 			false,
 		},
 		{
 			"Space in filename",
-			[]attachment{{"Empty with spaces", &bytes.Buffer{}, false, ""}},
-			"text/plain; charset=utf-8;\n\tfilename=\"Empty with spaces\"",
-			"attachment;\n\tfilename=\"Empty with spaces\"",
+			[]attachment{{"Empty with spaces", &bytes.Buffer{}, false, "", nil}},
+			"text/plain; charset=utf-8;\r\n\tfilename=\"Empty with spaces\"",
+			"attachment;\r\n\tfilename=\"Empty with spaces\"",
 			"",
 			false,
 		},
 		{
 			"With specified MIME type",
-			[]attachment{{"Empty with spaces", &bytes.Buffer{}, false, "text/csv; charset=utf-8"}},
-			"text/csv; charset=utf-8;\n\tfilename=\"Empty with spaces\"",
-			"attachment;\n\tfilename=\"Empty with spaces\"",
+			[]attachment{{"Empty with spaces", &bytes.Buffer{}, false, "text/csv; charset=utf-8", nil}},
+			"text/csv; charset=utf-8;\r\n\tfilename=\"Empty with spaces\"",
+			"attachment;\r\n\tfilename=\"Empty with spaces\"",
 			"",
 			false,
 		},
@@ -295,10 +299,11 @@ func TestMailYakWriteAttachments(t *testing.T) {
 					),
 					false,
 					"",
+					nil,
 				},
 			},
-			"text/plain; charset=utf-8;\n\tfilename=\"partyinvite.txt\"",
-			"attachment;\n\tfilename=\"partyinvite.txt\"",
+			"text/plain; charset=utf-8;\r\n\tfilename=\"partyinvite.txt\"",
+			"attachment;\r\n\tfilename=\"partyinvite.txt\"",
 			"SWYgQmFsZHJpY2sgc2VydmVkIGEgbWVhbCBhdCBIUSBoZSB3b3VsZCBiZSBhcnJlc3Rl" +
 				"ZCBmb3IgdGhlIGJpZ2dlc3QgbWFzcyBwb2lzb25pbmcgc2luY2UgTHVjcmV0aWEgQm9y" +
 				"Z2lhIGludml0ZWQgNTAwIGZyaWVuZHMgZm9yIGEgV2luZSBhbmQgQW50aHJheCBQYXJ0eS4=",
@@ -323,10 +328,11 @@ func TestMailYakWriteAttachments(t *testing.T) {
 					),
 					false,
 					"",
+					nil,
 				},
 			},
-			"text/plain; charset=utf-8;\n\tfilename=\"qed.txt\"",
-			"attachment;\n\tfilename=\"qed.txt\"",
+			"text/plain; charset=utf-8;\r\n\tfilename=\"qed.txt\"",
+			"attachment;\r\n\tfilename=\"qed.txt\"",
 			"Tm93IGl0IGlzIHN1Y2ggYSBiaXphcnJlbHkgaW1wcm9iYWJsZSBjb2luY2lkZW5jZSB0a" +
 				"GF0IGFueXRoaW5nIHNvIG1pbmQtYm9nZ2xpbmdseSB1c2VmdWwgY291bGQgaGF2ZSBldm" +
 				"9sdmVkIHB1cmVseSBieSBjaGFuY2UgdGhhdCBzb21lIHRoaW5rZXJzIGhhdmUgY2hvc2V" +
@@ -346,17 +352,17 @@ func TestMailYakWriteAttachments(t *testing.T) {
 		},
 		{
 			"HTML",
-			[]attachment{{"name.html", strings.NewReader("<html><head></head></html>"), false, ""}},
-			"text/html; charset=utf-8;\n\tfilename=\"name.html\"",
-			"attachment;\n\tfilename=\"name.html\"",
+			[]attachment{{"name.html", strings.NewReader("<html><head></head></html>"), false, "", nil}},
+			"text/html; charset=utf-8;\r\n\tfilename=\"name.html\"",
+			"attachment;\r\n\tfilename=\"name.html\"",
 			"PGh0bWw+PGhlYWQ+PC9oZWFkPjwvaHRtbD4=",
 			false,
 		},
 		{
 			"HTML - wrong extension",
-			[]attachment{{"name.png", strings.NewReader("<html><head></head></html>"), false, ""}},
-			"text/html; charset=utf-8;\n\tfilename=\"name.png\"",
-			"attachment;\n\tfilename=\"name.png\"",
+			[]attachment{{"name.png", strings.NewReader("<html><head></head></html>"), false, "", nil}},
+			"text/html; charset=utf-8;\r\n\tfilename=\"name.png\"",
+			"attachment;\r\n\tfilename=\"name.png\"",
 			"PGh0bWw+PGhlYWQ+PC9oZWFkPjwvaHRtbD4=",
 			false,
 		},
@@ -364,17 +370,17 @@ func TestMailYakWriteAttachments(t *testing.T) {
 		// inline attachments
 		{
 			"Empty inline",
-			[]attachment{{"Empty", &bytes.Buffer{}, true, ""}},
-			"text/plain; charset=utf-8;\n\tfilename=\"Empty\"",
-			"inline;\n\tfilename=\"Empty\"",
+			[]attachment{{"Empty", &bytes.Buffer{}, true, "", nil}},
+			"text/plain; charset=utf-8;\r\n\tfilename=\"Empty\"",
+			"inline;\r\n\tfilename=\"Empty\"",
 			"",
 			false,
 		},
 		{
 			"Short string inline",
-			[]attachment{{"advice", strings.NewReader("Don't Panic"), true, ""}},
-			"text/plain; charset=utf-8;\n\tfilename=\"advice\"",
-			"inline;\n\tfilename=\"advice\"",
+			[]attachment{{"advice", strings.NewReader("Don't Panic"), true, "", nil}},
+			"text/plain; charset=utf-8;\r\n\tfilename=\"advice\"",
+			"inline;\r\n\tfilename=\"advice\"",
 			"RG9uJ3QgUGFuaWM=",
 			false,
 		},
@@ -389,10 +395,11 @@ func TestMailYakWriteAttachments(t *testing.T) {
 					),
 					true,
 					"",
+					nil,
 				},
 			},
-			"text/plain; charset=utf-8;\n\tfilename=\"partyinvite.txt\"",
-			"inline;\n\tfilename=\"partyinvite.txt\"",
+			"text/plain; charset=utf-8;\r\n\tfilename=\"partyinvite.txt\"",
+			"inline;\r\n\tfilename=\"partyinvite.txt\"",
 			"SWYgQmFsZHJpY2sgc2VydmVkIGEgbWVhbCBhdCBIUSBoZSB3b3VsZCBiZSBhcnJlc3Rl" +
 				"ZCBmb3IgdGhlIGJpZ2dlc3QgbWFzcyBwb2lzb25pbmcgc2luY2UgTHVjcmV0aWEgQm9y" +
 				"Z2lhIGludml0ZWQgNTAwIGZyaWVuZHMgZm9yIGEgV2luZSBhbmQgQW50aHJheCBQYXJ0eS4=",
@@ -417,10 +424,11 @@ func TestMailYakWriteAttachments(t *testing.T) {
 					),
 					true,
 					"",
+					nil,
 				},
 			},
-			"text/plain; charset=utf-8;\n\tfilename=\"qed.txt\"",
-			"inline;\n\tfilename=\"qed.txt\"",
+			"text/plain; charset=utf-8;\r\n\tfilename=\"qed.txt\"",
+			"inline;\r\n\tfilename=\"qed.txt\"",
 			"Tm93IGl0IGlzIHN1Y2ggYSBiaXphcnJlbHkgaW1wcm9iYWJsZSBjb2luY2lkZW5jZSB0a" +
 				"GF0IGFueXRoaW5nIHNvIG1pbmQtYm9nZ2xpbmdseSB1c2VmdWwgY291bGQgaGF2ZSBldm" +
 				"9sdmVkIHB1cmVseSBieSBjaGFuY2UgdGhhdCBzb21lIHRoaW5rZXJzIGhhdmUgY2hvc2V" +
@@ -440,17 +448,17 @@ func TestMailYakWriteAttachments(t *testing.T) {
 		},
 		{
 			"HTML inline",
-			[]attachment{{"name.html", strings.NewReader("<html><head></head></html>"), true, ""}},
-			"text/html; charset=utf-8;\n\tfilename=\"name.html\"",
-			"inline;\n\tfilename=\"name.html\"",
+			[]attachment{{"name.html", strings.NewReader("<html><head></head></html>"), true, "", nil}},
+			"text/html; charset=utf-8;\r\n\tfilename=\"name.html\"",
+			"inline;\r\n\tfilename=\"name.html\"",
 			"PGh0bWw+PGhlYWQ+PC9oZWFkPjwvaHRtbD4=",
 			false,
 		},
 		{
 			"HTML - wrong extension inline",
-			[]attachment{{"name.png", strings.NewReader("<html><head></head></html>"), true, ""}},
-			"text/html; charset=utf-8;\n\tfilename=\"name.png\"",
-			"inline;\n\tfilename=\"name.png\"",
+			[]attachment{{"name.png", strings.NewReader("<html><head></head></html>"), true, "", nil}},
+			"text/html; charset=utf-8;\r\n\tfilename=\"name.png\"",
+			"inline;\r\n\tfilename=\"name.png\"",
 			"PGh0bWw+PGhlYWQ+PC9oZWFkPjwvaHRtbD4=",
 			false,
 		},
@@ -478,10 +486,11 @@ func TestMailYakWriteAttachments(t *testing.T) {
 					)),
 					false,
 					"",
+					nil,
 				},
 			},
-			"text/plain; charset=utf-8;\n\tfilename=\"qed.txt\"",
-			"attachment;\n\tfilename=\"qed.txt\"",
+			"text/plain; charset=utf-8;\r\n\tfilename=\"qed.txt\"",
+			"attachment;\r\n\tfilename=\"qed.txt\"",
 			"Tm93IGl0IGlzIHN1Y2ggYSBiaXphcnJlbHkgaW1wcm9iYWJsZSBjb2luY2lkZW5jZSB0a" +
 				"GF0IGFueXRoaW5nIHNvIG1pbmQtYm9nZ2xpbmdseSB1c2VmdWwgY291bGQgaGF2ZSBldm" +
 				"9sdmVkIHB1cmVseSBieSBjaGFuY2UgdGhhdCBzb21lIHRoaW5rZXJzIGhhdmUgY2hvc2V" +
@@ -532,6 +541,298 @@ func TestMailYakWriteAttachments(t *testing.T) {
 	}
 }
 
+// TestMailYakAttachPart ensures custom part headers are added to the
+// attachment, overriding the defaults except for the transfer encoding.
+func TestMailYakAttachPart(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		header textproto.MIMEHeader
+		// Expected results.
+		want     textproto.MIMEHeader
+		wantData string
+	}{
+		{
+			"No headers",
+			nil,
+			textproto.MIMEHeader{
+				"Content-Type":              {"text/plain; charset=utf-8;\r\n\tfilename=\"advice.txt\""},
+				"Content-Disposition":       {"attachment;\r\n\tfilename=\"advice.txt\""},
+				"Content-Transfer-Encoding": {"base64"},
+				"Content-ID":                {"<advice.txt>"},
+			},
+			"RG9uJ3QgUGFuaWM=",
+		},
+		{
+			"Description and custom header",
+			textproto.MIMEHeader{
+				"Content-Description": {"Good advice"},
+				"x-provider-id":       {"42"},
+			},
+			textproto.MIMEHeader{
+				"Content-Type":              {"text/plain; charset=utf-8;\r\n\tfilename=\"advice.txt\""},
+				"Content-Disposition":       {"attachment;\r\n\tfilename=\"advice.txt\""},
+				"Content-Transfer-Encoding": {"base64"},
+				"Content-ID":                {"<advice.txt>"},
+				"Content-Description":       {"Good advice"},
+				"X-Provider-Id":             {"42"},
+			},
+			"RG9uJ3QgUGFuaWM=",
+		},
+		{
+			"Overrides",
+			textproto.MIMEHeader{
+				"Content-Type":              {"text/x-advice"},
+				"Content-Disposition":       {`attachment; filename="advice.txt"; size=11`},
+				"Content-Transfer-Encoding": {"7bit"},
+				"Content-Id":                {"<advice>"},
+			},
+			textproto.MIMEHeader{
+				"Content-Type":              {"text/x-advice"},
+				"Content-Disposition":       {`attachment; filename="advice.txt"; size=11`},
+				"Content-Transfer-Encoding": {"7bit"},
+				"Content-Id":                {"<advice>"},
+			},
+			"Don't Panic",
+		},
+		{
+			"Quoted-printable",
+			textproto.MIMEHeader{
+				"content-transfer-encoding": {"Quoted-Printable"},
+			},
+			textproto.MIMEHeader{
+				"Content-Type":              {"text/plain; charset=utf-8;\r\n\tfilename=\"advice.txt\""},
+				"Content-Disposition":       {"attachment;\r\n\tfilename=\"advice.txt\""},
+				"Content-Transfer-Encoding": {"quoted-printable"},
+				"Content-ID":                {"<advice.txt>"},
+			},
+			"Don't Panic",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := MailYak{}
+			m.AttachPart("advice.txt", strings.NewReader("Don't Panic"), tt.header)
+
+			pc := testPartCreator{}
+			if err := m.writeAttachments(&pc, nopBuilder{}); err != nil {
+				t.Fatalf("%q. MailYak.writeAttachments() error = %v", tt.name, err)
+			}
+
+			if len(pc.attachments) != 1 {
+				t.Fatalf("%q. MailYak.writeAttachments() unexpected number of attachments = %v, want 1", tt.name, len(pc.attachments))
+			}
+
+			if got := pc.attachments[0].header; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%q. MailYak.writeAttachments() header = %v, want %v", tt.name, got, tt.want)
+			}
+
+			if got := pc.attachments[0].data.String(); got != tt.wantData {
+				t.Errorf("%q. MailYak.writeAttachments() data = %v, want %v", tt.name, got, tt.wantData)
+			}
+		})
+	}
+}
+
+// TestMailYakAttachFS ensures files are attached from a fs.FS with the MIME
+// type inferred from the extension
+func TestMailYakAttachFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"assets/logo.png": {Data: []byte("Don't Panic")},
+		"assets/notes":    {Data: []byte("Don't Panic")},
+	}
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		paths []string
+		// Expected results.
+		wantTypes []string
+		wantErr   bool
+	}{
+		{
+			"Extension",
+			[]string{"assets/logo.png"},
+			[]string{"image/png;\r\n\tfilename=\"logo.png\""},
+			false,
+		},
+		{
+			"No extension",
+			[]string{"assets/notes"},
+			[]string{"text/plain; charset=utf-8;\r\n\tfilename=\"notes\""},
+			false,
+		},
+		{
+			"Multiple",
+			[]string{"assets/logo.png", "assets/notes"},
+			[]string{
+				"image/png;\r\n\tfilename=\"logo.png\"",
+				"text/plain; charset=utf-8;\r\n\tfilename=\"notes\"",
+			},
+			false,
+		},
+		{
+			"Missing file",
+			[]string{"assets/logo.png", "assets/missing.txt"},
+			nil,
+			true,
+		},
+		{
+			"Directory",
+			[]string{"assets"},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := MailYak{}
+			if err := m.AttachFS(fsys, tt.paths...); (err != nil) != tt.wantErr {
+				t.Fatalf("%q. MailYak.AttachFS() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+
+			pc := testPartCreator{}
+			if err := m.writeAttachments(&pc, nopBuilder{}); err != nil {
+				t.Fatalf("%q. MailYak.writeAttachments() error = %v", tt.name, err)
+			}
+
+			if len(pc.attachments) != len(tt.wantTypes) {
+				t.Fatalf("%q. MailYak.writeAttachments() unexpected number of attachments = %v, want %v", tt.name, len(pc.attachments), len(tt.wantTypes))
+			}
+
+			for i, want := range tt.wantTypes {
+				if got := pc.attachments[i].contentType; got != want {
+					t.Errorf("%q. MailYak.writeAttachments() content type = %v, want %v", tt.name, got, want)
+				}
+
+				if got := pc.attachments[i].data.String(); got != "RG9uJ3QgUGFuaWM=" {
+					t.Errorf("%q. MailYak.writeAttachments() data = %v, want %v", tt.name, got, "RG9uJ3QgUGFuaWM=")
+				}
+			}
+		})
+	}
+}
+
+// TestMailYakAttachMessage ensures attached messages are written unencoded
+func TestMailYakAttachMessage(t *testing.T) {
+	t.Parallel()
+
+	msg := "From: dom@itsallbroken.com\r\nSubject: Original\r\n\r\nSo long, and thanks for all the fish.\r\n"
+
+	m := MailYak{}
+	m.AttachMessage("original.eml", strings.NewReader(msg))
+
+	pc := testPartCreator{}
+	if err := m.writeAttachments(&pc, nopBuilder{}); err != nil {
+		t.Fatalf("MailYak.writeAttachments() error = %v", err)
+	}
+
+	if len(pc.attachments) != 1 {
+		t.Fatalf("MailYak.writeAttachments() unexpected number of attachments = %v, want 1", len(pc.attachments))
+	}
+
+	want := textproto.MIMEHeader{
+		"Content-Type":              {"message/rfc822;\r\n\tfilename=\"original.eml\""},
+		"Content-Disposition":       {"attachment;\r\n\tfilename=\"original.eml\""},
+		"Content-Transfer-Encoding": {"8bit"},
+		"Content-ID":                {"<original.eml>"},
+	}
+	if got := pc.attachments[0].header; !reflect.DeepEqual(got, want) {
+		t.Errorf("MailYak.writeAttachments() header = %v, want %v", got, want)
+	}
+
+	if got := pc.attachments[0].data.String(); got != msg {
+		t.Errorf("MailYak.writeAttachments() data = %q, want %q", got, msg)
+	}
+}
+
+// TestMailYakAttachMessage_lineEndings ensures attached messages with LF line
+// endings are written with CRLF line endings
+func TestMailYakAttachMessage_lineEndings(t *testing.T) {
+	t.Parallel()
+
+	msg := "From: dom@itsallbroken.com\nSubject: Original\n\nSo long, and thanks for all the fish.\n"
+
+	m := New("", nil)
+	m.From("from@example.org")
+	m.To("to@example.org")
+	m.Plain().Set("Forwarded")
+	m.AttachMessage("original.eml", strings.NewReader(msg))
+
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		t.Fatalf("MailYak.WriteTo() error = %v", err)
+	}
+
+	got := buf.String()
+	if n := strings.Count(got, "\n") - strings.Count(got, "\r\n"); n != 0 {
+		t.Errorf("MailYak.WriteTo() wrote %d bare LF line endings:\n%q", n, got)
+	}
+
+	want := strings.ReplaceAll(msg, "\n", "\r\n")
+	if !strings.Contains(got, want) {
+		t.Errorf("MailYak.WriteTo() = %q, want message %q", got, want)
+	}
+}
+
+// TestFilenameParams ensures non-ASCII filenames are RFC 2231 encoded with an
+// RFC 2047 fallback
+func TestFilenameParams(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		filename string
+		// Want
+		want string
+	}{
+		{
+			"ASCII",
+			"report.pdf",
+			`filename="report.pdf"`,
+		},
+		{
+			"ASCII with quotes",
+			`"quoted".txt`,
+			`filename="\"quoted\".txt"`,
+		},
+		{
+			"Non-ASCII",
+			"Rechnung Müller.pdf",
+			"filename=\"=?UTF-8?q?Rechnung_M=C3=BCller.pdf?=\";\r\n\tfilename*=UTF-8''Rechnung%20M%C3%BCller.pdf",
+		},
+		{
+			"Non-ASCII with reserved characters",
+			"日本語 (1).txt",
+			"filename=\"=?UTF-8?q?=E6=97=A5=E6=9C=AC=E8=AA=9E_(1).txt?=\";\r\n\tfilename*=UTF-8''%E6%97%A5%E6%9C%AC%E8%AA%9E%20%281%29.txt",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := filenameParams(tt.filename); got != tt.want {
+				t.Errorf("%q. filenameParams() = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
 // TestMailYakWriteAttachments_multipleAttachments ensures multiple attachments
 // are correctly handled
 func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
@@ -548,11 +849,11 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 	}{
 		{
 			"Single Attachment",
-			[]attachment{{"name.txt", strings.NewReader("test"), false, ""}},
+			[]attachment{{"name.txt", strings.NewReader("test"), false, "", nil}},
 			[]testAttachment{
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"name.txt\"",
-					disposition: "attachment;\n\tfilename=\"name.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"name.txt\"",
+					disposition: "attachment;\r\n\tfilename=\"name.txt\"",
 					data:        *bytes.NewBufferString("dGVzdA=="),
 				},
 			},
@@ -560,11 +861,11 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 		},
 		{
 			"Single Attachment with specified MIME type",
-			[]attachment{{"name.txt", strings.NewReader("test"), false, "text/csv; charset=utf-8"}},
+			[]attachment{{"name.txt", strings.NewReader("test"), false, "text/csv; charset=utf-8", nil}},
 			[]testAttachment{
 				{
-					contentType: "text/csv; charset=utf-8;\n\tfilename=\"name.txt\"",
-					disposition: "attachment;\n\tfilename=\"name.txt\"",
+					contentType: "text/csv; charset=utf-8;\r\n\tfilename=\"name.txt\"",
+					disposition: "attachment;\r\n\tfilename=\"name.txt\"",
 					data:        *bytes.NewBufferString("dGVzdA=="),
 				},
 			},
@@ -573,18 +874,18 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 		{
 			"Multiple Attachment - same types",
 			[]attachment{
-				{"name.txt", strings.NewReader("test"), false, ""},
-				{"different.txt", strings.NewReader("another"), false, ""},
+				{"name.txt", strings.NewReader("test"), false, "", nil},
+				{"different.txt", strings.NewReader("another"), false, "", nil},
 			},
 			[]testAttachment{
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"name.txt\"",
-					disposition: "attachment;\n\tfilename=\"name.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"name.txt\"",
+					disposition: "attachment;\r\n\tfilename=\"name.txt\"",
 					data:        *bytes.NewBufferString("dGVzdA=="),
 				},
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"different.txt\"",
-					disposition: "attachment;\n\tfilename=\"different.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"different.txt\"",
+					disposition: "attachment;\r\n\tfilename=\"different.txt\"",
 					data:        *bytes.NewBufferString("YW5vdGhlcg=="),
 				},
 			},
@@ -593,18 +894,18 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 		{
 			"Multiple Attachment - different types",
 			[]attachment{
-				{"name.txt", strings.NewReader("test"), false, ""},
-				{"html.txt", strings.NewReader("<html><head></head></html>"), false, ""},
+				{"name.txt", strings.NewReader("test"), false, "", nil},
+				{"html.txt", strings.NewReader("<html><head></head></html>"), false, "", nil},
 			},
 			[]testAttachment{
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"name.txt\"",
-					disposition: "attachment;\n\tfilename=\"name.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"name.txt\"",
+					disposition: "attachment;\r\n\tfilename=\"name.txt\"",
 					data:        *bytes.NewBufferString("dGVzdA=="),
 				},
 				{
-					contentType: "text/html; charset=utf-8;\n\tfilename=\"html.txt\"",
-					disposition: "attachment;\n\tfilename=\"html.txt\"",
+					contentType: "text/html; charset=utf-8;\r\n\tfilename=\"html.txt\"",
+					disposition: "attachment;\r\n\tfilename=\"html.txt\"",
 					data:        *bytes.NewBufferString("PGh0bWw+PGhlYWQ+PC9oZWFkPjwvaHRtbD4="),
 				},
 			},
@@ -613,18 +914,18 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 		{
 			"Multiple Attachment - different specified MIME types",
 			[]attachment{
-				{"name.txt", strings.NewReader("test"), false, "text/csv; charset=utf-8"},
-				{"html.txt", strings.NewReader("<html><head></head></html>"), false, "application/xml"},
+				{"name.txt", strings.NewReader("test"), false, "text/csv; charset=utf-8", nil},
+				{"html.txt", strings.NewReader("<html><head></head></html>"), false, "application/xml", nil},
 			},
 			[]testAttachment{
 				{
-					contentType: "text/csv; charset=utf-8;\n\tfilename=\"name.txt\"",
-					disposition: "attachment;\n\tfilename=\"name.txt\"",
+					contentType: "text/csv; charset=utf-8;\r\n\tfilename=\"name.txt\"",
+					disposition: "attachment;\r\n\tfilename=\"name.txt\"",
 					data:        *bytes.NewBufferString("dGVzdA=="),
 				},
 				{
-					contentType: "application/xml;\n\tfilename=\"html.txt\"",
-					disposition: "attachment;\n\tfilename=\"html.txt\"",
+					contentType: "application/xml;\r\n\tfilename=\"html.txt\"",
+					disposition: "attachment;\r\n\tfilename=\"html.txt\"",
 					data:        *bytes.NewBufferString("PGh0bWw+PGhlYWQ+PC9oZWFkPjwvaHRtbD4="),
 				},
 			},
@@ -646,6 +947,7 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 					),
 					false,
 					"",
+					nil,
 				},
 				{
 					"520.txt", strings.NewReader(
@@ -658,12 +960,13 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 					),
 					false,
 					"",
+					nil,
 				},
 			},
 			[]testAttachment{
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"550.txt\"",
-					disposition: "attachment;\n\tfilename=\"550.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"550.txt\"",
+					disposition: "attachment;\r\n\tfilename=\"550.txt\"",
 					data: *bytes.NewBufferString(
 						"TG9yZW0gaXBzdW0gZG9sb3Igc2l0IGFtZXQsIGNvbnNlY3RldHVyIGFkaXBpc2NpbmcgZWxpdC4gTWF1cmlzIHV0IG5pc" +
 							"2wgZmVsaXMuIEFlbmVhbiBmZWxpcyBqdXN0bywgZ3JhdmlkYSBlZ2V0IGxlbyBhbGlxdWV0LCBtb2xlc3RpZSBhbGlxdW" +
@@ -676,8 +979,8 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 					),
 				},
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"520.txt\"",
-					disposition: "attachment;\n\tfilename=\"520.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"520.txt\"",
+					disposition: "attachment;\r\n\tfilename=\"520.txt\"",
 					data: *bytes.NewBufferString(
 						"TG9yZW0gaXBzdW0gZG9sb3Igc2l0IGFtZXQsIGNvbnNlY3RldHVyIGFkaXBpc2NpbmcgZWxpdC4gRG9uZWMgZXUgdmVz" +
 							"dGlidWx1bSBkb2xvci4gTnVuYyBhYyBwb3N1ZXJlIGZlbGlzLCBhIG1hdHRpcyBsZW8uIER1aXMgZWxlbWVudHVtIHRl" +
@@ -707,6 +1010,7 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 					),
 					false,
 					"",
+					nil,
 				},
 				{
 					"550.txt",
@@ -721,12 +1025,13 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 					),
 					false,
 					"",
+					nil,
 				},
 			},
 			[]testAttachment{
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"520.txt\"",
-					disposition: "attachment;\n\tfilename=\"520.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"520.txt\"",
+					disposition: "attachment;\r\n\tfilename=\"520.txt\"",
 					data: *bytes.NewBufferString(
 						"TG9yZW0gaXBzdW0gZG9sb3Igc2l0IGFtZXQsIGNvbnNlY3RldHVyIGFkaXBpc2NpbmcgZWxpdC4gRG9uZWMgZXUgdmVz" +
 							"dGlidWx1bSBkb2xvci4gTnVuYyBhYyBwb3N1ZXJlIGZlbGlzLCBhIG1hdHRpcyBsZW8uIER1aXMgZWxlbWVudHVtIHRl" +
@@ -739,8 +1044,8 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 					),
 				},
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"550.txt\"",
-					disposition: "attachment;\n\tfilename=\"550.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"550.txt\"",
+					disposition: "attachment;\r\n\tfilename=\"550.txt\"",
 					data: *bytes.NewBufferString(
 						"TG9yZW0gaXBzdW0gZG9sb3Igc2l0IGFtZXQsIGNvbnNlY3RldHVyIGFkaXBpc2NpbmcgZWxpdC4gTWF1cmlzIHV0IG5p" +
 							"c2wgZmVsaXMuIEFlbmVhbiBmZWxpcyBqdXN0bywgZ3JhdmlkYSBlZ2V0IGxlbyBhbGlxdWV0LCBtb2xlc3RpZSBhbGlx" +
@@ -759,11 +1064,11 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 		// inline attachments
 		{
 			"Single Inline Attachment",
-			[]attachment{{"name.txt", strings.NewReader("test"), true, ""}},
+			[]attachment{{"name.txt", strings.NewReader("test"), true, "", nil}},
 			[]testAttachment{
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"name.txt\"",
-					disposition: "inline;\n\tfilename=\"name.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"name.txt\"",
+					disposition: "inline;\r\n\tfilename=\"name.txt\"",
 					data:        *bytes.NewBufferString("dGVzdA=="),
 				},
 			},
@@ -771,11 +1076,11 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 		},
 		{
 			"Single Inline Attachment with specified MIME type",
-			[]attachment{{"name.txt", strings.NewReader("test"), true, "text/csv; charset=utf-8"}},
+			[]attachment{{"name.txt", strings.NewReader("test"), true, "text/csv; charset=utf-8", nil}},
 			[]testAttachment{
 				{
-					contentType: "text/csv; charset=utf-8;\n\tfilename=\"name.txt\"",
-					disposition: "inline;\n\tfilename=\"name.txt\"",
+					contentType: "text/csv; charset=utf-8;\r\n\tfilename=\"name.txt\"",
+					disposition: "inline;\r\n\tfilename=\"name.txt\"",
 					data:        *bytes.NewBufferString("dGVzdA=="),
 				},
 			},
@@ -784,18 +1089,18 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 		{
 			"Multiple Inline Attachments - same types",
 			[]attachment{
-				{"name.txt", strings.NewReader("test"), true, ""},
-				{"different.txt", strings.NewReader("another"), true, ""},
+				{"name.txt", strings.NewReader("test"), true, "", nil},
+				{"different.txt", strings.NewReader("another"), true, "", nil},
 			},
 			[]testAttachment{
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"name.txt\"",
-					disposition: "inline;\n\tfilename=\"name.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"name.txt\"",
+					disposition: "inline;\r\n\tfilename=\"name.txt\"",
 					data:        *bytes.NewBufferString("dGVzdA=="),
 				},
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"different.txt\"",
-					disposition: "inline;\n\tfilename=\"different.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"different.txt\"",
+					disposition: "inline;\r\n\tfilename=\"different.txt\"",
 					data:        *bytes.NewBufferString("YW5vdGhlcg=="),
 				},
 			},
@@ -804,18 +1109,18 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 		{
 			"Multiple Attachments - One Inline, One not",
 			[]attachment{
-				{"name.txt", strings.NewReader("test"), false, ""},
-				{"different.txt", strings.NewReader("another"), true, ""},
+				{"name.txt", strings.NewReader("test"), false, "", nil},
+				{"different.txt", strings.NewReader("another"), true, "", nil},
 			},
 			[]testAttachment{
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"name.txt\"",
-					disposition: "attachment;\n\tfilename=\"name.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"name.txt\"",
+					disposition: "attachment;\r\n\tfilename=\"name.txt\"",
 					data:        *bytes.NewBufferString("dGVzdA=="),
 				},
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"different.txt\"",
-					disposition: "inline;\n\tfilename=\"different.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"different.txt\"",
+					disposition: "inline;\r\n\tfilename=\"different.txt\"",
 					data:        *bytes.NewBufferString("YW5vdGhlcg=="),
 				},
 			},
@@ -824,18 +1129,18 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 		{
 			"Multiple Inline Attachments - different types",
 			[]attachment{
-				{"name.txt", strings.NewReader("test"), true, ""},
-				{"html.txt", strings.NewReader("<html><head></head></html>"), true, ""},
+				{"name.txt", strings.NewReader("test"), true, "", nil},
+				{"html.txt", strings.NewReader("<html><head></head></html>"), true, "", nil},
 			},
 			[]testAttachment{
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"name.txt\"",
-					disposition: "inline;\n\tfilename=\"name.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"name.txt\"",
+					disposition: "inline;\r\n\tfilename=\"name.txt\"",
 					data:        *bytes.NewBufferString("dGVzdA=="),
 				},
 				{
-					contentType: "text/html; charset=utf-8;\n\tfilename=\"html.txt\"",
-					disposition: "inline;\n\tfilename=\"html.txt\"",
+					contentType: "text/html; charset=utf-8;\r\n\tfilename=\"html.txt\"",
+					disposition: "inline;\r\n\tfilename=\"html.txt\"",
 					data:        *bytes.NewBufferString("PGh0bWw+PGhlYWQ+PC9oZWFkPjwvaHRtbD4="),
 				},
 			},
@@ -844,18 +1149,18 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 		{
 			"Multiple Inline Attachments - specified MIME types",
 			[]attachment{
-				{"name.txt", strings.NewReader("test"), true, "text/csv; charset=utf-8"},
-				{"different.txt", strings.NewReader("<html><head></head></html>"), true, "application/xml"},
+				{"name.txt", strings.NewReader("test"), true, "text/csv; charset=utf-8", nil},
+				{"different.txt", strings.NewReader("<html><head></head></html>"), true, "application/xml", nil},
 			},
 			[]testAttachment{
 				{
-					contentType: "text/csv; charset=utf-8;\n\tfilename=\"name.txt\"",
-					disposition: "inline;\n\tfilename=\"name.txt\"",
+					contentType: "text/csv; charset=utf-8;\r\n\tfilename=\"name.txt\"",
+					disposition: "inline;\r\n\tfilename=\"name.txt\"",
 					data:        *bytes.NewBufferString("dGVzdA=="),
 				},
 				{
-					contentType: "application/xml;\n\tfilename=\"different.txt\"",
-					disposition: "inline;\n\tfilename=\"different.txt\"",
+					contentType: "application/xml;\r\n\tfilename=\"different.txt\"",
+					disposition: "inline;\r\n\tfilename=\"different.txt\"",
 					data:        *bytes.NewBufferString("PGh0bWw+PGhlYWQ+PC9oZWFkPjwvaHRtbD4="),
 				},
 			},
@@ -877,6 +1182,7 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 					),
 					true,
 					"",
+					nil,
 				},
 				{
 					"520.txt", strings.NewReader(
@@ -889,12 +1195,13 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 					),
 					true,
 					"",
+					nil,
 				},
 			},
 			[]testAttachment{
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"550.txt\"",
-					disposition: "inline;\n\tfilename=\"550.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"550.txt\"",
+					disposition: "inline;\r\n\tfilename=\"550.txt\"",
 					data: *bytes.NewBufferString(
 						"TG9yZW0gaXBzdW0gZG9sb3Igc2l0IGFtZXQsIGNvbnNlY3RldHVyIGFkaXBpc2NpbmcgZWxpdC4gTWF1cmlzIHV0IG5pc" +
 							"2wgZmVsaXMuIEFlbmVhbiBmZWxpcyBqdXN0bywgZ3JhdmlkYSBlZ2V0IGxlbyBhbGlxdWV0LCBtb2xlc3RpZSBhbGlxdW" +
@@ -907,8 +1214,8 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 					),
 				},
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"520.txt\"",
-					disposition: "inline;\n\tfilename=\"520.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"520.txt\"",
+					disposition: "inline;\r\n\tfilename=\"520.txt\"",
 					data: *bytes.NewBufferString(
 						"TG9yZW0gaXBzdW0gZG9sb3Igc2l0IGFtZXQsIGNvbnNlY3RldHVyIGFkaXBpc2NpbmcgZWxpdC4gRG9uZWMgZXUgdmVz" +
 							"dGlidWx1bSBkb2xvci4gTnVuYyBhYyBwb3N1ZXJlIGZlbGlzLCBhIG1hdHRpcyBsZW8uIER1aXMgZWxlbWVudHVtIHRl" +
@@ -938,6 +1245,7 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 					),
 					true,
 					"",
+					nil,
 				},
 				{
 					"550.txt",
@@ -952,12 +1260,13 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 					),
 					true,
 					"",
+					nil,
 				},
 			},
 			[]testAttachment{
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"520.txt\"",
-					disposition: "inline;\n\tfilename=\"520.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"520.txt\"",
+					disposition: "inline;\r\n\tfilename=\"520.txt\"",
 					data: *bytes.NewBufferString(
 						"TG9yZW0gaXBzdW0gZG9sb3Igc2l0IGFtZXQsIGNvbnNlY3RldHVyIGFkaXBpc2NpbmcgZWxpdC4gRG9uZWMgZXUgdmVz" +
 							"dGlidWx1bSBkb2xvci4gTnVuYyBhYyBwb3N1ZXJlIGZlbGlzLCBhIG1hdHRpcyBsZW8uIER1aXMgZWxlbWVudHVtIHRl" +
@@ -970,8 +1279,8 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 					),
 				},
 				{
-					contentType: "text/plain; charset=utf-8;\n\tfilename=\"550.txt\"",
-					disposition: "inline;\n\tfilename=\"550.txt\"",
+					contentType: "text/plain; charset=utf-8;\r\n\tfilename=\"550.txt\"",
+					disposition: "inline;\r\n\tfilename=\"550.txt\"",
 					data: *bytes.NewBufferString(
 						"TG9yZW0gaXBzdW0gZG9sb3Igc2l0IGFtZXQsIGNvbnNlY3RldHVyIGFkaXBpc2NpbmcgZWxpdC4gTWF1cmlzIHV0IG5p" +
 							"c2wgZmVsaXMuIEFlbmVhbiBmZWxpcyBqdXN0bywgZ3JhdmlkYSBlZ2V0IGxlbyBhbGlxdWV0LCBtb2xlc3RpZSBhbGlx" +
@@ -1023,3 +1332,43 @@ func TestMailYakWriteAttachments_multipleAttachments(t *testing.T) {
 		})
 	}
 }
+
+// TestAttachmentTransferEncoding ensures the transfer encoding of attachments
+// is selected automatically, or from the part headers.
+func TestAttachmentTransferEncoding(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		mimeType string
+		header   textproto.MIMEHeader
+		// Want
+		want    TransferEncoding
+		wantErr bool
+	}{
+		{"Auto", "image/png", nil, EncodingBase64, false},
+		{"Auto message", "message/rfc822", nil, Encoding8Bit, false},
+		{"Override", "text/plain", textproto.MIMEHeader{"Content-Transfer-Encoding": {" 8BIT "}}, Encoding8Bit, false},
+		{"Message 7bit", "message/rfc822", textproto.MIMEHeader{"Content-Transfer-Encoding": {"7bit"}}, Encoding7Bit, false},
+		{"Message base64", "message/rfc822", textproto.MIMEHeader{"Content-Transfer-Encoding": {"base64"}}, "", true},
+		{"Unsupported", "text/plain", textproto.MIMEHeader{"Content-Transfer-Encoding": {"binary"}}, "", true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := attachment{filename: "test", mimeType: tt.mimeType, header: tt.header}
+
+			got, err := a.transferEncoding()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%q. attachment.transferEncoding() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("%q. attachment.transferEncoding() = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
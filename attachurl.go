@@ -0,0 +1,120 @@
+package mailyak
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// maxURLAttachmentSize is the maximum size of an attachment downloaded by
+// AttachURL when AttachmentLimits.MaxSize is not set.
+const maxURLAttachmentSize = 25 << 20
+
+// AttachURL downloads the resource at rawURL using client, and adds it to the
+// email as an attachment with name as the filename:
+//
+//	err := mail.AttachURL(ctx, "invoice.pdf", "https://itsallbroken.com/invoices/42", nil)
+//
+// If name is empty, the filename is taken from the Content-Disposition of the
+// response, or the last element of the URL path. The MIME type is taken from
+// the Content-Type of the response, falling back to detecting it from the
+// content. If client is nil, http.DefaultClient is used.
+//
+// The download is limited to AttachmentLimits.MaxSize, or 25MB if unset,
+// returning an *AttachmentLimitError if exceeded. The content is held in memory
+// until the email is sent, so the limits should be set appropriately when
+// attaching user supplied URLs.
+//
+// An error is returned if the request fails, the response status is not 2xx,
+// or the attachment exceeds the AttachmentLimits of the email, in which case
+// nothing is attached.
+func (m *MailYak) AttachURL(ctx context.Context, name, rawURL string, client *http.Client) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("mailyak: invalid attachment URL: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("mailyak: invalid attachment URL: %w", err)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mailyak: downloading attachment: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("mailyak: downloading attachment %s: unexpected status %s", u.Redacted(), resp.Status)
+	}
+
+	if name == "" {
+		name = urlFilename(resp, u)
+	}
+
+	limit := m.attachmentLimits.MaxSize
+	if limit <= 0 {
+		limit = maxURLAttachmentSize
+	}
+
+	// fail before downloading a response of a known size exceeding the limit
+	if resp.ContentLength > limit {
+		return &AttachmentLimitError{Err: ErrAttachmentTooLarge, Filename: name, Size: resp.ContentLength, Limit: limit}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return fmt.Errorf("mailyak: downloading attachment %s: %w", u.Redacted(), err)
+	}
+	if int64(len(data)) > limit {
+		return &AttachmentLimitError{Err: ErrAttachmentTooLarge, Filename: name, Size: int64(len(data)), Limit: limit}
+	}
+
+	attachments := append(m.attachments[:len(m.attachments):len(m.attachments)], attachment{
+		filename: name,
+		content:  bytes.NewReader(data),
+		inline:   false,
+		mimeType: responseMimeType(resp),
+	})
+	if err := m.checkAttachments(attachments); err != nil {
+		return err
+	}
+
+	m.attachments = attachments
+	return nil
+}
+
+// urlFilename returns the filename of the response to a request for u, taken
+// from the Content-Disposition of resp or the last element of the URL path.
+func urlFilename(resp *http.Response, u *url.URL) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		return path.Base(params["filename"])
+	}
+
+	if base := path.Base(u.Path); base != "." && base != "/" {
+		return base
+	}
+
+	return "attachment"
+}
+
+// responseMimeType returns the MIME type of resp, or an empty string to detect
+// it from the content if not set.
+func responseMimeType(resp *http.Response) string {
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.Contains(mediaType, "/") {
+		return ""
+	}
+	return mime.FormatMediaType(mediaType, params)
+}
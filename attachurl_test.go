@@ -0,0 +1,166 @@
+package mailyak
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestMailYakAttachURL ensures remote resources are attached with the
+// filename and MIME type of the response.
+func TestMailYakAttachURL(t *testing.T) {
+	t.Parallel()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/invoice":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", `attachment; filename="invoice-42.pdf"`)
+		case "/reports/report.csv":
+			w.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+		case "/missing":
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("Don't Panic"))
+	})
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		filename string
+		path     string
+		// Want
+		wantType string
+		wantErr  bool
+	}{
+		{
+			name:     "Named",
+			filename: "statement.pdf",
+			path:     "/invoice",
+			wantType: "application/pdf;\r\n\tfilename=\"statement.pdf\"",
+		},
+		{
+			name:     "Content-Disposition",
+			path:     "/invoice",
+			wantType: "application/pdf;\r\n\tfilename=\"invoice-42.pdf\"",
+		},
+		{
+			name:     "URL path",
+			path:     "/reports/report.csv?download=1",
+			wantType: "text/csv; charset=UTF-8;\r\n\tfilename=\"report.csv\"",
+		},
+		{
+			name:     "Detected",
+			path:     "/",
+			wantType: "text/plain; charset=utf-8;\r\n\tfilename=\"attachment\"",
+		},
+		{
+			name:    "Not found",
+			path:    "/missing",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			m := MailYak{}
+			err := m.AttachURL(context.Background(), tt.filename, srv.URL+tt.path, srv.Client())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MailYak.AttachURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(m.attachments) != 0 {
+					t.Errorf("MailYak.AttachURL() attached %d files, want 0", len(m.attachments))
+				}
+				return
+			}
+
+			pc := testPartCreator{}
+			if err := m.writeAttachments(&pc, nopBuilder{}); err != nil {
+				t.Fatalf("MailYak.writeAttachments() error = %v", err)
+			}
+
+			if len(pc.attachments) != 1 {
+				t.Fatalf("MailYak.writeAttachments() wrote %d attachments, want 1", len(pc.attachments))
+			}
+			if got := pc.attachments[0].contentType; got != tt.wantType {
+				t.Errorf("MailYak.writeAttachments() content type = %q, want %q", got, tt.wantType)
+			}
+			if got := pc.attachments[0].data.String(); got != "RG9uJ3QgUGFuaWM=" {
+				t.Errorf("MailYak.writeAttachments() data = %v, want %v", got, "RG9uJ3QgUGFuaWM=")
+			}
+		})
+	}
+}
+
+// TestMailYakAttachURL_limits ensures downloads exceeding the attachment
+// limits are not attached.
+func TestMailYakAttachURL_limits(t *testing.T) {
+	t.Parallel()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// flushing before writing the body hides the content length
+		if r.URL.Path == "/chunked" {
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(strings.Repeat("a", 100)))
+	})
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		limits   AttachmentLimits
+		existing bool
+		// Parameters.
+		path string
+		// Want
+		wantErr   error
+		wantCount int
+	}{
+		{"Within limit", AttachmentLimits{MaxSize: 100}, false, "/", nil, 1},
+		{"Too large", AttachmentLimits{MaxSize: 50}, false, "/", ErrAttachmentTooLarge, 0},
+		{"Too large unknown size", AttachmentLimits{MaxSize: 50}, false, "/chunked", ErrAttachmentTooLarge, 0},
+		{"Too many", AttachmentLimits{MaxCount: 1}, true, "/", ErrTooManyAttachments, 1},
+		{"Total too large", AttachmentLimits{MaxTotalSize: 150}, true, "/", ErrAttachmentsTooLarge, 1},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			m := New("", nil)
+			m.AttachmentLimits(tt.limits)
+			if tt.existing {
+				m.Attach("existing.txt", strings.NewReader(strings.Repeat("b", 100)))
+			}
+
+			err := m.AttachURL(context.Background(), "a.txt", srv.URL+tt.path, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MailYak.AttachURL() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var limitErr *AttachmentLimitError
+			if tt.wantErr == ErrAttachmentTooLarge && (!errors.As(err, &limitErr) || limitErr.Filename != "a.txt" || limitErr.Limit != 50) {
+				t.Errorf("MailYak.AttachURL() error = %+v, want a.txt limited to 50 bytes", err)
+			}
+
+			if len(m.attachments) != tt.wantCount {
+				t.Errorf("MailYak.AttachURL() attachments = %d, want %d", len(m.attachments), tt.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package mailyak
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AttachZip adds the contents of files to the email as a single zip archive
+// attachment with name as the filename, keyed by their path within the
+// archive:
+//
+//	mail.AttachZip("reports.zip", map[string]io.Reader{
+//		"sales.csv":        sales,
+//		"regions/emea.csv": emea,
+//	})
+//
+// The archive is compressed as it is written when Send is called, rather than
+// being built in memory or on disk beforehand. Files are added to the archive
+// in order of their path, and each reader is read once, in turn.
+//
+// Paths use forward slashes, and are made relative to the root of the archive
+// so the files cannot be extracted outside of the destination directory.
+func (m *MailYak) AttachZip(name string, files map[string]io.Reader) {
+	entries := make([]zipEntry, 0, len(files))
+	for p, r := range files {
+		entries = append(entries, zipEntry{name: zipPath(p), content: r})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+
+	m.attachments = append(m.attachments, attachment{
+		filename: name,
+		content:  &zipReader{entries: entries, now: m.now},
+		inline:   false,
+		mimeType: "application/zip",
+	})
+}
+
+// zipPath returns p relative to the root of a zip archive, removing any
+// leading slashes and parent directory elements.
+func zipPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+// zipEntry is a file to be written to a zip archive.
+type zipEntry struct {
+	name    string
+	content io.Reader
+}
+
+// zipReader reads a zip archive of entries, compressing the content of each
+// entry as the archive is read.
+type zipReader struct {
+	entries []zipEntry
+	now     func() time.Time
+
+	buf   bytes.Buffer
+	zw    *zip.Writer
+	w     io.Writer
+	chunk []byte
+	err   error
+}
+
+func (r *zipReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 && r.err == nil {
+		r.err = r.fill()
+	}
+
+	if r.buf.Len() > 0 {
+		return r.buf.Read(p)
+	}
+
+	return 0, r.err
+}
+
+// fill writes the next chunk of the archive to buf, returning io.EOF once the
+// archive is complete.
+//
+// The compressor buffers its output, so a call may not write to buf.
+func (r *zipReader) fill() error {
+	if r.zw == nil {
+		r.zw = zip.NewWriter(&r.buf)
+		r.chunk = make([]byte, 32<<10)
+	}
+
+	if r.w == nil {
+		if len(r.entries) == 0 {
+			if err := r.zw.Close(); err != nil {
+				return err
+			}
+			return io.EOF
+		}
+
+		w, err := r.zw.CreateHeader(&zip.FileHeader{
+			Name:     r.entries[0].name,
+			Method:   zip.Deflate,
+			Modified: r.now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		r.w = w
+		return nil
+	}
+
+	n, err := r.entries[0].content.Read(r.chunk)
+	if n > 0 {
+		if _, err := r.w.Write(r.chunk[:n]); err != nil {
+			return err
+		}
+	}
+
+	if err == io.EOF {
+		r.w = nil
+		r.entries = r.entries[1:]
+		return nil
+	}
+
+	return err
+}
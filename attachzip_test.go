@@ -0,0 +1,174 @@
+package mailyak
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// TestMailYakAttachZip ensures the files are written to a zip archive
+// attachment, in order of their path.
+func TestMailYakAttachZip(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		files map[string]io.Reader
+		// Want
+		want map[string]string
+	}{
+		{
+			name: "Files",
+			files: map[string]io.Reader{
+				"sales.csv":        strings.NewReader("region,total\nemea,42\n"),
+				"regions/emea.csv": strings.NewReader(strings.Repeat("Don't Panic\n", 10000)),
+				"empty.txt":        strings.NewReader(""),
+			},
+			want: map[string]string{
+				"empty.txt":        "",
+				"regions/emea.csv": strings.Repeat("Don't Panic\n", 10000),
+				"sales.csv":        "region,total\nemea,42\n",
+			},
+		},
+		{
+			name: "Relative paths",
+			files: map[string]io.Reader{
+				"/abs.txt":          strings.NewReader("abs"),
+				"../../etc/passwd":  strings.NewReader("passwd"),
+				"a/../b/./file.txt": strings.NewReader("file"),
+			},
+			want: map[string]string{
+				"abs.txt":    "abs",
+				"b/file.txt": "file",
+				"etc/passwd": "passwd",
+			},
+		},
+		{
+			name:  "No files",
+			files: map[string]io.Reader{},
+			want:  map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := MailYak{}
+			m.AttachZip("reports.zip", tt.files)
+
+			pc := testPartCreator{}
+			if err := m.writeAttachments(&pc, nopBuilder{}); err != nil {
+				t.Fatalf("MailYak.writeAttachments() error = %v", err)
+			}
+
+			if len(pc.attachments) != 1 {
+				t.Fatalf("MailYak.writeAttachments() wrote %d attachments, want 1", len(pc.attachments))
+			}
+			if got, want := pc.attachments[0].contentType, "application/zip;\r\n\tfilename=\"reports.zip\""; got != want {
+				t.Errorf("MailYak.writeAttachments() content type = %q, want %q", got, want)
+			}
+
+			data, err := base64.StdEncoding.DecodeString(pc.attachments[0].data.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("zip.NewReader() error = %v", err)
+			}
+
+			if len(zr.File) != len(tt.want) {
+				t.Fatalf("AttachZip() archive has %d files, want %d", len(zr.File), len(tt.want))
+			}
+
+			for i, f := range zr.File {
+				if i > 0 && zr.File[i-1].Name > f.Name {
+					t.Errorf("AttachZip() file %q after %q, want in order of path", f.Name, zr.File[i-1].Name)
+				}
+
+				want, ok := tt.want[f.Name]
+				if !ok {
+					t.Errorf("AttachZip() unexpected file %q", f.Name)
+					continue
+				}
+
+				rc, err := f.Open()
+				if err != nil {
+					t.Fatalf("%s: Open() error = %v", f.Name, err)
+				}
+				got, err := io.ReadAll(rc)
+				rc.Close()
+				if err != nil {
+					t.Fatalf("%s: ReadAll() error = %v", f.Name, err)
+				}
+
+				if string(got) != want {
+					t.Errorf("AttachZip() file %q = %d bytes, want %d bytes", f.Name, len(got), len(want))
+				}
+			}
+		})
+	}
+}
+
+// TestMailYakAttachZip_deterministic ensures the archive is reproducible with
+// DeterministicOutput.
+func TestMailYakAttachZip_deterministic(t *testing.T) {
+	t.Parallel()
+
+	m := MailYak{}
+	m.DeterministicOutput(42)
+	m.AttachZip("reports.zip", map[string]io.Reader{
+		"a.txt": strings.NewReader("a"),
+		"b.txt": strings.NewReader("b"),
+	})
+
+	pc := testPartCreator{}
+	if err := m.writeAttachments(&pc, nopBuilder{}); err != nil {
+		t.Fatalf("MailYak.writeAttachments() error = %v", err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(pc.attachments[0].data.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+
+	for _, f := range zr.File {
+		if !f.Modified.Equal(time.Unix(0, 0)) {
+			t.Errorf("AttachZip() file %q modified %v, want the Unix epoch", f.Name, f.Modified)
+		}
+	}
+}
+
+// TestMailYakAttachZip_readError ensures an error reading a file is returned
+// when writing the attachment.
+func TestMailYakAttachZip_readError(t *testing.T) {
+	t.Parallel()
+
+	errRead := errors.New("read failed")
+
+	m := MailYak{}
+	m.AttachZip("reports.zip", map[string]io.Reader{
+		"a.txt": strings.NewReader("a"),
+		"b.txt": iotest.ErrReader(errRead),
+	})
+
+	pc := testPartCreator{}
+	if err := m.writeAttachments(&pc, nopBuilder{}); !errors.Is(err, errRead) {
+		t.Errorf("MailYak.writeAttachments() error = %v, want %v", err, errRead)
+	}
+}
@@ -0,0 +1,111 @@
+package mailyak
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+// errUnencryptedAuth is returned when authenticating with credentials over an
+// unencrypted connection to a remote server.
+var errUnencryptedAuth = errors.New("mailyak: refusing to send credentials over an unencrypted connection")
+
+// XOAUTH2Auth returns an smtp.Auth implementing the XOAUTH2 mechanism, as
+// required by Gmail and Microsoft 365, authenticating as username with the
+// access tokens supplied by tokens:
+//
+//	mail.Auth(mailyak.XOAUTH2Auth("dom@itsallbroken.com", tokenSource))
+//
+// A token is requested from tokens each time a connection is authenticated,
+// allowing the TokenSource to refresh expired tokens.
+//
+// As with smtp.PlainAuth, credentials are only sent over TLS connections or to
+// localhost.
+func XOAUTH2Auth(username string, tokens TokenSource) smtp.Auth {
+	return &xoauth2Auth{username: username, tokens: tokens}
+}
+
+// xoauth2Auth implements the XOAUTH2 SASL mechanism.
+type xoauth2Auth struct {
+	username string
+	tokens   TokenSource
+}
+
+func (a *xoauth2Auth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errUnencryptedAuth
+	}
+
+	token, err := a.tokens.AccessToken(context.Background())
+	if err != nil {
+		return "", nil, fmt.Errorf("mailyak: fetching XOAUTH2 access token: %w", err)
+	}
+
+	return "XOAUTH2", []byte("user=" + a.username + "\x01auth=Bearer " + token + "\x01\x01"), nil
+}
+
+func (a *xoauth2Auth) Next(fromServer []byte, more bool) ([]byte, error) {
+	// The server responds to a rejected token with a challenge containing
+	// the error details as JSON
+	if more {
+		return nil, fmt.Errorf("mailyak: XOAUTH2 authentication failed: %s", fromServer)
+	}
+	return nil, nil
+}
+
+// LoginAuth returns an smtp.Auth implementing the LOGIN mechanism, required by
+// many corporate relays (such as Microsoft Exchange) that do not support PLAIN:
+//
+//	mail.Auth(mailyak.LoginAuth("dom", "password"))
+//
+// As with smtp.PlainAuth, credentials are only sent over TLS connections or to
+// localhost.
+func LoginAuth(username, password string) smtp.Auth {
+	return &loginAuth{username: username, password: password}
+}
+
+// loginAuth implements the LOGIN SASL mechanism.
+type loginAuth struct {
+	username string
+	password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errUnencryptedAuth
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	// The server prompts for the username and then the password
+	switch strings.TrimSuffix(strings.ToLower(strings.TrimSpace(string(fromServer))), ":") {
+	case "username", "user name":
+		return []byte(a.username), nil
+	case "password":
+		return []byte(a.password), nil
+	}
+	return nil, fmt.Errorf("mailyak: unexpected LOGIN challenge %q", fromServer)
+}
+
+// CRAMMD5Auth returns an smtp.Auth implementing the CRAM-MD5 mechanism,
+// authenticating as username with secret:
+//
+//	mail.Auth(mailyak.CRAMMD5Auth("dom", "secret"))
+//
+// Unlike LoginAuth and PLAIN, the secret is never sent to the server, so
+// CRAM-MD5 may be used over unencrypted connections.
+func CRAMMD5Auth(username, secret string) smtp.Auth {
+	return smtp.CRAMMD5Auth(username, secret)
+}
+
+// isLocalhost returns true if name is a loopback hostname or address.
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
@@ -0,0 +1,161 @@
+package mailyak
+
+import (
+	"context"
+	"errors"
+	"net/smtp"
+	"testing"
+)
+
+// TestXOAUTH2Auth ensures the XOAUTH2 initial response is formatted correctly,
+// and only sent over encrypted connections.
+func TestXOAUTH2Auth(t *testing.T) {
+	t.Parallel()
+
+	tokenErr := errors.New("refresh failed")
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		server   smtp.ServerInfo
+		tokenErr error
+		// Want
+		wantResp string
+		wantErr  error
+	}{
+		{
+			name:     "TLS",
+			server:   smtp.ServerInfo{Name: "smtp.gmail.com", TLS: true, Auth: []string{"XOAUTH2"}},
+			wantResp: "user=dom@itsallbroken.com\x01auth=Bearer token\x01\x01",
+		},
+		{
+			name:     "Localhost",
+			server:   smtp.ServerInfo{Name: "localhost"},
+			wantResp: "user=dom@itsallbroken.com\x01auth=Bearer token\x01\x01",
+		},
+		{
+			name:    "Unencrypted",
+			server:  smtp.ServerInfo{Name: "smtp.gmail.com"},
+			wantErr: errUnencryptedAuth,
+		},
+		{
+			name:     "Token error",
+			server:   smtp.ServerInfo{Name: "smtp.gmail.com", TLS: true},
+			tokenErr: tokenErr,
+			wantErr:  tokenErr,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			auth := XOAUTH2Auth("dom@itsallbroken.com", TokenSourceFunc(func(ctx context.Context) (string, error) {
+				return "token", tt.tokenErr
+			}))
+
+			proto, resp, err := auth.Start(&tt.server)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("xoauth2Auth.Start() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if proto != "XOAUTH2" || string(resp) != tt.wantResp {
+				t.Errorf("xoauth2Auth.Start() = %q, %q, want %q, %q", proto, resp, "XOAUTH2", tt.wantResp)
+			}
+		})
+	}
+}
+
+// TestXOAUTH2Auth_rejected ensures the error details sent by the server when
+// a token is rejected are returned.
+func TestXOAUTH2Auth_rejected(t *testing.T) {
+	t.Parallel()
+
+	auth := XOAUTH2Auth("dom@itsallbroken.com", TokenSourceFunc(func(ctx context.Context) (string, error) {
+		return "token", nil
+	}))
+
+	if resp, err := auth.Next(nil, false); err != nil || resp != nil {
+		t.Errorf("xoauth2Auth.Next() = %q, %v, want no response", resp, err)
+	}
+
+	_, err := auth.Next([]byte(`{"status":"401","schemes":"bearer"}`), true)
+	if err == nil || err.Error() != `mailyak: XOAUTH2 authentication failed: {"status":"401","schemes":"bearer"}` {
+		t.Errorf("xoauth2Auth.Next() error = %v", err)
+	}
+}
+
+// TestLoginAuth ensures the username and password are sent in response to the
+// server prompts.
+func TestLoginAuth(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		fromServer string
+		more       bool
+		// Want
+		want    string
+		wantErr bool
+	}{
+		{"Username", "Username:", true, "dom", false},
+		{"Lowercase username", "username:", true, "dom", false},
+		{"Password", "Password:", true, "secret", false},
+		{"Done", "", false, "", false},
+		{"Unknown prompt", "Token:", true, "", true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := LoginAuth("dom", "secret").Next([]byte(tt.fromServer), tt.more)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loginAuth.Next() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("loginAuth.Next() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLoginAuth_unencrypted ensures credentials are not sent over unencrypted
+// connections to remote servers.
+func TestLoginAuth_unencrypted(t *testing.T) {
+	t.Parallel()
+
+	if _, _, err := LoginAuth("dom", "secret").Start(&smtp.ServerInfo{Name: "smtp.itsallbroken.com"}); err != errUnencryptedAuth {
+		t.Errorf("loginAuth.Start() error = %v, want %v", err, errUnencryptedAuth)
+	}
+
+	proto, resp, err := LoginAuth("dom", "secret").Start(&smtp.ServerInfo{Name: "smtp.itsallbroken.com", TLS: true})
+	if err != nil || proto != "LOGIN" || resp != nil {
+		t.Errorf("loginAuth.Start() = %q, %q, %v, want LOGIN", proto, resp, err)
+	}
+}
+
+// TestCRAMMD5Auth ensures the response matches the example in RFC 2195.
+func TestCRAMMD5Auth(t *testing.T) {
+	t.Parallel()
+
+	auth := CRAMMD5Auth("tim", "tanstaaftanstaaf")
+
+	if proto, _, err := auth.Start(&smtp.ServerInfo{Name: "smtp.itsallbroken.com"}); err != nil || proto != "CRAM-MD5" {
+		t.Fatalf("CRAMMD5Auth.Start() = %q, %v, want CRAM-MD5", proto, err)
+	}
+
+	got, err := auth.Next([]byte("<1896.697170952@postoffice.reston.mci.net>"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "tim b913a602c7eda7a495b4e6e7334d3890"; string(got) != want {
+		t.Errorf("CRAMMD5Auth.Next() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,22 @@
+package mailyak
+
+// MarkAutomated marks the email as sent automatically rather than by a person,
+// such as a notification or password reset, so that vacation responders and
+// other auto-responders do not reply to it.
+//
+// The Auto-Submitted header is set to "auto-generated" (RFC 3834), and the
+// X-Auto-Response-Suppress header to suppress the out-of-office and automatic
+// replies of Microsoft Exchange. Both are set as with SetHeader, and removed by
+// ClearHeaders.
+func (m *MailYak) MarkAutomated() {
+	m.SetHeader("Auto-Submitted", "auto-generated")
+	m.SetHeader("X-Auto-Response-Suppress", "OOF, AutoReply")
+}
+
+// MarkBulk marks the email as one of many sent automatically, such as a
+// newsletter or mass notification, setting the Precedence header to "bulk" in
+// addition to the headers set by MarkAutomated.
+func (m *MailYak) MarkBulk() {
+	m.MarkAutomated()
+	m.SetHeader("Precedence", "bulk")
+}
@@ -0,0 +1,59 @@
+package mailyak
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMailYakMarkAutomated(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		mark func(m *MailYak)
+		// Want
+		want map[string][]string
+	}{
+		{
+			"Automated",
+			(*MailYak).MarkAutomated,
+			map[string][]string{
+				"Auto-Submitted":           {"auto-generated"},
+				"X-Auto-Response-Suppress": {"OOF, AutoReply"},
+			},
+		},
+		{
+			"Bulk",
+			(*MailYak).MarkBulk,
+			map[string][]string{
+				"Auto-Submitted":           {"auto-generated"},
+				"X-Auto-Response-Suppress": {"OOF, AutoReply"},
+				"Precedence":               {"bulk"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.AddHeader("Precedence", "list")
+
+			// Marking twice must not duplicate the headers
+			tt.mark(m)
+			tt.mark(m)
+
+			want := map[string][]string{"Precedence": {"list"}}
+			for k, v := range tt.want {
+				want[k] = v
+			}
+
+			if got := m.GetHeaderValues(); !reflect.DeepEqual(got, want) {
+				t.Errorf("%q. MailYak.Mark() headers = %v, want %v", tt.name, got, want)
+			}
+		})
+	}
+}
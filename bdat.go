@@ -0,0 +1,94 @@
+package mailyak
+
+import (
+	"io"
+	"net/smtp"
+	"strconv"
+)
+
+// bdatChunkSize is the maximum size of each chunk of message data sent with
+// the BDAT command.
+const bdatChunkSize = 256 << 10
+
+// sendChunked writes the MIME data written by msg using the BDAT command of the
+// CHUNKING extension (RFC 3030), returning the server response to the final
+// chunk.
+//
+// Unlike DATA, the message data is sent without dot-stuffing in chunks of a
+// declared size, so the server does not need to scan the content for the end
+// of the message. As with DATA, LF line endings are converted to CRLF, as
+// required by RFC 3030.
+//
+// If msg fails, the final chunk is not sent and the connection must be closed,
+// causing the server to discard the partial message.
+func sendChunked(smtpClient *smtp.Client, msg io.WriterTo) (int, string, error) {
+	w := &bdatWriter{c: smtpClient, buf: make([]byte, 0, bdatChunkSize)}
+	if _, err := msg.WriteTo(&crlfWriter{w: w}); err != nil {
+		return -1, "", err
+	}
+
+	return w.close()
+}
+
+// bdatWriter buffers the message data written to it, sending each full chunk
+// with the BDAT command.
+type bdatWriter struct {
+	c   *smtp.Client
+	buf []byte
+}
+
+func (w *bdatWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		c := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+c]
+		p = p[c:]
+		n += c
+
+		if len(w.buf) == cap(w.buf) {
+			if _, _, err := w.chunk(false); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// close sends the buffered data as the final chunk, returning the server
+// response to the message.
+func (w *bdatWriter) close() (int, string, error) {
+	return w.chunk(true)
+}
+
+// chunk sends the buffered data with the BDAT command and reads the response,
+// marking it as the final chunk if last is true.
+func (w *bdatWriter) chunk(last bool) (int, string, error) {
+	text := w.c.Text
+
+	id := text.Next()
+	text.StartRequest(id)
+
+	line := "BDAT " + strconv.Itoa(len(w.buf))
+	if last {
+		line += " LAST"
+	}
+
+	_, err := text.W.WriteString(line + "\r\n")
+	if err == nil {
+		_, err = text.W.Write(w.buf)
+	}
+	if err == nil {
+		err = text.W.Flush()
+	}
+	text.EndRequest(id)
+	if err != nil {
+		return -1, "", err
+	}
+
+	w.buf = w.buf[:0]
+
+	text.StartResponse(id)
+	defer text.EndResponse(id)
+
+	return text.ReadResponse(250)
+}
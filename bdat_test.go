@@ -0,0 +1,146 @@
+package mailyak
+
+import (
+	"bytes"
+	"net"
+	"net/smtp"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMailYakChunking ensures the message is sent with BDAT when the server
+// supports CHUNKING.
+func TestMailYakChunking(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		body string
+		// Want
+		wantCmds []string
+	}{
+		{
+			name: "Single chunk",
+			body: "..leading dots\r\n.\r\n",
+			wantCmds: []string{
+				"EHLO localhost",
+				"MAIL FROM:<from@example.org>",
+				"RCPT TO:<to@example.org>",
+				"BDAT",
+			},
+		},
+		{
+			name: "Multiple chunks",
+			body: strings.Repeat("a", bdatChunkSize*2),
+			wantCmds: []string{
+				"EHLO localhost",
+				"MAIL FROM:<from@example.org>",
+				"RCPT TO:<to@example.org>",
+				"BDAT",
+				"BDAT",
+				"BDAT",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, map[string]string{
+				"EHLO": "250-localhost\r\n250 CHUNKING",
+				".":    "250 2.0.0 Queued as 1234",
+			})
+			defer srv.Close()
+
+			mail := New(srv.Addr(), nil)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+			mail.Subject("Chunked")
+			mail.Date(time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC))
+			mail.DeterministicOutput(42)
+			mail.Plain().Set(tt.body)
+
+			want, err := mail.MimeBuf()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			code, msg, err := mail.Send("localhost")
+			if err != nil {
+				t.Fatalf("MailYak.Send() error = %v", err)
+			}
+			if code != 250 || msg != "2.0.0 Queued as 1234" {
+				t.Errorf("MailYak.Send() = %v, %q, want final chunk response", code, msg)
+			}
+
+			cmds := srv.Commands()
+			for i, cmd := range cmds {
+				if strings.HasPrefix(cmd, "BDAT ") {
+					cmds[i] = "BDAT"
+				}
+			}
+			if !reflect.DeepEqual(cmds, tt.wantCmds) {
+				t.Errorf("MailYak.Send() commands = %q, want %q", cmds, tt.wantCmds)
+			}
+
+			if !bytes.Equal(srv.Data(), want.Bytes()) {
+				t.Errorf("MailYak.Send() data differs from MimeBuf()")
+			}
+		})
+	}
+}
+
+// TestSendChunked_lineEndings ensures LF line endings are converted to CRLF
+// when sending with BDAT.
+func TestSendChunked_lineEndings(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTestSMTPServer(l, map[string]string{
+		"EHLO": "250-localhost\r\n250 CHUNKING",
+	})
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := smtp.NewClient(conn, "localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Mail("from@example.org"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Rcpt("to@example.org"); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := bytes.NewBufferString("Subject: Chunked\n\nLine 1\r\nLine 2\n")
+	if _, _, err := sendChunked(c, msg); err != nil {
+		t.Fatalf("sendChunked() error = %v", err)
+	}
+	c.Quit()
+
+	want := "Subject: Chunked\r\n\r\nLine 1\r\nLine 2\r\n"
+	if got := string(srv.Data()); got != want {
+		t.Errorf("sendChunked() data = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+package mailyak
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Calendar adds the iCalendar (RFC 5545) data read from ics to the email as a
+// text/calendar alternative to the plain-text and HTML bodies, with method as
+// the iTIP (RFC 5546) method of the calendar data, such as "REQUEST" or
+// "CANCEL":
+//
+//	mail.Plain().Set("You're invited to the launch party")
+//	if err := mail.Calendar("REQUEST", invite); err != nil {
+//		return err
+//	}
+//
+// Email clients such as Outlook and Gmail render the invitation with RSVP
+// buttons, rather than as an attachment. method must match the METHOD property
+// of the calendar data.
+//
+// ics is read immediately, and an error is returned if it cannot be read or
+// method is not a valid iTIP method name.
+func (m *MailYak) Calendar(method string, ics io.Reader) error {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if method == "" || strings.IndexFunc(method, func(r rune) bool {
+		return (r < 'A' || r > 'Z') && r != '-'
+	}) >= 0 {
+		return fmt.Errorf("mailyak: invalid calendar method %q", method)
+	}
+
+	data, err := io.ReadAll(ics)
+	if err != nil {
+		return err
+	}
+
+	m.calendar = data
+	m.calendarMethod = method
+	return nil
+}
@@ -0,0 +1,88 @@
+package mailyak
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestMailYakCalendar ensures the calendar data is added as a text/calendar
+// alternative part with the method parameter.
+func TestMailYakCalendar(t *testing.T) {
+	t.Parallel()
+
+	const ics = "BEGIN:VCALENDAR\r\nMETHOD:REQUEST\r\nEND:VCALENDAR\r\n"
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		method string
+		// Want
+		wantCType string
+		wantErr   bool
+	}{
+		{
+			"Request",
+			"REQUEST",
+			"text/calendar; method=REQUEST; charset=UTF-8",
+			false,
+		},
+		{
+			"Lowercase",
+			" cancel ",
+			"text/calendar; method=CANCEL; charset=UTF-8",
+			false,
+		},
+		{
+			"Empty",
+			"",
+			"",
+			true,
+		},
+		{
+			"Injection",
+			"REQUEST\r\nBcc: attacker@example.org",
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.Plain().Set("plain")
+			m.HTML().Set("html")
+
+			err := m.Calendar(tt.method, strings.NewReader(ics))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%q. MailYak.Calendar() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+
+			var buf bytes.Buffer
+			if err := m.writeBody(&buf, "test"); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.String()
+
+			if tt.wantErr {
+				if strings.Contains(got, "text/calendar") {
+					t.Errorf("%q. MailYak.writeBody() = %q, want no calendar part", tt.name, got)
+				}
+				return
+			}
+
+			want := "Content-Type: " + tt.wantCType + "\r\n\r\n" + ics
+			if !strings.Contains(got, want) {
+				t.Errorf("%q. MailYak.writeBody() = %q, want calendar part %q", tt.name, got, want)
+			}
+
+			// The calendar part must follow the HTML part
+			if strings.Index(got, "text/calendar") < strings.Index(got, "text/html") {
+				t.Errorf("%q. MailYak.writeBody() = %q, want calendar after HTML", tt.name, got)
+			}
+		})
+	}
+}
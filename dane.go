@@ -0,0 +1,200 @@
+package mailyak
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrDANEMismatch is returned when the certificate presented by the SMTP
+// server does not match any of its usable TLSA records.
+var ErrDANEMismatch = errors.New("mailyak: server certificate does not match TLSA records")
+
+// TLSA certificate usages, selectors and matching types usable for SMTP (RFC
+// 7672 section 3.1).
+const (
+	tlsaUsageDANETA = 2
+	tlsaUsageDANEEE = 3
+
+	tlsaSelectorCert = 0
+	tlsaSelectorSPKI = 1
+
+	tlsaMatchFull   = 0
+	tlsaMatchSHA256 = 1
+	tlsaMatchSHA512 = 2
+)
+
+// TLSARecord is a DNS TLSA resource record (RFC 6698 section 2.1).
+type TLSARecord struct {
+	// Usage is the certificate usage field - DANE-TA (2) and DANE-EE (3)
+	// records are used to authenticate the server.
+	Usage uint8
+
+	// Selector is the part of the certificate matched - the full certificate
+	// (0) or its SubjectPublicKeyInfo (1).
+	Selector uint8
+
+	// MatchingType is how Data is matched - an exact match (0), or a SHA-256
+	// (1) or SHA-512 (2) hash.
+	MatchingType uint8
+
+	// Data is the certificate association data.
+	Data []byte
+}
+
+// TLSAResolver looks up the TLSA records of a server.
+//
+// The standard library does not support TLSA records or DNSSEC validation, so
+// a TLSAResolver is typically implemented using a DNS library, querying a
+// validating resolver and checking the AD bit of the response.
+type TLSAResolver interface {
+	// LookupTLSA returns the TLSA records for name, such as
+	// "_25._tcp.mx.itsallbroken.com", and whether the response was DNSSEC
+	// validated.
+	//
+	// A name with no TLSA records must return a nil error. Any error causes
+	// delivery to the server to fail.
+	LookupTLSA(ctx context.Context, name string) (records []TLSARecord, secure bool, err error)
+}
+
+// DANE causes the certificate of the SMTP server to be authenticated using
+// its TLSA records, looked up with r, following the DANE SMTP rules of RFC
+// 7672. A nil r disables DANE, the default.
+//
+// When the server has DNSSEC validated TLSA records, STARTTLS is required and
+// the certificate must match a usable DANE-TA or DANE-EE record, otherwise
+// Send fails with ErrDANEMismatch. DANE-EE records match the server
+// certificate regardless of its names and expiry, and DANE-TA records match a
+// trust anchor in the chain presented by the server, which must issue a
+// certificate for the server hostname. When none of the records are usable,
+// STARTTLS is required without authenticating the server.
+//
+// Servers without TLSA records, or whose records are not DNSSEC validated, are
+// connected to as if DANE were disabled. A failed lookup fails the delivery to
+// the server, moving on to any FallbackHosts.
+//
+// DANE does not apply to servers addressed by IP address or Unix socket.
+func (m *MailYak) DANE(r TLSAResolver) {
+	m.tlsaResolver = r
+}
+
+// applyDANE updates policy with the DANE requirements of the TLSA records of
+// serverName for port.
+func (m *MailYak) applyDANE(ctx context.Context, policy *tlsPolicy, serverName, port string) error {
+	if net.ParseIP(serverName) != nil {
+		return nil
+	}
+
+	name := "_" + port + "._tcp." + serverName
+	records, secure, err := m.tlsaResolver.LookupTLSA(ctx, name)
+	if err != nil {
+		return fmt.Errorf("mailyak: TLSA lookup for %s failed: %w", name, err)
+	}
+	if !secure || len(records) == 0 {
+		return nil
+	}
+
+	usable := make([]TLSARecord, 0, len(records))
+	for _, r := range records {
+		if isUsableTLSA(r) {
+			usable = append(usable, r)
+		}
+	}
+
+	// TLS is mandatory when the server publishes TLSA records, even if none of
+	// them can be used to authenticate it (RFC 7672 section 2.2)
+	policy.required = true
+	policy.dane = true
+	policy.config.InsecureSkipVerify = true
+	policy.config.VerifyConnection = nil
+
+	if len(usable) > 0 {
+		policy.config.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyDANE(cs.PeerCertificates, usable, serverName)
+		}
+	}
+
+	return nil
+}
+
+// isUsableTLSA returns true if r has a certificate usage, selector and
+// matching type supported for SMTP.
+func isUsableTLSA(r TLSARecord) bool {
+	switch {
+	case r.Usage != tlsaUsageDANETA && r.Usage != tlsaUsageDANEEE:
+		return false
+	case r.Selector != tlsaSelectorCert && r.Selector != tlsaSelectorSPKI:
+		return false
+	case r.MatchingType > tlsaMatchSHA512:
+		return false
+	}
+	return true
+}
+
+// verifyDANE returns ErrDANEMismatch if the certificate chain presented by the
+// server does not match any of records.
+func verifyDANE(certs []*x509.Certificate, records []TLSARecord, serverName string) error {
+	if len(certs) == 0 {
+		return ErrDANEMismatch
+	}
+
+	for _, r := range records {
+		if r.Usage == tlsaUsageDANEEE && matchTLSA(certs[0], r) {
+			return nil
+		}
+	}
+
+	for i, anchor := range certs[1:] {
+		for _, r := range records {
+			if r.Usage != tlsaUsageDANETA || !matchTLSA(anchor, r) {
+				continue
+			}
+
+			roots := x509.NewCertPool()
+			roots.AddCert(anchor)
+
+			intermediates := x509.NewCertPool()
+			for _, c := range certs[1 : i+1] {
+				intermediates.AddCert(c)
+			}
+
+			_, err := certs[0].Verify(x509.VerifyOptions{
+				DNSName:       serverName,
+				Roots:         roots,
+				Intermediates: intermediates,
+			})
+			if err == nil {
+				return nil
+			}
+			return fmt.Errorf("%w: %w", ErrDANEMismatch, err)
+		}
+	}
+
+	return ErrDANEMismatch
+}
+
+// matchTLSA returns true if cert matches the certificate association data of
+// r.
+func matchTLSA(cert *x509.Certificate, r TLSARecord) bool {
+	data := cert.Raw
+	if r.Selector == tlsaSelectorSPKI {
+		data = cert.RawSubjectPublicKeyInfo
+	}
+
+	switch r.MatchingType {
+	case tlsaMatchSHA256:
+		sum := sha256.Sum256(data)
+		data = sum[:]
+	case tlsaMatchSHA512:
+		sum := sha512.Sum512(data)
+		data = sum[:]
+	}
+
+	return bytes.Equal(data, r.Data)
+}
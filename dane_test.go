@@ -0,0 +1,293 @@
+package mailyak
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testTLSAResolver returns fixed TLSA lookup results, recording the names
+// looked up.
+type testTLSAResolver struct {
+	records []TLSARecord
+	secure  bool
+	err     error
+
+	mu    sync.Mutex
+	names []string
+}
+
+func (r *testTLSAResolver) LookupTLSA(ctx context.Context, name string) ([]TLSARecord, bool, error) {
+	r.mu.Lock()
+	r.names = append(r.names, name)
+	r.mu.Unlock()
+
+	return r.records, r.secure, r.err
+}
+
+// redirectDialer connects to addr regardless of the address dialed.
+type redirectDialer struct {
+	addr string
+}
+
+func (d redirectDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	var nd net.Dialer
+	return nd.DialContext(ctx, "tcp", d.addr)
+}
+
+// testDANEChain returns a certificate chain for mx.example.org issued by a
+// CA, and the leaf and CA certificates.
+func testDANEChain(t *testing.T) (tls.Certificate, *x509.Certificate, *x509.Certificate) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "mx.example.org"},
+		DNSNames:     []string{"mx.example.org"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain := tls.Certificate{Certificate: [][]byte{der, caDER}, PrivateKey: key}
+	return chain, leaf, ca
+}
+
+// TestMailYakDANE ensures the server certificate is authenticated using the
+// TLSA records of the server.
+func TestMailYakDANE(t *testing.T) {
+	t.Parallel()
+
+	chain, leaf, ca := testDANEChain(t)
+
+	spki := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+	caSum := sha256.Sum256(ca.Raw)
+	other := sha256.Sum256([]byte("other"))
+
+	startTLS := map[string]string{
+		"EHLO":     "250-localhost\r\n250 STARTTLS",
+		"STARTTLS": "220 Ready",
+	}
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		resolver *testTLSAResolver
+		replies  map[string]string
+		// Want
+		wantErr      error
+		wantAnyErr   bool
+		wantStartTLS bool
+	}{
+		{
+			name: "DANE-EE",
+			resolver: &testTLSAResolver{
+				records: []TLSARecord{{Usage: 3, Selector: 1, MatchingType: 1, Data: spki[:]}},
+				secure:  true,
+			},
+			replies:      startTLS,
+			wantStartTLS: true,
+		},
+		{
+			name: "DANE-EE full certificate",
+			resolver: &testTLSAResolver{
+				records: []TLSARecord{{Usage: 3, Selector: 0, MatchingType: 0, Data: leaf.Raw}},
+				secure:  true,
+			},
+			replies:      startTLS,
+			wantStartTLS: true,
+		},
+		{
+			name: "DANE-TA",
+			resolver: &testTLSAResolver{
+				records: []TLSARecord{
+					{Usage: 3, Selector: 1, MatchingType: 1, Data: other[:]},
+					{Usage: 2, Selector: 0, MatchingType: 1, Data: caSum[:]},
+				},
+				secure: true,
+			},
+			replies:      startTLS,
+			wantStartTLS: true,
+		},
+		{
+			name: "Mismatch",
+			resolver: &testTLSAResolver{
+				records: []TLSARecord{
+					{Usage: 3, Selector: 1, MatchingType: 1, Data: other[:]},
+					{Usage: 2, Selector: 0, MatchingType: 1, Data: other[:]},
+				},
+				secure: true,
+			},
+			replies:      startTLS,
+			wantErr:      ErrDANEMismatch,
+			wantStartTLS: true,
+		},
+		{
+			name: "DANE-TA does not match leaf",
+			resolver: &testTLSAResolver{
+				records: []TLSARecord{{Usage: 2, Selector: 1, MatchingType: 1, Data: spki[:]}},
+				secure:  true,
+			},
+			replies:      startTLS,
+			wantErr:      ErrDANEMismatch,
+			wantStartTLS: true,
+		},
+		{
+			name: "Unusable records",
+			resolver: &testTLSAResolver{
+				records: []TLSARecord{{Usage: 1, Selector: 1, MatchingType: 1, Data: other[:]}},
+				secure:  true,
+			},
+			replies:      startTLS,
+			wantStartTLS: true,
+		},
+		{
+			name: "STARTTLS unsupported",
+			resolver: &testTLSAResolver{
+				records: []TLSARecord{{Usage: 3, Selector: 1, MatchingType: 1, Data: spki[:]}},
+				secure:  true,
+			},
+			wantErr: ErrStartTLSUnsupported,
+		},
+		{
+			name: "Insecure records ignored",
+			resolver: &testTLSAResolver{
+				records: []TLSARecord{{Usage: 3, Selector: 1, MatchingType: 1, Data: other[:]}},
+			},
+		},
+		{
+			name:     "No records",
+			resolver: &testTLSAResolver{secure: true},
+		},
+		{
+			name:       "Lookup failed",
+			resolver:   &testTLSAResolver{err: errors.New("SERVFAIL")},
+			replies:    startTLS,
+			wantAnyErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := startTestSMTPServer(&testSMTPServer{
+				l:         l,
+				replies:   tt.replies,
+				tlsConfig: &tls.Config{Certificates: []tls.Certificate{chain}},
+			})
+			defer srv.Close()
+
+			mail := New("mx.example.org:25", nil)
+			mail.Dialer(redirectDialer{addr: srv.Addr()})
+			mail.DANE(tt.resolver)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+
+			_, _, err = mail.Send("localhost")
+			switch {
+			case tt.wantAnyErr:
+				if err == nil {
+					t.Fatal("MailYak.Send() error = nil, want error")
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Fatalf("MailYak.Send() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got := tt.resolver.names; len(got) == 0 || got[0] != "_25._tcp.mx.example.org" {
+				t.Errorf("LookupTLSA() names = %v, want _25._tcp.mx.example.org", got)
+			}
+
+			var gotStartTLS bool
+			for _, cmd := range srv.Commands() {
+				gotStartTLS = gotStartTLS || cmd == "STARTTLS"
+			}
+			if gotStartTLS != tt.wantStartTLS {
+				t.Errorf("MailYak.Send() STARTTLS = %v, want %v", gotStartTLS, tt.wantStartTLS)
+			}
+		})
+	}
+}
+
+// TestMailYakDANE_ipAddress ensures TLSA records are not looked up for servers
+// addressed by IP address.
+func TestMailYakDANE_ipAddress(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTestSMTPServer(l, nil)
+	defer srv.Close()
+
+	resolver := &testTLSAResolver{err: errors.New("SERVFAIL")}
+
+	mail := New(srv.Addr(), nil)
+	mail.DANE(resolver)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+
+	if _, _, err := mail.Send("localhost"); err != nil {
+		t.Fatalf("MailYak.Send() error = %v", err)
+	}
+
+	if len(resolver.names) != 0 {
+		t.Errorf("LookupTLSA() names = %v, want no lookups", resolver.names)
+	}
+}
@@ -0,0 +1,204 @@
+package mailyak
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// DebugWriter logs the SMTP conversation with the server to w, to help
+// diagnose connection, STARTTLS and rejection problems:
+//
+//	mail.DebugWriter(os.Stderr)
+//
+// Each line sent to the server is prefixed with "C: ", and each line received
+// with "S: ", with the conversation logged unencrypted when using TLS. The
+// credentials sent during authentication are redacted, but the message content
+// is logged in full.
+//
+// When sending with a Pool or Queue, the conversations of concurrent
+// connections are written to w concurrently. Passing nil disables logging.
+func (m *MailYak) DebugWriter(w io.Writer) {
+	m.debugWriter = w
+}
+
+// debugLog writes the SMTP conversation of a connection to w, one line at a
+// time.
+type debugLog struct {
+	w io.Writer
+
+	// partial lines written by the client and server
+	client []byte
+	server []byte
+
+	// inAuth is true during an authentication exchange, while the lines sent
+	// by the client are redacted
+	inAuth bool
+}
+
+// clientWriter returns an io.Writer logging the data sent by the client.
+func (d *debugLog) clientWriter() io.Writer {
+	return debugLogWriter(func(p []byte) { d.client = d.lines(d.client, p, d.clientLine) })
+}
+
+// serverWriter returns an io.Writer logging the data sent by the server.
+func (d *debugLog) serverWriter() io.Writer {
+	return debugLogWriter(func(p []byte) { d.server = d.lines(d.server, p, d.serverLine) })
+}
+
+// lines appends p to the partial line buf, calling fn with each complete line
+// and returning the remaining partial line.
+func (d *debugLog) lines(buf, p []byte, fn func(line string)) []byte {
+	buf = append(buf, p...)
+	for {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			return buf
+		}
+
+		fn(strings.TrimSuffix(string(buf[:i]), "\r"))
+		buf = buf[i+1:]
+	}
+}
+
+func (d *debugLog) clientLine(line string) {
+	switch {
+	case d.inAuth:
+		line = "[redacted]"
+
+	case len(line) >= 5 && strings.EqualFold(line[:5], "AUTH "):
+		d.inAuth = true
+
+		// redact any initial response following the mechanism name
+		if f := strings.Fields(line); len(f) > 2 {
+			line = f[0] + " " + f[1] + " [redacted]"
+		}
+	}
+
+	fmt.Fprintf(d.w, "C: %s\n", line)
+}
+
+func (d *debugLog) serverLine(line string) {
+	// the exchange continues while the server responds with a challenge
+	if d.inAuth && !strings.HasPrefix(line, "334") {
+		d.inAuth = false
+	}
+
+	fmt.Fprintf(d.w, "S: %s\n", line)
+}
+
+// debugLogWriter is an io.Writer passing each write to a logging function.
+type debugLogWriter func(p []byte)
+
+func (fn debugLogWriter) Write(p []byte) (int, error) {
+	fn(p)
+	return len(p), nil
+}
+
+// debugConn is a net.Conn logging the data read and written to a debugLog.
+type debugConn struct {
+	net.Conn
+	log *debugLog
+
+	// tls is true if Conn is encrypted
+	tls bool
+
+	// tlsConn is the connection upgraded by startTLS, if any
+	tlsConn *tls.Conn
+}
+
+// newDebugConn returns conn logging to w if set, or conn unchanged otherwise.
+func newDebugConn(conn net.Conn, w io.Writer, encrypted bool) net.Conn {
+	if w == nil {
+		return conn
+	}
+	return &debugConn{Conn: conn, log: &debugLog{w: w}, tls: encrypted}
+}
+
+func (c *debugConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.log.serverWriter().Write(p[:n])
+	return n, err
+}
+
+func (c *debugConn) Write(p []byte) (int, error) {
+	c.log.clientWriter().Write(p)
+	return c.Conn.Write(p)
+}
+
+// startTLS upgrades the SMTP session over c with STARTTLS, returning a new
+// session logging the decrypted conversation.
+//
+// smtpClient.StartTLS() cannot be used as it encrypts the connection it was
+// created with, which would log the encrypted data. Instead the STARTTLS
+// command is issued directly, and a new session is started over the encrypted
+// connection.
+func (c *debugConn) startTLS(smtpClient *smtp.Client, config *tls.Config, serverName, localHostName string) (*smtp.Client, error) {
+	id, err := smtpClient.Text.Cmd("STARTTLS")
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := readResponse(smtpClient, id, 220); err != nil {
+		return nil, err
+	}
+
+	tlsConn := tls.Client(c.Conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, err
+	}
+
+	// the server does not greet the client again, so the new session is
+	// given the greeting it expects without it being logged
+	upgraded := &debugConn{Conn: tlsConn, log: c.log, tls: true}
+	client, err := smtp.NewClient(&greetedConn{Conn: upgraded, greeting: []byte("220 " + serverName + "\r\n")}, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Hello(localHostName); err != nil {
+		return nil, err
+	}
+
+	c.tls = true
+	c.tlsConn = tlsConn
+	return client, nil
+}
+
+// greetedConn is a net.Conn returning greeting before the data read from the
+// connection.
+type greetedConn struct {
+	net.Conn
+	greeting []byte
+}
+
+func (c *greetedConn) Read(p []byte) (int, error) {
+	if len(c.greeting) > 0 {
+		n := copy(p, c.greeting)
+		c.greeting = c.greeting[n:]
+		return n, nil
+	}
+	return c.Conn.Read(p)
+}
+
+// tlsAuth is an smtp.Auth reporting the connection as encrypted to auth.
+//
+// smtp.Client only recognises a connection as encrypted if it is a *tls.Conn,
+// so an encrypted debugConn is treated as unencrypted, causing
+// smtp.PlainAuth to refuse to send credentials.
+type tlsAuth struct {
+	auth smtp.Auth
+}
+
+func (a tlsAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	info := *server
+	info.TLS = true
+	return a.auth.Start(&info)
+}
+
+func (a tlsAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	return a.auth.Next(fromServer, more)
+}
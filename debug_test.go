@@ -0,0 +1,171 @@
+package mailyak
+
+import (
+	"bytes"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// TestMailYakDebugWriter ensures the SMTP conversation is logged, with
+// credentials redacted.
+func TestMailYakDebugWriter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		tls  string
+		auth smtp.Auth
+		// Want
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "PLAIN",
+			auth: smtp.PlainAuth("", "user", "secret", "127.0.0.1"),
+			want: []string{
+				"S: 220 localhost ESMTP\n",
+				"C: EHLO localhost\n",
+				"S: 250-localhost\n",
+				"S: 250 AUTH PLAIN LOGIN\n",
+				"C: AUTH PLAIN [redacted]\n",
+				"S: 235 OK\n",
+				"C: MAIL FROM:<from@example.org>\n",
+				"C: Subject: Debug\n",
+				"C: .\n",
+			},
+			notWant: []string{"AHVzZXIAc2VjcmV0"},
+		},
+		{
+			name: "LOGIN",
+			auth: LoginAuth("user", "secret"),
+			want: []string{
+				"C: AUTH LOGIN\n",
+				"S: 334 VXNlcm5hbWU6\n",
+				"C: [redacted]\n",
+				"S: 334 UGFzc3dvcmQ6\n",
+				"C: [redacted]\n",
+				"S: 235 OK\n",
+				"C: MAIL FROM:<from@example.org>\n",
+			},
+			notWant: []string{"dXNlcg==", "c2VjcmV0"},
+		},
+		{
+			name: "Implicit TLS",
+			tls:  "implicit",
+			auth: smtp.PlainAuth("", "user", "secret", "127.0.0.1"),
+			want: []string{
+				"S: 220 localhost ESMTP\n",
+				"C: EHLO localhost\n",
+				"C: AUTH PLAIN [redacted]\n",
+				"C: MAIL FROM:<from@example.org>\n",
+			},
+		},
+		{
+			name: "STARTTLS",
+			tls:  "starttls",
+			auth: smtp.PlainAuth("", "user", "secret", "127.0.0.1"),
+			want: []string{
+				"C: EHLO localhost\n",
+				"S: 250-STARTTLS\n",
+				"C: STARTTLS\n",
+				"S: 220 Go ahead\n",
+				"C: EHLO localhost\n",
+				"C: AUTH PLAIN [redacted]\n",
+				"C: MAIL FROM:<from@example.org>\n",
+				"C: Subject: Debug\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			replies := map[string]string{
+				"EHLO":     "250-localhost\r\n250 AUTH PLAIN LOGIN",
+				"AUTH":     "235 OK",
+				"STARTTLS": "220 Go ahead",
+			}
+			if _, ok := tt.auth.(*loginAuth); ok {
+				replies["AUTH"] = "334 VXNlcm5hbWU6"
+				replies["DXNLCG=="] = "334 UGFzc3dvcmQ6"
+				replies["C2VJCMV0"] = "235 OK"
+			}
+
+			var mail *MailYak
+			switch tt.tls {
+			case "implicit":
+				srv, config := newTLSTestSMTPServer(t, replies)
+				defer srv.Close()
+
+				mail = NewWithTLS(srv.Addr(), tt.auth, config)
+
+			case "starttls":
+				replies["EHLO"] = "250-localhost\r\n250-STARTTLS\r\n250 AUTH PLAIN"
+				srv, config := newStartTLSTestSMTPServer(t, replies)
+				defer srv.Close()
+
+				mail = New(srv.Addr(), tt.auth)
+				mail.TLSConfig(config)
+
+			default:
+				l, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				srv := newTestSMTPServer(l, replies)
+				defer srv.Close()
+
+				mail = New(srv.Addr(), tt.auth)
+			}
+
+			var log syncBuffer
+			mail.DebugWriter(&log)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+			mail.Subject("Debug")
+
+			if _, _, err := mail.Send("localhost"); err != nil {
+				t.Fatalf("MailYak.Send() error = %v", err)
+			}
+
+			got := log.String()
+			for _, want := range tt.want {
+				i := strings.Index(got, want)
+				if i < 0 {
+					t.Fatalf("MailYak.Send() log =\n%s\nmissing %q in order", log.String(), want)
+				}
+				got = got[i+len(want):]
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(log.String(), notWant) {
+					t.Errorf("MailYak.Send() log contains %q", notWant)
+				}
+			}
+		})
+	}
+}
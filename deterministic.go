@@ -0,0 +1,45 @@
+package mailyak
+
+import (
+	"crypto/rand"
+	"io"
+	mathrand "math/rand"
+	"time"
+)
+
+// DeterministicOutput makes the generated MIME data byte-for-byte
+// reproducible, allowing emails to be compared against golden files in tests:
+//
+//	mail.DeterministicOutput(42)
+//	buf, err := mail.MimeBuf()
+//
+// The MIME boundaries and Message-ID are derived from seed rather than
+// generated randomly, and the Date header (unless set with Date), DKIM
+// signature timestamp and AttachZip file times use the Unix epoch. Emails
+// signed or encrypted with S/MIME or PGP are not reproducible.
+//
+// DeterministicOutput must not be used when sending emails, as predictable
+// MIME boundaries allow content injection attacks.
+func (m *MailYak) DeterministicOutput(seed int64) {
+	m.deterministic = true
+	m.seed = seed
+	m.messageID = ""
+}
+
+// randomSource returns the source of randomness used to generate MIME
+// boundaries and the Message-ID, seeded by DeterministicOutput if set.
+func (m *MailYak) randomSource() io.Reader {
+	if m.deterministic {
+		return mathrand.New(mathrand.NewSource(m.seed))
+	}
+	return rand.Reader
+}
+
+// now returns the current time, or the Unix epoch if DeterministicOutput is
+// set.
+func (m *MailYak) now() time.Time {
+	if m.deterministic {
+		return time.Unix(0, 0).UTC()
+	}
+	return time.Now()
+}
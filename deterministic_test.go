@@ -0,0 +1,79 @@
+package mailyak
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+// TestMailYakDeterministicOutput ensures emails built with the same seed are
+// identical, and differ from those built with another seed.
+func TestMailYakDeterministicOutput(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		dkim bool
+	}{
+		{"Plain", false},
+		{"DKIM", true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			build := func(seed int64) []byte {
+				m := New("", nil)
+				m.From("from@example.org")
+				m.To("to@example.org")
+				m.Subject("Golden")
+				m.HTML().Set("<p>HTML</p>")
+				m.Plain().Set("Plain")
+				m.Attach("test.txt", strings.NewReader("attachment"))
+				m.DeterministicOutput(seed)
+
+				if tt.dkim {
+					if err := m.DKIM(DKIMOptions{Domain: "example.org", Selector: "s", Signer: key}); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				buf, err := m.MimeBuf()
+				if err != nil {
+					t.Fatal(err)
+				}
+				return buf.Bytes()
+			}
+
+			first, second := build(42), build(42)
+			if !bytes.Equal(first, second) {
+				t.Errorf("%q. MailYak.MimeBuf() = %q, want %q", tt.name, second, first)
+			}
+
+			if bytes.Equal(first, build(43)) {
+				t.Errorf("%q. MailYak.MimeBuf() with another seed is identical", tt.name)
+			}
+
+			got := string(first)
+			if want := "Date: Thu, 01 Jan 1970 00:00:00 +0000\r\n"; !strings.Contains(got, want) {
+				t.Errorf("%q. MailYak.MimeBuf() = %q, want %q", tt.name, got, want)
+			}
+			if want := "Message-ID: <0."; !strings.Contains(got, want) {
+				t.Errorf("%q. MailYak.MimeBuf() = %q, want %q", tt.name, got, want)
+			}
+			if tt.dkim && !strings.Contains(got, "; t=0;") {
+				t.Errorf("%q. MailYak.MimeBuf() = %q, want DKIM timestamp 0", tt.name, got)
+			}
+		})
+	}
+}
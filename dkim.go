@@ -0,0 +1,311 @@
+package mailyak
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Canonicalization is a DKIM canonicalization algorithm, as defined in RFC 6376
+// section 3.4.
+type Canonicalization string
+
+const (
+	// CanonicalizationSimple tolerates almost no modification of the message.
+	CanonicalizationSimple Canonicalization = "simple"
+
+	// CanonicalizationRelaxed tolerates common modifications such as
+	// whitespace replacement and header line rewrapping.
+	CanonicalizationRelaxed Canonicalization = "relaxed"
+)
+
+// defaultDKIMHeaders are the headers signed when DKIMOptions.Headers is empty.
+//
+// Only headers present in the email are signed.
+var defaultDKIMHeaders = []string{
+	"From",
+	"Sender",
+	"Reply-To",
+	"Subject",
+	"Date",
+	"To",
+	"Cc",
+	"Message-ID",
+	"In-Reply-To",
+	"References",
+	"Mime-Version",
+	"Content-Type",
+	"List-Unsubscribe",
+	"List-Unsubscribe-Post",
+}
+
+// DKIMOptions configures DKIM signing of outgoing emails.
+type DKIMOptions struct {
+	// Domain is the signing domain (the d= tag), typically the domain of the
+	// From address.
+	Domain string
+
+	// Selector identifies the public key within Domain (the s= tag), published
+	// in DNS at <Selector>._domainkey.<Domain>.
+	Selector string
+
+	// Signer is the private key used to sign emails, either an
+	// *rsa.PrivateKey or an ed25519.PrivateKey.
+	Signer crypto.Signer
+
+	// HeaderCanonicalization is the canonicalization algorithm applied to the
+	// signed headers. Defaults to CanonicalizationRelaxed.
+	HeaderCanonicalization Canonicalization
+
+	// BodyCanonicalization is the canonicalization algorithm applied to the
+	// email body. Defaults to CanonicalizationRelaxed.
+	BodyCanonicalization Canonicalization
+
+	// Headers lists the names of the headers to sign. The From header is
+	// always signed. Defaults to the common identifying headers (From, To,
+	// Subject, Date, etc).
+	Headers []string
+}
+
+// dkimSigner generates DKIM-Signature headers.
+type dkimSigner struct {
+	opts      DKIMOptions
+	algorithm string
+	now       func() time.Time
+}
+
+// DKIM enables DKIM signing (RFC 6376) of the email with the key and domain
+// in opts. A DKIM-Signature header is added to the generated MIME when sending
+// or calling MimeBuf.
+//
+// Both rsa-sha256 and ed25519-sha256 (RFC 8463) signatures are supported.
+//
+//	key, _ := x509.ParsePKCS1PrivateKey(der)
+//	err := mail.DKIM(mailyak.DKIMOptions{
+//		Domain:   "itsallbroken.com",
+//		Selector: "mail",
+//		Signer:   key,
+//	})
+func (m *MailYak) DKIM(opts DKIMOptions) error {
+	if opts.Domain == "" || opts.Selector == "" {
+		return errors.New("mailyak: DKIM domain and selector are required")
+	}
+
+	var algorithm string
+	switch opts.Signer.(type) {
+	case *rsa.PrivateKey:
+		algorithm = "rsa-sha256"
+	case ed25519.PrivateKey:
+		algorithm = "ed25519-sha256"
+	default:
+		return fmt.Errorf("mailyak: unsupported DKIM signer %T", opts.Signer)
+	}
+
+	if opts.HeaderCanonicalization == "" {
+		opts.HeaderCanonicalization = CanonicalizationRelaxed
+	}
+	if opts.BodyCanonicalization == "" {
+		opts.BodyCanonicalization = CanonicalizationRelaxed
+	}
+
+	for _, c := range []Canonicalization{opts.HeaderCanonicalization, opts.BodyCanonicalization} {
+		if c != CanonicalizationSimple && c != CanonicalizationRelaxed {
+			return fmt.Errorf("mailyak: unsupported DKIM canonicalization %q", c)
+		}
+	}
+
+	if len(opts.Headers) == 0 {
+		opts.Headers = defaultDKIMHeaders
+	}
+
+	// The From header must always be signed
+	if !containsFold(opts.Headers, "From") {
+		opts.Headers = append([]string{"From"}, opts.Headers...)
+	}
+
+	m.dkim = &dkimSigner{opts: opts, algorithm: algorithm, now: time.Now}
+	return nil
+}
+
+// containsFold returns true if s contains v, ignoring case.
+func containsFold(s []string, v string) bool {
+	for _, item := range s {
+		if strings.EqualFold(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// sign returns a copy of msg with a DKIM-Signature header prepended.
+func (d *dkimSigner) sign(msg []byte) (*bytes.Buffer, error) {
+	// Line endings are normalised to CRLF, as they are when sent over SMTP
+	normalised := getBuffer()
+	defer putBuffer(normalised)
+
+	writeCRLF(normalised, msg)
+	msg = normalised.Bytes()
+
+	headers, body := splitMessage(msg)
+
+	canonical := getBuffer()
+	writeCanonicalBody(canonical, body, d.opts.BodyCanonicalization)
+	bodyHash := sha256.Sum256(canonical.Bytes())
+	putBuffer(canonical)
+
+	// Select the header fields to sign, from the bottom up for repeated
+	// headers
+	var (
+		names  []string
+		signed []string
+	)
+	for _, name := range d.opts.Headers {
+		fields := headerFields(headers, name)
+		for i := len(fields) - 1; i >= 0; i-- {
+			names = append(names, strings.ToLower(name))
+			signed = append(signed, fields[i])
+		}
+	}
+
+	if len(signed) == 0 {
+		return nil, errors.New("mailyak: DKIM signing requires a From header")
+	}
+
+	sig := fmt.Sprintf(
+		"DKIM-Signature: v=1; a=%s; c=%s/%s; d=%s; s=%s; t=%d;\r\n\th=%s;\r\n\tbh=%s;\r\n\tb=",
+		d.algorithm,
+		d.opts.HeaderCanonicalization,
+		d.opts.BodyCanonicalization,
+		d.opts.Domain,
+		d.opts.Selector,
+		d.now().Unix(),
+		strings.Join(names, ":"),
+		base64.StdEncoding.EncodeToString(bodyHash[:]),
+	)
+
+	h := sha256.New()
+	for _, field := range signed {
+		h.Write([]byte(canonicalHeader(field, d.opts.HeaderCanonicalization)))
+	}
+
+	// The signature header itself is signed without the trailing CRLF
+	h.Write([]byte(strings.TrimSuffix(canonicalHeader(sig+"\r\n", d.opts.HeaderCanonicalization), "\r\n")))
+
+	var (
+		b   []byte
+		err error
+	)
+	switch d.opts.Signer.(type) {
+	case ed25519.PrivateKey:
+		// RFC 8463 signs the SHA-256 hash with PureEd25519
+		b, err = d.opts.Signer.Sign(rand.Reader, h.Sum(nil), crypto.Hash(0))
+	default:
+		b, err = d.opts.Signer.Sign(rand.Reader, h.Sum(nil), crypto.SHA256)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(sig) + len(msg) + 512)
+	buf.WriteString(sig)
+	buf.WriteString(foldBase64(base64.StdEncoding.EncodeToString(b)))
+	buf.WriteString("\r\n")
+	buf.Write(msg)
+
+	return &buf, nil
+}
+
+// foldBase64 breaks s into lines of at most 72 characters, folded with CRLF
+// and a tab.
+func foldBase64(s string) string {
+	const lineLen = 72
+
+	var b strings.Builder
+	for len(s) > lineLen {
+		b.WriteString(s[:lineLen])
+		b.WriteString("\r\n\t")
+		s = s[lineLen:]
+	}
+	b.WriteString(s)
+
+	return b.String()
+}
+
+// canonicalHeader returns the header field canonicalised with c.
+func canonicalHeader(field string, c Canonicalization) string {
+	if c == CanonicalizationSimple {
+		return field
+	}
+
+	i := strings.IndexByte(field, ':')
+	name := strings.ToLower(strings.TrimRight(field[:i], " \t"))
+
+	// Unfold, and reduce all whitespace to a single space
+	value := strings.Replace(field[i+1:], "\r\n", "", -1)
+	value = strings.Join(strings.FieldsFunc(value, isWSP), " ")
+
+	return name + ":" + value + "\r\n"
+}
+
+// canonicalBody returns the message body canonicalised with c.
+func canonicalBody(body []byte, c Canonicalization) []byte {
+	var buf bytes.Buffer
+	writeCanonicalBody(&buf, body, c)
+	return buf.Bytes()
+}
+
+// writeCanonicalBody writes the message body canonicalised with c to buf.
+func writeCanonicalBody(buf *bytes.Buffer, body []byte, c Canonicalization) {
+	start := buf.Len()
+	buf.Grow(len(body) + 2)
+
+	if c == CanonicalizationRelaxed {
+		for len(body) > 0 {
+			line := body
+			if i := bytes.Index(body, []byte("\r\n")); i >= 0 {
+				line, body = body[:i], body[i+2:]
+			} else {
+				body = nil
+			}
+
+			// Reduce whitespace sequences to a single space, and remove
+			// trailing whitespace
+			space := false
+			for _, b := range line {
+				if isWSP(rune(b)) {
+					space = true
+					continue
+				}
+				if space {
+					buf.WriteByte(' ')
+					space = false
+				}
+				buf.WriteByte(b)
+			}
+			buf.WriteString("\r\n")
+		}
+	} else {
+		buf.Write(body)
+	}
+
+	// Remove all trailing empty lines, ensuring the body ends with CRLF
+	out := bytes.TrimRight(buf.Bytes()[start:], "\r\n")
+	buf.Truncate(start + len(out))
+	if len(out) == 0 && c == CanonicalizationRelaxed {
+		return
+	}
+	buf.WriteString("\r\n")
+}
+
+func isWSP(r rune) bool {
+	return r == ' ' || r == '\t'
+}
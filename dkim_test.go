@@ -0,0 +1,245 @@
+package mailyak
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCanonicalHeader ensures headers are canonicalised as described in
+// RFC 6376 section 3.4.5.
+func TestCanonicalHeader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		field string
+		c     Canonicalization
+		// Want
+		want string
+	}{
+		{"Simple", "A: X\r\n", CanonicalizationSimple, "A: X\r\n"},
+		{"Simple folded", "B : Y\t\r\n\tZ  \r\n", CanonicalizationSimple, "B : Y\t\r\n\tZ  \r\n"},
+		{"Relaxed", "A: X\r\n", CanonicalizationRelaxed, "a:X\r\n"},
+		{"Relaxed folded", "B : Y\t\r\n\tZ  \r\n", CanonicalizationRelaxed, "b:Y Z\r\n"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := canonicalHeader(tt.field, tt.c); got != tt.want {
+				t.Errorf("%q. canonicalHeader() = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCanonicalBody ensures bodies are canonicalised as described in RFC 6376
+// section 3.4.5.
+func TestCanonicalBody(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		body string
+		c    Canonicalization
+		// Want
+		want string
+	}{
+		{"Simple", " C \r\nD \t E\r\n\r\n\r\n", CanonicalizationSimple, " C \r\nD \t E\r\n"},
+		{"Simple empty", "", CanonicalizationSimple, "\r\n"},
+		{"Simple no trailing CRLF", "A", CanonicalizationSimple, "A\r\n"},
+		{"Relaxed", " C \r\nD \t E\r\n\r\n\r\n", CanonicalizationRelaxed, " C\r\nD E\r\n"},
+		{"Relaxed empty", "", CanonicalizationRelaxed, ""},
+		{"Relaxed whitespace lines", "A\r\n \t\r\n\r\n", CanonicalizationRelaxed, "A\r\n"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := string(canonicalBody([]byte(tt.body), tt.c)); got != tt.want {
+				t.Errorf("%q. canonicalBody() = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMailYakDKIM_options ensures invalid DKIM options are rejected.
+func TestMailYakDKIM_options(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		opts DKIMOptions
+		// Want
+		wantErr bool
+	}{
+		{"Valid", DKIMOptions{Domain: "itsallbroken.com", Selector: "s", Signer: key}, false},
+		{"No domain", DKIMOptions{Selector: "s", Signer: key}, true},
+		{"No selector", DKIMOptions{Domain: "itsallbroken.com", Signer: key}, true},
+		{"No signer", DKIMOptions{Domain: "itsallbroken.com", Selector: "s"}, true},
+		{
+			"Bad canonicalization",
+			DKIMOptions{Domain: "itsallbroken.com", Selector: "s", Signer: key, BodyCanonicalization: "nope"},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := NewBlank()
+			if err := m.DKIM(tt.opts); (err != nil) != tt.wantErr {
+				t.Errorf("%q. MailYak.DKIM() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestMailYakDKIM ensures the generated DKIM-Signature header verifies with the
+// signing key.
+func TestMailYakDKIM(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		opts DKIMOptions
+		// Want
+		wantTags string
+		verify   func(hashed, sig []byte) bool
+	}{
+		{
+			"RSA relaxed",
+			DKIMOptions{Domain: "itsallbroken.com", Selector: "mail", Signer: rsaKey},
+			"v=1; a=rsa-sha256; c=relaxed/relaxed; d=itsallbroken.com; s=mail; t=1234567890;",
+			func(hashed, sig []byte) bool {
+				return rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, hashed, sig) == nil
+			},
+		},
+		{
+			"Ed25519 simple",
+			DKIMOptions{
+				Domain:                 "itsallbroken.com",
+				Selector:               "mail",
+				Signer:                 edKey,
+				HeaderCanonicalization: CanonicalizationSimple,
+				BodyCanonicalization:   CanonicalizationSimple,
+			},
+			"v=1; a=ed25519-sha256; c=simple/simple; d=itsallbroken.com; s=mail; t=1234567890;",
+			func(hashed, sig []byte) bool {
+				return ed25519.Verify(edPub, hashed, sig)
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.From("dom@itsallbroken.com")
+			m.To("one@itsallbroken.com", "two@itsallbroken.com")
+			m.Subject("DKIM")
+			m.Plain().Set("Hello,   world  \n\n")
+			m.Attach("test.txt", strings.NewReader("attachment"))
+
+			if err := m.DKIM(tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			m.dkim.now = func() time.Time { return time.Unix(1234567890, 0) }
+
+			buf, err := m.MimeBuf()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			headers, body := splitMessage(buf.Bytes())
+			sigFields := headerFields(headers, "DKIM-Signature")
+			if len(sigFields) != 1 {
+				t.Fatalf("%q. got %d DKIM-Signature headers, want 1", tt.name, len(sigFields))
+			}
+			sigField := sigFields[0]
+
+			if !strings.HasPrefix(sigField, "DKIM-Signature: "+tt.wantTags) {
+				t.Errorf("%q. DKIM-Signature = %q, want tags %q", tt.name, sigField, tt.wantTags)
+			}
+
+			if !strings.Contains(sigField, "h=from:subject:date:to:to:message-id:mime-version:content-type;") {
+				t.Errorf("%q. DKIM-Signature = %q, want signed headers", tt.name, sigField)
+			}
+
+			// Verify the body hash
+			bodyHash := sha256.Sum256(canonicalBody(body, m.dkim.opts.BodyCanonicalization))
+			if !strings.Contains(sigField, "bh="+base64.StdEncoding.EncodeToString(bodyHash[:])+";") {
+				t.Errorf("%q. DKIM-Signature = %q, want body hash", tt.name, sigField)
+			}
+
+			// Verify the signature over the signed headers
+			c := m.dkim.opts.HeaderCanonicalization
+			h := sha256.New()
+			for _, name := range []string{"From", "Subject", "Date"} {
+				h.Write([]byte(canonicalHeader(headerFields(headers, name)[0], c)))
+			}
+			to := headerFields(headers, "To")
+			h.Write([]byte(canonicalHeader(to[1], c)))
+			h.Write([]byte(canonicalHeader(to[0], c)))
+			for _, name := range []string{"Message-ID", "Mime-Version", "Content-Type"} {
+				h.Write([]byte(canonicalHeader(headerFields(headers, name)[0], c)))
+			}
+
+			b := regexp.MustCompile(`b=([A-Za-z0-9+/=\r\n\t]+)\r\n$`).FindStringSubmatch(sigField)
+			if b == nil {
+				t.Fatalf("%q. DKIM-Signature = %q, missing b= tag", tt.name, sigField)
+			}
+			unsigned := strings.TrimSuffix(sigField, b[1]+"\r\n")
+			h.Write([]byte(strings.TrimSuffix(canonicalHeader(unsigned+"\r\n", c), "\r\n")))
+
+			sig, err := base64.StdEncoding.DecodeString(strings.NewReplacer("\r\n", "", "\t", "").Replace(b[1]))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !tt.verify(h.Sum(nil), sig) {
+				t.Errorf("%q. DKIM signature does not verify", tt.name)
+			}
+
+			if !bytes.HasPrefix(buf.Bytes(), []byte("DKIM-Signature: ")) {
+				t.Errorf("%q. DKIM-Signature is not the first header", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package mailyak
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+)
+
+// ErrDryRunTransport is returned by SendDryRun when a Transport is set, as the
+// email can only be checked against an SMTP server.
+var ErrDryRunTransport = errors.New("mailyak: dry run is not supported with a Transport")
+
+// SendDryRun checks the SMTP server would accept the email without sending it,
+// such as to check the server configuration and credentials, or whether the
+// recipients exist before sending:
+//
+//	result, err := mail.SendDryRun(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	log.Printf("%d recipients accepted", len(result.Accepted))
+//
+// The email is validated, then a connection is established as when sending,
+// negotiating STARTTLS and authenticating as configured, and the MAIL and RCPT
+// commands are issued for the envelope sender and recipients. The transaction
+// is then abandoned with RSET and the connection closed with QUIT, without
+// sending the message data - the attachments are not read, and the OnBefore
+// and OnAfterSend hooks are not called.
+//
+// The returned SendResult records the response to each recipient, and is
+// returned alongside any *RecipientRejectedError. As when sending, rejected
+// recipients fail the dry run unless PartialDelivery is enabled and at least
+// one recipient is accepted. The server may still reject the message data,
+// such as for its content or size, when it is sent.
+//
+// localHostName is used as for Send.
+func (m *MailYak) SendDryRun(ctx context.Context, localHostName string) (*SendResult, error) {
+	if m.transport != nil {
+		return nil, ErrDryRunTransport
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel, ctxErr := m.sendContext(ctx)
+	defer cancel()
+
+	sc, err := m.connect(ctx, localHostName, mtaSTSCheck{client: m.mtaSTS, rcpts: m.recipients()})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctxErr()
+		}
+		return nil, err
+	}
+	conn, smtpClient, host := sc.conn, sc.client, sc.host
+
+	defer closeOnDone(ctx, conn)()
+	defer smtpClient.Close()
+
+	if err := setDeadline(conn, m.timeouts.Data); err != nil {
+		return nil, err
+	}
+
+	// the message is never written, so it is not built
+	msg := &mimeMessage{m: m, result: &SendResult{}}
+
+	_, _, _, err = startMail(smtpClient, m.envelopeSender(), m.recipients(), msg)
+
+	result := msg.result
+	result.Host = host
+	result.TLS = tlsState(smtpClient, conn)
+
+	// the session remains usable after the server rejects a command, so the
+	// transaction is abandoned and the session ended either way
+	var tpErr *textproto.Error
+	if err == nil || errors.As(err, &tpErr) {
+		endErr := smtpClient.Reset()
+		if endErr == nil {
+			endErr = smtpClient.Quit()
+		}
+		if err == nil {
+			err = endErr
+		}
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return result, ctxErr()
+		}
+		return result, stageError("data", err)
+	}
+
+	return result, nil
+}
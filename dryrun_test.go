@@ -0,0 +1,160 @@
+package mailyak
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// unreadableReader is an io.Reader failing the test if it is read.
+type unreadableReader struct {
+	t *testing.T
+}
+
+func (r unreadableReader) Read(p []byte) (int, error) {
+	r.t.Error("attachment read during dry run")
+	return 0, io.EOF
+}
+
+// TestMailYakSendDryRun ensures the envelope is checked with the server
+// without sending the message data.
+func TestMailYakSendDryRun(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		partial  bool
+		rejected []string
+		// Want
+		wantErr      error
+		wantAccepted int
+		wantRejected int
+	}{
+		{
+			name:         "Accepted",
+			wantAccepted: 2,
+		},
+		{
+			name:         "Rejected",
+			rejected:     []string{"b@example.org"},
+			wantErr:      ErrRecipientRejected,
+			wantAccepted: 1,
+			wantRejected: 1,
+		},
+		{
+			name:         "Partial",
+			partial:      true,
+			rejected:     []string{"b@example.org"},
+			wantAccepted: 1,
+			wantRejected: 1,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			replies := map[string]string{}
+			for _, addr := range tt.rejected {
+				replies["RCPT TO:<"+addr+">"] = "550 No such user"
+			}
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, replies)
+			defer srv.Close()
+
+			mail := New(srv.Addr(), nil)
+			mail.PartialDelivery(tt.partial)
+			mail.From("from@example.org")
+			mail.To("a@example.org", "b@example.org")
+			mail.Attach("a.txt", unreadableReader{t})
+
+			got, err := mail.SendDryRun(context.Background(), "localhost")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MailYak.SendDryRun() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got == nil {
+				t.Fatal("MailYak.SendDryRun() result = nil")
+			}
+			if len(got.Accepted) != tt.wantAccepted || len(got.Rejected) != tt.wantRejected {
+				t.Errorf("MailYak.SendDryRun() accepted %v, rejected %v, want %d and %d", got.Accepted, got.Rejected, tt.wantAccepted, tt.wantRejected)
+			}
+			if got.Host != srv.Addr() {
+				t.Errorf("MailYak.SendDryRun() host = %q, want %q", got.Host, srv.Addr())
+			}
+
+			cmds := srv.Commands()
+			for _, cmd := range cmds {
+				if strings.HasPrefix(cmd, "DATA") || strings.HasPrefix(cmd, "BDAT") {
+					t.Errorf("MailYak.SendDryRun() sent %q", cmd)
+				}
+			}
+			if n := len(cmds); n < 2 || cmds[n-2] != "RSET" || cmds[n-1] != "QUIT" {
+				t.Errorf("MailYak.SendDryRun() commands = %v, want RSET and QUIT last", cmds)
+			}
+
+			if mail.GetSendResult() != nil {
+				t.Errorf("MailYak.GetSendResult() = %+v, want nil", mail.GetSendResult())
+			}
+		})
+	}
+}
+
+// TestMailYakSendDryRun_notSent ensures an invalid email or one sent with a
+// Transport fails the dry run without connecting to the server.
+func TestMailYakSendDryRun_notSent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		to        string
+		transport Transport
+		// Want
+		wantErr error
+	}{
+		{"Transport", "to@example.org", NewSendmailTransport(), ErrDryRunTransport},
+		{"Invalid address", "garbage", nil, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dialer := &testDialer{}
+
+			mail := New("mail.invalid:25", nil)
+			mail.Dialer(dialer)
+			mail.From("from@example.org")
+			mail.To(tt.to)
+			if tt.transport != nil {
+				mail.Transport(tt.transport)
+			}
+
+			_, err := mail.SendDryRun(context.Background(), "")
+
+			var addrErr *AddressError
+			switch {
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Errorf("MailYak.SendDryRun() error = %v, want %v", err, tt.wantErr)
+			case tt.wantErr == nil && !errors.As(err, &addrErr):
+				t.Errorf("MailYak.SendDryRun() error = %v, want *AddressError", err)
+			}
+
+			if len(dialer.addrs) != 0 {
+				t.Errorf("MailYak.SendDryRun() dialed %v, want no connection", dialer.addrs)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package mailyak
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DSNReturn is the portion of the email included in a failure notification.
+type DSNReturn string
+
+const (
+	// DSNReturnFull requests the full email is returned in failure
+	// notifications.
+	DSNReturnFull DSNReturn = "FULL"
+
+	// DSNReturnHeaders requests only the headers of the email are returned in
+	// failure notifications.
+	DSNReturnHeaders DSNReturn = "HDRS"
+)
+
+// DSNNotify is a delivery outcome for which a notification is requested.
+type DSNNotify string
+
+const (
+	// DSNNotifySuccess requests a notification when the email is delivered.
+	DSNNotifySuccess DSNNotify = "SUCCESS"
+
+	// DSNNotifyFailure requests a notification when the email cannot be
+	// delivered.
+	DSNNotifyFailure DSNNotify = "FAILURE"
+
+	// DSNNotifyDelay requests a notification when delivery of the email is
+	// delayed.
+	DSNNotifyDelay DSNNotify = "DELAY"
+
+	// DSNNotifyNever requests no notifications are sent, and cannot be
+	// combined with any other DSNNotify value.
+	DSNNotifyNever DSNNotify = "NEVER"
+)
+
+// DSNOptions configures the Delivery Status Notifications (RFC 3461) requested
+// for an email.
+type DSNOptions struct {
+	// Return is the portion of the email included in failure notifications.
+	// If empty, the server default is used.
+	Return DSNReturn
+
+	// Notify is the delivery outcomes each recipient is notified of. If empty,
+	// the server default (typically failures only) is used.
+	Notify []DSNNotify
+
+	// EnvelopeID is an identifier included in notifications, allowing them to
+	// be matched to the sent email.
+	EnvelopeID string
+}
+
+// DSN requests Delivery Status Notifications for the email, reporting its
+// delivery outcome to the envelope sender:
+//
+//	mail.DSN(mailyak.DSNOptions{
+//		Return:     mailyak.DSNReturnHeaders,
+//		Notify:     []mailyak.DSNNotify{mailyak.DSNNotifySuccess, mailyak.DSNNotifyFailure},
+//		EnvelopeID: "order-1234",
+//	})
+//
+// The notifications are requested using the RET and ENVID parameters of the
+// MAIL command and the NOTIFY parameter of each RCPT command, which are only
+// sent when the SMTP server supports the DSN extension. DSN has no effect when
+// sending with a Transport other than the built-in SMTP client or a Pool.
+func (m *MailYak) DSN(opts DSNOptions) {
+	opts.Notify = append([]DSNNotify(nil), opts.Notify...)
+	m.dsn = &opts
+}
+
+// dsnParams returns the DSN parameters of the MAIL and RCPT commands for msg,
+// if Delivery Status Notifications were requested.
+func dsnParams(msg io.WriterTo) (mailParams, rcptParams []string) {
+	mm, ok := msg.(*mimeMessage)
+	if !ok || mm.m.dsn == nil {
+		return nil, nil
+	}
+	opts := mm.m.dsn
+
+	if opts.Return != "" {
+		mailParams = append(mailParams, "RET="+string(opts.Return))
+	}
+	if opts.EnvelopeID != "" {
+		mailParams = append(mailParams, "ENVID="+xtext(opts.EnvelopeID))
+	}
+
+	if len(opts.Notify) > 0 {
+		notify := make([]string, len(opts.Notify))
+		for i, n := range opts.Notify {
+			notify[i] = string(n)
+		}
+		rcptParams = append(rcptParams, "NOTIFY="+strings.Join(notify, ","))
+	}
+
+	return mailParams, rcptParams
+}
+
+// xtext encodes s as an xtext (RFC 3461, section 4), hex-encoding "+", "=" and
+// any characters outside the printable ASCII range.
+func xtext(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '!' || c > '~' || c == '+' || c == '=' {
+			fmt.Fprintf(&b, "+%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
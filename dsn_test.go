@@ -0,0 +1,129 @@
+package mailyak
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+// TestMailYakDSN ensures the DSN parameters are sent only when requested and
+// supported by the server.
+func TestMailYakDSN(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		ehlo string
+		dsn  *DSNOptions
+		// Want
+		wantCmds []string
+	}{
+		{
+			name: "Not requested",
+			ehlo: "250-localhost\r\n250 DSN",
+			wantCmds: []string{
+				"EHLO localhost",
+				"MAIL FROM:<from@example.org>",
+				"RCPT TO:<to@example.org>",
+				"RCPT TO:<bcc@example.org>",
+				"DATA",
+			},
+		},
+		{
+			name: "Supported",
+			ehlo: "250-localhost\r\n250-8BITMIME\r\n250 DSN",
+			dsn: &DSNOptions{
+				Return:     DSNReturnHeaders,
+				Notify:     []DSNNotify{DSNNotifySuccess, DSNNotifyFailure},
+				EnvelopeID: "order=1234 5",
+			},
+			wantCmds: []string{
+				"EHLO localhost",
+				"MAIL FROM:<from@example.org> BODY=8BITMIME RET=HDRS ENVID=order+3D1234+205",
+				"RCPT TO:<to@example.org> NOTIFY=SUCCESS,FAILURE",
+				"RCPT TO:<bcc@example.org> NOTIFY=SUCCESS,FAILURE",
+				"DATA",
+			},
+		},
+		{
+			name: "Unsupported",
+			ehlo: "250 localhost",
+			dsn: &DSNOptions{
+				Return: DSNReturnFull,
+				Notify: []DSNNotify{DSNNotifyNever},
+			},
+			wantCmds: []string{
+				"EHLO localhost",
+				"MAIL FROM:<from@example.org>",
+				"RCPT TO:<to@example.org>",
+				"RCPT TO:<bcc@example.org>",
+				"DATA",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, map[string]string{"EHLO": tt.ehlo})
+			defer srv.Close()
+
+			mail := New(srv.Addr(), nil)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+			mail.Bcc("bcc@example.org")
+			if tt.dsn != nil {
+				mail.DSN(*tt.dsn)
+			}
+
+			if _, _, err := mail.Send("localhost"); err != nil {
+				t.Fatalf("MailYak.Send() error = %v", err)
+			}
+
+			got := srv.Commands()
+			if len(got) > len(tt.wantCmds) {
+				got = got[:len(tt.wantCmds)]
+			}
+			if !reflect.DeepEqual(got, tt.wantCmds) {
+				t.Errorf("MailYak.Send() commands = %q, want %q", got, tt.wantCmds)
+			}
+		})
+	}
+}
+
+// TestXtext ensures DSN parameter values are encoded as xtext.
+func TestXtext(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		s string
+		// Want
+		want string
+	}{
+		{"Printable", "abc-123@example.org", "abc-123@example.org"},
+		{"Plus and equals", "a+b=c", "a+2Bb+3Dc"},
+		{"Space", "a b", "a+20b"},
+		{"Non-ASCII", "é", "+C3+A9"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := xtext(tt.s); got != tt.want {
+				t.Errorf("xtext() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package mailyak
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime/quotedprintable"
+	"strings"
+)
+
+// TransferEncoding is the Content-Transfer-Encoding (RFC 2045) of a MIME part.
+type TransferEncoding string
+
+// The transfer encodings that can be selected for body parts and attachments.
+const (
+	// EncodingAuto selects quoted-printable for body parts, and base64 for
+	// attachments (except attached messages, which are sent as 8bit).
+	EncodingAuto TransferEncoding = ""
+
+	// EncodingBase64 encodes the content as base64, broken into lines of 76
+	// characters.
+	EncodingBase64 TransferEncoding = "base64"
+
+	// EncodingQuotedPrintable encodes the content as quoted-printable,
+	// suitable for mostly ASCII text.
+	EncodingQuotedPrintable TransferEncoding = "quoted-printable"
+
+	// Encoding7Bit sends the content unencoded. The content must be ASCII
+	// text with lines of at most 998 characters. LF line endings are
+	// converted to CRLF.
+	Encoding7Bit TransferEncoding = "7bit"
+
+	// Encoding8Bit sends the content unencoded. The content must be text with
+	// lines of at most 998 characters, and requires a server supporting the
+	// 8BITMIME extension. LF line endings are converted to CRLF.
+	Encoding8Bit TransferEncoding = "8bit"
+)
+
+// err7Bit is returned when writing non-ASCII content with Encoding7Bit.
+var err7Bit = errors.New("mailyak: 7bit content contains non-ASCII data")
+
+// parseTransferEncoding returns the TransferEncoding named by s, or an error if
+// it is not supported.
+func parseTransferEncoding(s string) (TransferEncoding, error) {
+	switch e := TransferEncoding(strings.ToLower(strings.TrimSpace(s))); e {
+	case EncodingBase64, EncodingQuotedPrintable, Encoding7Bit, Encoding8Bit:
+		return e, nil
+	}
+	return "", fmt.Errorf("mailyak: unsupported Content-Transfer-Encoding %q", s)
+}
+
+// encoder returns a writer encoding the data written to it with e, writing the
+// encoded data to w. Close must be called to flush any buffered data.
+//
+// If text is false, quoted-printable encoding preserves line endings as binary
+// data. The 7bit and 8bit encodings always convert LF line endings to CRLF, as
+// the content must be lines of text (RFC 2045 section 2.7).
+func (e TransferEncoding) encoder(w io.Writer, text bool) (io.WriteCloser, error) {
+	switch e {
+	case EncodingBase64:
+		return base64.NewEncoder(base64.StdEncoding, lineSplitterBuilder{}.new(w)), nil
+	case EncodingQuotedPrintable:
+		qpw := quotedprintable.NewWriter(w)
+		qpw.Binary = !text
+		return qpw, nil
+	case Encoding7Bit:
+		return sevenBitWriter{&crlfWriter{w: w}}, nil
+	case Encoding8Bit:
+		return nopWriteCloser{&crlfWriter{w: w}}, nil
+	}
+	return nil, fmt.Errorf("mailyak: unsupported Content-Transfer-Encoding %q", string(e))
+}
+
+// sevenBitWriter writes to w, returning an error if any non-ASCII data is
+// written.
+type sevenBitWriter struct {
+	w io.Writer
+}
+
+func (s sevenBitWriter) Write(p []byte) (int, error) {
+	for i, c := range p {
+		if c >= 0x80 {
+			n, err := s.w.Write(p[:i])
+			if err != nil {
+				return n, err
+			}
+			return n, err7Bit
+		}
+	}
+	return s.w.Write(p)
+}
+
+func (s sevenBitWriter) Close() error { return nil }
+
+// crlfWriter writes to w, converting LF line endings not preceded by a CR to
+// CRLF, as when writing the message data with the DATA command.
+type crlfWriter struct {
+	w io.Writer
+
+	// cr is true if the last byte written was a CR
+	cr bool
+}
+
+func (c *crlfWriter) Write(p []byte) (int, error) {
+	n := 0
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			written, err := c.w.Write(p)
+			if len(p) > 0 {
+				c.cr = p[len(p)-1] == '\r'
+			}
+			return n + written, err
+		}
+
+		// write up to and including a CRLF line ending unchanged
+		if (i == 0 && c.cr) || (i > 0 && p[i-1] == '\r') {
+			written, err := c.w.Write(p[:i+1])
+			n += written
+			if err != nil {
+				return n, err
+			}
+		} else {
+			written, err := c.w.Write(p[:i])
+			n += written
+			if err != nil {
+				return n, err
+			}
+			if _, err := io.WriteString(c.w, "\r\n"); err != nil {
+				return n, err
+			}
+			n++
+		}
+
+		c.cr = false
+		p = p[i+1:]
+	}
+}
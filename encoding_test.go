@@ -0,0 +1,52 @@
+package mailyak
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestCRLFWriter ensures LF line endings are converted to CRLF, including CRLF
+// line endings split across writes.
+func TestCRLFWriter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		writes []string
+		// Want
+		want string
+	}{
+		{"LF", []string{"a\nb\n"}, "a\r\nb\r\n"},
+		{"CRLF", []string{"a\r\nb\r\n"}, "a\r\nb\r\n"},
+		{"Mixed", []string{"a\r\nb\nc"}, "a\r\nb\r\nc"},
+		{"Leading LF", []string{"\n\na"}, "\r\n\r\na"},
+		{"Bare CR", []string{"a\rb"}, "a\rb"},
+		{"Split CRLF", []string{"a\r", "\nb"}, "a\r\nb"},
+		{"Split LF", []string{"a", "\nb"}, "a\r\nb"},
+		{"Empty write", []string{"a\r", "", "\nb"}, "a\r\nb"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w := &crlfWriter{w: &buf}
+			for _, s := range tt.writes {
+				n, err := w.Write([]byte(s))
+				if err != nil {
+					t.Fatalf("crlfWriter.Write() error = %v", err)
+				}
+				if n != len(s) {
+					t.Errorf("crlfWriter.Write() = %d, want %d", n, len(s))
+				}
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("crlfWriter wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package mailyak
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+)
+
+var (
+	// ErrDial is wrapped by the error returned when the connection to the SMTP
+	// server cannot be established.
+	ErrDial = errors.New("mailyak: dial failed")
+
+	// ErrAuth is wrapped by the error returned when authenticating with the
+	// SMTP server fails.
+	ErrAuth = errors.New("mailyak: authentication failed")
+
+	// ErrRecipientRejected is matched by a *RecipientRejectedError, allowing
+	// rejected recipients to be detected with errors.Is.
+	ErrRecipientRejected = errors.New("mailyak: recipient rejected")
+
+	// ErrDataRejected is wrapped by the error returned when the SMTP server
+	// rejects the message content.
+	ErrDataRejected = errors.New("mailyak: message rejected")
+)
+
+// RecipientRejectedError is returned when the SMTP server rejects a recipient
+// address of the email:
+//
+//	var rejected *mailyak.RecipientRejectedError
+//	if errors.As(err, &rejected) && rejected.Code == 550 {
+//		unsubscribe(rejected.Addr)
+//	}
+//
+// A RecipientRejectedError matches ErrRecipientRejected with errors.Is.
+type RecipientRejectedError struct {
+	// Addr is the rejected recipient address.
+	Addr string
+
+	// Code and Msg are the response of the server to the RCPT command.
+	Code int
+	Msg  string
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *RecipientRejectedError) Error() string {
+	return fmt.Sprintf("mailyak: recipient %s rejected: %d %s", e.Addr, e.Code, e.Msg)
+}
+
+// Unwrap returns the underlying error.
+func (e *RecipientRejectedError) Unwrap() error { return e.Err }
+
+// Is returns true if target is ErrRecipientRejected.
+func (e *RecipientRejectedError) Is(target error) bool {
+	return target == ErrRecipientRejected
+}
+
+// rejectedError wraps err in an error for cmd if it is a rejection by the
+// server, returning other errors unchanged.
+func rejectedError(cmd smtpCommand, err error) error {
+	tpErr, ok := err.(*textproto.Error)
+	if !ok || cmd.rcpt == "" {
+		return err
+	}
+
+	return &RecipientRejectedError{Addr: cmd.rcpt, Code: tpErr.Code, Msg: tpErr.Msg, Err: err}
+}
+
+// dataError wraps err with ErrDataRejected if it is a rejection of the message
+// content by the server, returning other errors unchanged.
+func dataError(err error) error {
+	var tpErr *textproto.Error
+	if !errors.As(err, &tpErr) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", ErrDataRejected, err)
+}
@@ -0,0 +1,134 @@
+package mailyak
+
+import (
+	"errors"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"testing"
+)
+
+// TestMailYakSend_errors ensures failures are returned as typed errors wrapping
+// the underlying cause.
+func TestMailYakSend_errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		replies map[string]string
+		auth    smtp.Auth
+		closed  bool
+		// Want
+		wantIs   error
+		wantCode int
+		wantAddr string
+	}{
+		{
+			name:   "Dial",
+			closed: true,
+			wantIs: ErrDial,
+		},
+		{
+			name: "Auth",
+			replies: map[string]string{
+				"EHLO": "250-localhost\r\n250 AUTH PLAIN",
+				"AUTH": "535 Authentication failed",
+			},
+			auth:     smtp.PlainAuth("", "user", "secret", "127.0.0.1"),
+			wantIs:   ErrAuth,
+			wantCode: 535,
+		},
+		{
+			name: "Recipient",
+			replies: map[string]string{
+				"RCPT": "550 No such user",
+			},
+			wantIs:   ErrRecipientRejected,
+			wantCode: 550,
+			wantAddr: "to@example.org",
+		},
+		{
+			name: "Recipient pipelined",
+			replies: map[string]string{
+				"EHLO": "250-localhost\r\n250 PIPELINING",
+				"RCPT": "550 No such user",
+			},
+			wantIs:   ErrRecipientRejected,
+			wantCode: 550,
+			wantAddr: "to@example.org",
+		},
+		{
+			name: "DATA",
+			replies: map[string]string{
+				"DATA": "554 No valid recipients",
+			},
+			wantIs:   ErrDataRejected,
+			wantCode: 554,
+		},
+		{
+			name: "Message content",
+			replies: map[string]string{
+				".": "554 Spam detected",
+			},
+			wantIs:   ErrDataRejected,
+			wantCode: 554,
+		},
+		{
+			name: "BDAT",
+			replies: map[string]string{
+				"EHLO": "250-localhost\r\n250 CHUNKING",
+				".":    "554 Spam detected",
+			},
+			wantIs:   ErrDataRejected,
+			wantCode: 554,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, tt.replies)
+			defer srv.Close()
+
+			if tt.closed {
+				srv.Close()
+			}
+
+			mail := New(srv.Addr(), tt.auth)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+			mail.Subject("Errors")
+			mail.Plain().Set("Hello")
+
+			_, _, err = mail.Send("localhost")
+			if !errors.Is(err, tt.wantIs) {
+				t.Fatalf("MailYak.Send() error = %v, want %v", err, tt.wantIs)
+			}
+
+			if tt.wantCode != 0 {
+				var tpErr *textproto.Error
+				if !errors.As(err, &tpErr) || tpErr.Code != tt.wantCode {
+					t.Errorf("MailYak.Send() error = %v, want code %d", err, tt.wantCode)
+				}
+			}
+
+			if tt.wantAddr != "" {
+				var rejected *RecipientRejectedError
+				if !errors.As(err, &rejected) {
+					t.Fatalf("MailYak.Send() error = %v, want *RecipientRejectedError", err)
+				}
+				if rejected.Addr != tt.wantAddr || rejected.Code != tt.wantCode || rejected.Msg != "No such user" {
+					t.Errorf("MailYak.Send() error = %+v, want addr %q code %d", rejected, tt.wantAddr, tt.wantCode)
+				}
+			}
+		})
+	}
+}
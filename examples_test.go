@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"io"
 	"net/smtp"
+	"strings"
 	"text/template"
 )
 
@@ -33,7 +34,7 @@ func Example() {
 	mail.Plain().Set("Get a real email client")
 
 	// And you're done!
-	if err := mail.Send(); err != nil {
+	if _, _, err := mail.Send("localhost"); err != nil {
 		panic(" :( ")
 	}
 }
@@ -53,7 +54,25 @@ func Example_attachments() {
 	// buf could be anything that implements io.Reader
 	mail.Attach("sticky.txt", buf)
 
-	if err := mail.Send(); err != nil {
+	if _, _, err := mail.Send("localhost"); err != nil {
+		panic(" :( ")
+	}
+}
+
+func ExampleMailYak_AttachWithMimeType() {
+	// Create a new email - specify the SMTP host and auth
+	mail := New("mail.host.com:25", smtp.PlainAuth("", "user", "pass", "mail.host.com"))
+
+	mail.To("dom@itsallbroken.com")
+	mail.From("jsmith@example.com")
+	mail.HTML().Set("Please find the report attached")
+
+	// Content sniffing would detect this as text/plain - set the MIME type
+	// explicitly instead
+	report := strings.NewReader("name,towels\nArthur,1\nFord,2\n")
+	mail.AttachWithMimeType("report.csv", report, "text/csv; charset=utf-8")
+
+	if _, _, err := mail.Send("localhost"); err != nil {
 		panic(" :( ")
 	}
 }
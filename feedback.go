@@ -0,0 +1,48 @@
+package mailyak
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FeedbackID sets the Feedback-ID header used by the Gmail feedback loop to
+// report spam complaints in Postmaster Tools, aggregated by each identifier:
+//
+//	err := mail.FeedbackID("spring-sale", "customer42", "newsletter", "itsallbroken")
+//
+// writes the header:
+//
+//	Feedback-ID: spring-sale:customer42:newsletter:itsallbroken
+//
+// senderID is required and should be consistent across all emails sent, while
+// the campaign, customer and mailType identifiers are optional and omitted if
+// empty. Passing only empty identifiers removes the header.
+//
+// An error is returned, leaving the header unchanged, if senderID is empty
+// while another identifier is set, or an identifier contains a colon,
+// whitespace or characters other than printable ASCII.
+func (m *MailYak) FeedbackID(campaign, customer, mailType, senderID string) error {
+	if campaign == "" && customer == "" && mailType == "" && senderID == "" {
+		m.deleteHeader("Feedback-ID")
+		return nil
+	}
+
+	if senderID == "" {
+		return errors.New("mailyak: Feedback-ID sender ID is required")
+	}
+
+	var fields []string
+	for _, id := range []string{campaign, customer, mailType, senderID} {
+		if id == "" {
+			continue
+		}
+		if strings.IndexFunc(id, func(r rune) bool { return r <= ' ' || r > '~' || r == ':' }) >= 0 {
+			return fmt.Errorf("mailyak: invalid Feedback-ID identifier %q", id)
+		}
+		fields = append(fields, id)
+	}
+
+	m.SetHeader("Feedback-ID", strings.Join(fields, ":"))
+	return nil
+}
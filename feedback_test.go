@@ -0,0 +1,51 @@
+package mailyak
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMailYakFeedbackID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		campaign string
+		customer string
+		mailType string
+		senderID string
+		// Want
+		want    []string
+		wantErr bool
+	}{
+		{"All", "spring-sale", "customer42", "newsletter", "itsallbroken", []string{"spring-sale:customer42:newsletter:itsallbroken"}, false},
+		{"Sender only", "", "", "", "itsallbroken", []string{"itsallbroken"}, false},
+		{"Omitted", "spring-sale", "", "newsletter", "itsallbroken", []string{"spring-sale:newsletter:itsallbroken"}, false},
+		{"Removed", "", "", "", "", nil, false},
+		{"No sender", "spring-sale", "", "", "", []string{"previous"}, true},
+		{"Colon", "spring:sale", "", "", "itsallbroken", []string{"previous"}, true},
+		{"Whitespace", "", "customer 42", "", "itsallbroken", []string{"previous"}, true},
+		{"Line break", "", "", "news\r\nBcc: evil@example.com", "itsallbroken", []string{"previous"}, true},
+		{"Non-ASCII", "", "", "", "itsällbroken", []string{"previous"}, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.SetHeader("Feedback-ID", "previous")
+
+			err := m.FeedbackID(tt.campaign, tt.customer, tt.mailType, tt.senderID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%q. MailYak.FeedbackID() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+
+			if got := m.GetHeaderValues()["Feedback-ID"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%q. MailYak.FeedbackID() header = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
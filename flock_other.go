@@ -0,0 +1,15 @@
+//go:build !unix
+
+package mailyak
+
+import "os"
+
+// lockFile is a no-op, as flock(2) is not supported on this platform.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op, as flock(2) is not supported on this platform.
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package mailyak
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile blocks until an exclusive flock(2) lock is held on f.
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+// unlockFile releases the lock on f.
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
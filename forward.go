@@ -0,0 +1,143 @@
+package mailyak
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// forwardSeparator introduces the headers of a forwarded email quoted inline.
+const forwardSeparator = "---------- Forwarded message ---------"
+
+// ForwardOptions configures the forward built by Forward.
+type ForwardOptions struct {
+	// Inline quotes the headers and body of the original email in the body of
+	// the forward, and carries over its attachments, rather than attaching the
+	// original email as a message/rfc822 attachment.
+	Inline bool
+}
+
+// Forward returns a new email forwarding m, typically an inbound email loaded
+// with Parse:
+//
+//	fwd, err := orig.Forward(mailyak.ForwardOptions{})
+//	if err != nil {
+//		return err
+//	}
+//
+//	fwd.From("support@itsallbroken.com")
+//	fwd.To("escalations@itsallbroken.com")
+//	fwd.Plain().Set("See the attached email.")
+//
+// The subject is prefixed with "Fwd:". By default the original email is built
+// and attached intact as a message/rfc822 attachment. When opts.Inline is set,
+// the From, Date, Subject, To and Cc headers of the original email are quoted
+// in the body of the forward followed by the original body, and the original
+// attachments are carried over.
+//
+// In both cases the attachments of m are read by the forward, so m should not
+// be sent afterwards.
+//
+// The SMTP server, authentication, TLS, dialer, timeout and signing
+// configuration of m is retained, but the From and recipient addresses must be
+// set.
+func (m *MailYak) Forward(opts ForwardOptions) (*MailYak, error) {
+	f := m.Clone()
+	attachments := f.attachments
+
+	f.Reset()
+	f.From("")
+	f.FromName("")
+	f.EnvelopeFrom("")
+	f.Sender("")
+	f.ReplyTo("")
+
+	subject := decodeHeader(m.subject)
+	if !hasSubjectPrefix(subject, "fwd:") && !hasSubjectPrefix(subject, "fw:") {
+		subject = "Fwd: " + subject
+	}
+	f.Subject(subject)
+
+	if !opts.Inline {
+		buf, err := m.MimeBuf()
+		if err != nil {
+			return nil, fmt.Errorf("mailyak: building forwarded email: %w", err)
+		}
+		f.AttachMessage(forwardFilename(m), bytes.NewReader(buf.Bytes()))
+		return f, nil
+	}
+
+	headers := m.forwardHeaders()
+
+	if m.plain.Len() > 0 || m.html.Len() == 0 {
+		var b strings.Builder
+		b.WriteString(forwardSeparator + "\n")
+		for _, h := range headers {
+			b.WriteString(h[0] + ": " + h[1] + "\n")
+		}
+		b.WriteString("\n")
+		b.Write(m.plain.Bytes())
+		f.plain.Set(b.String())
+	}
+
+	if m.html.Len() > 0 {
+		var b strings.Builder
+		b.WriteString("<div>" + forwardSeparator + "<br>\n")
+		for _, h := range headers {
+			b.WriteString(h[0] + ": " + html.EscapeString(h[1]) + "<br>\n")
+		}
+		b.WriteString("</div>\n<br>\n")
+		b.WriteString(htmlBodyContent(m.html.String()))
+		b.WriteString("\n")
+		f.html.Set(b.String())
+	}
+
+	f.attachments = attachments
+
+	return f, nil
+}
+
+// forwardHeaders returns the name and value of the headers of m quoted in an
+// inline forward.
+func (m *MailYak) forwardHeaders() [][2]string {
+	decodeAll := func(addrs []string) string {
+		decoded := make([]string, len(addrs))
+		for i, addr := range addrs {
+			decoded[i] = decodeHeader(addr)
+		}
+		return strings.Join(decoded, ", ")
+	}
+
+	var headers [][2]string
+	for _, h := range [][2]string{
+		{"From", m.fromHeaderAddress()},
+		{"Date", m.date},
+		{"Subject", decodeHeader(m.subject)},
+		{"To", decodeAll(m.toAddrs)},
+		{"Cc", decodeAll(m.ccAddrs)},
+	} {
+		if h[1] != "" {
+			headers = append(headers, h)
+		}
+	}
+
+	return headers
+}
+
+// forwardFilename returns the filename of m when attached to a forward, based
+// on its subject.
+func forwardFilename(m *MailYak) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(decodeHeader(m.subject)))
+
+	if name == "" {
+		name = "forwarded message"
+	}
+
+	return name + ".eml"
+}
@@ -0,0 +1,128 @@
+package mailyak
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMailYakForward ensures forwards carry the original email.
+func TestMailYakForward(t *testing.T) {
+	t.Parallel()
+
+	orig := func() *MailYak {
+		m := New("mail.host.com:25", nil)
+		m.From("dom@itsallbroken.com")
+		m.FromName("Dom")
+		m.To("support@itsallbroken.com", "Zoë <zoe@itsallbroken.com>")
+		m.Subject("Broken build")
+		m.Date(time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC))
+		m.Plain().Set("It's broken.\n")
+		m.HTML().Set("<html><body><p>It's <b>broken</b>.</p></body></html>")
+		m.Attach("build.log", strings.NewReader("FAIL\n"))
+		return m
+	}
+
+	tests := []struct {
+		// Test description.
+		name string
+
+		// Receiver fields.
+		rOrig func() *MailYak
+
+		// Parameters.
+		opts ForwardOptions
+
+		// Want
+		wantSubject     string
+		wantPlain       string
+		wantHTML        string
+		wantAttachments []string
+	}{
+		{
+			name:            "Attached",
+			rOrig:           orig,
+			wantSubject:     "Fwd: Broken build",
+			wantAttachments: []string{"Broken build.eml"},
+		},
+		{
+			name:        "Inline",
+			rOrig:       orig,
+			opts:        ForwardOptions{Inline: true},
+			wantSubject: "Fwd: Broken build",
+			wantPlain: "---------- Forwarded message ---------\n" +
+				"From: \"Dom\" <dom@itsallbroken.com>\n" +
+				"Date: Thu, 04 Mar 2021 05:06:07 +0000\n" +
+				"Subject: Broken build\n" +
+				"To: support@itsallbroken.com, Zoë <zoe@itsallbroken.com>\n" +
+				"\n" +
+				"It's broken.\n",
+			wantHTML: "<div>---------- Forwarded message ---------<br>\n" +
+				"From: &#34;Dom&#34; &lt;dom@itsallbroken.com&gt;<br>\n" +
+				"Date: Thu, 04 Mar 2021 05:06:07 +0000<br>\n" +
+				"Subject: Broken build<br>\n" +
+				"To: support@itsallbroken.com, Zoë &lt;zoe@itsallbroken.com&gt;<br>\n" +
+				"</div>\n<br>\n" +
+				"<p>It's <b>broken</b>.</p>\n",
+			wantAttachments: []string{"build.log"},
+		},
+		{
+			name: "Existing prefix",
+			rOrig: func() *MailYak {
+				m := orig()
+				m.Subject("FW: Broken build")
+				return m
+			},
+			wantSubject:     "FW: Broken build",
+			wantAttachments: []string{"FW_ Broken build.eml"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := tt.rOrig().Forward(tt.opts)
+			if err != nil {
+				t.Fatalf("Forward() error = %v", err)
+			}
+
+			if f.fromAddr != "" || len(f.toAddrs) != 0 {
+				t.Errorf("Forward() from = %q, to = %v, want empty", f.fromAddr, f.toAddrs)
+			}
+			if got := decodeHeader(f.subject); got != tt.wantSubject {
+				t.Errorf("Forward() subject = %q, want %q", got, tt.wantSubject)
+			}
+			if got := f.plain.String(); got != tt.wantPlain {
+				t.Errorf("Forward() plain = %q, want %q", got, tt.wantPlain)
+			}
+			if got := f.html.String(); got != tt.wantHTML {
+				t.Errorf("Forward() html = %q, want %q", got, tt.wantHTML)
+			}
+
+			if len(f.attachments) != len(tt.wantAttachments) {
+				t.Fatalf("Forward() attachments = %d, want %d", len(f.attachments), len(tt.wantAttachments))
+			}
+			for i, want := range tt.wantAttachments {
+				if got := f.attachments[i].filename; got != want {
+					t.Errorf("Forward() attachment %d = %q, want %q", i, got, want)
+				}
+			}
+
+			// The attached original must be a complete email
+			if !tt.opts.Inline {
+				raw, _ := ioutil.ReadAll(f.attachments[0].content)
+				m, err := Parse(bytes.NewReader(raw))
+				if err != nil {
+					t.Fatalf("Parse() attached original error = %v", err)
+				}
+				if m.fromAddr != "dom@itsallbroken.com" || m.plain.String() != "It's broken.\r\n" || len(m.attachments) != 1 {
+					t.Errorf("Forward() attached original = from %q, plain %q, %d attachments", m.fromAddr, m.plain.String(), len(m.attachments))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Package gmail provides a mailyak.Transport sending emails through the Gmail
+// API.
+package gmail
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/domodwyer/mailyak/v3"
+	"github.com/domodwyer/mailyak/v3/internal/apimail"
+)
+
+// Transport is a mailyak.Transport sending emails through the Gmail API
+// users.messages.send method, for Google Workspace environments where SMTP is
+// blocked:
+//
+//	mail.Transport(gmail.New(tokenSource))
+//
+// The email is sent as raw MIME data from the mailbox of the authenticated
+// user, so all MailYak features are supported. The access tokens must be
+// granted one of the https://www.googleapis.com/auth/gmail.send,
+// gmail.compose, gmail.modify or https://mail.google.com/ scopes.
+//
+// Errors returned by Gmail are returned as a *mailyak.APIError.
+type Transport struct {
+	tokens   mailyak.TokenSource
+	user     string
+	endpoint string
+	client   *http.Client
+}
+
+// New returns a Transport authenticating with the access tokens supplied by
+// tokens.
+func New(tokens mailyak.TokenSource) *Transport {
+	return &Transport{
+		tokens:   tokens,
+		user:     "me",
+		endpoint: "https://gmail.googleapis.com",
+	}
+}
+
+// User sets the email address of the mailbox emails are sent from, such as
+// when using domain-wide delegation. Defaults to "me", the authenticated user.
+func (t *Transport) User(id string) {
+	t.user = id
+}
+
+// Endpoint sets the base URL of the Gmail API. Defaults to
+// "https://gmail.googleapis.com".
+func (t *Transport) Endpoint(u string) {
+	t.endpoint = strings.TrimSuffix(u, "/")
+}
+
+// HTTPClient sets the HTTP client used to call Gmail. Defaults to
+// http.DefaultClient.
+func (t *Transport) HTTPClient(c *http.Client) {
+	t.client = c
+}
+
+// Send sends msg using the Gmail API.
+//
+// Gmail delivers the email to the recipients in its To, Cc and Bcc headers,
+// rather than rcpts, so any other recipients in rcpts are added to a Bcc header
+// in the sent MIME data, which is removed by Gmail.
+func (t *Transport) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	data, err := apimail.Buffer(msg)
+	if err != nil {
+		return err
+	}
+
+	if data, err = apimail.WithBcc(data, rcpts); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(struct {
+		Raw string `json:"raw"`
+	}{
+		Raw: base64.URLEncoding.EncodeToString(data),
+	})
+	if err != nil {
+		return err
+	}
+
+	token, err := t.tokens.AccessToken(ctx)
+	if err != nil {
+		return fmt.Errorf("mailyak: fetching Gmail access token: %w", err)
+	}
+
+	u := t.endpoint + "/gmail/v1/users/" + url.PathEscape(t.user) + "/messages/send"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	return apimail.Do(t.client, req, "Gmail", nil)
+}
@@ -0,0 +1,166 @@
+package gmail
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/domodwyer/mailyak/v3"
+)
+
+// testSigner is a mailyak.PGPSigner producing a fixed signature.
+type testSigner struct{}
+
+func (testSigner) ArmoredDetachSign(w io.Writer, message io.Reader) error {
+	if _, err := io.Copy(io.Discard, message); err != nil {
+		return err
+	}
+	_, err := fmt.Fprint(w, "-----BEGIN PGP SIGNATURE-----\n\nsignature\n-----END PGP SIGNATURE-----\n")
+	return err
+}
+
+func (testSigner) Micalg() string { return "pgp-sha256" }
+
+// TestTransport ensures emails are sent as raw MIME data to the Gmail API.
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		user   string
+		signed bool
+		bcc    bool
+		token  error
+		// Want
+		wantPath    string
+		wantRequest bool
+		wantBcc     bool
+		wantErr     bool
+	}{
+		{
+			name:        "OK",
+			wantPath:    "/gmail/v1/users/me/messages/send",
+			wantRequest: true,
+		},
+		{
+			name:        "Delegated user",
+			user:        "dom@example.org",
+			wantPath:    "/gmail/v1/users/dom@example.org/messages/send",
+			wantRequest: true,
+		},
+		{
+			name:        "Bcc",
+			bcc:         true,
+			wantPath:    "/gmail/v1/users/me/messages/send",
+			wantRequest: true,
+			wantBcc:     true,
+		},
+		{
+			name:        "Signed",
+			signed:      true,
+			wantPath:    "/gmail/v1/users/me/messages/send",
+			wantRequest: true,
+		},
+		{
+			name:        "Signed with Bcc",
+			signed:      true,
+			bcc:         true,
+			wantPath:    "/gmail/v1/users/me/messages/send",
+			wantRequest: true,
+			wantBcc:     true,
+		},
+		{
+			name:    "Token error",
+			token:   errors.New("token expired"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				gotRequest bool
+				path       string
+				auth       string
+				raw        []byte
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRequest = true
+				path = r.URL.Path
+				auth = r.Header.Get("Authorization")
+
+				var body struct {
+					Raw string `json:"raw"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("decoding request: %v", err)
+				}
+
+				var err error
+				if raw, err = base64.URLEncoding.DecodeString(body.Raw); err != nil {
+					t.Errorf("decoding raw message: %v", err)
+				}
+
+				w.Write([]byte(`{"id": "abc", "threadId": "abc"}`))
+			}))
+			defer srv.Close()
+
+			transport := New(mailyak.TokenSourceFunc(func(ctx context.Context) (string, error) {
+				return "token", tt.token
+			}))
+			transport.Endpoint(srv.URL)
+			if tt.user != "" {
+				transport.User(tt.user)
+			}
+
+			mail := mailyak.New("", nil)
+			mail.Transport(transport)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+			mail.Subject("Gmail")
+			if tt.bcc {
+				mail.Bcc("bcc@example.org")
+			}
+			if tt.signed {
+				if err := mail.PGP(mailyak.PGPOptions{Signer: testSigner{}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, _, err := mail.Send("localhost")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Transport.Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotRequest != tt.wantRequest {
+				t.Fatalf("Transport.Send() sent request = %v, want %v", gotRequest, tt.wantRequest)
+			}
+			if !gotRequest {
+				return
+			}
+
+			if path != tt.wantPath {
+				t.Errorf("Transport.Send() path = %q, want %q", path, tt.wantPath)
+			}
+			if auth != "Bearer token" {
+				t.Errorf("Transport.Send() Authorization = %q", auth)
+			}
+			if !strings.Contains(string(raw), "Subject: Gmail\r\n") || !strings.Contains(string(raw), "Message-ID: "+mail.GetMessageID()) {
+				t.Errorf("Transport.Send() raw = %q", raw)
+			}
+			if got := strings.Contains(string(raw), "Bcc: bcc@example.org\r\n"); got != tt.wantBcc {
+				t.Errorf("Transport.Send() wrote Bcc header = %v, want %v", got, tt.wantBcc)
+			}
+		})
+	}
+}
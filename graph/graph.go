@@ -0,0 +1,241 @@
+// Package graph provides a mailyak.Transport sending emails through the
+// Microsoft Graph API.
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/domodwyer/mailyak/v3"
+	"github.com/domodwyer/mailyak/v3/internal/apimail"
+)
+
+// Transport is a mailyak.Transport sending emails through the Microsoft Graph
+// sendMail API, for Microsoft 365 tenants where SMTP authentication is
+// disabled:
+//
+//	t := graph.New(tokenSource)
+//	t.User("support@itsallbroken.com")
+//	mail.Transport(t)
+//
+// By default the bodies, addresses and attachments of the email are converted
+// into a Graph message. Graph messages have a single body, so the HTML body is
+// sent if set, otherwise the plain-text body. Only custom headers starting
+// with "X-" are allowed by Graph, so other headers such as In-Reply-To are not
+// sent. Signed or encrypted emails and calendar invitations cannot be sent
+// this way - enable MIME to send the raw MIME data of the email instead.
+//
+// The access tokens must be granted the Mail.Send permission. Errors returned
+// by Graph are returned as a *mailyak.APIError.
+type Transport struct {
+	tokens   mailyak.TokenSource
+	user     string
+	endpoint string
+	client   *http.Client
+	mime     bool
+}
+
+// New returns a Transport authenticating with the access tokens supplied by
+// tokens.
+func New(tokens mailyak.TokenSource) *Transport {
+	return &Transport{
+		tokens:   tokens,
+		endpoint: "https://graph.microsoft.com/v1.0",
+	}
+}
+
+// User sets the ID or user principal name (typically the email address) of the
+// user emails are sent as, which is required when authenticating as an
+// application. Defaults to the signed-in user.
+func (t *Transport) User(id string) {
+	t.user = id
+}
+
+// Endpoint sets the base URL of the Graph API, such as a national cloud
+// deployment. Defaults to "https://graph.microsoft.com/v1.0".
+func (t *Transport) Endpoint(u string) {
+	t.endpoint = strings.TrimSuffix(u, "/")
+}
+
+// HTTPClient sets the HTTP client used to call Graph. Defaults to
+// http.DefaultClient.
+func (t *Transport) HTTPClient(c *http.Client) {
+	t.client = c
+}
+
+// MIME sends emails as raw MIME data when enabled, supporting all MailYak
+// features including signing, encryption and all headers. Defaults to false.
+//
+// Graph delivers MIME emails to the recipients in their To, Cc and Bcc headers,
+// so any other recipients are added to a Bcc header in the sent MIME data.
+func (t *Transport) MIME(enabled bool) {
+	t.mime = enabled
+}
+
+// sendMailRequest is the body of a Graph sendMail request.
+type sendMailRequest struct {
+	Message message `json:"message"`
+}
+
+type message struct {
+	Subject                string       `json:"subject"`
+	Body                   itemBody     `json:"body"`
+	From                   *recipient   `json:"from,omitempty"`
+	ToRecipients           []recipient  `json:"toRecipients,omitempty"`
+	CcRecipients           []recipient  `json:"ccRecipients,omitempty"`
+	BccRecipients          []recipient  `json:"bccRecipients,omitempty"`
+	ReplyTo                []recipient  `json:"replyTo,omitempty"`
+	InternetMessageHeaders []header     `json:"internetMessageHeaders,omitempty"`
+	Attachments            []attachment `json:"attachments,omitempty"`
+}
+
+type itemBody struct {
+	ContentType string `json:"contentType"`
+	Content     string `json:"content"`
+}
+
+type recipient struct {
+	EmailAddress emailAddress `json:"emailAddress"`
+}
+
+type emailAddress struct {
+	Address string `json:"address"`
+	Name    string `json:"name,omitempty"`
+}
+
+type header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type attachment struct {
+	ODataType    string `json:"@odata.type"`
+	Name         string `json:"name"`
+	ContentType  string `json:"contentType"`
+	ContentBytes []byte `json:"contentBytes"`
+	IsInline     bool   `json:"isInline"`
+	ContentID    string `json:"contentId,omitempty"`
+}
+
+// Send sends the email using the Graph sendMail API.
+//
+// The recipients are taken from the To and Cc addresses of the email, with any
+// other recipients in rcpts sent as Bcc recipients.
+func (t *Transport) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	var (
+		body        []byte
+		contentType string
+		err         error
+	)
+	if t.mime {
+		body, err = mimeBody(rcpts, msg)
+		contentType = "text/plain"
+	} else {
+		body, err = jsonBody(rcpts, msg)
+		contentType = "application/json"
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := t.tokens.AccessToken(ctx)
+	if err != nil {
+		return fmt.Errorf("mailyak: fetching Graph access token: %w", err)
+	}
+
+	u := t.endpoint + "/me/sendMail"
+	if t.user != "" {
+		u = t.endpoint + "/users/" + url.PathEscape(t.user) + "/sendMail"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", contentType)
+
+	return apimail.Do(t.client, req, "Graph", nil)
+}
+
+// mimeBody returns the body of a sendMail request sending msg to rcpts as
+// base64 encoded MIME data.
+func mimeBody(rcpts []string, msg io.WriterTo) ([]byte, error) {
+	data, err := apimail.Buffer(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err = apimail.WithBcc(data, rcpts); err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, base64.StdEncoding.EncodedLen(len(data)))
+	base64.StdEncoding.Encode(body, data)
+	return body, nil
+}
+
+// jsonBody returns the body of a sendMail request sending msg to rcpts as a
+// Graph message.
+func jsonBody(rcpts []string, msg io.WriterTo) ([]byte, error) {
+	e, err := apimail.NewEmail(msg, rcpts, "Graph")
+	if err != nil {
+		return nil, err
+	}
+
+	m := message{
+		Subject:       e.Subject,
+		Body:          itemBody{ContentType: "Text", Content: e.Plain},
+		From:          &recipient{emailAddress{Address: e.From.Address, Name: e.From.Name}},
+		ToRecipients:  recipients(e.To),
+		CcRecipients:  recipients(e.Cc),
+		BccRecipients: recipients(e.Bcc),
+	}
+	if e.HTML != "" {
+		m.Body = itemBody{ContentType: "HTML", Content: e.HTML}
+	}
+	if e.ReplyTo != nil {
+		m.ReplyTo = recipients([]*mail.Address{e.ReplyTo})
+	}
+
+	for _, k := range apimail.SortedKeys(e.Headers) {
+		if strings.HasPrefix(strings.ToLower(k), "x-") {
+			for _, v := range e.HeaderValues[k] {
+				m.InternetMessageHeaders = append(m.InternetMessageHeaders, header{Name: k, Value: v})
+			}
+		}
+	}
+
+	for _, a := range e.Attachments {
+		att := attachment{
+			ODataType:    "#microsoft.graph.fileAttachment",
+			Name:         a.Filename,
+			ContentType:  a.ContentType,
+			ContentBytes: a.Data,
+			IsInline:     a.Inline,
+		}
+		if a.Inline {
+			att.ContentID = a.ContentID
+		}
+		m.Attachments = append(m.Attachments, att)
+	}
+
+	return json.Marshal(sendMailRequest{Message: m})
+}
+
+// recipients returns addrs as Graph recipients.
+func recipients(addrs []*mail.Address) []recipient {
+	var out []recipient
+	for _, a := range addrs {
+		out = append(out, recipient{emailAddress{Address: a.Address, Name: a.Name}})
+	}
+	return out
+}
@@ -0,0 +1,147 @@
+package graph
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/domodwyer/mailyak/v3"
+)
+
+// request is a request received by a test Graph server.
+type request struct {
+	path        string
+	auth        string
+	contentType string
+	body        []byte
+}
+
+// newServer returns a test server recording the last request in req.
+func newServer(t *testing.T, req *request) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req.path = r.URL.Path
+		req.auth = r.Header.Get("Authorization")
+		req.contentType = r.Header.Get("Content-Type")
+
+		var err error
+		if req.body, err = io.ReadAll(r.Body); err != nil {
+			t.Errorf("reading request: %v", err)
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+}
+
+// TestTransport ensures emails are converted into Graph messages.
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	var req request
+	srv := newServer(t, &req)
+	defer srv.Close()
+
+	transport := New(mailyak.TokenSourceFunc(func(ctx context.Context) (string, error) {
+		return "token", nil
+	}))
+	transport.Endpoint(srv.URL + "/v1.0/")
+	transport.User("from@example.org")
+
+	mail := mailyak.New("", nil)
+	mail.Transport(transport)
+	mail.From("from@example.org")
+	mail.FromName("Dom")
+	mail.To("Zoë <zoe@example.org>")
+	mail.Bcc("bcc@example.org")
+	mail.ReplyTo("reply@example.org")
+	mail.Subject("Hello")
+	mail.InReplyTo("<parent@example.org>")
+	mail.AddHeader("X-Campaign", "launch")
+	mail.AddHeader("X-Campaign", "spring")
+	mail.Plain().Set("plain")
+	mail.HTML().Set("<p>html</p>")
+	mail.AttachInlineWithMimeType("logo.png", strings.NewReader("png"), "image/png")
+
+	if _, _, err := mail.Send("localhost"); err != nil {
+		t.Fatalf("Transport.Send() error = %v", err)
+	}
+
+	if req.path != "/v1.0/users/from@example.org/sendMail" || req.auth != "Bearer token" || req.contentType != "application/json" {
+		t.Errorf("Transport.Send() path = %q, auth = %q, content type = %q", req.path, req.auth, req.contentType)
+	}
+
+	var got sendMailRequest
+	if err := json.Unmarshal(req.body, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := sendMailRequest{Message: message{
+		Subject:       "Hello",
+		Body:          itemBody{ContentType: "HTML", Content: "<p>html</p>"},
+		From:          &recipient{emailAddress{Address: "from@example.org", Name: "Dom"}},
+		ToRecipients:  []recipient{{emailAddress{Address: "zoe@example.org", Name: "Zoë"}}},
+		BccRecipients: []recipient{{emailAddress{Address: "bcc@example.org"}}},
+		ReplyTo:       []recipient{{emailAddress{Address: "reply@example.org"}}},
+		InternetMessageHeaders: []header{
+			{Name: "X-Campaign", Value: "launch"},
+			{Name: "X-Campaign", Value: "spring"},
+			{Name: "X-Mailer", Value: mailyak.DefaultMailer},
+		},
+		Attachments: []attachment{{
+			ODataType:    "#microsoft.graph.fileAttachment",
+			Name:         "logo.png",
+			ContentType:  "image/png",
+			ContentBytes: []byte("png"),
+			IsInline:     true,
+			ContentID:    "logo.png",
+		}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Transport.Send() request =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+// TestTransport_mime ensures the raw MIME data is sent when enabled.
+func TestTransport_mime(t *testing.T) {
+	t.Parallel()
+
+	var req request
+	srv := newServer(t, &req)
+	defer srv.Close()
+
+	transport := New(mailyak.TokenSourceFunc(func(ctx context.Context) (string, error) {
+		return "token", nil
+	}))
+	transport.Endpoint(srv.URL)
+	transport.MIME(true)
+
+	mail := mailyak.New("", nil)
+	mail.Transport(transport)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+	mail.Bcc("bcc@example.org")
+	mail.Subject("MIME")
+
+	if _, _, err := mail.Send("localhost"); err != nil {
+		t.Fatalf("Transport.Send() error = %v", err)
+	}
+
+	if req.path != "/me/sendMail" || req.contentType != "text/plain" {
+		t.Errorf("Transport.Send() path = %q, content type = %q", req.path, req.contentType)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(string(req.body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Subject: MIME\r\n", "Bcc: bcc@example.org\r\n"} {
+		if !strings.Contains(string(raw), want) {
+			t.Errorf("Transport.Send() raw = %q, missing %q", raw, want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package mailyak
+
+import (
+	"strings"
+)
+
+// addressGroup is a named list of addresses written to the To header using the
+// group syntax of RFC 5322 section 3.4.
+type addressGroup struct {
+	name  string
+	addrs []string
+}
+
+// ToGroup adds a group of recipient addresses, written to the To header with
+// the group syntax of RFC 5322 as:
+//
+//	To: Team: one@itsallbroken.com, two@itsallbroken.com;
+//
+// Any groups are written to a single To header, following the addresses added
+// with To and AddTo.
+//
+// Each address in the group is delivered to as with To. A group with no
+// addresses can be used to indicate the recipients are undisclosed, delivering
+// to Bcc addresses only:
+//
+//	mail.ToGroup("undisclosed-recipients")
+//	mail.Bcc(recipients...)
+//
+// The group name is quoted if it contains special characters, and encoded
+// according to RFC 2047 if it contains non-ASCII characters. ToGroup can be
+// called more than once to add several groups, which are removed by
+// ClearRecipients. If name is empty, the addresses are added as with AddTo.
+func (m *MailYak) ToGroup(name string, addrs ...string) {
+	name = strings.TrimSpace(m.trimRegex.ReplaceAllString(name, ""))
+	if name == "" {
+		m.AddTo(addrs...)
+		return
+	}
+
+	m.toGroups = append(m.toGroups, addressGroup{
+		name:  name,
+		addrs: m.appendAddrs([]string{}, addrs),
+	})
+}
+
+// groupAddrs returns the addresses of all the groups added with ToGroup.
+func (m *MailYak) groupAddrs() []string {
+	var addrs []string
+	for _, g := range m.toGroups {
+		addrs = append(addrs, g.addrs...)
+	}
+	return addrs
+}
+
+// toHeader returns a single To header holding addrs followed by groups, folded
+// if it is too long for a single line, such as:
+//
+//	To: dom@itsallbroken.com, Team: one@itsallbroken.com, two@itsallbroken.com;
+func toHeader(addrs []string, groups []addressGroup) string {
+	var values []string
+	separate := func() {
+		if n := len(values); n > 0 {
+			values[n-1] += ","
+		}
+	}
+
+	for _, addr := range addrs {
+		separate()
+		values = append(values, encodeAddress(addr))
+	}
+	for _, g := range groups {
+		separate()
+		values = append(values, g.values()...)
+	}
+
+	return foldHeader("To:", values)
+}
+
+// values returns the group formatted with the group syntax, split into the
+// values passed to foldHeader.
+func (g addressGroup) values() []string {
+	name := quoteDisplayName(encodeDisplayName(g.name)) + ":"
+	if len(g.addrs) == 0 {
+		return []string{name + ";"}
+	}
+
+	values := make([]string, 0, len(g.addrs)+1)
+	values = append(values, name)
+	for i, addr := range g.addrs {
+		sep := ","
+		if i == len(g.addrs)-1 {
+			sep = ";"
+		}
+		values = append(values, encodeAddress(addr)+sep)
+	}
+
+	return values
+}
+
+// cloneGroups returns a deep copy of groups.
+func cloneGroups(groups []addressGroup) []addressGroup {
+	if groups == nil {
+		return nil
+	}
+
+	c := make([]addressGroup, len(groups))
+	for i, g := range groups {
+		c[i] = addressGroup{name: g.name, addrs: cloneStrings(g.addrs)}
+	}
+	return c
+}
@@ -0,0 +1,137 @@
+package mailyak
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestMailYakToGroup ensures groups are written to the To header using the
+// group syntax, and their addresses delivered to.
+func TestMailYakToGroup(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		group string
+		addrs []string
+		// Want
+		wantHeader     string
+		wantRecipients []string
+	}{
+		{
+			"Group",
+			"Team",
+			[]string{"one@itsallbroken.com", "Two <two@itsallbroken.com>"},
+			"To: Team: one@itsallbroken.com, Two <two@itsallbroken.com>;\r\n",
+			[]string{"one@itsallbroken.com", "two@itsallbroken.com", "bcc@itsallbroken.com"},
+		},
+		{
+			"Undisclosed",
+			"undisclosed-recipients",
+			nil,
+			"To: undisclosed-recipients:;\r\n",
+			[]string{"bcc@itsallbroken.com"},
+		},
+		{
+			"Quoted name",
+			"Team: Sales",
+			[]string{"one@itsallbroken.com"},
+			"To: \"Team: Sales\": one@itsallbroken.com;\r\n",
+			[]string{"one@itsallbroken.com", "bcc@itsallbroken.com"},
+		},
+		{
+			"Non-ASCII name",
+			"Équipe",
+			[]string{"one@itsallbroken.com"},
+			"To: =?UTF-8?q?=C3=89quipe?=: one@itsallbroken.com;\r\n",
+			[]string{"one@itsallbroken.com", "bcc@itsallbroken.com"},
+		},
+		{
+			"Empty name",
+			"",
+			[]string{"one@itsallbroken.com"},
+			"To: one@itsallbroken.com\r\n",
+			[]string{"one@itsallbroken.com", "bcc@itsallbroken.com"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.From("from@itsallbroken.com")
+			m.ToGroup(tt.group, tt.addrs...)
+			m.Bcc("bcc@itsallbroken.com")
+
+			if err := m.Validate(); err != nil {
+				t.Fatalf("MailYak.Validate() error = %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := m.writeHeaders(&buf); err != nil {
+				t.Fatalf("MailYak.writeHeaders() error = %v", err)
+			}
+			if !strings.Contains(buf.String(), tt.wantHeader) {
+				t.Errorf("MailYak.writeHeaders() = %q, want %q", buf.String(), tt.wantHeader)
+			}
+
+			if got := m.recipients(); !reflect.DeepEqual(got, tt.wantRecipients) {
+				t.Errorf("MailYak.recipients() = %q, want %q", got, tt.wantRecipients)
+			}
+		})
+	}
+}
+
+// TestMailYakToGroup_combined ensures To addresses and groups are written to a
+// single To header.
+func TestMailYakToGroup_combined(t *testing.T) {
+	t.Parallel()
+
+	m := New("", nil)
+	m.From("from@itsallbroken.com")
+	m.To("dom@itsallbroken.com")
+	m.ToGroup("Team", "one@itsallbroken.com", "two@itsallbroken.com")
+	m.ToGroup("undisclosed-recipients")
+
+	var buf bytes.Buffer
+	if err := m.writeHeaders(&buf); err != nil {
+		t.Fatalf("MailYak.writeHeaders() error = %v", err)
+	}
+
+	want := "To: dom@itsallbroken.com, Team: one@itsallbroken.com, two@itsallbroken.com;,\r\n undisclosed-recipients:;\r\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("MailYak.writeHeaders() = %q, want %q", buf.String(), want)
+	}
+	if got := strings.Count(buf.String(), "To: "); got != 1 {
+		t.Errorf("MailYak.writeHeaders() wrote %v To headers, want 1", got)
+	}
+}
+
+// TestMailYakToGroup_remove ensures group addresses are removed by
+// RemoveRecipient and ClearRecipients, and not shared with clones.
+func TestMailYakToGroup_remove(t *testing.T) {
+	t.Parallel()
+
+	m := New("", nil)
+	m.ToGroup("Team", "one@itsallbroken.com", "two@itsallbroken.com")
+
+	c := m.Clone()
+	m.RemoveRecipient("one@itsallbroken.com")
+
+	if got, want := m.recipients(), []string{"two@itsallbroken.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MailYak.RemoveRecipient() recipients = %q, want %q", got, want)
+	}
+	if got := c.recipients(); len(got) != 2 {
+		t.Errorf("MailYak.Clone() recipients = %q, want 2 addresses", got)
+	}
+
+	m.ClearRecipients()
+	if got := m.recipients(); len(got) != 0 {
+		t.Errorf("MailYak.ClearRecipients() recipients = %q, want none", got)
+	}
+}
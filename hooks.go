@@ -0,0 +1,89 @@
+package mailyak
+
+// hooks are the functions registered to be called when sending an email.
+type hooks struct {
+	beforeBuild []func(m *MailYak) error
+	beforeSend  []func(m *MailYak, envelopeFrom string, rcpts []string) error
+	afterSend   []func(m *MailYak, code int, msg string, err error)
+}
+
+// clone returns a copy of h that can be registered with without affecting h,
+// by limiting the capacity of each slice so appending to it reallocates.
+func (h hooks) clone() hooks {
+	return hooks{
+		beforeBuild: h.beforeBuild[:len(h.beforeBuild):len(h.beforeBuild)],
+		beforeSend:  h.beforeSend[:len(h.beforeSend):len(h.beforeSend)],
+		afterSend:   h.afterSend[:len(h.afterSend):len(h.afterSend)],
+	}
+}
+
+// OnBeforeBuild registers fn to be called when sending the email, before its
+// MIME message is built, allowing the email to be modified - such as adding a
+// tracking header:
+//
+//	mail.OnBeforeBuild(func(m *mailyak.MailYak) error {
+//		m.AddHeader("X-Request-ID", requestID)
+//		return nil
+//	})
+//
+// If fn returns an error the email is not sent, and the error is returned by
+// Send. Hooks are called in the order they are registered, and are copied to
+// clones of the email.
+func (m *MailYak) OnBeforeBuild(fn func(m *MailYak) error) {
+	m.hooks.beforeBuild = append(m.hooks.beforeBuild, fn)
+}
+
+// OnBeforeSend registers fn to be called when sending the email, after its
+// MIME message is built and before it is delivered, with the envelope sender
+// and recipients - allowing sends to be audited or vetoed:
+//
+//	mail.OnBeforeSend(func(m *mailyak.MailYak, envelopeFrom string, rcpts []string) error {
+//		if len(rcpts) > 100 {
+//			return errors.New("too many recipients")
+//		}
+//		return nil
+//	})
+//
+// If fn returns an error the email is not sent, and the error is returned by
+// Send. The email must not be modified by fn. Hooks are called in the order
+// they are registered, and are copied to clones of the email.
+func (m *MailYak) OnBeforeSend(fn func(m *MailYak, envelopeFrom string, rcpts []string) error) {
+	m.hooks.beforeSend = append(m.hooks.beforeSend, fn)
+}
+
+// OnAfterSend registers fn to be called once sending the email has completed,
+// with the result returned by Send - including when the send was vetoed by a
+// hook or failed.
+//
+// Hooks are called in the order they are registered, and are copied to clones
+// of the email.
+func (m *MailYak) OnAfterSend(fn func(m *MailYak, code int, msg string, err error)) {
+	m.hooks.afterSend = append(m.hooks.afterSend, fn)
+}
+
+// runBeforeBuild calls the OnBeforeBuild hooks, returning the first error.
+func (m *MailYak) runBeforeBuild() error {
+	for _, fn := range m.hooks.beforeBuild {
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runBeforeSend calls the OnBeforeSend hooks, returning the first error.
+func (m *MailYak) runBeforeSend(envelopeFrom string, rcpts []string) error {
+	for _, fn := range m.hooks.beforeSend {
+		if err := fn(m, envelopeFrom, rcpts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runAfterSend calls the OnAfterSend hooks with the result of sending.
+func (m *MailYak) runAfterSend(code int, msg string, err error) {
+	for _, fn := range m.hooks.afterSend {
+		fn(m, code, msg, err)
+	}
+}
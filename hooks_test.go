@@ -0,0 +1,114 @@
+package mailyak
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestMailYakHooks ensures the hooks are called in order when sending, and
+// can modify or veto the email.
+func TestMailYakHooks(t *testing.T) {
+	t.Parallel()
+
+	veto := errors.New("vetoed")
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		buildErr error
+		sendErr  error
+		// Want
+		wantCalls []string
+		wantSent  bool
+		wantErr   error
+	}{
+		{
+			name:      "Sent",
+			wantCalls: []string{"build", "send to@example.org", "after 0 <nil>"},
+			wantSent:  true,
+		},
+		{
+			name:      "Build veto",
+			buildErr:  veto,
+			wantCalls: []string{"build", "after -1 vetoed"},
+			wantErr:   veto,
+		},
+		{
+			name:      "Send veto",
+			sendErr:   veto,
+			wantCalls: []string{"build", "send to@example.org", "after -1 vetoed"},
+			wantErr:   veto,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			transport := &testTransport{}
+
+			var calls []string
+			mail := New("", nil)
+			mail.Transport(transport)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+			mail.OnBeforeBuild(func(m *MailYak) error {
+				calls = append(calls, "build")
+				m.AddHeader("X-Tracking", "abc")
+				return tt.buildErr
+			})
+			mail.OnBeforeSend(func(m *MailYak, envelopeFrom string, rcpts []string) error {
+				calls = append(calls, "send "+strings.Join(rcpts, ","))
+				return tt.sendErr
+			})
+			mail.OnAfterSend(func(m *MailYak, code int, msg string, err error) {
+				calls = append(calls, fmt.Sprintf("after %d %v", code, err))
+			})
+
+			if _, _, err := mail.Send("localhost"); err != tt.wantErr {
+				t.Fatalf("MailYak.Send() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("MailYak.Send() hook calls = %q, want %q", calls, tt.wantCalls)
+			}
+
+			if got := transport.data.Len() > 0; got != tt.wantSent {
+				t.Fatalf("MailYak.Send() sent = %v, want %v", got, tt.wantSent)
+			}
+			if tt.wantSent && !strings.Contains(transport.data.String(), "X-Tracking: abc\r\n") {
+				t.Errorf("MailYak.Send() msg = %q, want X-Tracking header", transport.data.String())
+			}
+		})
+	}
+}
+
+// TestMailYakHooks_clone ensures hooks registered on a clone do not affect the
+// original email.
+func TestMailYakHooks_clone(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+
+	mail := New("", nil)
+	mail.Transport(&testTransport{})
+	mail.OnAfterSend(func(m *MailYak, code int, msg string, err error) {
+		calls = append(calls, "original")
+	})
+
+	clone := mail.Clone()
+	clone.OnAfterSend(func(m *MailYak, code int, msg string, err error) {
+		calls = append(calls, "clone")
+	})
+
+	mail.Send("localhost")
+	clone.Send("localhost")
+
+	if want := []string{"original", "original", "clone"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("hook calls = %q, want %q", calls, want)
+	}
+}
@@ -0,0 +1,296 @@
+package mailyak
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// hrefRegex matches the href attribute of an HTML tag.
+var hrefRegex = regexp.MustCompile(`(?is)\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+// htmlBlockTags are the HTML elements rendered as a separate paragraph.
+var htmlBlockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"div": true, "dl": true, "footer": true, "form": true, "h1": true,
+	"h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "main": true, "nav": true, "ol": true, "p": true,
+	"pre": true, "section": true, "table": true, "ul": true,
+}
+
+// htmlLineTags are the HTML elements rendered on a separate line.
+var htmlLineTags = map[string]bool{
+	"br": true, "dd": true, "dt": true, "li": true, "tr": true,
+}
+
+// htmlSkipTags are the HTML elements whose content is not rendered.
+var htmlSkipTags = map[string]bool{
+	"head": true, "script": true, "style": true, "template": true, "title": true,
+}
+
+// PlainFromHTML generates the plain-text body from the HTML body when true and
+// no plain-text body is set. Defaults to false.
+//
+// Emails without a plain-text alternative are more likely to be marked as spam,
+// so enabling this is recommended when only authoring HTML. The generated text
+// strips all tags, keeping line breaks, paragraphs and list items, and
+// including the target of each link after its text:
+//
+//	<p>Read the <a href="https://itsallbroken.com">blog</a></p>
+//
+// becomes:
+//
+//	Read the blog (https://itsallbroken.com)
+func (m *MailYak) PlainFromHTML(enabled bool) {
+	m.plainFromHTML = enabled
+}
+
+// plainBody returns the plain-text body, generating it from the HTML body if
+// PlainFromHTML is enabled and no plain-text body is set.
+func (m *MailYak) plainBody() []byte {
+	if m.plain.Len() > 0 || !m.plainFromHTML {
+		return m.plain.Bytes()
+	}
+	return htmlToText(m.html.Bytes())
+}
+
+// htmlToText returns a plain-text rendering of the HTML document src.
+func htmlToText(src []byte) []byte {
+	var (
+		t     textWriter
+		links []htmlLink
+		skip  string
+		pre   int
+	)
+
+	for s := string(src); len(s) > 0; {
+		// The content of skipped elements may contain unescaped '<', so skip
+		// directly to the closing tag
+		if skip != "" {
+			end := indexCloseTag(s, skip)
+			if end < 0 {
+				break
+			}
+			s = s[end:]
+		}
+
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			i = len(s)
+		}
+
+		if skip == "" {
+			t.text(html.UnescapeString(s[:i]), pre > 0)
+		}
+		s = s[i:]
+		if s == "" {
+			break
+		}
+
+		// Comments and declarations are dropped
+		if strings.HasPrefix(s, "<!--") {
+			end := strings.Index(s, "-->")
+			if end < 0 {
+				break
+			}
+			s = s[end+3:]
+			continue
+		}
+
+		end := tagEnd(s)
+		if end < 0 {
+			break
+		}
+		tag := s[1:end]
+		s = s[end+1:]
+
+		closing := strings.HasPrefix(tag, "/")
+		name := strings.ToLower(strings.TrimLeft(tag, "/!?"))
+		if n := strings.IndexAny(name, " \t\r\n/"); n >= 0 {
+			name = name[:n]
+		}
+
+		if skip != "" {
+			if closing && name == skip {
+				skip = ""
+			}
+			continue
+		}
+
+		switch {
+		case htmlSkipTags[name] && !closing && !strings.HasSuffix(tag, "/"):
+			skip = name
+
+		case name == "a" && !closing:
+			href := ""
+			if match := hrefRegex.FindStringSubmatch(tag); match != nil {
+				href = html.UnescapeString(match[1] + match[2] + match[3])
+			}
+			links = append(links, htmlLink{href: href, start: t.buf.Len()})
+
+		case name == "a" && len(links) > 0:
+			link := links[len(links)-1]
+			links = links[:len(links)-1]
+			t.link(link)
+
+		case name == "pre" && closing:
+			if pre > 0 {
+				pre--
+			}
+			t.lineBreak(2)
+
+		case name == "pre":
+			pre++
+			t.lineBreak(2)
+
+		case name == "li" && !closing:
+			t.lineBreak(1)
+			t.text("* ", true)
+
+		case name == "hr":
+			t.lineBreak(2)
+			t.text("---", true)
+			t.lineBreak(2)
+
+		case name == "td" || name == "th":
+			t.space = true
+
+		case htmlBlockTags[name]:
+			t.lineBreak(2)
+
+		case htmlLineTags[name]:
+			t.lineBreak(1)
+		}
+	}
+
+	return append(bytes.TrimSpace(t.buf.Bytes()), '\n')
+}
+
+// tagEnd returns the index of the '>' closing the tag at the start of s,
+// ignoring any within quoted attribute values, or -1 if the tag is not closed.
+func tagEnd(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i
+		}
+	}
+	return -1
+}
+
+// indexCloseTag returns the index of the first closing tag of the named element
+// in s, or -1 if there is none.
+func indexCloseTag(s, name string) int {
+	for i := 0; ; {
+		n := strings.Index(s[i:], "</")
+		if n < 0 {
+			return -1
+		}
+		i += n
+
+		if end := i + 2 + len(name); end <= len(s) && strings.EqualFold(s[i+2:end], name) {
+			return i
+		}
+		i += 2
+	}
+}
+
+// htmlLink is an open <a> element.
+type htmlLink struct {
+	href  string
+	start int
+}
+
+// textWriter accumulates rendered text, collapsing whitespace.
+type textWriter struct {
+	buf      bytes.Buffer
+	newlines int
+	space    bool
+}
+
+// text writes s, collapsing runs of whitespace into a single space unless
+// preformatted is true.
+func (t *textWriter) text(s string, preformatted bool) {
+	if preformatted {
+		if s != "" {
+			t.flush()
+			t.buf.WriteString(s)
+		}
+		return
+	}
+
+	if s != "" && isHTMLSpace(rune(s[0])) {
+		t.space = true
+	}
+
+	for i, word := range strings.FieldsFunc(s, isHTMLSpace) {
+		if i > 0 {
+			t.space = true
+		}
+		t.flush()
+		t.buf.WriteString(word)
+
+		t.space = isHTMLSpace(rune(s[len(s)-1]))
+	}
+}
+
+// flush writes any pending line breaks or space before more text is written.
+func (t *textWriter) flush() {
+	switch {
+	case t.buf.Len() == 0:
+	case t.newlines > 0:
+		t.buf.WriteString(strings.Repeat("\n", t.newlines))
+	case t.space && !bytes.HasSuffix(t.buf.Bytes(), []byte(" ")):
+		t.buf.WriteByte(' ')
+	}
+	t.newlines = 0
+	t.space = false
+}
+
+// lineBreak ensures at least n line breaks are written before any more text.
+func (t *textWriter) lineBreak(n int) {
+	if n > t.newlines {
+		t.newlines = n
+	}
+	t.space = false
+}
+
+// link writes the target of link after its text, if it differs from the text.
+func (t *textWriter) link(link htmlLink) {
+	text := strings.TrimSpace(t.buf.String()[link.start:])
+	href := link.href
+
+	switch {
+	case href == "" || strings.HasPrefix(href, "#"):
+		return
+	case text == href || "mailto:"+text == href:
+		return
+	}
+
+	if text == "" {
+		t.flush()
+		t.buf.WriteString(href)
+		return
+	}
+
+	t.space = true
+	t.flush()
+	t.buf.WriteString("(" + href + ")")
+}
+
+// isHTMLSpace returns true if r is an HTML whitespace character.
+func isHTMLSpace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\r', '\f':
+		return true
+	}
+	return false
+}
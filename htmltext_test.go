@@ -0,0 +1,156 @@
+package mailyak
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestHTMLToText ensures HTML is rendered as readable plain text.
+func TestHTMLToText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		html string
+		// Want
+		want string
+	}{
+		{
+			"Text",
+			"Hello   world",
+			"Hello world\n",
+		},
+		{
+			"Inline tags",
+			"Hello <b>bold</b><i>italic</i> world",
+			"Hello bolditalic world\n",
+		},
+		{
+			"Paragraphs",
+			"<html><head><title>Title</title><style>p { color: red; }</style></head><body>\n<p>One</p>\n<p>Two<br>Three</p></body></html>",
+			"One\n\nTwo\nThree\n",
+		},
+		{
+			"Links",
+			`<p>Read the <a href="https://itsallbroken.com/?a=1&amp;b=2">blog</a>.</p>`,
+			"Read the blog (https://itsallbroken.com/?a=1&b=2).\n",
+		},
+		{
+			"Link matching text",
+			`<a href="https://itsallbroken.com">https://itsallbroken.com</a> <a href="mailto:dom@itsallbroken.com">dom@itsallbroken.com</a> <a href="#top">top</a>`,
+			"https://itsallbroken.com dom@itsallbroken.com top\n",
+		},
+		{
+			"Image link",
+			`<a href='https://itsallbroken.com'><img src="logo.png"></a>`,
+			"https://itsallbroken.com\n",
+		},
+		{
+			"Lists",
+			"<ul>\n<li>One</li>\n<li> Two </li>\n</ul><p>After</p>",
+			"* One\n* Two\n\nAfter\n",
+		},
+		{
+			"Tables",
+			"<table><tr><td>A</td><td>B</td></tr><tr><td>C</td><td>D</td></tr></table>",
+			"A B\nC D\n",
+		},
+		{
+			"Preformatted",
+			"<p>Code:</p><pre>a  b\n  c</pre>",
+			"Code:\n\na  b\n  c\n",
+		},
+		{
+			"Entities and comments",
+			"Fish &amp; chips<!-- <p>hidden</p> --> &lt;3 &eacute;",
+			"Fish & chips <3 é\n",
+		},
+		{
+			"Quoted attribute",
+			`<p title="a > b">Text</p>`,
+			"Text\n",
+		},
+		{
+			"Script",
+			"<script>if (a < b) { alert(1) }</script>Text",
+			"Text\n",
+		},
+		{
+			"Rule",
+			"One<hr/>Two",
+			"One\n\n---\n\nTwo\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := string(htmlToText([]byte(tt.html))); got != tt.want {
+				t.Errorf("%q. htmlToText() = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMailYakPlainFromHTML ensures the plain-text part is generated from the
+// HTML body only when enabled and no plain-text body is set.
+func TestMailYakPlainFromHTML(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		plain   string
+		enabled bool
+		// Want
+		want string
+	}{
+		{
+			"Disabled",
+			"",
+			false,
+			"",
+		},
+		{
+			"Enabled",
+			"",
+			true,
+			"Hello world\r\n",
+		},
+		{
+			"Plain set",
+			"Plain",
+			true,
+			"Plain",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.HTML().Set("<p>Hello <b>world</b></p>")
+			m.Plain().Set(tt.plain)
+			m.PlainFromHTML(tt.enabled)
+
+			var buf bytes.Buffer
+			if err := m.writeBody(&buf, "test"); err != nil {
+				t.Fatal(err)
+			}
+
+			got := ""
+			if parts := strings.SplitN(buf.String(), "Content-Type: text/plain; charset=UTF-8\r\n\r\n", 2); len(parts) == 2 {
+				got = strings.SplitN(parts[1], "\r\n--test", 2)[0]
+			}
+			if got != tt.want {
+				t.Errorf("%q. MailYak.writeBody() plain part = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
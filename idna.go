@@ -0,0 +1,61 @@
+package mailyak
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// maxLabelLen is the maximum length of a DNS label, in octets.
+const maxLabelLen = 63
+
+// toASCII converts the internationalized domain to its ASCII form, encoding
+// each non-ASCII label as an A-label ("xn--" followed by the punycode encoding
+// of the label).
+//
+// Domains are mapped, normalized and validated with the IDNA2008 lookup
+// profile (UTS #46), so visually identical domains written with composed or
+// decomposed characters convert to the same A-labels. ASCII domains are
+// returned unchanged.
+func toASCII(domain string) (string, error) {
+	if isASCII(domain) {
+		return domain, nil
+	}
+
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", fmt.Errorf("mailyak: invalid domain %q: %w", domain, err)
+	}
+
+	return ascii, nil
+}
+
+// asciiAddress returns addr with the domain converted to its ASCII form,
+// preserving any display name. ok is false if the local part of the address
+// contains non-ASCII characters, as it cannot be represented without SMTPUTF8.
+func asciiAddress(addr string) (string, bool) {
+	prefix, inner, suffix := "", addr, ""
+	if strings.HasSuffix(addr, ">") {
+		if i := strings.LastIndexByte(addr, '<'); i >= 0 {
+			prefix, inner, suffix = addr[:i+1], addr[i+1:len(addr)-1], ">"
+		}
+	}
+
+	at := strings.LastIndexByte(inner, '@')
+	if at < 0 {
+		return addr, isASCII(inner)
+	}
+
+	local, domain := inner[:at], inner[at+1:]
+	if !isASCII(local) {
+		return addr, false
+	}
+
+	domain, err := toASCII(domain)
+	if err != nil {
+		return addr, false
+	}
+
+	return prefix + local + "@" + domain + suffix, true
+}
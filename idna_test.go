@@ -0,0 +1,152 @@
+package mailyak
+
+import "testing"
+
+// TestToASCII ensures internationalized domains are converted to A-labels
+func TestToASCII(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		domain string
+		// Want
+		want    string
+		wantErr bool
+	}{
+		{
+			"ASCII",
+			"itsallbroken.com",
+			"itsallbroken.com",
+			false,
+		},
+		{
+			"Mixed label",
+			"bücher.example",
+			"xn--bcher-kva.example",
+			false,
+		},
+		{
+			"Uppercase",
+			"Bücher.example",
+			"xn--bcher-kva.example",
+			false,
+		},
+		{
+			"Non-latin",
+			"例え.jp",
+			"xn--r8jz45g.jp",
+			false,
+		},
+		{
+			"Multiple labels",
+			"münchen.日本語.jp",
+			"xn--mnchen-3ya.xn--wgv71a119e.jp",
+			false,
+		},
+		{
+			"Ideographic full stop",
+			"例え。jp",
+			"xn--r8jz45g.jp",
+			false,
+		},
+		{
+			"Decomposed",
+			"bu\u0308cher.example",
+			"xn--bcher-kva.example",
+			false,
+		},
+		{
+			"Fullwidth",
+			"ｂücher.example",
+			"xn--bcher-kva.example",
+			false,
+		},
+		{
+			"Disallowed",
+			"bücher_.example",
+			"",
+			true,
+		},
+		{
+			"Label too long",
+			"ü" + string(make([]byte, 60)) + ".example",
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := toASCII(tt.domain)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%q. toASCII() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("%q. toASCII() = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestASCIIAddress ensures address domains are converted, preserving the
+// display name
+func TestASCIIAddress(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		addr string
+		// Want
+		want   string
+		wantOK bool
+	}{
+		{
+			"ASCII",
+			"dom@itsallbroken.com",
+			"dom@itsallbroken.com",
+			true,
+		},
+		{
+			"Internationalized domain",
+			"dom@bücher.example",
+			"dom@xn--bcher-kva.example",
+			true,
+		},
+		{
+			"Display name",
+			"Jürgen <jm@bücher.example>",
+			"Jürgen <jm@xn--bcher-kva.example>",
+			true,
+		},
+		{
+			"Internationalized local part",
+			"用户@例え.jp",
+			"用户@例え.jp",
+			false,
+		},
+		{
+			"Empty",
+			"",
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := asciiAddress(tt.addr)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("%q. asciiAddress() = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Package apimail implements the parts of the email API transports shared
+// between the API sub-packages - calling the APIs, and converting the MIME
+// message passed to a mailyak.Transport into the fields of an API request.
+package apimail
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/domodwyer/mailyak/v3"
+)
+
+// maxErrorBody is the maximum number of bytes of an error response read from
+// an email API.
+const maxErrorBody = 64 << 10
+
+// Do sends req using client, decoding a successful JSON response into out if
+// it is not nil, and returning a *mailyak.APIError for unsuccessful responses.
+func Do(client *http.Client, req *http.Request, service string, out interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mailyak: %s API request: %w", service, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return &mailyak.APIError{
+			Service:    service,
+			StatusCode: resp.StatusCode,
+			Message:    errorMessage(body),
+		}
+	}
+
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("mailyak: decoding %s API response: %w", service, err)
+	}
+
+	return nil
+}
+
+// errorMessage returns the error message in the error response body.
+//
+// Email APIs describe errors in a variety of JSON structures, such as
+// {"message": "..."}, {"error": {"message": "..."}} or
+// {"errors": [{"message": "..."}]} - the first message found is returned, or
+// the body itself if no message is found.
+func errorMessage(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err == nil {
+		if msg := findErrorMessage(v); msg != "" {
+			return msg
+		}
+	}
+
+	if msg := strings.TrimSpace(string(body)); msg != "" {
+		return msg
+	}
+	return "no error message"
+}
+
+// findErrorMessage returns the first string value of a "message" key in the
+// decoded JSON value v.
+func findErrorMessage(v interface{}) string {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if s, ok := val.(string); ok && strings.EqualFold(k, "message") && s != "" {
+				return s
+			}
+		}
+		for _, k := range []string{"error", "Error", "errors", "Errors"} {
+			if msg := findErrorMessage(v[k]); msg != "" {
+				return msg
+			}
+		}
+	case []interface{}:
+		for _, val := range v {
+			if msg := findErrorMessage(val); msg != "" {
+				return msg
+			}
+		}
+	case string:
+		return v
+	}
+	return ""
+}
+
+// Buffer returns the MIME message written by msg.
+func Buffer(msg io.WriterTo) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := msg.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SplitHeaderList returns the comma separated values in v, with surrounding
+// whitespace removed.
+func SplitHeaderList(v string) []string {
+	var values []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// SortedKeys returns the keys of m in sorted order.
+func SortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,36 @@
+package apimail
+
+import "testing"
+
+// TestErrorMessage ensures error messages are extracted from the error
+// responses of the email APIs.
+func TestErrorMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		body string
+		// Want
+		want string
+	}{
+		{"Message", `{"message": "not verified"}`, "not verified"},
+		{"Capitalised", `{"Message": "not verified"}`, "not verified"},
+		{"Nested error", `{"error": {"code": 400, "message": "bad request"}}`, "bad request"},
+		{"Error list", `{"errors": [{"field": "from", "message": "invalid from"}]}`, "invalid from"},
+		{"Error string", `{"error": "invalid_grant"}`, "invalid_grant"},
+		{"Plain text", "Forbidden\n", "Forbidden"},
+		{"Empty", "", "no error message"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := errorMessage([]byte(tt.body)); got != tt.want {
+				t.Errorf("errorMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package apimail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// skipHeaders are the headers represented by dedicated fields of an Email, or
+// describing the MIME structure of the message and set by the API itself.
+var skipHeaders = map[string]bool{
+	"From":                      true,
+	"To":                        true,
+	"Cc":                        true,
+	"Bcc":                       true,
+	"Reply-To":                  true,
+	"Subject":                   true,
+	"Date":                      true,
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+}
+
+// signedTypes are the media types of signed or encrypted MIME messages, which
+// cannot be converted into API requests without invalidating the signature.
+var signedTypes = map[string]bool{
+	"multipart/signed":         true,
+	"multipart/encrypted":      true,
+	"application/pkcs7-mime":   true,
+	"application/x-pkcs7-mime": true,
+}
+
+// Email is an email decomposed into the fields used by email APIs that do not
+// accept raw MIME data.
+type Email struct {
+	From        *mail.Address
+	ReplyTo     *mail.Address
+	To, Cc, Bcc []*mail.Address
+	Subject     string
+	Plain       string
+	HTML        string
+
+	// Headers are the headers not represented by the fields above, such as
+	// the Message-ID, threading and custom headers, with any RFC 2047
+	// encoding removed. Repeated headers are joined with a comma.
+	Headers map[string]string
+
+	// HeaderValues holds each value of the Headers, for email APIs that
+	// accept a header more than once.
+	HeaderValues map[string][]string
+
+	Attachments []Attachment
+}
+
+// Attachment is an attachment of an Email.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	ContentID   string
+	Inline      bool
+	Data        []byte
+}
+
+// NewEmail returns the email written by msg as an Email for the named email
+// API, sent to rcpts.
+//
+// Recipients in rcpts not listed in the To, Cc or Bcc headers of the email are
+// added to the Bcc addresses. Signed or encrypted emails and calendar
+// invitations are not supported.
+func NewEmail(msg io.WriterTo, rcpts []string, service string) (*Email, error) {
+	data, err := Buffer(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("mailyak: parsing %s API email: %w", service, err)
+	}
+
+	ctype := m.Header.Get("Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(ctype); signedTypes[mediaType] || m.Header.Get("Dkim-Signature") != "" {
+		return nil, fmt.Errorf("mailyak: %s API transport cannot send signed or encrypted emails", service)
+	}
+
+	e := &Email{
+		From:         &mail.Address{},
+		Subject:      decodeHeader(m.Header.Get("Subject")),
+		Headers:      map[string]string{},
+		HeaderValues: map[string][]string{},
+	}
+
+	if addrs, err := addressList(m.Header, "From"); err != nil {
+		return nil, err
+	} else if len(addrs) > 0 {
+		e.From = addrs[0]
+	}
+
+	if addrs, err := addressList(m.Header, "Reply-To"); err != nil {
+		return nil, err
+	} else if len(addrs) > 0 {
+		e.ReplyTo = addrs[0]
+	}
+
+	for _, list := range []struct {
+		name string
+		dst  *[]*mail.Address
+	}{
+		{"To", &e.To},
+		{"Cc", &e.Cc},
+		{"Bcc", &e.Bcc},
+	} {
+		if *list.dst, err = addressList(m.Header, list.name); err != nil {
+			return nil, err
+		}
+	}
+
+	bcc, err := unlisted(m.Header, rcpts)
+	if err != nil {
+		return nil, err
+	}
+	e.Bcc = append(e.Bcc, bcc...)
+
+	for k, v := range m.Header {
+		if skipHeaders[k] || len(v) == 0 {
+			continue
+		}
+		e.Headers[k] = decodeHeader(strings.Join(v, ", "))
+		for _, v := range v {
+			e.HeaderValues[k] = append(e.HeaderValues[k], decodeHeader(v))
+		}
+	}
+
+	h := textproto.MIMEHeader{
+		"Content-Type":              m.Header["Content-Type"],
+		"Content-Transfer-Encoding": m.Header["Content-Transfer-Encoding"],
+	}
+	if err := e.parsePart(h, m.Body, service); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// PopHeader removes the named header from the email, returning its value.
+func (e *Email) PopHeader(name string) string {
+	name = textproto.CanonicalMIMEHeaderKey(name)
+	v := e.Headers[name]
+	delete(e.Headers, name)
+	delete(e.HeaderValues, name)
+	return v
+}
+
+// parsePart populates the bodies and attachments of e from the MIME part with
+// header h and content body, recursing into multipart parts.
+func (e *Email) parsePart(h textproto.MIMEHeader, body io.Reader, service string) error {
+	ctype := h.Get("Content-Type")
+	if ctype == "" {
+		ctype = "text/plain; charset=us-ascii"
+	}
+
+	mediaType, params, err := mime.ParseMediaType(ctype)
+	if err != nil {
+		return fmt.Errorf("mailyak: invalid Content-Type %q: %w", ctype, err)
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := e.parsePart(part.Header, part, service); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := decodeTransferEncoding(h.Get("Content-Transfer-Encoding"), body)
+	if err != nil {
+		return err
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := dparams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	filename = decodeHeader(filename)
+
+	if disposition != "attachment" && filename == "" {
+		switch {
+		case mediaType == "text/plain" && e.Plain == "":
+			e.Plain = string(data)
+			return nil
+		case mediaType == "text/html" && e.HTML == "":
+			e.HTML = string(data)
+			return nil
+		case mediaType == "text/calendar" && params["method"] != "":
+			return fmt.Errorf("mailyak: %s API transport cannot send calendar invitations", service)
+		}
+	}
+
+	// The filename parameters are sent separately
+	delete(params, "name")
+	delete(params, "filename")
+
+	a := Attachment{
+		Filename:    filename,
+		ContentType: mime.FormatMediaType(mediaType, params),
+		ContentID:   strings.Trim(h.Get("Content-Id"), "<> "),
+		Inline:      disposition == "inline",
+		Data:        data,
+	}
+	if a.ContentID == "" {
+		a.ContentID = filename
+	}
+	e.Attachments = append(e.Attachments, a)
+
+	return nil
+}
+
+// WithBcc returns the MIME message data with a Bcc header listing the
+// recipients in rcpts not in its To, Cc or Bcc headers, for email APIs that
+// deliver raw MIME data to the recipients in its headers rather than an
+// envelope.
+//
+// The Bcc header is added before the existing headers, leaving any signature
+// of the message intact.
+func WithBcc(data []byte, rcpts []string) ([]byte, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("mailyak: parsing email: %w", err)
+	}
+
+	bcc, err := unlisted(m.Header, rcpts)
+	if err != nil || len(bcc) == 0 {
+		return data, err
+	}
+
+	addrs := make([]string, len(bcc))
+	for i, a := range bcc {
+		addrs[i] = a.Address
+	}
+
+	return append([]byte("Bcc: "+strings.Join(addrs, ", ")+"\r\n"), data...), nil
+}
+
+// unlisted returns the recipients in rcpts not listed in the To, Cc or Bcc
+// headers of h, such as the Bcc recipients of an email written without the
+// Bcc header.
+func unlisted(h mail.Header, rcpts []string) ([]*mail.Address, error) {
+	listed := make(map[string]bool)
+	for _, name := range []string{"To", "Cc", "Bcc"} {
+		addrs, err := addressList(h, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			listed[addressKey(a.Address)] = true
+		}
+	}
+
+	var out []*mail.Address
+	for _, rcpt := range rcpts {
+		if key := addressKey(rcpt); !listed[key] {
+			listed[key] = true
+			out = append(out, &mail.Address{Address: rcpt})
+		}
+	}
+	return out, nil
+}
+
+// addressList returns the addresses in all instances of the named header of h,
+// ignoring empty headers.
+func addressList(h mail.Header, name string) ([]*mail.Address, error) {
+	var addrs []*mail.Address
+	for _, v := range h[textproto.CanonicalMIMEHeaderKey(name)] {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+
+		list, err := mail.ParseAddressList(v)
+		if err != nil {
+			return nil, fmt.Errorf("mailyak: invalid %s header: %w", name, err)
+		}
+		addrs = append(addrs, list...)
+	}
+
+	return addrs, nil
+}
+
+// addressKey returns addr in a form comparable with other addresses, with the
+// domain converted to lowercase ASCII.
+func addressKey(addr string) string {
+	addr = strings.ToLower(addr)
+
+	at := strings.LastIndexByte(addr, '@')
+	if at < 0 {
+		return addr
+	}
+
+	domain, err := idna.Lookup.ToASCII(addr[at+1:])
+	if err != nil {
+		return addr
+	}
+	return addr[:at+1] + domain
+}
+
+// decodeHeader returns s with any RFC 2047 encoded words decoded.
+func decodeHeader(s string) string {
+	decoded, err := new(mime.WordDecoder).DecodeHeader(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+// decodeTransferEncoding returns the content read from r, decoded according to
+// the Content-Transfer-Encoding cte.
+func decodeTransferEncoding(cte string, r io.Reader) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(cte)) {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	}
+	return io.ReadAll(r)
+}
@@ -0,0 +1,271 @@
+package apimail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/domodwyer/mailyak/v3"
+)
+
+// testSigner is a mailyak.PGPSigner producing a fixed signature.
+type testSigner struct{}
+
+func (testSigner) ArmoredDetachSign(w io.Writer, message io.Reader) error {
+	if _, err := io.Copy(io.Discard, message); err != nil {
+		return err
+	}
+	_, err := fmt.Fprint(w, "-----BEGIN PGP SIGNATURE-----\n\nsignature\n-----END PGP SIGNATURE-----\n")
+	return err
+}
+
+func (testSigner) Micalg() string { return "pgp-sha256" }
+
+// emailTransport is a mailyak.Transport recording each email as an Email.
+type emailTransport struct {
+	email *Email
+}
+
+func (t *emailTransport) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	e, err := NewEmail(msg, rcpts, "Test")
+	t.email = e
+	return err
+}
+
+// TestNewEmail ensures the fields of the email are parsed from the MIME
+// message.
+func TestNewEmail(t *testing.T) {
+	t.Parallel()
+
+	transport := &emailTransport{}
+
+	m := mailyak.New("", nil)
+	m.Transport(transport)
+	m.From("from@example.org")
+	m.FromName("Zoë")
+	m.To("Dom <to@example.org>", "other@example.org")
+	m.Cc("cc@example.org")
+	m.Bcc("bcc@example.org")
+	m.ReplyTo("reply@example.org")
+	m.Subject("Héllo")
+	m.AddHeader("Comments", "one")
+	m.AddHeader("Comments", "twö")
+	m.Plain().Set("plain")
+	m.HTML().Set("<p>html</p>")
+	m.Attach("report.csv", strings.NewReader("a,b"))
+	m.AttachInlineWithMimeType("logo.png", strings.NewReader("png"), "image/png")
+
+	if _, _, err := m.Send("localhost"); err != nil {
+		t.Fatalf("NewEmail() error = %v", err)
+	}
+
+	want := &Email{
+		From:    &mail.Address{Name: "Zoë", Address: "from@example.org"},
+		ReplyTo: &mail.Address{Address: "reply@example.org"},
+		To:      []*mail.Address{{Name: "Dom", Address: "to@example.org"}, {Address: "other@example.org"}},
+		Cc:      []*mail.Address{{Address: "cc@example.org"}},
+		Bcc:     []*mail.Address{{Address: "bcc@example.org"}},
+		Subject: "Héllo",
+		Plain:   "plain",
+		HTML:    "<p>html</p>",
+		Headers: map[string]string{
+			"Comments":   "one, twö",
+			"Message-Id": m.GetMessageID(),
+			"X-Mailer":   mailyak.DefaultMailer,
+		},
+		HeaderValues: map[string][]string{
+			"Comments":   {"one", "twö"},
+			"Message-Id": {m.GetMessageID()},
+			"X-Mailer":   {mailyak.DefaultMailer},
+		},
+		Attachments: []Attachment{
+			{Filename: "report.csv", ContentType: "text/plain; charset=utf-8", ContentID: "report.csv", Data: []byte("a,b")},
+			{Filename: "logo.png", ContentType: "image/png", ContentID: "logo.png", Inline: true, Data: []byte("png")},
+		},
+	}
+	if !reflect.DeepEqual(transport.email, want) {
+		t.Errorf("NewEmail() =\n%+v\nwant\n%+v", transport.email, want)
+	}
+}
+
+// TestNewEmail_unsupported ensures emails that cannot be converted into API
+// requests are rejected.
+func TestNewEmail_unsupported(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		setup func(m *mailyak.MailYak) error
+		// Want
+		wantErr string
+	}{
+		{
+			name: "Signed",
+			setup: func(m *mailyak.MailYak) error {
+				return m.PGP(mailyak.PGPOptions{Signer: testSigner{}})
+			},
+			wantErr: "signed or encrypted",
+		},
+		{
+			name: "Calendar",
+			setup: func(m *mailyak.MailYak) error {
+				return m.Calendar("REQUEST", strings.NewReader("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"))
+			},
+			wantErr: "calendar invitations",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := mailyak.New("", nil)
+			m.Transport(&emailTransport{})
+			m.From("from@example.org")
+			m.To("to@example.org")
+			if err := tt.setup(m); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, _, err := m.Send("localhost"); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewEmail() error = %v, want %s error", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestNewEmail_limits ensures the attachment and message size limits of the
+// email are enforced.
+func TestNewEmail_limits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		limits         mailyak.AttachmentLimits
+		maxMessageSize int64
+		// Want
+		wantErr error
+	}{
+		{"No limits", mailyak.AttachmentLimits{}, 0, nil},
+		{"Within limits", mailyak.AttachmentLimits{MaxSize: 1000, MaxTotalSize: 2000}, 10000, nil},
+		{"Too large", mailyak.AttachmentLimits{MaxSize: 500}, 0, mailyak.ErrAttachmentTooLarge},
+		{"Total too large", mailyak.AttachmentLimits{MaxTotalSize: 1500}, 0, mailyak.ErrAttachmentsTooLarge},
+		{"Message too large", mailyak.AttachmentLimits{}, 2000, mailyak.ErrMessageTooLarge},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			transport := &emailTransport{}
+
+			m := mailyak.New("", nil)
+			m.Transport(transport)
+			m.From("from@example.org")
+			m.To("to@example.org")
+			m.AttachmentLimits(tt.limits)
+			m.MaxMessageSize(tt.maxMessageSize)
+
+			// the size of the attachments cannot be determined in advance
+			m.Attach("a.txt", io.MultiReader(strings.NewReader(strings.Repeat("a", 1000))))
+			m.Attach("b.txt", io.MultiReader(strings.NewReader(strings.Repeat("b", 1000))))
+
+			_, _, err := m.Send("localhost")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewEmail() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && len(transport.email.Attachments) != 2 {
+				t.Errorf("NewEmail() attachments = %d, want 2", len(transport.email.Attachments))
+			}
+		})
+	}
+}
+
+// TestNewEmail_deduplicateAttachments ensures identical attachments are
+// included once.
+func TestNewEmail_deduplicateAttachments(t *testing.T) {
+	t.Parallel()
+
+	transport := &emailTransport{}
+
+	m := mailyak.New("", nil)
+	m.Transport(transport)
+	m.DeduplicateAttachments(true)
+	m.From("from@example.org")
+	m.To("to@example.org")
+	m.AttachInline("logo.png", strings.NewReader("png"))
+	m.Attach("report.pdf", strings.NewReader("pdf"))
+	m.AttachInline("logo.png", strings.NewReader("png"))
+
+	if _, _, err := m.Send("localhost"); err != nil {
+		t.Fatalf("NewEmail() error = %v", err)
+	}
+
+	if a := transport.email.Attachments; len(a) != 2 || a[0].Filename != "logo.png" || a[1].Filename != "report.pdf" {
+		t.Errorf("NewEmail() attachments = %+v, want logo.png and report.pdf", a)
+	}
+}
+
+// TestWithBcc ensures recipients not in the headers of the message are added
+// to a Bcc header.
+func TestWithBcc(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		data  string
+		rcpts []string
+		// Want
+		want string
+	}{
+		{
+			name:  "Listed",
+			data:  "To: Dom <to@example.org>\r\nCc: cc@example.org\r\n\r\nHello",
+			rcpts: []string{"to@example.org", "CC@example.org"},
+			want:  "To: Dom <to@example.org>\r\nCc: cc@example.org\r\n\r\nHello",
+		},
+		{
+			name:  "Unlisted",
+			data:  "To: to@example.org\r\n\r\nHello",
+			rcpts: []string{"to@example.org", "bcc@example.org", "other@example.org", "bcc@example.org"},
+			want:  "Bcc: bcc@example.org, other@example.org\r\nTo: to@example.org\r\n\r\nHello",
+		},
+		{
+			name:  "Bcc header",
+			data:  "To: to@example.org\r\nBcc: bcc@example.org\r\n\r\nHello",
+			rcpts: []string{"to@example.org", "bcc@example.org"},
+			want:  "To: to@example.org\r\nBcc: bcc@example.org\r\n\r\nHello",
+		},
+		{
+			name:  "Internationalized domain",
+			data:  "To: dom@bücher.example\r\n\r\nHello",
+			rcpts: []string{"dom@xn--bcher-kva.example"},
+			want:  "To: dom@bücher.example\r\n\r\nHello",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := WithBcc([]byte(tt.data), tt.rcpts)
+			if err != nil {
+				t.Fatalf("WithBcc() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("WithBcc() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package mailyak
+
+import (
+	"bytes"
+	"net"
+	"net/smtp"
+	"net/textproto"
+)
+
+// LMTP causes the email to be delivered using LMTP (RFC 2033) rather than SMTP
+// when true, such as directly into the mailboxes of a local Dovecot or Cyrus
+// server. Defaults to false.
+//
+//	mail := mailyak.New("localhost:24", nil)
+//	mail.LMTP(true)
+//
+// The session is started with LHLO rather than EHLO, and the server responds
+// to the message data once for each accepted recipient. Recipients rejected
+// at that point are listed by GetSendResult, and fail the send unless
+// PartialDelivery is enabled and at least one recipient was delivered to.
+//
+// STARTTLS is not negotiated, as LMTP is intended for delivery to a local or
+// trusted server - use NewWithTLS for an encrypted connection. If RequireTLS is
+// set without NewWithTLS, Send returns ErrStartTLSUnsupported.
+func (m *MailYak) LMTP(enabled bool) {
+	m.lmtp = enabled
+}
+
+// lmtpConn is a net.Conn greeting the server with LHLO rather than the EHLO
+// command sent by smtp.Client.
+type lmtpConn struct {
+	net.Conn
+}
+
+func (c *lmtpConn) Write(p []byte) (int, error) {
+	if bytes.HasPrefix(p, []byte("EHLO ")) {
+		lhlo := append([]byte("LHLO "), p[len("EHLO "):]...)
+		return c.Conn.Write(lhlo)
+	}
+	return c.Conn.Write(p)
+}
+
+// lmtpResponses reads the response to the message data for each recipient
+// accepted in result, moving any rejected to result.Rejected.
+//
+// The responses to all recipients are read, keeping the session in sync, before
+// returning the first rejection, unless partial is true and at least one
+// recipient was delivered to. The first successful response is returned.
+func lmtpResponses(smtpClient *smtp.Client, result *SendResult, partial bool) (int, string, error) {
+	accepted := result.Accepted
+	result.Accepted = nil
+
+	var (
+		code, firstCode int
+		msg, firstMsg   string
+		err, rejected   error
+	)
+	for _, status := range accepted {
+		code, msg, err = smtpClient.Text.ReadResponse(250)
+		if _, ok := err.(*textproto.Error); err != nil && !ok {
+			return -1, "", err
+		}
+
+		status.Code, status.Msg = code, msg
+		if err != nil {
+			result.Rejected = append(result.Rejected, status)
+			if rejected == nil {
+				rejected = dataError(rejectedError(smtpCommand{rcpt: status.Addr}, err))
+			}
+			continue
+		}
+
+		result.Accepted = append(result.Accepted, status)
+		if len(result.Accepted) == 1 {
+			firstCode, firstMsg = code, msg
+		}
+	}
+
+	if rejected != nil && (!partial || len(result.Accepted) == 0) {
+		return -1, "", rejected
+	}
+
+	return firstCode, firstMsg, nil
+}
@@ -0,0 +1,161 @@
+package mailyak
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestMailYakLMTP ensures the session is started with LHLO, and the response
+// to the message data for each recipient is recorded.
+func TestMailYakLMTP(t *testing.T) {
+	t.Parallel()
+
+	delivered := func(addr string) RecipientStatus {
+		return RecipientStatus{Addr: addr, Code: 250, Msg: "2.0.0 <" + addr + "> Saved"}
+	}
+	overQuota := func(addr string) RecipientStatus {
+		return RecipientStatus{Addr: addr, Code: 552, Msg: "5.2.2 <" + addr + "> Mailbox full"}
+	}
+	reply := func(statuses ...RecipientStatus) string {
+		lines := make([]string, len(statuses))
+		for i, s := range statuses {
+			lines[i] = s.Msg
+			if s.Code == 250 {
+				lines[i] = "250 " + lines[i]
+			} else {
+				lines[i] = "552 " + lines[i]
+			}
+		}
+		return strings.Join(lines, "\r\n")
+	}
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		partial    bool
+		requireTLS bool
+		replies    map[string]string
+		// Want
+		wantErr      error
+		wantCode     int
+		wantAccepted []RecipientStatus
+		wantRejected []RecipientStatus
+	}{
+		{
+			name: "Delivered",
+			replies: map[string]string{
+				".": reply(delivered("a@example.org"), delivered("b@example.org")),
+			},
+			wantCode:     250,
+			wantAccepted: []RecipientStatus{delivered("a@example.org"), delivered("b@example.org")},
+		},
+		{
+			name: "Rejected",
+			replies: map[string]string{
+				".": reply(delivered("a@example.org"), overQuota("b@example.org")),
+			},
+			wantErr:      ErrDataRejected,
+			wantAccepted: []RecipientStatus{delivered("a@example.org")},
+			wantRejected: []RecipientStatus{overQuota("b@example.org")},
+		},
+		{
+			name:    "Rejected partial",
+			partial: true,
+			replies: map[string]string{
+				".": reply(overQuota("a@example.org"), delivered("b@example.org")),
+			},
+			wantCode:     250,
+			wantAccepted: []RecipientStatus{delivered("b@example.org")},
+			wantRejected: []RecipientStatus{overQuota("a@example.org")},
+		},
+		{
+			name:    "All rejected partial",
+			partial: true,
+			replies: map[string]string{
+				".": reply(overQuota("a@example.org"), overQuota("b@example.org")),
+			},
+			wantErr:      ErrRecipientRejected,
+			wantRejected: []RecipientStatus{overQuota("a@example.org"), overQuota("b@example.org")},
+		},
+		{
+			name:    "Recipient rejected partial",
+			partial: true,
+			replies: map[string]string{
+				"RCPT TO:<a@example.org>": "550 No such user",
+				".":                       reply(delivered("b@example.org")),
+			},
+			wantCode:     250,
+			wantAccepted: []RecipientStatus{delivered("b@example.org")},
+			wantRejected: []RecipientStatus{{Addr: "a@example.org", Code: 550, Msg: "No such user"}},
+		},
+		{
+			name:       "Require TLS",
+			requireTLS: true,
+			wantErr:    ErrStartTLSUnsupported,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			replies := map[string]string{"LHLO": "250-localhost\r\n250 STARTTLS"}
+			for k, v := range tt.replies {
+				replies[k] = v
+			}
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, replies)
+			defer srv.Close()
+
+			mail := New(srv.Addr(), nil)
+			mail.LMTP(true)
+			mail.PartialDelivery(tt.partial)
+			mail.RequireTLS(tt.requireTLS)
+			mail.From("from@example.org")
+			mail.To("a@example.org", "b@example.org")
+			mail.Plain().Set("Hello")
+
+			code, _, err := mail.Send("localhost")
+			if !errors.Is(err, tt.wantErr) || (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("MailYak.Send() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && code != tt.wantCode {
+				t.Errorf("MailYak.Send() code = %d, want %d", code, tt.wantCode)
+			}
+
+			cmds := srv.Commands()
+			if len(cmds) == 0 || cmds[0] != "LHLO localhost" {
+				t.Errorf("MailYak.Send() commands = %q, want LHLO first", cmds)
+			}
+			for _, cmd := range cmds {
+				if cmd == "STARTTLS" {
+					t.Errorf("MailYak.Send() commands = %q, want no STARTTLS", cmds)
+				}
+			}
+
+			if tt.wantErr == ErrStartTLSUnsupported {
+				return
+			}
+
+			got := mail.GetSendResult()
+			if got == nil {
+				t.Fatal("MailYak.GetSendResult() = nil")
+			}
+			if !reflect.DeepEqual(got.Accepted, tt.wantAccepted) {
+				t.Errorf("MailYak.GetSendResult().Accepted = %v, want %v", got.Accepted, tt.wantAccepted)
+			}
+			if !reflect.DeepEqual(got.Rejected, tt.wantRejected) {
+				t.Errorf("MailYak.GetSendResult().Rejected = %v, want %v", got.Rejected, tt.wantRejected)
+			}
+		})
+	}
+}
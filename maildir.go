@@ -0,0 +1,112 @@
+package mailyak
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// maildirDeliveries counts the deliveries made by this process, making each
+// Maildir filename unique.
+var maildirDeliveries uint64
+
+// MaildirTransport is a Transport delivering emails into a local Maildir, for
+// testing, local agents or systems running their own mail delivery agent:
+//
+//	t, err := mailyak.NewMaildirTransport("/home/dom/Maildir")
+//	if err != nil {
+//		return err
+//	}
+//	mail.Transport(t)
+//
+// Each email is written to the "tmp" subdirectory and moved into "new" once
+// complete, so readers of the Maildir never see a partially written email.
+// All emails are delivered into the same Maildir, regardless of the
+// recipients.
+type MaildirTransport struct {
+	dir      string
+	hostname string
+}
+
+// NewMaildirTransport returns a MaildirTransport delivering into the Maildir at
+// dir, creating the Maildir if it does not exist.
+func NewMaildirTransport(dir string) (*MaildirTransport, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return nil, fmt.Errorf("mailyak: creating maildir: %w", err)
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	// The hostname must not contain the path separator, or the ':' separating
+	// the flags of delivered emails
+	hostname = strings.NewReplacer("/", `\057`, ":", `\072`).Replace(hostname)
+
+	return &MaildirTransport{dir: dir, hostname: hostname}, nil
+}
+
+// Send writes msg into the Maildir, prefixed with a Return-Path header
+// containing envelopeFrom.
+func (t *MaildirTransport) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%d.M%dP%dQ%d.%s",
+		now.Unix(),
+		now.Nanosecond()/1000,
+		os.Getpid(),
+		atomic.AddUint64(&maildirDeliveries, 1),
+		t.hostname,
+	)
+
+	tmp := filepath.Join(t.dir, "tmp", name)
+	if err := writeMaildirFile(tmp, envelopeFrom, msg); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("mailyak: maildir delivery: %w", err)
+	}
+
+	if err := os.Rename(tmp, filepath.Join(t.dir, "new", name)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("mailyak: maildir delivery: %w", err)
+	}
+
+	return nil
+}
+
+// writeMaildirFile writes msg to the new file name, synced to disk before
+// returning.
+func writeMaildirFile(name, envelopeFrom string, msg io.WriterTo) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if _, err := fmt.Fprintf(w, "Return-Path: <%s>\r\n", envelopeFrom); err != nil {
+		return err
+	}
+	if _, err := msg.WriteTo(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,98 @@
+package mailyak
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMaildirTransport ensures emails are delivered into the new directory of
+// the Maildir.
+func TestMaildirTransport(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "Maildir")
+
+	transport, err := NewMaildirTransport(dir)
+	if err != nil {
+		t.Fatalf("NewMaildirTransport() error = %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		mail := New("", nil)
+		mail.Transport(transport)
+		mail.From("from@example.org")
+		mail.To("to@example.org")
+		mail.Subject("Delivered")
+		mail.Plain().Set("Hello")
+
+		if _, _, err := mail.Send("localhost"); err != nil {
+			t.Fatalf("MailYak.Send() error = %v", err)
+		}
+	}
+
+	for sub, want := range map[string]int{"tmp": 0, "new": 2, "cur": 0} {
+		files, err := ioutil.ReadDir(filepath.Join(dir, sub))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != want {
+			t.Errorf("NewMaildirTransport() %s contains %d files, want %d", sub, len(files), want)
+		}
+	}
+
+	files, _ := ioutil.ReadDir(filepath.Join(dir, "new"))
+	for _, f := range files {
+		if strings.Contains(f.Name(), ":") || f.Mode().Perm() != 0600 {
+			t.Errorf("MaildirTransport.Send() wrote %q with mode %v", f.Name(), f.Mode())
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "new", f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, []byte("Return-Path: <from@example.org>\r\nFrom: from@example.org\r\n")) {
+			t.Errorf("MaildirTransport.Send() wrote %q", data)
+		}
+
+		m, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if got := m.plain.String(); got != "Hello" {
+			t.Errorf("MaildirTransport.Send() plain = %q, want %q", got, "Hello")
+		}
+	}
+}
+
+// TestMaildirTransport_cancelled ensures nothing is delivered once the context
+// is cancelled.
+func TestMaildirTransport_cancelled(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	transport, err := NewMaildirTransport(dir)
+	if err != nil {
+		t.Fatalf("NewMaildirTransport() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mail := New("", nil)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+
+	if err := transport.Send(ctx, "from@example.org", mail.recipients(), mail); err != context.Canceled {
+		t.Fatalf("MaildirTransport.Send() error = %v, want %v", err, context.Canceled)
+	}
+
+	files, _ := ioutil.ReadDir(filepath.Join(dir, "new"))
+	if len(files) != 0 {
+		t.Errorf("MaildirTransport.Send() delivered %d files, want none", len(files))
+	}
+}
@@ -0,0 +1,37 @@
+package mailyak
+
+import (
+	"strings"
+)
+
+// Version is the version of mailyak, included in the default X-Mailer header.
+const Version = "3.0.0"
+
+// DefaultMailer is the value of the X-Mailer header written by default,
+// identifying the sending library in mail logs.
+const DefaultMailer = "mailyak/" + Version
+
+// XMailer sets the value of the X-Mailer header, identifying the software that
+// sent the email. Defaults to DefaultMailer.
+//
+// Passing an empty string removes the X-Mailer header. An X-Mailer header added
+// with AddHeader or SetHeader is written instead of the default.
+func (m *MailYak) XMailer(value string) {
+	m.xMailer = m.trimRegex.ReplaceAllString(value, "")
+}
+
+// mailerHeader returns the X-Mailer header to write, or an empty string if
+// none should be written.
+func (m *MailYak) mailerHeader() string {
+	if m.xMailer == "" {
+		return ""
+	}
+
+	for name := range m.headers {
+		if strings.EqualFold(name, "X-Mailer") {
+			return ""
+		}
+	}
+
+	return "X-Mailer: " + m.headerValue(m.xMailer) + "\r\n"
+}
@@ -0,0 +1,68 @@
+package mailyak
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestMailYakXMailer ensures the X-Mailer header is written by default, and
+// can be overridden or removed.
+func TestMailYakXMailer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		setup func(m *MailYak)
+		// Want
+		want []string
+	}{
+		{
+			"Default",
+			func(m *MailYak) {},
+			[]string{"X-Mailer: mailyak/" + Version},
+		},
+		{
+			"Override",
+			func(m *MailYak) { m.XMailer("Acme Newsletter\r\nBcc: evil@example.com") },
+			[]string{"X-Mailer: Acme NewsletterBcc: evil@example.com"},
+		},
+		{
+			"Suppressed",
+			func(m *MailYak) { m.XMailer("") },
+			nil,
+		},
+		{
+			"Custom header",
+			func(m *MailYak) { m.AddHeader("x-mailer", "Custom") },
+			[]string{"x-mailer: Custom"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			tt.setup(m)
+
+			var buf bytes.Buffer
+			if err := m.writeHeaders(&buf); err != nil {
+				t.Fatalf("MailYak.writeHeaders() error = %v", err)
+			}
+
+			var got []string
+			for _, line := range strings.Split(buf.String(), "\r\n") {
+				if strings.HasPrefix(strings.ToLower(line), "x-mailer:") {
+					got = append(got, line)
+				}
+			}
+
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("MailYak.writeHeaders() X-Mailer = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+// Package mailgun provides a mailyak.Transport sending emails through the
+// Mailgun messages API.
+package mailgun
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+
+	"github.com/domodwyer/mailyak/v3/internal/apimail"
+)
+
+const (
+	// EUEndpoint is the base URL of the Mailgun API for domains in the EU
+	// region, for use with Transport.Endpoint.
+	EUEndpoint = "https://api.eu.mailgun.net"
+
+	// TagHeader is the custom header holding the comma separated Mailgun tags
+	// of an email sent by Transport:
+	//
+	//	mail.AddHeader(mailgun.TagHeader, "welcome, onboarding")
+	TagHeader = "X-Mailgun-Tag"
+
+	// VariablesHeader is the custom header holding a JSON object of Mailgun
+	// variables attached to an email sent by Transport, and included in its
+	// webhooks and events:
+	//
+	//	mail.AddHeader(mailgun.VariablesHeader, `{"user_id": 42}`)
+	VariablesHeader = "X-Mailgun-Variables"
+)
+
+// Transport is a mailyak.Transport sending emails through the Mailgun messages
+// API:
+//
+//	mail.Transport(mailgun.New("mg.itsallbroken.com", os.Getenv("MAILGUN_API_KEY")))
+//
+// By default the bodies, addresses, attachments and headers of the email are
+// converted into the fields of a Mailgun request, with the TagHeader and
+// VariablesHeader custom headers sent as the tags and variables of the email.
+// Signed or encrypted emails and calendar invitations cannot be sent this way
+// - enable MIME to send the raw MIME data of the email instead.
+//
+// Errors returned by Mailgun are returned as a *mailyak.APIError.
+type Transport struct {
+	domain   string
+	apiKey   string
+	endpoint string
+	client   *http.Client
+	mime     bool
+}
+
+// New returns a Transport sending emails from the Mailgun domain,
+// authenticating with apiKey.
+func New(domain, apiKey string) *Transport {
+	return &Transport{
+		domain:   domain,
+		apiKey:   apiKey,
+		endpoint: "https://api.mailgun.net",
+	}
+}
+
+// Endpoint sets the base URL of the Mailgun API, such as EUEndpoint for
+// domains in the EU region. Defaults to "https://api.mailgun.net" (the US
+// region).
+func (t *Transport) Endpoint(u string) {
+	t.endpoint = strings.TrimSuffix(u, "/")
+}
+
+// HTTPClient sets the HTTP client used to call Mailgun. Defaults to
+// http.DefaultClient.
+func (t *Transport) HTTPClient(c *http.Client) {
+	t.client = c
+}
+
+// MIME sends emails as raw MIME data to the Mailgun messages.mime endpoint when
+// enabled, supporting all MailYak features including signing and encryption.
+// Defaults to false.
+//
+// Mailgun reads the tags and variables of MIME emails from the TagHeader and
+// VariablesHeader headers itself, which must then contain a single tag.
+func (t *Transport) MIME(enabled bool) {
+	t.mime = enabled
+}
+
+// Send sends the email to the Mailgun API.
+//
+// When MIME is enabled the email is delivered to rcpts, otherwise the
+// recipients are taken from the To and Cc addresses of the email, with any
+// other recipients in rcpts sent as Bcc recipients.
+func (t *Transport) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	var (
+		body bytes.Buffer
+		path = "/v3/" + t.domain + "/messages"
+		err  error
+	)
+
+	form := multipart.NewWriter(&body)
+	if t.mime {
+		path += ".mime"
+		err = writeMIME(form, rcpts, msg)
+	} else {
+		err = writeFields(form, rcpts, msg)
+	}
+	if err != nil {
+		return err
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint+path, &body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth("api", t.apiKey)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	return apimail.Do(t.client, req, "Mailgun", nil)
+}
+
+// writeMIME writes the fields of a messages.mime request sending msg to
+// rcpts.
+func writeMIME(form *multipart.Writer, rcpts []string, msg io.WriterTo) error {
+	for _, rcpt := range rcpts {
+		if err := form.WriteField("to", rcpt); err != nil {
+			return err
+		}
+	}
+
+	part, err := form.CreateFormFile("message", "message.eml")
+	if err != nil {
+		return err
+	}
+	_, err = msg.WriteTo(part)
+	return err
+}
+
+// writeFields writes the fields of a messages request sending msg to rcpts.
+func writeFields(form *multipart.Writer, rcpts []string, msg io.WriterTo) error {
+	e, err := apimail.NewEmail(msg, rcpts, "Mailgun")
+	if err != nil {
+		return err
+	}
+
+	fields := [][2]string{
+		{"from", e.From.String()},
+		{"subject", e.Subject},
+	}
+	for _, list := range []struct {
+		name  string
+		addrs []*mail.Address
+	}{
+		{"to", e.To},
+		{"cc", e.Cc},
+		{"bcc", e.Bcc},
+	} {
+		for _, a := range list.addrs {
+			fields = append(fields, [2]string{list.name, a.String()})
+		}
+	}
+
+	if e.Plain != "" {
+		fields = append(fields, [2]string{"text", e.Plain})
+	}
+	if e.HTML != "" {
+		fields = append(fields, [2]string{"html", e.HTML})
+	}
+
+	for _, tag := range apimail.SplitHeaderList(e.PopHeader(TagHeader)) {
+		fields = append(fields, [2]string{"o:tag", tag})
+	}
+
+	if v := e.PopHeader(VariablesHeader); v != "" {
+		vars := map[string]json.RawMessage{}
+		if err := json.Unmarshal([]byte(v), &vars); err != nil {
+			return fmt.Errorf("mailyak: invalid %s header: %w", VariablesHeader, err)
+		}
+		keys := make([]string, 0, len(vars))
+		for k := range vars {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			fields = append(fields, [2]string{"v:" + k, variable(vars[k])})
+		}
+	}
+
+	if e.ReplyTo != nil {
+		fields = append(fields, [2]string{"h:Reply-To", e.ReplyTo.String()})
+	}
+	for _, k := range apimail.SortedKeys(e.Headers) {
+		fields = append(fields, [2]string{"h:" + k, e.Headers[k]})
+	}
+
+	for _, f := range fields {
+		if err := form.WriteField(f[0], f[1]); err != nil {
+			return err
+		}
+	}
+
+	// Mailgun uses the filename of inline attachments as their Content-ID
+	for _, a := range e.Attachments {
+		field, filename := "attachment", a.Filename
+		if a.Inline {
+			field, filename = "inline", a.ContentID
+		}
+
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, field, escapeQuotes(filename)))
+		h.Set("Content-Type", a.ContentType)
+
+		part, err := form.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(a.Data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// variable returns the value of a Mailgun variable - strings are sent
+// unquoted, and other JSON values as-is.
+func variable(v json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(v, &s); err == nil {
+		return s
+	}
+	return string(v)
+}
+
+// escapeQuotes escapes backslashes and double quotes in s, for use in a quoted
+// header parameter.
+func escapeQuotes(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
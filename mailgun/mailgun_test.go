@@ -0,0 +1,174 @@
+package mailgun
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/domodwyer/mailyak/v3"
+)
+
+// testSigner is a mailyak.PGPSigner producing a fixed signature.
+type testSigner struct{}
+
+func (testSigner) ArmoredDetachSign(w io.Writer, message io.Reader) error {
+	if _, err := io.Copy(io.Discard, message); err != nil {
+		return err
+	}
+	_, err := fmt.Fprint(w, "-----BEGIN PGP SIGNATURE-----\n\nsignature\n-----END PGP SIGNATURE-----\n")
+	return err
+}
+
+func (testSigner) Micalg() string { return "pgp-sha256" }
+
+// request is a request received by a test Mailgun server.
+type request struct {
+	path   string
+	user   string
+	pass   string
+	fields map[string][]string
+	files  map[string][]string
+}
+
+// newServer returns a test server recording the last request in req.
+func newServer(t *testing.T, req *request) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req.path = r.URL.Path
+		req.user, req.pass, _ = r.BasicAuth()
+
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parsing request: %v", err)
+		}
+		req.fields = r.MultipartForm.Value
+		req.files = map[string][]string{}
+		for name, files := range r.MultipartForm.File {
+			for _, fh := range files {
+				req.files[name] = append(req.files[name], fh.Filename+"|"+fh.Header.Get("Content-Type")+"|"+readFile(t, fh))
+			}
+		}
+
+		w.Write([]byte(`{"id": "<id@mg.example.org>", "message": "Queued. Thank you."}`))
+	}))
+}
+
+// readFile returns the content of the uploaded file fh.
+func readFile(t *testing.T, fh *multipart.FileHeader) string {
+	f, err := fh.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// TestTransport ensures emails are converted into Mailgun messages
+// requests.
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	var req request
+	srv := newServer(t, &req)
+	defer srv.Close()
+
+	transport := New("mg.example.org", "key")
+	transport.Endpoint(srv.URL)
+
+	mail := mailyak.New("", nil)
+	mail.Transport(transport)
+	mail.From("from@example.org")
+	mail.FromName("Dom")
+	mail.To("to@example.org", "Zoë <zoe@example.org>")
+	mail.Bcc("bcc@example.org")
+	mail.ReplyTo("reply@example.org")
+	mail.Subject("Hello")
+	mail.AddHeader("X-Campaign", "launch")
+	mail.AddHeader(TagHeader, "welcome, onboarding")
+	mail.AddHeader(VariablesHeader, `{"user_id": 42, "plan": "free"}`)
+	mail.Plain().Set("plain")
+	mail.HTML().Set("<p>html</p>")
+	mail.Attach("report.csv", strings.NewReader("a,b"))
+	mail.AttachInlineWithMimeType("logo.png", strings.NewReader("png"), "image/png")
+
+	if _, _, err := mail.Send("localhost"); err != nil {
+		t.Fatalf("Transport.Send() error = %v", err)
+	}
+
+	if req.path != "/v3/mg.example.org/messages" || req.user != "api" || req.pass != "key" {
+		t.Errorf("Transport.Send() path = %q, auth = %q:%q", req.path, req.user, req.pass)
+	}
+
+	wantFields := map[string][]string{
+		"from":         {`"Dom" <from@example.org>`},
+		"to":           {"<to@example.org>", "=?utf-8?q?Zo=C3=AB?= <zoe@example.org>"},
+		"bcc":          {"<bcc@example.org>"},
+		"subject":      {"Hello"},
+		"text":         {"plain"},
+		"html":         {"<p>html</p>"},
+		"o:tag":        {"welcome", "onboarding"},
+		"v:plan":       {"free"},
+		"v:user_id":    {"42"},
+		"h:Reply-To":   {"<reply@example.org>"},
+		"h:Message-Id": {mail.GetMessageID()},
+		"h:X-Campaign": {"launch"},
+		"h:X-Mailer":   {mailyak.DefaultMailer},
+	}
+	if !reflect.DeepEqual(req.fields, wantFields) {
+		t.Errorf("Transport.Send() fields =\n%v\nwant\n%v", req.fields, wantFields)
+	}
+
+	wantFiles := map[string][]string{
+		"attachment": {"report.csv|text/plain; charset=utf-8|a,b"},
+		"inline":     {"logo.png|image/png|png"},
+	}
+	if !reflect.DeepEqual(req.files, wantFiles) {
+		t.Errorf("Transport.Send() files = %v, want %v", req.files, wantFiles)
+	}
+}
+
+// TestTransport_mime ensures the raw MIME data is sent to the
+// messages.mime endpoint when enabled.
+func TestTransport_mime(t *testing.T) {
+	t.Parallel()
+
+	var req request
+	srv := newServer(t, &req)
+	defer srv.Close()
+
+	transport := New("mg.example.org", "key")
+	transport.Endpoint(srv.URL + "/")
+	transport.MIME(true)
+
+	mail := mailyak.New("", nil)
+	mail.Transport(transport)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+	mail.Bcc("bcc@example.org")
+	mail.Subject("Signed")
+	if err := mail.PGP(mailyak.PGPOptions{Signer: testSigner{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := mail.Send("localhost"); err != nil {
+		t.Fatalf("Transport.Send() error = %v", err)
+	}
+
+	if req.path != "/v3/mg.example.org/messages.mime" {
+		t.Errorf("Transport.Send() path = %q", req.path)
+	}
+	if want := []string{"to@example.org", "bcc@example.org"}; !reflect.DeepEqual(req.fields["to"], want) {
+		t.Errorf("Transport.Send() to = %v, want %v", req.fields["to"], want)
+	}
+	if len(req.files["message"]) != 1 || !strings.Contains(req.files["message"][0], "Content-Type: multipart/signed") {
+		t.Errorf("Transport.Send() message = %v", req.files["message"])
+	}
+}
@@ -1,10 +1,19 @@
 package mailyak
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/mail"
 	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"os"
 	"regexp"
 	"strings"
 	"time"
@@ -21,19 +30,101 @@ type MailYak struct {
 	toAddrs        []string
 	ccAddrs        []string
 	bccAddrs       []string
+	toGroups       []addressGroup
 	subject        string
 	fromAddr       string
 	fromName       string
 	replyTo        string
-	headers        map[string]string // arbitrary headers
+	headers        map[string]string   // arbitrary headers, with their first value
+	extraHeaders   map[string][]string // values of headers after the first
+	headerNames    []string            // header names in the order added
+	headerOrder    []string            // header names written first
+	xMailer        string
 	attachments    []attachment
 	auth           smtp.Auth
 	trimRegex      *regexp.Regexp
 	host           string
 	writeBccHeader bool
 	date           string
+	tlsConfig      *tls.Config
+	implicitTLS    bool
+	requireTLS     bool
+	dialer         ContextDialer
+	proxy          *url.URL
+	timeouts       Timeouts
+	dkim           *dkimSigner
+	smime          *smimeWrapper
+	pgp            *pgpWrapper
+	transport      Transport
+
+	messageID       string
+	messageIDDomain string
+
+	// messageIDBuilt is true once messageID has been included in a built
+	// message and not read since, causing a new Message-ID to be generated
+	// for the next build, unless messageIDFixed is true as it was set
+	// explicitly
+	messageIDBuilt bool
+	messageIDFixed bool
+
+	inReplyTo       string
+	references      []string
+
+	listUnsubscribe     []string
+	listUnsubscribePost bool
+	priority            PriorityLevel
+	readReceipt         string
+	plainFromHTML       bool
+	calendar            []byte
+	calendarMethod      string
+	deterministic       bool
+	seed                int64
+	sender              string
+	envelopeFrom        string
+	dsn                 *DSNOptions
+	retry               *RetryPolicy
+	fallbackHosts       []string
+	sentHost            string
+	partialDelivery     bool
+	lmtp                bool
+	tlsaResolver        TLSAResolver
+	mtaSTS              *MTASTSClient
+	helloName           string
+	mxResolver          MXResolver
+	strictAddresses     bool
+	dedupRecipients     bool
+	maxMessageSize      int64
+	attachmentLimits    AttachmentLimits
+	dedupAttachments    bool
+	sendResult          *SendResult
+	rateLimiter         *RateLimiter
+	hooks               hooks
+	debugWriter         io.Writer
+	tracer              Tracer
+	metrics             Metrics
+}
+
+// ContextDialer establishes connections to the SMTP server.
+//
+// ContextDialer is implemented by *net.Dialer, and is compatible with
+// golang.org/x/net/proxy.ContextDialer.
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
 }
 
+// ErrStartTLSUnsupported is returned when sending with RequireTLS enabled to a
+// server that does not support STARTTLS.
+var ErrStartTLSUnsupported = errors.New("mailyak: server does not support STARTTLS")
+
+// ErrSMTPUTF8Unsupported is returned when sending to or from an
+// internationalized address, such as 用户@例え.jp, via a server that does not
+// support the SMTPUTF8 extension (RFC 6531).
+//
+// Addresses with only an internationalized domain, such as dom@bücher.example,
+// are instead converted to their ASCII form (dom@xn--bcher-kva.example) in
+// both the envelope and headers, unless the email is signed or encrypted.
+var ErrSMTPUTF8Unsupported = errors.New("mailyak: server does not support SMTPUTF8, required for internationalized addresses")
+
 // add some expects for the various fields for testing
 func (my *MailYak) GetToAddrs() []string          { return my.toAddrs }
 func (my *MailYak) GetCCAddrs() []string          { return my.ccAddrs }
@@ -45,13 +136,26 @@ func (my *MailYak) GetReplyTo() string            { return my.replyTo }
 func (my *MailYak) GetHeaders() map[string]string { return my.headers }
 func (my *MailYak) GetAttachments() []attachment  { return my.attachments }
 
+// GetHeaderValues returns a copy of the custom headers with all of their
+// values, in the order they were added.
+//
+// GetHeaders returns only the first value of each header, such as when
+// AddHeader is called more than once with the same name.
+func (my *MailYak) GetHeaderValues() map[string][]string {
+	headers := make(map[string][]string, len(my.headers))
+	for k := range my.headers {
+		headers[k] = my.headerValues(k)
+	}
+	return headers
+}
+
 // NewBlank returns an instance of MailYak
 func NewBlank() *MailYak {
 	return &MailYak{
 		headers:        map[string]string{},
-		trimRegex:      regexp.MustCompile("\r?\n"),
+		trimRegex:      regexp.MustCompile("[\r\n]"),
 		writeBccHeader: false,
-		date:           time.Now().Format(time.RFC1123Z),
+		xMailer:        DefaultMailer,
 	}
 }
 
@@ -59,10 +163,84 @@ func (m *MailYak) Host(value string) {
 	m.host = value
 }
 
+// FallbackHosts sets the SMTP servers tried in order when the server set by
+// Host cannot be connected to, or fails during the greeting or TLS
+// negotiation, such as the backups of a primary relay:
+//
+//	mail := mailyak.New("smtp1.itsallbroken.com:587", auth)
+//	mail.FallbackHosts("smtp2.itsallbroken.com:587", "smtp3.itsallbroken.com:587")
+//
+// Each host must include the port number, or be a Unix domain socket path as
+// accepted by New, and is authenticated with the same credentials. After a
+// successful Send, GetSentHost returns the host that accepted the email.
+func (m *MailYak) FallbackHosts(hosts ...string) {
+	m.fallbackHosts = cloneStrings(hosts)
+}
+
+// GetSentHost returns the SMTP host that accepted the email during the last
+// successful call to Send or SendWithContext, or an empty string if the email
+// has not been sent over SMTP.
+func (m *MailYak) GetSentHost() string {
+	return m.sentHost
+}
+
 func (m *MailYak) Auth(value smtp.Auth) {
 	m.auth = value
 }
 
+// TLSConfig sets the TLS configuration used when upgrading the SMTP connection
+// with STARTTLS, or when connecting to a server with implicit TLS (see
+// NewWithTLS).
+//
+// If config.ServerName is empty, it is set to the hostname of the SMTP server.
+// config is not modified - a copy is made when sending.
+func (m *MailYak) TLSConfig(config *tls.Config) {
+	m.tlsConfig = config
+}
+
+// Dialer sets the ContextDialer used to connect to the SMTP server, allowing
+// control over the source address, keepalives, name resolution, etc.
+//
+// If unset, a zero value net.Dialer is used. If a proxy is set with Proxy, d
+// is used to connect to the proxy.
+func (m *MailYak) Dialer(d ContextDialer) {
+	m.dialer = d
+}
+
+// HelloName sets the name the client identifies itself with in the EHLO (or
+// HELO) command, which should be the fully qualified domain name of the host
+// sending the email.
+//
+// If unset, the hostname of the system is used, or "localhost" if it cannot
+// be determined. A localHostName passed to Send takes precedence.
+func (m *MailYak) HelloName(name string) {
+	m.helloName = stripCRLF(strings.TrimSpace(name))
+}
+
+// helloHost returns the name to identify as in the EHLO command - localHostName
+// if not empty, otherwise the HelloName or system hostname.
+func (m *MailYak) helloHost(localHostName string) string {
+	if localHostName != "" {
+		return localHostName
+	}
+	if m.helloName != "" {
+		return m.helloName
+	}
+	if name, err := os.Hostname(); err == nil && name != "" {
+		return name
+	}
+	return "localhost"
+}
+
+// RequireTLS causes Send to fail with ErrStartTLSUnsupported when the SMTP
+// server does not advertise STARTTLS, instead of continuing over an unencrypted
+// connection. Defaults to false.
+//
+// A failed STARTTLS handshake always causes Send to return an error.
+func (m *MailYak) RequireTLS(require bool) {
+	m.requireTLS = require
+}
+
 // New returns an instance of MailYak using host as the SMTP server, and
 // authenticating with auth where required.
 //
@@ -75,86 +253,1112 @@ func (m *MailYak) Auth(value smtp.Auth) {
 // 			"stmp.itsallbroken.com",
 //		))
 //
+// host can instead be the path of a Unix domain socket, either absolute or
+// prefixed with "unix:", to connect to a local MTA or LMTP server without TCP:
+//
+//	mail := mailyak.New("unix:/var/run/dovecot/lmtp", nil)
+//
 func New(host string, auth smtp.Auth) *MailYak {
 	return &MailYak{
 		headers:        map[string]string{},
 		host:           host,
 		auth:           auth,
-		trimRegex:      regexp.MustCompile("\r?\n"),
+		trimRegex:      regexp.MustCompile("[\r\n]"),
 		writeBccHeader: false,
-		date:           time.Now().Format(time.RFC1123Z),
+		xMailer:        DefaultMailer,
+	}
+}
+
+// NewWithTLS returns an instance of MailYak using host as the SMTP server, and
+// authenticating with auth where required, connecting with implicit TLS (also
+// known as SMTPS) rather than upgrading the connection with STARTTLS.
+//
+// host must include the port number, typically 465:
+//
+// 		mail := mailyak.NewWithTLS("smtp.itsallbroken.com:465", smtp.PlainAuth(
+// 			"",
+// 			"username",
+// 			"password",
+// 			"stmp.itsallbroken.com",
+//		), nil)
+//
+// If config is nil, a default configuration verifying the server hostname is
+// used.
+func NewWithTLS(host string, auth smtp.Auth, config *tls.Config) *MailYak {
+	m := New(host, auth)
+	m.tlsConfig = config
+	m.implicitTLS = true
+	return m
+}
+
+// Reset clears the content of the email so m can be reused to send another,
+// without leaking recipients or attachments from previous sends.
+//
+// The recipients, subject, body, calendar invitation, attachments, custom
+// headers, Date, Message-ID, threading, List-Unsubscribe, priority, read
+// receipt and DSN settings are cleared.
+// The SMTP server, authentication, TLS, dialer, timeout, retry and rate limit
+// configuration, the From, FromName, EnvelopeFrom, Sender and Reply-To
+// addresses and any signing or encryption configuration are retained.
+func (m *MailYak) Reset() {
+	m.html.Reset()
+	m.plain.Reset()
+
+	m.ClearRecipients()
+	m.ClearAttachments()
+	m.ClearHeaders()
+
+	m.subject = ""
+	m.date = ""
+	m.messageID = ""
+	m.inReplyTo = ""
+	m.references = nil
+	m.listUnsubscribe = nil
+	m.listUnsubscribePost = false
+	m.priority = 0
+	m.readReceipt = ""
+	m.calendar = nil
+	m.calendarMethod = ""
+	m.dsn = nil
+}
+
+// Clone returns a deep copy of m, allowing a base email to be forked into
+// per-recipient variants without changes to one affecting the other:
+//
+//	for _, user := range users {
+//		mail := base.Clone()
+//		mail.To(user.Email)
+//		...
+//	}
+//
+// The bodies, recipients, headers and attachment metadata are copied, and the
+// clone is given a new Message-ID. Attachment content is not copied - as the
+// io.Reader of each attachment is read when the email is sent, attachments
+// shared between clones should be added to each clone individually.
+func (m *MailYak) Clone() *MailYak {
+	c := *m
+
+	c.html = BodyPart{encoding: m.html.encoding}
+	c.html.Write(m.html.Bytes())
+	c.plain = BodyPart{encoding: m.plain.encoding}
+	c.plain.Write(m.plain.Bytes())
+
+	c.toAddrs = cloneStrings(m.toAddrs)
+	c.ccAddrs = cloneStrings(m.ccAddrs)
+	c.bccAddrs = cloneStrings(m.bccAddrs)
+	c.toGroups = cloneGroups(m.toGroups)
+	c.references = cloneStrings(m.references)
+	c.listUnsubscribe = cloneStrings(m.listUnsubscribe)
+	c.fallbackHosts = cloneStrings(m.fallbackHosts)
+	c.hooks = m.hooks.clone()
+	c.sentHost = ""
+	c.sendResult = nil
+
+	c.headers = make(map[string]string, len(m.headers))
+	for k, v := range m.headers {
+		c.headers[k] = v
+	}
+	c.extraHeaders = make(map[string][]string, len(m.extraHeaders))
+	for k, v := range m.extraHeaders {
+		c.extraHeaders[k] = cloneStrings(v)
+	}
+	c.headerNames = cloneStrings(m.headerNames)
+	c.headerOrder = cloneStrings(m.headerOrder)
+
+	c.attachments = make([]attachment, len(m.attachments))
+	for i, a := range m.attachments {
+		if a.header != nil {
+			header := make(textproto.MIMEHeader, len(a.header))
+			for k, v := range a.header {
+				header[k] = cloneStrings(v)
+			}
+			a.header = header
+		}
+		c.attachments[i] = a
 	}
+
+	c.messageID = ""
+
+	return &c
 }
 
-// Send attempts to send the built email via the configured SMTP server.
+// cloneStrings returns a copy of s, preserving nil.
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
+
+// Send attempts to send the built email via the configured SMTP server, or the
+// Transport if set.
+//
+// Attachments are read when Send() is called, and streamed directly to the
+// server without being held in memory (unless the email is signed or
+// encrypted). Any connection/authentication errors will be returned by Send().
+//
+// The client identifies itself to the server as localHostName, or if empty,
+// the name set by HelloName:
 //
-// Attachments are read when Send() is called, and any connection/authentication
-// errors will be returned by Send().
+//	mail.Send("")            // uses HelloName, or the system hostname
+//	mail.Send("example.org") // identifies as "example.org"
 func (m *MailYak) Send(localHostName string) (int, string, error) {
+	return m.SendWithContext(context.Background(), localHostName)
+}
 
-	buf, err := m.buildMime()
+// SendWithContext attempts to send the built email via the configured SMTP
+// server, aborting the SMTP conversation if ctx is cancelled or its deadline is
+// exceeded.
+//
+// The deadline of ctx (if any) bounds the entire exchange, from dialing the
+// server through to writing the message data. If ctx is done before the email
+// is sent, ctx.Err() is returned. Cancelling ctx while the message data is
+// being written stops reading the attachments and closes the connection,
+// causing the server to discard the partial message.
+//
+// localHostName is used as for Send.
+func (m *MailYak) SendWithContext(ctx context.Context, localHostName string) (int, string, error) {
+	return m.send(ctx, localHostName, m.transport)
+}
+
+// send sends the email using transport, or to the configured SMTP server if
+// transport is nil, recording the send and running the OnAfterSend hooks.
+func (m *MailYak) send(ctx context.Context, localHostName string, transport Transport) (int, string, error) {
+	ctx, span := m.startSpan(ctx, SpanSend)
+	span.SetAttribute(AttrRecipients, len(m.recipients()))
+
+	code, resp, err := m.sendWithContext(ctx, localHostName, transport)
+	span.End(err)
+
+	if err != nil {
+		m.recorder().IncFailed(err)
+	} else {
+		m.recorder().IncSent()
+	}
+
+	m.runAfterSend(code, resp, err)
+	return code, resp, err
+}
+
+// sendWithContext sends the email, returning the result of sending to be passed
+// to the OnAfterSend hooks.
+func (m *MailYak) sendWithContext(ctx context.Context, localHostName string, transport Transport) (int, string, error) {
+	m.sentHost = ""
+	m.sendResult = nil
+
+	if err := m.runBeforeBuild(); err != nil {
+		return -1, "", err
+	}
+
+	if err := m.Validate(); err != nil {
+		return -1, "", err
+	}
+
+	msg, err := m.buildMessage(ctx)
 	if err != nil {
 		return -1, "", err
 	}
 
-	// dial the host to get an smtp conn
-	smtpClient, err := smtp.Dial(m.host)
+	envelopeFrom, rcpts := m.envelopeSender(), m.recipients()
+	if err := m.runBeforeSend(envelopeFrom, rcpts); err != nil {
+		return -1, "", err
+	}
+
+	if m.rateLimiter != nil {
+		if err := m.rateLimiter.Wait(ctx); err != nil {
+			return -1, "", err
+		}
+	}
+
+	start := time.Now()
+	defer func() { m.recorder().ObserveSendDuration(time.Since(start)) }()
+
+	if transport != nil {
+		err := m.withRetry(ctx, func() error {
+			return transport.Send(ctx, envelopeFrom, rcpts, msg)
+		})
+		m.recordResult(msg, start)
+		if err != nil {
+			return -1, "", err
+		}
+		return 0, "", nil
+	}
+
+	t := &smtpTransport{m: m, localHostName: localHostName}
+	err = m.withRetry(ctx, func() error {
+		return t.Send(ctx, envelopeFrom, rcpts, msg)
+	})
+	m.recordResult(msg, start)
 	if err != nil {
 		return -1, "", err
 	}
+	m.sentHost = t.host
+
+	return t.code, t.msg, nil
+}
+
+// buildMessage returns the MIME message for m, recording a build span.
+//
+// The MIME data is streamed directly to the server where possible, otherwise
+// it is built before connecting.
+func (m *MailYak) buildMessage(ctx context.Context) (msg *mimeMessage, err error) {
+	_, span := m.startSpan(ctx, SpanBuild)
+	defer func() { span.End(err) }()
+
+	start := time.Now()
+
+	msg, err = m.mimeMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	// attachments are read as the message is written, so they are read in
+	// advance if the message may need to be resent
+	if m.retry != nil && msg.buf == nil && len(m.attachments) > 0 {
+		if err := msg.bufferAttachments(); err != nil {
+			return nil, err
+		}
+	}
+
+	if msg.buf != nil {
+		span.SetAttribute(AttrMessageSize, len(msg.buf))
+	}
+
+	m.recorder().ObserveBuildDuration(time.Since(start))
+
+	return msg, nil
+}
+
+// smtpTransport is the default Transport, delivering emails to the SMTP server
+// configured on m.
+type smtpTransport struct {
+	m             *MailYak
+	localHostName string
+
+	// the server response to the message data, and the host that accepted
+	// it
+	code int
+	msg  string
+	host string
+}
+
+// Send delivers msg to the SMTP server, recording the server response to the
+// message data and the host it was sent to.
+func (t *smtpTransport) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	m := t.m
+
+	ctx, cancel, ctxErr := m.sendContext(ctx)
+	defer cancel()
+
+	sc, err := m.connect(ctx, t.localHostName, mtaSTSCheck{client: m.mtaSTS, rcpts: rcpts})
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctxErr()
+		}
+		return err
+	}
+	conn, smtpClient, host := sc.conn, sc.client, sc.host
+
+	defer closeOnDone(ctx, conn)()
 
 	// make sure to quit client
 	defer smtpClient.Close()
 
+	t.code, t.msg, err = m.sendMail(ctx, smtpClient, conn, host, envelopeFrom, rcpts, msg)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctxErr()
+		}
+		return err
+	}
+
+	t.host = host
+	return nil
+}
+
+// sendContext returns ctx bounded by the overall timeout, and a function
+// returning the reason the returned context is done, distinguishing the
+// overall timeout from the caller's context.
+func (m *MailYak) sendContext(ctx context.Context) (context.Context, context.CancelFunc, func() error) {
+	parent := ctx
+
+	cancel := func() {}
+	if m.timeouts.Overall > 0 {
+		ctx, cancel = context.WithTimeout(ctx, m.timeouts.Overall)
+	}
+
+	ctxErr := func() error {
+		if ctx.Err() == context.DeadlineExceeded && parent.Err() == nil {
+			return &TimeoutError{Stage: "overall", Err: ctx.Err()}
+		}
+		return ctx.Err()
+	}
+
+	return ctx, cancel, ctxErr
+}
+
+// smtpConn is an established SMTP session with host, and the TLS policy it was
+// established with.
+type smtpConn struct {
+	conn   net.Conn
+	client *smtp.Client
+	host   string
+	policy tlsPolicy
+}
+
+// connect establishes an SMTP session with the configured host, or failing
+// that each of the fallback hosts in turn, satisfying the MTA-STS policies of
+// the recipients of sts.
+//
+// Hosts that cannot be connected to, or fail during the greeting or TLS
+// negotiation, are skipped. Authentication failures are returned immediately,
+// as the fallback hosts of a relay typically share credentials.
+func (m *MailYak) connect(ctx context.Context, localHostName string, sts mtaSTSCheck) (*smtpConn, error) {
+	hosts := append([]string{m.host}, m.fallbackHosts...)
+	localHostName = m.helloHost(localHostName)
+
+	var err error
+	for _, host := range hosts {
+		var (
+			conn       net.Conn
+			serverName string
+			policy     tlsPolicy
+			smtpClient *smtp.Client
+		)
+
+		_, span := m.startSpan(ctx, SpanDial)
+		span.SetAttribute(AttrServerAddress, host)
+		conn, serverName, policy, err = m.dial(ctx, host, sts)
+		span.End(err)
+
+		if err == nil {
+			stop := closeOnDone(ctx, conn)
+			smtpClient, err = m.greet(ctx, conn, serverName, localHostName, policy)
+			if err == nil {
+				err = m.authenticate(ctx, smtpClient, conn)
+				stop()
+				if err != nil {
+					return nil, err
+				}
+				return &smtpConn{conn: conn, client: smtpClient, host: host, policy: policy}, nil
+			}
+			stop()
+		}
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
+
+	if len(hosts) > 1 {
+		err = fmt.Errorf("mailyak: all SMTP hosts failed, last error from %s: %w", hosts[len(hosts)-1], err)
+	}
+	return nil, err
+}
+
+// dial connects to the SMTP server at host, returning the connection (wrapped
+// in TLS when using SMTPS), the server hostname and the TLS policy for the
+// connection.
+func (m *MailYak) dial(ctx context.Context, host string, sts mtaSTSCheck) (net.Conn, string, tlsPolicy, error) {
+	network, addr, serverName, err := splitHost(host)
+	if err != nil {
+		return nil, "", tlsPolicy{}, err
+	}
+
+	policy, err := m.tlsPolicy(ctx, network, addr, serverName, sts)
+	if err != nil {
+		return nil, "", tlsPolicy{}, err
+	}
+
+	// dial the host to get a connection
+	var dialer ContextDialer = &net.Dialer{}
+	if m.dialer != nil {
+		dialer = m.dialer
+	}
+	dialer = m.proxyDialer(dialer)
+
+	dialCtx := ctx
+	if m.timeouts.Dial > 0 {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, m.timeouts.Dial)
+		defer cancel()
+	}
+
+	conn, err := dialer.DialContext(dialCtx, network, addr)
+	if err != nil {
+		if ctx.Err() == nil && dialCtx.Err() == context.DeadlineExceeded {
+			err = &TimeoutError{Stage: "dial", Err: err}
+		}
+		return nil, "", tlsPolicy{}, fmt.Errorf("%w: %w", ErrDial, err)
+	}
+
+	// wrap the connection in TLS when using SMTPS
+	if m.implicitTLS {
+		conn = tls.Client(conn, policy.config)
+	}
+
+	return newDebugConn(conn, m.debugWriter, m.implicitTLS), serverName, policy, nil
+}
+
+// splitHost returns the network and address to dial for host, and the
+// hostname of the server.
+//
+// host is the path of a Unix domain socket if absolute or prefixed with
+// "unix:", with the server assumed to be "localhost", or a host and port to
+// connect to over TCP otherwise.
+func splitHost(host string) (network, addr, serverName string, err error) {
+	if path, ok := strings.CutPrefix(host, "unix:"); ok || strings.HasPrefix(host, "/") {
+		if !ok {
+			path = host
+		}
+		return "unix", path, "localhost", nil
+	}
+
+	serverName, _, err = net.SplitHostPort(host)
+	if err != nil {
+		return "", "", "", err
+	}
+	return "tcp", host, serverName, nil
+}
+
+// closeOnDone closes conn when ctx is done, unblocking any in-flight reads or
+// writes, until the returned function is called.
+func closeOnDone(ctx context.Context, conn net.Conn) func() {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// greet starts an SMTP session over conn, upgrading the connection with
+// STARTTLS where available, as required by policy.
+//
+// conn is closed if an error is returned.
+func (m *MailYak) greet(ctx context.Context, conn net.Conn, serverName, localHostName string, policy tlsPolicy) (*smtp.Client, error) {
+	if err := setDeadline(conn, m.timeouts.Hello); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	var clientConn net.Conn = conn
+	if m.lmtp {
+		clientConn = &lmtpConn{Conn: conn}
+	}
+
+	smtpClient, err := smtp.NewClient(clientConn, serverName)
+	if err != nil {
+		conn.Close()
+		return nil, stageError("hello", err)
+	}
+
+	fail := func(err error) (*smtp.Client, error) {
+		smtpClient.Close()
+		return nil, err
+	}
+
 	// say hello to the smtp client
 	if err = smtpClient.Hello(localHostName); err != nil {
-		return -1, "", err
+		return fail(stageError("hello", err))
+	}
+
+	if m.lmtp && !m.implicitTLS && policy.required {
+		return fail(ErrStartTLSUnsupported)
 	}
 
 	// if TLS is available use it
-	if ok, _ := smtpClient.Extension("STARTTLS"); ok {
-		config := &tls.Config{ServerName: localHostName}
-		if err = smtpClient.StartTLS(config); err != nil {
-			return -1, "", err
+	if !m.implicitTLS && !m.lmtp {
+		ok, _ := smtpClient.Extension("STARTTLS")
+		if !ok && policy.required {
+			return fail(ErrStartTLSUnsupported)
+		}
+
+		if ok {
+			_, span := m.startSpan(ctx, SpanStartTLS)
+			if dc, debug := conn.(*debugConn); debug {
+				var upgraded *smtp.Client
+				if upgraded, err = dc.startTLS(smtpClient, policy.config, serverName, localHostName); err == nil {
+					smtpClient = upgraded
+				}
+			} else {
+				err = smtpClient.StartTLS(policy.config)
+			}
+			span.End(err)
+
+			if err != nil {
+				return fail(stageError("hello", err))
+			}
 		}
 	}
 
+	return smtpClient, nil
+}
+
+// authenticate authenticates the SMTP session if credentials are configured
+// and the server supports authentication.
+//
+// The session is closed if an error is returned.
+func (m *MailYak) authenticate(ctx context.Context, smtpClient *smtp.Client, conn net.Conn) (err error) {
 	// if we have auth
 	if hasAuth, _ := smtpClient.Extension("AUTH"); hasAuth && m.auth != nil {
-		smtpClient.Auth(m.auth)
+		_, span := m.startSpan(ctx, SpanAuth)
+		defer func() { span.End(err) }()
+
+		if err := setDeadline(conn, m.timeouts.Auth); err != nil {
+			smtpClient.Close()
+			return err
+		}
+
+		// smtp.Client cannot recognise an encrypted connection it does not
+		// use directly
+		auth := m.auth
+		if dc, ok := conn.(*debugConn); (ok && dc.tls) || (m.lmtp && m.implicitTLS) {
+			auth = tlsAuth{auth: auth}
+		}
+
+		if err := smtpClient.Auth(auth); err != nil {
+			smtpClient.Close()
+			return fmt.Errorf("%w: %w", ErrAuth, stageError("auth", err))
+		}
 	}
 
-	// start the mailing
-	if err = smtpClient.Mail(m.fromAddr); err != nil {
+	return nil
+}
+
+// sendMail delivers the MIME data written by msg to rcpts over an established
+// SMTP session with host, applying the data timeout as a deadline on conn.
+func (m *MailYak) sendMail(ctx context.Context, smtpClient *smtp.Client, conn net.Conn, host, envelopeFrom string, rcpts []string, msg io.WriterTo) (code int, resp string, err error) {
+	_, span := m.startSpan(ctx, SpanData)
+	span.SetAttribute(AttrRecipients, len(rcpts))
+	defer func() { span.End(err) }()
+
+	if err := setDeadline(conn, m.timeouts.Data); err != nil {
 		return -1, "", err
 	}
 
-	// set the to addresses
-	for _, addr := range m.toAddrs {
-		if err = smtpClient.Rcpt(addr); err != nil {
-			return -1, "", err
-		}
+	var size int64
+	written := func(n int64) {
+		size = n
+		span.SetAttribute(AttrMessageSize, n)
+		m.recorder().AddBytesWritten(n)
 	}
 
-	// grab the underlying data writer
-	w, err := smtpClient.Data()
+	code, resp, err = sendData(ctx, smtpClient, envelopeFrom, rcpts, msg, written, m.lmtp)
+	if mm, ok := msg.(*mimeMessage); ok && mm.result != nil {
+		mm.result.recordSession(smtpClient, conn, host, size, code, resp, err)
+	}
 	if err != nil {
-		return -1, "", err
+		return -1, "", stageError("data", err)
 	}
 
-	// write the email string
-	_, err = w.Write(buf.Bytes())
+	span.SetAttribute(AttrResponseCode, code)
+	span.SetAttribute(AttrResponseMessage, resp)
+
+	return code, resp, nil
+}
+
+// sendData sets the envelope sender and recipients, and writes the MIME data
+// using msg.
+//
+// If lmtp is true, the response to the message data for each accepted recipient
+// is read rather than a single response.
+//
+// If msg fails, or ctx is done while writing the message data, the DATA command
+// is not terminated and the connection must be closed, causing the server to
+// discard the partial message.
+func sendData(ctx context.Context, smtpClient *smtp.Client, envelopeFrom string, rcpts []string, msg io.WriterTo, written func(n int64), lmtp bool) (int, string, error) {
+	msg, result, partial, err := startMail(smtpClient, envelopeFrom, rcpts, msg)
 	if err != nil {
 		return -1, "", err
 	}
 
-	err = w.Close()
-	if err != nil {
+	// report the size of the message data once written
+	counted := &countingMessage{msg: msg}
+	defer func() { written(counted.n) }()
+
+	// stop writing the message data promptly once ctx is done, rather than
+	// reading and encoding the remaining attachments
+	msg = &contextMessage{ctx: ctx, msg: counted}
+
+	// send the message in chunks without dot-stuffing if supported - LMTP
+	// responds to the last chunk for each recipient, which is not supported
+	if ok, _ := smtpClient.Extension("CHUNKING"); ok && !lmtp {
+		code, resp, err := sendChunked(smtpClient, msg)
+		return code, resp, dataError(err)
+	}
+
+	// issue the DATA command directly rather than using smtpClient.Data(), as
+	// its writer discards the server response to the message content
+	if err := smtpClient.Text.PrintfLine("DATA"); err != nil {
 		return -1, "", err
 	}
 
+	if _, _, err := smtpClient.Text.ReadResponse(354); err != nil {
+		return -1, "", dataError(err)
+	}
+
+	// write the email, streaming any attachments
+	w := smtpClient.Text.DotWriter()
+	if _, err := msg.WriteTo(w); err != nil {
+		return -1, "", err
+	}
+
+	if err := w.Close(); err != nil {
+		return -1, "", err
+	}
+
+	if lmtp {
+		return lmtpResponses(smtpClient, result, partial)
+	}
+
 	// return the response from the smtpClient
-	return smtpClient.Text.ReadResponse(0)
+	code, resp, err := smtpClient.Text.ReadResponse(250)
+	return code, resp, dataError(err)
+}
+
+// startMail sets the envelope sender and recipients of msg, returning msg
+// limited to the server size limit, the result recording the response to each
+// recipient and whether the send continues with the accepted recipients.
+func startMail(smtpClient *smtp.Client, envelopeFrom string, rcpts []string, msg io.WriterTo) (io.WriterTo, *SendResult, bool, error) {
+	// internationalized addresses require SMTPUTF8, which mailCommand()
+	// requests when the server supports it - otherwise any internationalized
+	// domains are converted to their ASCII form
+	if requiresSMTPUTF8(envelopeFrom, rcpts) {
+		if ok, _ := smtpClient.Extension("SMTPUTF8"); !ok {
+			var converted bool
+			if envelopeFrom, rcpts, msg, converted = asciiEnvelope(envelopeFrom, rcpts, msg); !converted {
+				return nil, nil, false, ErrSMTPUTF8Unsupported
+			}
+		}
+	}
+
+	// request delivery status notifications if supported
+	var mailParams, rcptParams []string
+	if ok, _ := smtpClient.Extension("DSN"); ok {
+		mailParams, rcptParams = dsnParams(msg)
+	}
+
+	// record the response to each recipient, continuing with the accepted
+	// recipients if partial delivery is enabled
+	result, partial := envelopeResult(msg)
+
+	// check the message fits within the server size limit
+	msg, sizeParams, err := sizeLimit(smtpClient, msg)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	mailParams = append(sizeParams, mailParams...)
+
+	// set the envelope sender and recipient addresses
+	if err := sendEnvelope(smtpClient, envelopeFrom, mailParams, rcpts, rcptParams, result, partial); err != nil {
+		return nil, nil, false, err
+	}
+
+	return msg, result, partial, nil
+}
+
+// smtpCommand is an SMTP command line and the response code expected to
+// indicate success.
+type smtpCommand struct {
+	line       string
+	expectCode int
+
+	// rcpt is the recipient address of a RCPT command
+	rcpt string
+}
+
+// sendEnvelope issues the MAIL command for envelopeFrom and a RCPT command for
+// each of rcpts, with the respective params, recording the response to each
+// recipient in result.
+//
+// When the server supports PIPELINING (RFC 2920) the commands are sent in a
+// single batch before reading any responses, rather than waiting for the
+// response to each in turn.
+//
+// If partial is true, rejected recipients are skipped rather than failing the
+// send, unless every recipient is rejected.
+func sendEnvelope(smtpClient *smtp.Client, envelopeFrom string, mailParams []string, rcpts []string, rcptParams []string, result *SendResult, partial bool) error {
+	cmds := make([]smtpCommand, 0, len(rcpts)+1)
+
+	mail, err := mailCommand(smtpClient, envelopeFrom, mailParams)
+	if err != nil {
+		return err
+	}
+	cmds = append(cmds, mail)
+
+	for _, addr := range rcpts {
+		rcpt, err := rcptCommand(addr, rcptParams)
+		if err != nil {
+			return err
+		}
+		cmds = append(cmds, rcpt)
+	}
+
+	// record the response to each recipient, returning any error that fails
+	// the send
+	var firstRejected error
+	record := func(cmd smtpCommand, code int, msg string, err error) error {
+		if cmd.rcpt == "" {
+			return err
+		}
+
+		status := RecipientStatus{Addr: cmd.rcpt, Code: code, Msg: msg}
+		if err == nil {
+			result.Accepted = append(result.Accepted, status)
+			return nil
+		}
+
+		err = rejectedError(cmd, err)
+		if _, ok := err.(*RecipientRejectedError); !ok {
+			return err
+		}
+
+		result.Rejected = append(result.Rejected, status)
+		if firstRejected == nil {
+			firstRejected = err
+		}
+		if partial {
+			return nil
+		}
+		return err
+	}
+
+	if ok, _ := smtpClient.Extension("PIPELINING"); ok {
+		err = pipelineCommands(smtpClient, cmds, record)
+	} else {
+		err = sequentialCommands(smtpClient, cmds, record)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(result.Accepted) == 0 && firstRejected != nil {
+		return firstRejected
+	}
+
+	return nil
+}
+
+// sequentialCommands sends each of cmds in turn, waiting for the response to
+// each before sending the next, and returning the first error returned by
+// record for a response.
+func sequentialCommands(smtpClient *smtp.Client, cmds []smtpCommand, record func(cmd smtpCommand, code int, msg string, err error) error) error {
+	for _, cmd := range cmds {
+		id, err := smtpClient.Text.Cmd("%s", cmd.line)
+		if err != nil {
+			return err
+		}
+
+		code, msg, err := readResponse(smtpClient, id, cmd.expectCode)
+		if err := record(cmd, code, msg, err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pipelineCommands sends all cmds before reading their responses, returning
+// the first error returned by record for a response.
+//
+// The responses to all cmds are read even if one is rejected, keeping the
+// session in sync.
+func pipelineCommands(smtpClient *smtp.Client, cmds []smtpCommand, record func(cmd smtpCommand, code int, msg string, err error) error) error {
+	ids := make([]uint, len(cmds))
+	for i, cmd := range cmds {
+		id, err := smtpClient.Text.Cmd("%s", cmd.line)
+		if err != nil {
+			return err
+		}
+		ids[i] = id
+	}
+
+	var first error
+	for i, cmd := range cmds {
+		code, msg, err := readResponse(smtpClient, ids[i], cmd.expectCode)
+		if err := record(cmd, code, msg, err); err != nil && first == nil {
+			first = err
+		}
+
+		// the remaining responses cannot be read if the connection failed
+		if _, ok := err.(*textproto.Error); err != nil && !ok {
+			break
+		}
+	}
+
+	return first
+}
+
+// readResponse reads the response to the command with the given pipeline id,
+// returning an error if the response code does not match expectCode.
+func readResponse(smtpClient *smtp.Client, id uint, expectCode int) (int, string, error) {
+	smtpClient.Text.StartResponse(id)
+	defer smtpClient.Text.EndResponse(id)
+
+	return smtpClient.Text.ReadResponse(expectCode)
+}
+
+// mailCommand returns the MAIL command for envelopeFrom with params, requesting
+// the 8BITMIME and SMTPUTF8 extensions when supported as smtpClient.Mail()
+// does.
+func mailCommand(smtpClient *smtp.Client, envelopeFrom string, params []string) (smtpCommand, error) {
+	if err := validateLine(envelopeFrom); err != nil {
+		return smtpCommand{}, err
+	}
+
+	line := "MAIL FROM:<" + envelopeFrom + ">"
+	if ok, _ := smtpClient.Extension("8BITMIME"); ok {
+		line += " BODY=8BITMIME"
+	}
+	if ok, _ := smtpClient.Extension("SMTPUTF8"); ok {
+		line += " SMTPUTF8"
+	}
+
+	return smtpCommand{line: withParams(line, params), expectCode: 250}, nil
+}
+
+// rcptCommand returns the RCPT command for addr with params.
+func rcptCommand(addr string, params []string) (smtpCommand, error) {
+	if err := validateLine(addr); err != nil {
+		return smtpCommand{}, err
+	}
+	return smtpCommand{line: withParams("RCPT TO:<"+addr+">", params), expectCode: 25, rcpt: addr}, nil
+}
+
+// withParams returns line followed by params, separated by spaces.
+func withParams(line string, params []string) string {
+	for _, p := range params {
+		line += " " + p
+	}
+	return line
+}
+
+// validateLine returns an error if line contains a CR or LF, which would allow
+// injecting additional SMTP commands.
+func validateLine(line string) error {
+	if strings.ContainsAny(line, "\r\n") {
+		return errors.New("mailyak: a line must not contain CR or LF")
+	}
+	return nil
+}
+
+// tlsPolicy is the TLS configuration for a connection to a server, and whether
+// the connection must be encrypted.
+type tlsPolicy struct {
+	config   *tls.Config
+	required bool
+
+	// dane is true if the server is authenticated using DANE
+	dane bool
+}
+
+// tlsPolicy returns the tlsPolicy for connecting to serverName at addr over
+// network, authenticating the server using DANE if configured, or the MTA-STS
+// policies of the recipients of sts.
+func (m *MailYak) tlsPolicy(ctx context.Context, network, addr, serverName string, sts mtaSTSCheck) (tlsPolicy, error) {
+	policy := tlsPolicy{
+		config:   m.clientTLSConfig(serverName),
+		required: m.requireTLS,
+	}
+
+	if m.tlsaResolver != nil && network == "tcp" {
+		_, port, _ := net.SplitHostPort(addr)
+		if err := m.applyDANE(ctx, &policy, serverName, port); err != nil {
+			return tlsPolicy{}, err
+		}
+	}
+
+	// DANE takes precedence over MTA-STS (RFC 8461 section 2)
+	if sts.client != nil && network == "tcp" && !policy.dane {
+		if err := sts.apply(ctx, &policy, serverName); err != nil {
+			return tlsPolicy{}, err
+		}
+	}
+
+	return policy, nil
+}
+
+// clientTLSConfig returns the TLS configuration to use when connecting to
+// serverName, derived from the user supplied configuration if set.
+func (m *MailYak) clientTLSConfig(serverName string) *tls.Config {
+	if m.tlsConfig == nil {
+		return &tls.Config{ServerName: serverName}
+	}
+
+	config := m.tlsConfig.Clone()
+	if config.ServerName == "" {
+		config.ServerName = serverName
+	}
+
+	return config
+}
+
+// recipients returns the addresses the email should be delivered to during the
+// SMTP RCPT phase - the To, group, Cc and Bcc addresses without any display
+// names, and with duplicates removed.
+func (m *MailYak) recipients() []string {
+	all := make([]string, 0, len(m.toAddrs)+len(m.ccAddrs)+len(m.bccAddrs))
+	all = append(all, m.toAddrs...)
+	all = append(all, m.groupAddrs()...)
+	all = append(all, m.ccAddrs...)
+	all = append(all, m.bccAddrs...)
+
+	seen := make(map[string]bool, len(all))
+	addrs := make([]string, 0, len(all))
+	for _, addr := range all {
+		addr = envelopeAddress(addr)
+
+		key := strings.ToLower(addr)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		addrs = append(addrs, addr)
+	}
+
+	return addrs
+}
+
+// headerRecipients returns the To, Cc and Bcc addresses to write to the email
+// headers, with duplicates removed if DeduplicateRecipients is enabled.
+func (m *MailYak) headerRecipients() (to, cc, bcc []string) {
+	if !m.dedupRecipients {
+		return m.toAddrs, m.ccAddrs, m.bccAddrs
+	}
+
+	seen := make(map[string]bool, len(m.toAddrs)+len(m.ccAddrs)+len(m.bccAddrs))
+	dedup := func(addrs []string) []string {
+		out := make([]string, 0, len(addrs))
+		for _, addr := range addrs {
+			key := strings.ToLower(envelopeAddress(addr))
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			out = append(out, addr)
+		}
+		return out
+	}
+
+	return dedup(m.toAddrs), dedup(m.ccAddrs), dedup(m.bccAddrs)
+}
+
+// envelopeAddress returns the address in addr without any display name, such
+// as "dom@itsallbroken.com" for "Dom <dom@itsallbroken.com>".
+//
+// addr is returned unchanged if it cannot be parsed.
+func envelopeAddress(addr string) string {
+	a, err := mail.ParseAddress(addr)
+	if err != nil {
+		return addr
+	}
+	return a.Address
+}
+
+// envelopeSender returns the address the email should be sent from during the
+// SMTP MAIL phase.
+func (m *MailYak) envelopeSender() string {
+	if m.envelopeFrom != "" {
+		return m.envelopeFrom
+	}
+	return m.fromAddr
+}
+
+// requiresSMTPUTF8 returns true if the envelope sender or any recipient address
+// contains non-ASCII characters.
+func requiresSMTPUTF8(envelopeFrom string, rcpts []string) bool {
+	if !isASCII(envelopeFrom) {
+		return true
+	}
+
+	for _, addr := range rcpts {
+		if !isASCII(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// asciiEnvelope returns the envelope sender, recipients and message with any
+// internationalized domains converted to their ASCII form.
+//
+// ok is false if any address has a non-ASCII local part, or the headers of msg
+// cannot be converted as it is not a streamable email built by MailYak.
+func asciiEnvelope(envelopeFrom string, rcpts []string, msg io.WriterTo) (string, []string, io.WriterTo, bool) {
+	mm, ok := msg.(*mimeMessage)
+	if !ok {
+		return "", nil, nil, false
+	}
+
+	converted, ok := mm.withASCIIDomains()
+	if !ok {
+		return "", nil, nil, false
+	}
+
+	from, ok := asciiAddress(envelopeFrom)
+	if !ok {
+		return "", nil, nil, false
+	}
+
+	asciiRcpts := make([]string, len(rcpts))
+	for i, addr := range rcpts {
+		if asciiRcpts[i], ok = asciiAddress(addr); !ok {
+			return "", nil, nil, false
+		}
+	}
+
+	return from, asciiRcpts, converted, true
+}
+
+// withASCIIDomains returns a copy of m with the domains of the sender,
+// recipient, Reply-To and Sender addresses converted to their ASCII form
+// (A-labels).
+//
+// ok is false if any sender or recipient address has a non-ASCII local part.
+func (m *MailYak) withASCIIDomains() (c *MailYak, ok bool) {
+	convert := func(addrs []string) []string {
+		out := make([]string, len(addrs))
+		for i, addr := range addrs {
+			var converted bool
+			if out[i], converted = asciiAddress(addr); !converted {
+				ok = false
+			}
+		}
+		return out
+	}
+
+	ok = true
+
+	// generate the Message-ID before copying, so it is retained by m
+	m.messageIDValue()
+
+	copied := *m
+	copied.fromAddr = convert([]string{m.fromAddr})[0]
+	if m.envelopeFrom != "" {
+		copied.envelopeFrom = convert([]string{m.envelopeFrom})[0]
+	}
+	copied.toAddrs = convert(m.toAddrs)
+	copied.ccAddrs = convert(m.ccAddrs)
+	copied.bccAddrs = convert(m.bccAddrs)
+	copied.toGroups = make([]addressGroup, len(m.toGroups))
+	for i, g := range m.toGroups {
+		copied.toGroups[i] = addressGroup{name: g.name, addrs: convert(g.addrs)}
+	}
+
+	// the Reply-To and Sender addresses are not part of the envelope, and are
+	// converted on a best-effort basis
+	copied.replyTo, _ = asciiAddress(m.replyTo)
+	copied.sender, _ = asciiAddress(m.sender)
+
+	return &copied, ok
 }
 
 // MimeBuf returns the buffer containing all the RAW MIME data.
@@ -162,6 +1366,8 @@ func (m *MailYak) Send(localHostName string) (int, string, error) {
 // MimeBuf is typically used with an API service such as Amazon SES that does
 // not use an SMTP interface.
 func (m *MailYak) MimeBuf() (*bytes.Buffer, error) {
+	m.buildMessageID()
+
 	buf, err := m.buildMime()
 	if err != nil {
 		return nil, err
@@ -169,6 +1375,52 @@ func (m *MailYak) MimeBuf() (*bytes.Buffer, error) {
 	return buf, nil
 }
 
+// WriteTo writes the raw MIME data of the email to w, returning the number of
+// bytes written.
+//
+// Unlike MimeBuf, the MIME data is streamed to w as it is generated, with
+// attachments read and encoded as they are written rather than buffered in
+// memory. Signed or encrypted emails must be built in full before being
+// written, and are buffered.
+func (m *MailYak) WriteTo(w io.Writer) (int64, error) {
+	msg, err := m.mimeMessage()
+	if err != nil {
+		return 0, err
+	}
+	return msg.WriteTo(w)
+}
+
+// WriteFile writes the raw MIME data of the email to the named file, such as
+// "welcome.eml", creating it if necessary and truncating it otherwise. The
+// file can be opened in most desktop email clients, archived, or handed off to
+// another system.
+//
+// The MIME data uses CRLF line endings as required by RFC 5322, regardless of
+// the platform. If the email cannot be built or written, the partially
+// written file is removed.
+func (m *MailYak) WriteFile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	_, err = m.WriteTo(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(name)
+		return err
+	}
+
+	return nil
+}
+
 // String returns a redacted description of the email state, typically for
 // logging or debugging purposes.
 //
@@ -184,8 +1436,8 @@ func (m *MailYak) String() string {
 
 	if len(m.headers) > 0 {
 		var hdrs []string
-		for k, v := range m.headers {
-			hdrs = append(hdrs, fmt.Sprintf("%s: %q", k, v))
+		for _, k := range m.customHeaderNames() {
+			hdrs = append(hdrs, fmt.Sprintf("%s: %q", k, strings.Join(m.headerValues(k), ", ")))
 		}
 		custom = strings.Join(hdrs, ", ") + ", "
 	}
@@ -1,10 +1,31 @@
 package mailyak
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
 	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // TestMailYakStringer ensures MailYak struct conforms to the Stringer interface.
@@ -33,3 +54,1390 @@ func TestMailYakStringer(t *testing.T) {
 		t.Errorf("MailYak.String() = %v, want %v", got, want)
 	}
 }
+
+// TestMailYakReset ensures Reset clears the email content while retaining the
+// sender and server configuration.
+func TestMailYakReset(t *testing.T) {
+	t.Parallel()
+
+	mail := New("mail.host.com:25", smtp.PlainAuth("", "user", "pass", "mail.host.com"))
+	mail.From("from@example.org")
+	mail.FromName("From Example")
+	mail.ReplyTo("replies@example.org")
+	mail.To("to@example.org")
+	mail.Cc("cc@example.org")
+	mail.Bcc("bcc@example.org")
+	mail.Subject("Test subject")
+	mail.HTML().Set("HTML")
+	mail.Plain().Set("Plain")
+	mail.Attach("test.txt", strings.NewReader("attachment"))
+	mail.AddHeader("Precedence", "bulk")
+	mail.Date(time.Unix(1234567890, 0))
+	mail.InReplyTo("<1@example.org>")
+	mail.References("<1@example.org>")
+	mail.Priority(PriorityHigh)
+	if err := mail.ListUnsubscribe("https://example.org/unsubscribe"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mail.RequestReadReceipt("from@example.org"); err != nil {
+		t.Fatal(err)
+	}
+	id := mail.GetMessageID()
+
+	mail.Reset()
+
+	cleared := []struct {
+		name  string
+		empty bool
+	}{
+		{"to", len(mail.toAddrs) == 0},
+		{"cc", len(mail.ccAddrs) == 0},
+		{"bcc", len(mail.bccAddrs) == 0},
+		{"subject", mail.subject == ""},
+		{"html", mail.html.Len() == 0},
+		{"plain", mail.plain.Len() == 0},
+		{"attachments", len(mail.attachments) == 0},
+		{"headers", len(mail.headers) == 0},
+		{"date", mail.date == ""},
+		{"inReplyTo", mail.inReplyTo == ""},
+		{"references", len(mail.references) == 0},
+		{"priority", mail.priority == 0},
+		{"listUnsubscribe", len(mail.listUnsubscribe) == 0 && !mail.listUnsubscribePost},
+		{"readReceipt", mail.readReceipt == ""},
+	}
+	for _, c := range cleared {
+		if !c.empty {
+			t.Errorf("MailYak.Reset() did not clear %s", c.name)
+		}
+	}
+
+	if mail.host != "mail.host.com:25" || mail.auth == nil {
+		t.Errorf("MailYak.Reset() host = %q, auth = %v, want server configuration retained", mail.host, mail.auth)
+	}
+	if mail.fromAddr != "from@example.org" || mail.fromName != "From Example" || mail.replyTo != "replies@example.org" {
+		t.Errorf("MailYak.Reset() from = %q, fromName = %q, replyTo = %q, want sender retained", mail.fromAddr, mail.fromName, mail.replyTo)
+	}
+
+	if got := mail.GetMessageID(); got == id {
+		t.Errorf("MailYak.GetMessageID() = %v, want new Message-ID", got)
+	}
+}
+
+// TestMailYakClone ensures changes to a clone do not affect the original.
+func TestMailYakClone(t *testing.T) {
+	t.Parallel()
+
+	base := New("mail.host.com:25", nil)
+	base.From("from@example.org")
+	base.To("to@example.org")
+	base.Cc("cc@example.org")
+	base.Bcc("bcc@example.org")
+	base.Subject("Base")
+	base.HTML().Set("HTML")
+	base.Plain().Set("Plain")
+	base.AddHeader("Precedence", "bulk")
+	base.References("<1@example.org>")
+	base.AttachPart("test.txt", strings.NewReader("attachment"), textproto.MIMEHeader{"Content-Description": {"Base"}})
+
+	clone := base.Clone()
+
+	if clone.GetMessageID() == base.GetMessageID() {
+		t.Errorf("MailYak.Clone() Message-ID = %v, want new Message-ID", clone.GetMessageID())
+	}
+
+	clone.To("other@example.org")
+	clone.toAddrs[0] = "changed@example.org"
+	clone.ccAddrs[0] = "changed@example.org"
+	clone.bccAddrs[0] = "changed@example.org"
+	clone.references[0] = "<2@example.org>"
+	clone.Subject("Clone")
+	clone.HTML().Set("Changed")
+	clone.Plain().WriteString(" changed")
+	clone.SetHeader("Precedence", "list")
+	clone.attachments[0].header.Set("Content-Description", "Clone")
+	clone.Attach("another.txt", strings.NewReader("attachment"))
+
+	if !reflect.DeepEqual(base.toAddrs, []string{"to@example.org"}) {
+		t.Errorf("MailYak.Clone() base to = %v", base.toAddrs)
+	}
+	if !reflect.DeepEqual(base.ccAddrs, []string{"cc@example.org"}) {
+		t.Errorf("MailYak.Clone() base cc = %v", base.ccAddrs)
+	}
+	if !reflect.DeepEqual(base.bccAddrs, []string{"bcc@example.org"}) {
+		t.Errorf("MailYak.Clone() base bcc = %v", base.bccAddrs)
+	}
+	if !reflect.DeepEqual(base.references, []string{"<1@example.org>"}) {
+		t.Errorf("MailYak.Clone() base references = %v", base.references)
+	}
+	if base.subject != "Base" {
+		t.Errorf("MailYak.Clone() base subject = %v", base.subject)
+	}
+	if base.HTML().String() != "HTML" || base.Plain().String() != "Plain" {
+		t.Errorf("MailYak.Clone() base html = %q, plain = %q", base.HTML().String(), base.Plain().String())
+	}
+	if base.headers["Precedence"] != "bulk" {
+		t.Errorf("MailYak.Clone() base headers = %v", base.headers)
+	}
+	if len(base.attachments) != 1 || base.attachments[0].header.Get("Content-Description") != "Base" {
+		t.Errorf("MailYak.Clone() base attachments = %v", base.attachments)
+	}
+}
+
+// TestMailYakRecipients ensures To, Cc and Bcc addresses are included in the
+// SMTP envelope recipients.
+func TestMailYakRecipients(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		rtoAddrs  []string
+		rccAddrs  []string
+		rbccAddrs []string
+		// Want
+		want []string
+	}{
+		{
+			"To only",
+			[]string{"to@itsallbroken.com"},
+			[]string{},
+			[]string{},
+			[]string{"to@itsallbroken.com"},
+		},
+		{
+			"Cc only",
+			[]string{},
+			[]string{"cc@itsallbroken.com"},
+			[]string{},
+			[]string{"cc@itsallbroken.com"},
+		},
+		{
+			"Bcc only",
+			[]string{},
+			[]string{},
+			[]string{"bcc@itsallbroken.com"},
+			[]string{"bcc@itsallbroken.com"},
+		},
+		{
+			"To and Cc",
+			[]string{"to1@itsallbroken.com", "to2@itsallbroken.com"},
+			[]string{"cc@itsallbroken.com"},
+			[]string{},
+			[]string{"to1@itsallbroken.com", "to2@itsallbroken.com", "cc@itsallbroken.com"},
+		},
+		{
+			"To, Cc and Bcc",
+			[]string{"to@itsallbroken.com"},
+			[]string{"cc@itsallbroken.com"},
+			[]string{"bcc@itsallbroken.com"},
+			[]string{"to@itsallbroken.com", "cc@itsallbroken.com", "bcc@itsallbroken.com"},
+		},
+		{
+			"Duplicates",
+			[]string{"dom@itsallbroken.com", "to@itsallbroken.com"},
+			[]string{"Dom <dom@itsallbroken.com>"},
+			[]string{"DOM@itsallbroken.com", "bcc@itsallbroken.com"},
+			[]string{"dom@itsallbroken.com", "to@itsallbroken.com", "bcc@itsallbroken.com"},
+		},
+		{
+			"Display names",
+			[]string{"To <to@itsallbroken.com>"},
+			[]string{"\"Cc, Carbon\" <cc@itsallbroken.com>"},
+			[]string{},
+			[]string{"to@itsallbroken.com", "cc@itsallbroken.com"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := &MailYak{
+				toAddrs:  tt.rtoAddrs,
+				ccAddrs:  tt.rccAddrs,
+				bccAddrs: tt.rbccAddrs,
+			}
+
+			if got := m.recipients(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%q. MailYak.recipients() = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMailYakSendWithContext ensures SendWithContext aborts the SMTP
+// conversation when the context is done.
+func TestMailYakSendWithContext(t *testing.T) {
+	t.Parallel()
+
+	// A server that accepts connections but never responds
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		ctx func() (context.Context, context.CancelFunc)
+		// Want
+		wantErr error
+	}{
+		{
+			"Cancelled",
+			func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			context.Canceled,
+		},
+		{
+			"Deadline exceeded",
+			func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+			context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			mail := New(l.Addr().String(), nil)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+
+			_, _, err := mail.SendWithContext(ctx, "localhost")
+			if err != tt.wantErr {
+				t.Errorf("%q. MailYak.SendWithContext() error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestMailYakClientTLSConfig ensures the TLS configuration defaults the
+// ServerName to the SMTP server, without modifying a user supplied config.
+func TestMailYakClientTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		rtlsConfig *tls.Config
+		// Want
+		wantServerName string
+		wantMinVersion uint16
+	}{
+		{
+			"Default",
+			nil,
+			"smtp.itsallbroken.com",
+			0,
+		},
+		{
+			"Custom config without ServerName",
+			&tls.Config{MinVersion: tls.VersionTLS12},
+			"smtp.itsallbroken.com",
+			tls.VersionTLS12,
+		},
+		{
+			"Custom config with ServerName",
+			&tls.Config{ServerName: "mx.itsallbroken.com", MinVersion: tls.VersionTLS13},
+			"mx.itsallbroken.com",
+			tls.VersionTLS13,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := &MailYak{tlsConfig: tt.rtlsConfig}
+
+			got := m.clientTLSConfig("smtp.itsallbroken.com")
+			if got.ServerName != tt.wantServerName {
+				t.Errorf("%q. MailYak.clientTLSConfig() ServerName = %v, want %v", tt.name, got.ServerName, tt.wantServerName)
+			}
+			if got.MinVersion != tt.wantMinVersion {
+				t.Errorf("%q. MailYak.clientTLSConfig() MinVersion = %v, want %v", tt.name, got.MinVersion, tt.wantMinVersion)
+			}
+			if tt.rtlsConfig != nil && got == tt.rtlsConfig {
+				t.Errorf("%q. MailYak.clientTLSConfig() returned the user config, want a copy", tt.name)
+			}
+		})
+	}
+}
+
+// testSMTPServer is a minimal SMTP server used to exercise the client side of
+// the SMTP conversation.
+//
+// Each command received is recorded, and answered with the reply in replies
+// keyed by the command verb (i.e. "EHLO", "MAIL"), falling back to
+// defaultReplies. The end of the DATA content and the final BDAT chunk are
+// keyed by ".", and the server greeting by "220". An empty reply causes the server to stop responding.
+type testSMTPServer struct {
+	l       net.Listener
+	replies map[string]string
+
+	// tlsConfig is used to upgrade connections in response to STARTTLS, if
+	// set
+	tlsConfig *tls.Config
+
+	mu   sync.Mutex
+	cmds []string
+	data []byte
+
+	// chunked is true while receiving BDAT chunks
+	chunked bool
+}
+
+var defaultReplies = map[string]string{
+	"220":  "220 localhost ESMTP",
+	"EHLO": "250 localhost",
+	"HELO": "250 localhost",
+	"MAIL": "250 OK",
+	"RCPT": "250 OK",
+	"DATA": "354 Go ahead",
+	".":    "250 OK",
+	"BDAT": "250 OK",
+	"RSET": "250 OK",
+	"NOOP": "250 OK",
+	"QUIT": "221 Bye",
+}
+
+// newTestSMTPServer starts a testSMTPServer accepting connections on l.
+func newTestSMTPServer(l net.Listener, replies map[string]string) *testSMTPServer {
+	return startTestSMTPServer(&testSMTPServer{l: l, replies: replies})
+}
+
+// startTestSMTPServer starts s accepting connections on s.l.
+func startTestSMTPServer(s *testSMTPServer) *testSMTPServer {
+	go func() {
+		for {
+			conn, err := s.l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// newTLSTestSMTPServer starts a testSMTPServer accepting implicit TLS
+// connections, returning the server and a client config trusting it.
+func newTLSTestSMTPServer(t *testing.T, replies map[string]string) (*testSMTPServer, *tls.Config) {
+	serverConfig, clientConfig := testTLSConfigs(t)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return newTestSMTPServer(l, replies), clientConfig
+}
+
+// newStartTLSTestSMTPServer starts a testSMTPServer supporting STARTTLS,
+// returning the server and a client config trusting it.
+func newStartTLSTestSMTPServer(t *testing.T, replies map[string]string) (*testSMTPServer, *tls.Config) {
+	serverConfig, clientConfig := testTLSConfigs(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := startTestSMTPServer(&testSMTPServer{l: l, replies: replies, tlsConfig: serverConfig})
+	return s, clientConfig
+}
+
+// testTLSConfigs returns a server TLS config with a self-signed certificate
+// for 127.0.0.1, and a client config trusting it.
+func testTLSConfigs(t *testing.T) (*tls.Config, *tls.Config) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	serverConfig := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+	return serverConfig, &tls.Config{RootCAs: pool}
+}
+
+// Addr returns the address the server is listening on.
+func (s *testSMTPServer) Addr() string {
+	return s.l.Addr().String()
+}
+
+// Close stops the server accepting new connections.
+func (s *testSMTPServer) Close() error {
+	return s.l.Close()
+}
+
+// Commands returns the commands received by the server.
+func (s *testSMTPServer) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.cmds...)
+}
+
+func (s *testSMTPServer) reply(verb string) (string, bool) {
+	if r, ok := s.replies[verb]; ok {
+		return r, r != ""
+	}
+	if r, ok := defaultReplies[verb]; ok {
+		return r, true
+	}
+	return "502 Command not implemented", true
+}
+
+// Data returns the message content received by the server.
+func (s *testSMTPServer) Data() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data
+}
+
+func (s *testSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+
+	// respond with reply, or block until the client hangs up if there is no
+	// reply
+	respond := func(reply string, ok bool) bool {
+		if !ok {
+			io.Copy(ioutil.Discard, conn)
+			return false
+		}
+		return text.PrintfLine("%s", reply) == nil
+	}
+
+	if !respond(s.reply("220")) {
+		return
+	}
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.cmds = append(s.cmds, line)
+		s.mu.Unlock()
+
+		fields := strings.Fields(line)
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		// read the chunk following a BDAT command before responding
+		if verb == "BDAT" && len(fields) > 1 {
+			size, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return
+			}
+
+			chunk := make([]byte, size)
+			if _, err := io.ReadFull(text.R, chunk); err != nil {
+				return
+			}
+
+			s.mu.Lock()
+			if s.chunked {
+				s.data = append(s.data, chunk...)
+			} else {
+				s.data = chunk
+			}
+			s.chunked = len(fields) < 3
+			s.mu.Unlock()
+
+			if len(fields) > 2 {
+				verb = "."
+			}
+		}
+
+		// a reply for the entire command line takes precedence over the verb
+		reply, ok := s.reply(verb)
+		if r, found := s.replies[line]; found {
+			reply, ok = r, r != ""
+		}
+		if !respond(reply, ok) {
+			return
+		}
+
+		switch {
+		case verb == "QUIT":
+			return
+		case verb == "STARTTLS" && s.tlsConfig != nil && strings.HasPrefix(reply, "220"):
+			conn = tls.Server(conn, s.tlsConfig)
+			defer conn.Close()
+			text = textproto.NewConn(conn)
+		case verb == "DATA" && strings.HasPrefix(reply, "354"):
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+
+			s.mu.Lock()
+			s.data = data
+			s.mu.Unlock()
+
+			if !respond(s.reply(".")) {
+				return
+			}
+		}
+	}
+}
+
+// TestNewWithTLS ensures NewWithTLS performs the SMTP conversation over an
+// implicit TLS connection.
+func TestNewWithTLS(t *testing.T) {
+	t.Parallel()
+
+	srv, config := newTLSTestSMTPServer(t, map[string]string{
+		"EHLO": "250-localhost\r\n250 STARTTLS",
+		"MAIL": "550 Go away",
+	})
+	defer srv.Close()
+
+	mail := NewWithTLS(srv.Addr(), nil, config)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+
+	_, _, err := mail.Send("localhost")
+	if err == nil || !strings.Contains(err.Error(), "Go away") {
+		t.Fatalf("NewWithTLS().Send() error = %v, want MAIL rejection", err)
+	}
+
+	want := []string{"EHLO localhost", "MAIL FROM:<from@example.org>"}
+	if got := srv.Commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewWithTLS().Send() commands = %q, want %q", got, want)
+	}
+}
+
+// TestMailYakRequireTLS ensures sending fails when TLS is required but the
+// server does not support STARTTLS.
+func TestMailYakRequireTLS(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		rrequireTLS bool
+		// Want
+		wantErr  bool
+		wantCmds []string
+	}{
+		{
+			"Not required",
+			false,
+			true, // MAIL is rejected by the server
+			[]string{"EHLO localhost", "MAIL FROM:<from@example.org>"},
+		},
+		{
+			"Required",
+			true,
+			true,
+			[]string{"EHLO localhost"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, map[string]string{"MAIL": "550 Go away"})
+			defer srv.Close()
+
+			mail := New(srv.Addr(), nil)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+			mail.RequireTLS(tt.rrequireTLS)
+
+			_, _, err = mail.Send("localhost")
+			if tt.rrequireTLS && err != ErrStartTLSUnsupported {
+				t.Errorf("%q. MailYak.Send() error = %v, want %v", tt.name, err, ErrStartTLSUnsupported)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%q. MailYak.Send() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+
+			if got := srv.Commands(); !reflect.DeepEqual(got, tt.wantCmds) {
+				t.Errorf("%q. MailYak.Send() commands = %q, want %q", tt.name, got, tt.wantCmds)
+			}
+		})
+	}
+}
+
+// TestMailYakSMTPUTF8 ensures internationalized addresses are sent using the
+// SMTPUTF8 extension, and rejected when the server does not support it.
+func TestMailYakSMTPUTF8(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		rfrom string
+		rto   string
+		// Parameters.
+		ehlo string
+		// Want
+		wantErr  error
+		wantCmds []string
+	}{
+		{
+			"ASCII addresses",
+			"from@example.org",
+			"to@example.org",
+			"250 localhost",
+			nil,
+			[]string{"EHLO localhost", "MAIL FROM:<from@example.org>", "RCPT TO:<to@example.org>", "DATA"},
+		},
+		{
+			"Internationalized sender",
+			"用户@例え.jp",
+			"to@example.org",
+			"250-localhost\r\n250 SMTPUTF8",
+			nil,
+			[]string{"EHLO localhost", "MAIL FROM:<用户@例え.jp> SMTPUTF8", "RCPT TO:<to@example.org>", "DATA"},
+		},
+		{
+			"Internationalized recipient",
+			"from@example.org",
+			"用户@例え.jp",
+			"250-localhost\r\n250 SMTPUTF8",
+			nil,
+			[]string{"EHLO localhost", "MAIL FROM:<from@example.org> SMTPUTF8", "RCPT TO:<用户@例え.jp>", "DATA"},
+		},
+		{
+			"Internationalized domain",
+			"from@example.org",
+			"dom@bücher.example",
+			"250 localhost",
+			nil,
+			[]string{"EHLO localhost", "MAIL FROM:<from@example.org>", "RCPT TO:<dom@xn--bcher-kva.example>", "DATA"},
+		},
+		{
+			"Unsupported",
+			"from@example.org",
+			"用户@例え.jp",
+			"250 localhost",
+			ErrSMTPUTF8Unsupported,
+			[]string{"EHLO localhost"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, map[string]string{"EHLO": tt.ehlo})
+			defer srv.Close()
+
+			mail := New(srv.Addr(), nil)
+			mail.From(tt.rfrom)
+			mail.To(tt.rto)
+
+			if _, _, err := mail.Send("localhost"); err != tt.wantErr {
+				t.Errorf("%q. MailYak.Send() error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+
+			if got := srv.Commands(); !reflect.DeepEqual(got, tt.wantCmds) {
+				t.Errorf("%q. MailYak.Send() commands = %q, want %q", tt.name, got, tt.wantCmds)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			// the To header matches the envelope recipient
+			rcpt := strings.TrimSuffix(strings.TrimPrefix(tt.wantCmds[len(tt.wantCmds)-2], "RCPT TO:<"), ">")
+			if !bytes.Contains(srv.Data(), []byte("To: "+rcpt+"\n")) {
+				t.Errorf("%q. MailYak.Send() data = %q, want To header %q", tt.name, srv.Data(), rcpt)
+			}
+		})
+	}
+}
+
+// TestMailYakEnvelopeFrom ensures the envelope sender is used for the MAIL
+// command, without changing the From header.
+func TestMailYakEnvelopeFrom(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTestSMTPServer(l, nil)
+	defer srv.Close()
+
+	mail := New(srv.Addr(), nil)
+	mail.From("news@itsallbroken.com")
+	mail.EnvelopeFrom("bounces+to=example.org@itsallbroken.com")
+	mail.To("to@example.org")
+
+	if _, _, err := mail.Send("localhost"); err != nil {
+		t.Fatalf("MailYak.Send() error = %v", err)
+	}
+
+	want := []string{
+		"EHLO localhost",
+		"MAIL FROM:<bounces+to=example.org@itsallbroken.com>",
+		"RCPT TO:<to@example.org>",
+		"DATA",
+	}
+	if got := srv.Commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MailYak.Send() commands = %q, want %q", got, want)
+	}
+
+	if !bytes.HasPrefix(srv.Data(), []byte("From: news@itsallbroken.com\n")) {
+		t.Errorf("MailYak.Send() data = %q, want From header", srv.Data())
+	}
+}
+
+// testDialer records the addresses dialed before connecting with a net.Dialer.
+type testDialer struct {
+	mu    sync.Mutex
+	addrs []string
+}
+
+func (d *testDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.mu.Lock()
+	d.addrs = append(d.addrs, network+"://"+address)
+	d.mu.Unlock()
+
+	var nd net.Dialer
+	return nd.DialContext(ctx, network, address)
+}
+
+// TestMailYakDialer ensures a user supplied ContextDialer is used to connect to
+// the SMTP server.
+func TestMailYakDialer(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTestSMTPServer(l, map[string]string{"MAIL": "550 Go away"})
+	defer srv.Close()
+
+	dialer := &testDialer{}
+
+	mail := New(srv.Addr(), nil)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+	mail.Dialer(dialer)
+
+	if _, _, err := mail.Send("localhost"); err == nil {
+		t.Fatal("MailYak.Send() error = nil, want MAIL rejection")
+	}
+
+	want := []string{"tcp://" + srv.Addr()}
+	if !reflect.DeepEqual(dialer.addrs, want) {
+		t.Errorf("MailYak.Send() dialed = %v, want %v", dialer.addrs, want)
+	}
+}
+
+// TestMailYakSend_unixSocket ensures a host given as a socket path is dialled
+// as a Unix domain socket.
+func TestMailYakSend_unixSocket(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix domain sockets are not supported")
+	}
+
+	// the socket path length is limited, so a short directory is used rather
+	// than t.TempDir()
+	dir, err := ioutil.TempDir("", "mailyak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "smtp.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTestSMTPServer(l, nil)
+	defer srv.Close()
+
+	for _, host := range []string{path, "unix:" + path} {
+		mail := New(host, nil)
+		mail.From("from@example.org")
+		mail.To("to@example.org")
+		mail.Plain().Set("Hello")
+
+		if _, _, err := mail.Send("localhost"); err != nil {
+			t.Fatalf("MailYak.Send(%q) error = %v", host, err)
+		}
+		if got := mail.GetSentHost(); got != host {
+			t.Errorf("MailYak.GetSentHost() = %q, want %q", got, host)
+		}
+	}
+}
+
+// TestMailYakHelloName ensures the client identifies itself with the
+// HelloName, the system hostname, or the localHostName passed to Send.
+func TestMailYakHelloName(t *testing.T) {
+	t.Parallel()
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "localhost"
+	}
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		helloName string
+		// Parameters.
+		localHostName string
+		// Want
+		want string
+	}{
+		{"Default", "", "", "EHLO " + hostname},
+		{"HelloName", "mx.itsallbroken.com", "", "EHLO mx.itsallbroken.com"},
+		{"Argument", "", "example.org", "EHLO example.org"},
+		{"Argument precedence", "mx.itsallbroken.com", "example.org", "EHLO example.org"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, nil)
+			defer srv.Close()
+
+			mail := New(srv.Addr(), nil)
+			mail.HelloName(tt.helloName)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+
+			if _, _, err := mail.Send(tt.localHostName); err != nil {
+				t.Fatalf("MailYak.Send() error = %v", err)
+			}
+
+			if got := srv.Commands(); len(got) == 0 || got[0] != tt.want {
+				t.Errorf("MailYak.Send() commands = %v, want %q first", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitHost(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		host string
+		// Want
+		wantNetwork    string
+		wantAddr       string
+		wantServerName string
+		wantErr        bool
+	}{
+		{"TCP", "smtp.itsallbroken.com:25", "tcp", "smtp.itsallbroken.com:25", "smtp.itsallbroken.com", false},
+		{"Absolute path", "/var/run/smtp.sock", "unix", "/var/run/smtp.sock", "localhost", false},
+		{"Prefixed path", "unix:/var/run/smtp.sock", "unix", "/var/run/smtp.sock", "localhost", false},
+		{"Relative prefixed path", "unix:smtp.sock", "unix", "smtp.sock", "localhost", false},
+		{"No port", "smtp.itsallbroken.com", "", "", "", true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			network, addr, serverName, err := splitHost(tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitHost(%q) error = %v, wantErr %v", tt.host, err, tt.wantErr)
+			}
+			if network != tt.wantNetwork || addr != tt.wantAddr || serverName != tt.wantServerName {
+				t.Errorf("splitHost(%q) = %q, %q, %q, want %q, %q, %q", tt.host, network, addr, serverName, tt.wantNetwork, tt.wantAddr, tt.wantServerName)
+			}
+		})
+	}
+}
+
+// TestMailYakSend ensures the email is delivered and the server response to
+// the message content is returned.
+func TestMailYakSend(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTestSMTPServer(l, map[string]string{".": "250 2.0.0 Queued as 1234"})
+	defer srv.Close()
+
+	mail := New(srv.Addr(), nil)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+	mail.Cc("cc@example.org")
+	mail.Bcc("bcc@example.org")
+	mail.Subject("Test")
+	mail.Plain().Set("Hello")
+
+	code, msg, err := mail.Send("localhost")
+	if err != nil {
+		t.Fatalf("MailYak.Send() error = %v", err)
+	}
+	if code != 250 || msg != "2.0.0 Queued as 1234" {
+		t.Errorf("MailYak.Send() = %v, %q, want 250, %q", code, msg, "2.0.0 Queued as 1234")
+	}
+
+	want := []string{
+		"EHLO localhost",
+		"MAIL FROM:<from@example.org>",
+		"RCPT TO:<to@example.org>",
+		"RCPT TO:<cc@example.org>",
+		"RCPT TO:<bcc@example.org>",
+		"DATA",
+	}
+	if got := srv.Commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MailYak.Send() commands = %q, want %q", got, want)
+	}
+
+	if !bytes.Contains(srv.Data(), []byte("Subject: Test\n")) {
+		t.Errorf("MailYak.Send() data = %q, want Subject header", srv.Data())
+	}
+
+	if bytes.Contains(srv.Data(), []byte("bcc@example.org")) {
+		t.Errorf("MailYak.Send() data = %q, want no Bcc address", srv.Data())
+	}
+}
+
+// errReader returns err after reading n bytes of data.
+type errReader struct {
+	n   int
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		return 0, r.err
+	}
+	if len(p) > r.n {
+		p = p[:r.n]
+	}
+	for i := range p {
+		p[i] = 'A'
+	}
+	r.n -= len(p)
+	return len(p), nil
+}
+
+// TestMailYakSend_attachmentError ensures a failure reading an attachment while
+// streaming it to the server aborts the message without completing DATA.
+func TestMailYakSend_attachmentError(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTestSMTPServer(l, nil)
+	defer srv.Close()
+
+	readErr := errors.New("read failed")
+
+	mail := New(srv.Addr(), nil)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+	mail.Attach("big.bin", &errReader{n: 64 * 1024, err: readErr})
+
+	if _, _, err := mail.Send("localhost"); !errors.Is(err, readErr) {
+		t.Fatalf("MailYak.Send() error = %v, want %v", err, readErr)
+	}
+
+	if srv.Data() != nil {
+		t.Errorf("MailYak.Send() delivered %d bytes, want none", len(srv.Data()))
+	}
+}
+
+// TestMailYakWriteTo ensures the MIME data is streamed to the writer, and that
+// errors reading attachments are returned.
+func TestMailYakWriteTo(t *testing.T) {
+	t.Parallel()
+
+	readErr := errors.New("read failed")
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		attachment io.Reader
+		// Want
+		wantErr error
+	}{
+		{
+			"OK",
+			strings.NewReader("attachment"),
+			nil,
+		},
+		{
+			"Attachment error",
+			&errReader{n: 64 * 1024, err: readErr},
+			readErr,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mail := New("", nil)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+			mail.Subject("Streamed")
+			mail.Plain().Set("plain")
+			mail.Attach("test.txt", tt.attachment)
+
+			var buf bytes.Buffer
+			n, err := mail.WriteTo(&buf)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("%q. MailYak.WriteTo() error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+
+			if n != int64(buf.Len()) {
+				t.Errorf("%q. MailYak.WriteTo() = %v, wrote %v bytes", tt.name, n, buf.Len())
+			}
+
+			if err != nil {
+				return
+			}
+
+			for _, want := range []string{"To: to@example.org\r\n", "Subject: Streamed\r\n", "YXR0YWNobWVudA=="} {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("%q. MailYak.WriteTo() = %q, missing %q", tt.name, buf.String(), want)
+				}
+			}
+		})
+	}
+}
+
+// TestMailYakWriteFile ensures the MIME data is written to the named file, and
+// that the file is removed if the email cannot be built.
+func TestMailYakWriteFile(t *testing.T) {
+	t.Parallel()
+
+	readErr := errors.New("read failed")
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		attachment io.Reader
+		// Want
+		wantErr error
+	}{
+		{
+			"OK",
+			strings.NewReader("attachment"),
+			nil,
+		},
+		{
+			"Attachment error",
+			&errReader{n: 64 * 1024, err: readErr},
+			readErr,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mail := New("", nil)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+			mail.Subject("Saved")
+			mail.Plain().Set("line one\nline two\n")
+			mail.Attach("test.txt", tt.attachment)
+
+			name := filepath.Join(t.TempDir(), "saved.eml")
+
+			err := mail.WriteFile(name)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("%q. MailYak.WriteFile() error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+
+			data, readErr := ioutil.ReadFile(name)
+			if err != nil {
+				if !os.IsNotExist(readErr) {
+					t.Errorf("%q. MailYak.WriteFile() left a partial file, read error = %v", tt.name, readErr)
+				}
+				return
+			}
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+
+			if bytes.Contains(bytes.ReplaceAll(data, []byte("\r\n"), nil), []byte("\n")) {
+				t.Errorf("%q. MailYak.WriteFile() = %q, contains bare LF", tt.name, data)
+			}
+			for _, want := range []string{"Subject: Saved\r\n", "line one\r\nline two\r\n", "YXR0YWNobWVudA=="} {
+				if !bytes.Contains(data, []byte(want)) {
+					t.Errorf("%q. MailYak.WriteFile() = %q, missing %q", tt.name, data, want)
+				}
+			}
+		})
+	}
+}
+
+// TestMailYakPipelining ensures the envelope commands are sent in a single
+// batch when the server supports PIPELINING.
+func TestMailYakPipelining(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		ehlo string
+		mail string
+		// Want
+		wantCmds []string
+		wantErr  bool
+	}{
+		{
+			name: "Pipelined",
+			ehlo: "250-localhost\r\n250 PIPELINING",
+			mail: "250 OK",
+			wantCmds: []string{
+				"EHLO localhost",
+				"MAIL FROM:<from@example.org>",
+				"RCPT TO:<to@example.org>",
+				"RCPT TO:<cc@example.org>",
+				"DATA",
+			},
+		},
+		{
+			name: "Pipelined rejection",
+			ehlo: "250-localhost\r\n250 PIPELINING",
+			mail: "550 Go away",
+			wantCmds: []string{
+				"EHLO localhost",
+				"MAIL FROM:<from@example.org>",
+				"RCPT TO:<to@example.org>",
+				"RCPT TO:<cc@example.org>",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Unsupported rejection",
+			ehlo: "250 localhost",
+			mail: "550 Go away",
+			wantCmds: []string{
+				"EHLO localhost",
+				"MAIL FROM:<from@example.org>",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, map[string]string{"EHLO": tt.ehlo, "MAIL": tt.mail})
+			defer srv.Close()
+
+			mail := New(srv.Addr(), nil)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+			mail.Cc("cc@example.org")
+
+			_, _, err = mail.Send("localhost")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MailYak.Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), "Go away") {
+				t.Errorf("MailYak.Send() error = %v, want MAIL rejection", err)
+			}
+
+			if got := srv.Commands(); !reflect.DeepEqual(got, tt.wantCmds) {
+				t.Errorf("MailYak.Send() commands = %q, want %q", got, tt.wantCmds)
+			}
+		})
+	}
+}
+
+// TestMailYakFallbackHosts ensures the fallback hosts are tried in order when
+// the primary host cannot be connected to or fails the greeting.
+func TestMailYakFallbackHosts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		primary map[string]string // nil if the primary host is down
+		backup  map[string]string // nil if the backup host is down
+		// Want
+		wantBackupCmds bool
+		wantSentHost   string
+		wantErr        string
+	}{
+		{
+			name:         "Primary",
+			primary:      map[string]string{},
+			backup:       map[string]string{},
+			wantSentHost: "primary",
+		},
+		{
+			name:           "Primary down",
+			backup:         map[string]string{},
+			wantBackupCmds: true,
+			wantSentHost:   "backup",
+		},
+		{
+			name:           "Primary greeting failure",
+			primary:        map[string]string{"220": "554 No service"},
+			backup:         map[string]string{},
+			wantBackupCmds: true,
+			wantSentHost:   "backup",
+		},
+		{
+			name: "Primary authentication failure",
+			primary: map[string]string{
+				"EHLO": "250-localhost\r\n250 AUTH PLAIN",
+				"AUTH": "535 Bad credentials",
+			},
+			backup:  map[string]string{},
+			wantErr: "Bad credentials",
+		},
+		{
+			name:    "Primary rejection",
+			primary: map[string]string{"MAIL": "550 Go away"},
+			backup:  map[string]string{},
+			wantErr: "Go away",
+		},
+		{
+			name:    "All down",
+			wantErr: "all SMTP hosts failed",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// start a server with replies, or return the address of a closed
+			// listener if replies is nil
+			start := func(replies map[string]string) (*testSMTPServer, string) {
+				l, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if replies == nil {
+					l.Close()
+					return nil, l.Addr().String()
+				}
+
+				srv := newTestSMTPServer(l, replies)
+				t.Cleanup(func() { srv.Close() })
+				return srv, srv.Addr()
+			}
+
+			_, primary := start(tt.primary)
+			backupSrv, backup := start(tt.backup)
+
+			mail := New(primary, smtp.PlainAuth("", "user", "pass", "127.0.0.1"))
+			mail.FallbackHosts(backup)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+
+			_, _, err := mail.Send("localhost")
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("MailYak.Send() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("MailYak.Send() error = %v, want %q", err, tt.wantErr)
+			}
+
+			wantSentHost := map[string]string{"primary": primary, "backup": backup}[tt.wantSentHost]
+			if got := mail.GetSentHost(); got != wantSentHost {
+				t.Errorf("MailYak.GetSentHost() = %q, want %q", got, wantSentHost)
+			}
+
+			if backupSrv != nil {
+				if got := len(backupSrv.Commands()) > 0; got != tt.wantBackupCmds {
+					t.Errorf("MailYak.Send() connected to backup = %v, want %v", got, tt.wantBackupCmds)
+				}
+			}
+		})
+	}
+}
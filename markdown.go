@@ -0,0 +1,328 @@
+package mailyak
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	mdHeadingRegex = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	mdRuleRegex    = regexp.MustCompile(`^(?:(?:\*\s*){3,}|(?:-\s*){3,}|(?:_\s*){3,})$`)
+	mdBulletRegex  = regexp.MustCompile(`^[*+-]\s+`)
+	mdOrderedRegex = regexp.MustCompile(`^\d{1,9}[.)]\s+`)
+	mdFenceRegex   = regexp.MustCompile("^(```+|~~~+)")
+)
+
+// BodyMarkdown sets the HTML body to the rendered Markdown src, and the
+// plain-text body to src itself, providing a formatted email with a readable
+// plain-text alternative in a single call:
+//
+//	mail.BodyMarkdown("# Build failed\n\nSee the [logs](https://ci.itsallbroken.com/42) for **details**.")
+//
+// A common subset of Markdown is supported: headings, paragraphs, emphasis,
+// inline code and fenced code blocks, links, images, block quotes, horizontal
+// rules, and ordered and unordered lists. Raw HTML in src is escaped, and only
+// http, https and mailto links are rendered.
+func (m *MailYak) BodyMarkdown(src string) {
+	m.html.Set(markdownToHTML(src))
+	m.plain.Set(src)
+}
+
+// markdownToHTML returns the HTML rendering of the Markdown document src.
+func markdownToHTML(src string) string {
+	src = strings.Replace(src, "\r\n", "\n", -1)
+
+	var b strings.Builder
+	renderMarkdownBlocks(&b, strings.Split(src, "\n"))
+	return b.String()
+}
+
+// renderMarkdownBlocks writes the HTML rendering of the block-level elements in
+// lines to b.
+func renderMarkdownBlocks(b *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimLeft(line, " ")
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case mdFenceRegex.MatchString(trimmed):
+			fence := mdFenceRegex.FindString(trimmed)
+			lang := strings.TrimSpace(trimmed[len(fence):])
+
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			i++
+
+			if lang != "" {
+				b.WriteString(`<pre><code class="language-` + html.EscapeString(strings.Fields(lang)[0]) + `">`)
+			} else {
+				b.WriteString("<pre><code>")
+			}
+			for _, l := range code {
+				b.WriteString(html.EscapeString(l) + "\n")
+			}
+			b.WriteString("</code></pre>\n")
+
+		case mdHeadingRegex.MatchString(trimmed):
+			match := mdHeadingRegex.FindStringSubmatch(trimmed)
+			tag := "h" + string(rune('0'+len(match[1])))
+			b.WriteString("<" + tag + ">" + renderMarkdownInline(match[2]) + "</" + tag + ">\n")
+			i++
+
+		case mdRuleRegex.MatchString(trimmed):
+			b.WriteString("<hr>\n")
+			i++
+
+		case strings.HasPrefix(trimmed, ">"):
+			var quoted []string
+			for ; i < len(lines); i++ {
+				l := strings.TrimLeft(lines[i], " ")
+				if !strings.HasPrefix(l, ">") {
+					break
+				}
+				quoted = append(quoted, strings.TrimPrefix(l[1:], " "))
+			}
+
+			b.WriteString("<blockquote>\n")
+			renderMarkdownBlocks(b, quoted)
+			b.WriteString("</blockquote>\n")
+
+		case mdBulletRegex.MatchString(trimmed), mdOrderedRegex.MatchString(trimmed):
+			marker, tag := mdBulletRegex, "ul"
+			if !mdBulletRegex.MatchString(trimmed) {
+				marker, tag = mdOrderedRegex, "ol"
+			}
+
+			// Each item continues until the next item or a blank line
+			var items []string
+			for ; i < len(lines); i++ {
+				l := strings.TrimSpace(lines[i])
+				if l == "" {
+					break
+				}
+				if loc := marker.FindStringIndex(l); loc != nil {
+					items = append(items, l[loc[1]:])
+					continue
+				}
+				if len(items) == 0 || !strings.HasPrefix(lines[i], " ") && isMarkdownBlockStart(l) {
+					break
+				}
+				items[len(items)-1] += "\n" + l
+			}
+
+			b.WriteString("<" + tag + ">\n")
+			for _, item := range items {
+				b.WriteString("<li>" + renderMarkdownInline(item) + "</li>\n")
+			}
+			b.WriteString("</" + tag + ">\n")
+
+		default:
+			var para []string
+			for ; i < len(lines); i++ {
+				l := strings.TrimLeft(lines[i], " ")
+				if strings.TrimSpace(l) == "" || len(para) > 0 && isMarkdownBlockStart(l) {
+					break
+				}
+				para = append(para, l)
+			}
+
+			b.WriteString("<p>" + renderMarkdownInline(strings.Join(para, "\n")) + "</p>\n")
+		}
+	}
+}
+
+// isMarkdownBlockStart returns true if line starts a block-level element that
+// interrupts a paragraph.
+func isMarkdownBlockStart(line string) bool {
+	return mdFenceRegex.MatchString(line) ||
+		mdHeadingRegex.MatchString(line) ||
+		mdRuleRegex.MatchString(strings.TrimSpace(line)) ||
+		strings.HasPrefix(line, ">") ||
+		mdBulletRegex.MatchString(line) ||
+		mdOrderedRegex.MatchString(line)
+}
+
+// renderMarkdownInline returns the HTML rendering of the inline elements in s.
+func renderMarkdownInline(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_{}[]()#+-.!<>", s[i+1]) >= 0:
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			b.WriteString("<br>\n")
+			i += 2
+			continue
+
+		case c == '\n':
+			if strings.HasSuffix(s[:i], "  ") {
+				b.WriteString("<br>")
+			}
+			b.WriteByte('\n')
+			i++
+			continue
+
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				b.WriteString("<code>" + html.EscapeString(s[i+1:i+1+end]) + "</code>")
+				i += end + 2
+				continue
+			}
+
+		case c == '!' && i+1 < len(s) && s[i+1] == '[':
+			if text, url, n, ok := markdownLink(s[i+1:]); ok {
+				if url := safeMarkdownURL(url); url != "" {
+					b.WriteString(`<img src="` + html.EscapeString(url) + `" alt="` + html.EscapeString(text) + `">`)
+				} else {
+					b.WriteString(html.EscapeString(text))
+				}
+				i += n + 1
+				continue
+			}
+
+		case c == '[':
+			if text, url, n, ok := markdownLink(s[i:]); ok {
+				if url := safeMarkdownURL(url); url != "" {
+					b.WriteString(`<a href="` + html.EscapeString(url) + `">` + renderMarkdownInline(text) + "</a>")
+				} else {
+					b.WriteString(renderMarkdownInline(text))
+				}
+				i += n
+				continue
+			}
+
+		case c == '<':
+			if end := strings.IndexByte(s[i:], '>'); end > 0 {
+				if url := safeMarkdownURL(s[i+1 : i+end]); url != "" && !strings.ContainsAny(url, " \n") {
+					b.WriteString(`<a href="` + html.EscapeString(url) + `">` + html.EscapeString(url) + "</a>")
+					i += end + 1
+					continue
+				}
+			}
+
+		case c == '*' || c == '_':
+			delim := string(c)
+			if strings.HasPrefix(s[i:], delim+delim) {
+				delim += delim
+			}
+
+			// Intraword underscores are literal, as in snake_case
+			intraword := c == '_' && i > 0 && isMarkdownWordChar(s[i-1])
+
+			if !intraword && i+len(delim) < len(s) && s[i+len(delim)] != ' ' {
+				if end := markdownCloseDelim(s[i+len(delim):], delim); end > 0 {
+					tag := "em"
+					if len(delim) == 2 {
+						tag = "strong"
+					}
+
+					inner := s[i+len(delim) : i+len(delim)+end]
+					b.WriteString("<" + tag + ">" + renderMarkdownInline(inner) + "</" + tag + ">")
+					i += end + 2*len(delim)
+					continue
+				}
+			}
+
+			b.WriteString(delim)
+			i += len(delim)
+			continue
+		}
+
+		b.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+
+	return b.String()
+}
+
+// markdownLink parses a link of the form "[text](url)" at the start of s,
+// returning the text, url and length of the link.
+func markdownLink(s string) (text, url string, n int, ok bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth > 0 {
+				continue
+			}
+
+			if i+1 >= len(s) || s[i+1] != '(' {
+				return "", "", 0, false
+			}
+
+			end := strings.IndexByte(s[i+2:], ')')
+			if end < 0 {
+				return "", "", 0, false
+			}
+
+			// Discard any link title
+			url = strings.TrimSpace(s[i+2 : i+2+end])
+			if sp := strings.IndexAny(url, " \t\n"); sp >= 0 {
+				url = url[:sp]
+			}
+
+			return s[1:i], strings.Trim(url, "<>"), i + 3 + end, true
+		}
+	}
+	return "", "", 0, false
+}
+
+// markdownCloseDelim returns the index of the emphasis delimiter closing the
+// span at the start of s, or -1 if it is not closed.
+func markdownCloseDelim(s, delim string) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				i += end + 1
+			}
+		case i > 0 && strings.HasPrefix(s[i:], delim) && s[i-1] != ' ':
+			// A single delimiter must not be the start of a double delimiter
+			if len(delim) == 1 && strings.HasPrefix(s[i+1:], delim) {
+				i++
+				continue
+			}
+			if delim[0] == '_' && i+len(delim) < len(s) && isMarkdownWordChar(s[i+len(delim)]) {
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// isMarkdownWordChar returns true if c is an ASCII letter or digit.
+func isMarkdownWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// safeMarkdownURL returns url if it uses the http, https or mailto scheme, or an
+// empty string otherwise.
+func safeMarkdownURL(url string) string {
+	lower := strings.ToLower(url)
+	for _, scheme := range []string{"http://", "https://", "mailto:"} {
+		if strings.HasPrefix(lower, scheme) {
+			return url
+		}
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package mailyak
+
+import "testing"
+
+// TestMarkdownToHTML ensures Markdown is rendered to the expected HTML.
+func TestMarkdownToHTML(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		src string
+		// Want
+		want string
+	}{
+		{
+			"Paragraphs",
+			"One\ntwo\n\nThree",
+			"<p>One\ntwo</p>\n<p>Three</p>\n",
+		},
+		{
+			"Line break",
+			"One  \ntwo",
+			"<p>One  <br>\ntwo</p>\n",
+		},
+		{
+			"Headings",
+			"# Title\n## Sub ##\nText",
+			"<h1>Title</h1>\n<h2>Sub</h2>\n<p>Text</p>\n",
+		},
+		{
+			"Emphasis",
+			"*em* _em_ **strong** __strong__ snake_case_name 2 * 3",
+			"<p><em>em</em> <em>em</em> <strong>strong</strong> <strong>strong</strong> snake_case_name 2 * 3</p>\n",
+		},
+		{
+			"Nested emphasis",
+			"**bold *and* italic**",
+			"<p><strong>bold <em>and</em> italic</strong></p>\n",
+		},
+		{
+			"Code",
+			"Run `a < b` now\n\n```go\nif a < b {\n}\n```",
+			"<p>Run <code>a &lt; b</code> now</p>\n<pre><code class=\"language-go\">if a &lt; b {\n}\n</code></pre>\n",
+		},
+		{
+			"Links",
+			"See [the *logs*](https://itsallbroken.com/?a=1&b=2 \"Logs\") or <https://itsallbroken.com>",
+			"<p>See <a href=\"https://itsallbroken.com/?a=1&amp;b=2\">the <em>logs</em></a> or <a href=\"https://itsallbroken.com\">https://itsallbroken.com</a></p>\n",
+		},
+		{
+			"Unsafe link",
+			"[click](javascript:alert(1))",
+			"<p>click)</p>\n",
+		},
+		{
+			"Image",
+			"![Logo](https://itsallbroken.com/logo.png)",
+			"<p><img src=\"https://itsallbroken.com/logo.png\" alt=\"Logo\"></p>\n",
+		},
+		{
+			"Lists",
+			"Items:\n- One\n- Two\n  continued\n\n1. First\n2. Second",
+			"<p>Items:</p>\n<ul>\n<li>One</li>\n<li>Two\ncontinued</li>\n</ul>\n<ol>\n<li>First</li>\n<li>Second</li>\n</ol>\n",
+		},
+		{
+			"Block quote",
+			"> Quoted\n> **text**\n\nAfter",
+			"<blockquote>\n<p>Quoted\n<strong>text</strong></p>\n</blockquote>\n<p>After</p>\n",
+		},
+		{
+			"Rule",
+			"One\n\n---\n\nTwo",
+			"<p>One</p>\n<hr>\n<p>Two</p>\n",
+		},
+		{
+			"Escaping",
+			"<script>alert(1)</script> \\*not em\\* & \"quotes\"",
+			"<p>&lt;script&gt;alert(1)&lt;/script&gt; *not em* &amp; &#34;quotes&#34;</p>\n",
+		},
+		{
+			"CRLF",
+			"One\r\n\r\nTwo",
+			"<p>One</p>\n<p>Two</p>\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := markdownToHTML(tt.src); got != tt.want {
+				t.Errorf("%q. markdownToHTML() = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMailYakBodyMarkdown ensures both body parts are set.
+func TestMailYakBodyMarkdown(t *testing.T) {
+	t.Parallel()
+
+	m := New("", nil)
+	m.BodyMarkdown("Hello **world**")
+
+	if got, want := m.HTML().String(), "<p>Hello <strong>world</strong></p>\n"; got != want {
+		t.Errorf("MailYak.BodyMarkdown() html = %q, want %q", got, want)
+	}
+	if got, want := m.Plain().String(), "Hello **world**"; got != want {
+		t.Errorf("MailYak.BodyMarkdown() plain = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,169 @@
+package mailyak
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// MboxTransport is a Transport appending emails to an mbox file, such as for
+// archiving sent emails into a mailbox readable by mutt or mailutils:
+//
+//	mail.Transport(mailyak.NewMboxTransport("/var/mail/sent"))
+//
+// Emails are written in the mboxrd format: each email starts with a "From "
+// line containing the envelope sender and delivery time, lines in the email
+// starting with "From " (after any number of '>') are escaped with a
+// preceding '>', and line endings are converted to LF.
+//
+// The file is locked with flock(2) while each email is appended, so it can be
+// shared with other processes using the same locking. File locking is not
+// supported on non-Unix platforms, where only appends made by the same
+// MboxTransport are serialised.
+type MboxTransport struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewMboxTransport returns an MboxTransport appending to the mbox file at path,
+// which is created when the first email is delivered if it does not exist.
+func NewMboxTransport(path string) *MboxTransport {
+	return &MboxTransport{path: path}
+}
+
+// Send appends msg to the mbox file.
+func (t *MboxTransport) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f, err := os.OpenFile(t.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("mailyak: mbox delivery: %w", err)
+	}
+	defer f.Close()
+
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("mailyak: locking mbox: %w", err)
+	}
+	defer unlockFile(f)
+
+	// Build the email in full before appending it to the file, so a failure
+	// to build it does not leave a partial email in the mbox
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if envelopeFrom == "" {
+		envelopeFrom = "MAILER-DAEMON"
+	}
+	fmt.Fprintf(buf, "From %s %s\n", envelopeFrom, time.Now().UTC().Format(time.ANSIC))
+
+	w := &mboxWriter{w: buf}
+	if _, err := msg.WriteTo(w); err != nil {
+		return err
+	}
+	w.Close()
+
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("mailyak: mbox delivery: %w", err)
+	}
+
+	return nil
+}
+
+// mboxFrom is the line prefix escaped in the mboxrd format.
+var mboxFrom = []byte("From ")
+
+// mboxWriter writes an email in the mboxrd format, escaping "From " lines and
+// converting CRLF line endings to LF.
+type mboxWriter struct {
+	w *bytes.Buffer
+
+	// prefix holds the start of the current line while it may match
+	// ">*From "
+	prefix []byte
+
+	// midLine is true once the current line is known not to need escaping
+	midLine bool
+
+	// cr is true when the last byte written was a '\r', which is dropped if
+	// followed by a '\n'
+	cr bool
+
+	// last is the last byte written to w
+	last byte
+}
+
+func (m *mboxWriter) Write(p []byte) (int, error) {
+	for _, c := range p {
+		if m.cr {
+			m.cr = false
+			if c != '\n' {
+				m.writeByte('\r')
+			}
+		}
+		if c == '\r' {
+			m.cr = true
+			continue
+		}
+		m.writeByte(c)
+	}
+	return len(p), nil
+}
+
+// writeByte writes c, escaping the line if it starts with ">*From ".
+func (m *mboxWriter) writeByte(c byte) {
+	if m.midLine {
+		m.put(c)
+		if c == '\n' {
+			m.midLine = false
+		}
+		return
+	}
+
+	m.prefix = append(m.prefix, c)
+
+	rest := bytes.TrimLeft(m.prefix, ">")
+	switch {
+	case bytes.Equal(rest, mboxFrom):
+		m.put('>')
+	case bytes.HasPrefix(mboxFrom, rest):
+		return
+	}
+
+	for _, b := range m.prefix {
+		m.put(b)
+	}
+	m.prefix = m.prefix[:0]
+	m.midLine = c != '\n'
+}
+
+// put writes c to the underlying writer.
+func (m *mboxWriter) put(c byte) {
+	m.w.WriteByte(c)
+	m.last = c
+}
+
+// Close writes any buffered data, terminating the email with a blank line.
+func (m *mboxWriter) Close() {
+	if m.cr {
+		m.cr = false
+		m.writeByte('\r')
+	}
+	for _, b := range m.prefix {
+		m.put(b)
+	}
+	m.prefix = m.prefix[:0]
+
+	if m.last != '\n' && m.last != 0 {
+		m.put('\n')
+	}
+	m.put('\n')
+}
@@ -0,0 +1,142 @@
+package mailyak
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestMboxWriter ensures emails are written in the mboxrd format.
+func TestMboxWriter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		writes []string
+		// Want
+		want string
+	}{
+		{
+			"CRLF",
+			[]string{"Subject: Hi\r\n\r\nHello\r\n"},
+			"Subject: Hi\n\nHello\n\n",
+		},
+		{
+			"CRLF split across writes",
+			[]string{"Hello\r", "\nWorld\r", "\n"},
+			"Hello\nWorld\n\n",
+		},
+		{
+			"Bare CR",
+			[]string{"a\rb\r"},
+			"a\rb\r\n\n",
+		},
+		{
+			"No trailing newline",
+			[]string{"Hello"},
+			"Hello\n\n",
+		},
+		{
+			"From line",
+			[]string{"Hi\r\nFrom here\r\n>From there\r\n>>From everywhere\r\n"},
+			"Hi\n>From here\n>>From there\n>>>From everywhere\n\n",
+		},
+		{
+			"From line split across writes",
+			[]string{"Hi\r\n>Fr", "om here\r\n"},
+			"Hi\n>>From here\n\n",
+		},
+		{
+			"Not a From line",
+			[]string{"Fromage\r\nFrom\r\n> From\r\nSee From here\r\nFrom"},
+			"Fromage\nFrom\n> From\nSee From here\nFrom\n\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w := &mboxWriter{w: &buf}
+			for _, s := range tt.writes {
+				if _, err := w.Write([]byte(s)); err != nil {
+					t.Fatalf("mboxWriter.Write() error = %v", err)
+				}
+			}
+			w.Close()
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("mboxWriter = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMboxTransport ensures emails are appended to the mbox file.
+func TestMboxTransport(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sent")
+	transport := NewMboxTransport(path)
+
+	for _, body := range []string{"First", "From the second"} {
+		mail := New("", nil)
+		mail.Transport(transport)
+		mail.From("from@example.org")
+		mail.To("to@example.org")
+		mail.Plain().Set(body)
+
+		if _, _, err := mail.Send("localhost"); err != nil {
+			t.Fatalf("MailYak.Send() error = %v", err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromLine := regexp.MustCompile(`(?m)^From from@example\.org \w{3} \w{3} [ \d]\d \d{2}:\d{2}:\d{2} \d{4}$`)
+	if n := len(fromLine.FindAll(data, -1)); n != 2 {
+		t.Errorf("MboxTransport.Send() wrote %d From lines, want 2:\n%s", n, data)
+	}
+	if bytes.Contains(data, []byte("\r")) {
+		t.Errorf("MboxTransport.Send() wrote CRLF line endings:\n%s", data)
+	}
+	if !strings.Contains(string(data), "\n>From the second\n") {
+		t.Errorf("MboxTransport.Send() did not escape the From line:\n%s", data)
+	}
+	if !bytes.HasSuffix(data, []byte("\n\n")) {
+		t.Errorf("MboxTransport.Send() did not terminate the email with a blank line:\n%s", data)
+	}
+}
+
+// TestMboxTransport_cancelled ensures nothing is appended once the context is
+// cancelled.
+func TestMboxTransport_cancelled(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sent")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mail := New("", nil)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+
+	if err := NewMboxTransport(path).Send(ctx, "from@example.org", mail.recipients(), mail); err != context.Canceled {
+		t.Fatalf("MboxTransport.Send() error = %v, want %v", err, context.Canceled)
+	}
+
+	if _, err := ioutil.ReadFile(path); err == nil {
+		t.Errorf("MboxTransport.Send() created the mbox file")
+	}
+}
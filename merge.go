@@ -0,0 +1,276 @@
+package mailyak
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"sync"
+	texttemplate "text/template"
+)
+
+// MergeRecipient is a recipient of a mail merge, with the data used to render
+// their personalised email.
+type MergeRecipient struct {
+	// Addr is the recipient address, optionally including a display name.
+	Addr string
+
+	// Data is passed to the subject and body templates when rendering the
+	// email sent to Addr.
+	Data map[string]interface{}
+}
+
+// MergeError is returned by Merge.Send when the email to one or more
+// recipients could not be rendered or delivered.
+type MergeError struct {
+	// Errs holds the error for each recipient, in the same order as the
+	// recipients passed to NewMerge, with a nil entry for each email delivered
+	// successfully.
+	Errs []error
+}
+
+// Error implements the error interface.
+func (e *MergeError) Error() string {
+	failed := failedErrs(e.Errs)
+	if len(failed) == 0 {
+		return "mailyak: no recipients failed"
+	}
+	return fmt.Sprintf("mailyak: %d of %d recipients failed: %v", len(failed), len(e.Errs), failed[0])
+}
+
+// Unwrap returns the errors of the recipients that failed.
+func (e *MergeError) Unwrap() []error {
+	return failedErrs(e.Errs)
+}
+
+// Merge sends a personalised copy of a template email to each of a list of
+// recipients, rendering the subject and bodies of the template email with the
+// data of each recipient.
+//
+//	tmpl := mailyak.New("smtp.itsallbroken.com:587", auth)
+//	tmpl.From("news@itsallbroken.com")
+//	tmpl.Subject("Hello {{.Name}}")
+//	tmpl.HTML().Set("<p>Your balance is {{.Balance}}</p>")
+//
+//	merge := mailyak.NewMerge(tmpl, []mailyak.MergeRecipient{
+//		{Addr: "dom@itsallbroken.com", Data: map[string]interface{}{"Name": "Dom", "Balance": 42}},
+//	})
+//	merge.Concurrency(4)
+//	err := merge.Send(ctx, "localhost")
+//
+// The subject and plain-text body are rendered with text/template, and the
+// HTML body with html/template. Referencing a key missing from the data of a
+// recipient is an error.
+//
+// Each email is a clone of the template email with the To addresses and groups
+// replaced by the recipient, sharing the server, sender, Cc and Bcc addresses, headers and
+// attachments of the template email. Setting a Pool as the Transport of the
+// template email reuses connections between emails.
+type Merge struct {
+	template    *MailYak
+	recipients  []MergeRecipient
+	concurrency int
+	progress    func(done, total int)
+	rateLimiter *RateLimiter
+}
+
+// NewMerge returns a Merge sending template to each of recipients.
+func NewMerge(template *MailYak, recipients []MergeRecipient) *Merge {
+	return &Merge{
+		template:    template,
+		recipients:  recipients,
+		concurrency: 1,
+	}
+}
+
+// Concurrency sets the number of emails sent in parallel. Defaults to 1.
+func (mm *Merge) Concurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+	mm.concurrency = n
+}
+
+// Progress sets fn to be called after each email is sent (or fails), with the
+// number of recipients handled so far and the total number of recipients.
+//
+// Calls to fn are serialised, even when sending emails concurrently.
+func (mm *Merge) Progress(fn func(done, total int)) {
+	mm.progress = fn
+}
+
+// RateLimit sets the RateLimiter each email waits on before being sent,
+// overriding any set on the template email. Passing nil uses the RateLimiter
+// of the template email, if any.
+func (mm *Merge) RateLimit(l *RateLimiter) {
+	mm.rateLimiter = l
+}
+
+// mergeTemplates are the parsed templates of a template email.
+type mergeTemplates struct {
+	subject *texttemplate.Template
+	html    *htmltemplate.Template
+	plain   *texttemplate.Template
+}
+
+// Send renders and sends the email to each recipient, returning once all have
+// been handled.
+//
+// An error is returned before any email is sent if the template email cannot
+// be parsed. Otherwise, a failure to render or deliver the email to one
+// recipient does not prevent sending to the others - if any recipient fails, a
+// *MergeError is returned holding the error for each recipient.
+//
+// If ctx is done before every email has been sent, the remaining recipients
+// are not sent to and fail with the error of ctx.
+//
+// The content of each attachment of the template email is read once, and
+// shared by every email.
+func (mm *Merge) Send(ctx context.Context, localHostName string) error {
+	tmpl, err := mm.parse()
+	if err != nil {
+		return err
+	}
+
+	// Attachment content is read when sending, so buffer it to be shared by
+	// every email
+	attachments, err := mm.bufferAttachments()
+	if err != nil {
+		return err
+	}
+
+	var (
+		errs   = make([]error, len(mm.recipients))
+		next   = make(chan int)
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		done   int
+		failed bool
+	)
+
+	for i := 0; i < mm.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for idx := range next {
+				err := mm.send(ctx, localHostName, tmpl, attachments, mm.recipients[idx])
+
+				mu.Lock()
+				errs[idx] = err
+				failed = failed || err != nil
+				done++
+				if mm.progress != nil {
+					mm.progress(done, len(mm.recipients))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := range mm.recipients {
+		if ctx.Err() == nil {
+			select {
+			case next <- i:
+				continue
+			case <-ctx.Done():
+			}
+		}
+
+		// ctx is done, so fail the recipients not yet sent to
+		mu.Lock()
+		for j := i; j < len(mm.recipients); j++ {
+			errs[j] = fmt.Errorf("mailyak: %s: %w", mm.recipients[j].Addr, ctx.Err())
+		}
+		failed = true
+		mu.Unlock()
+		break
+	}
+	close(next)
+	wg.Wait()
+
+	if failed {
+		return &MergeError{Errs: errs}
+	}
+
+	return nil
+}
+
+// parse parses the subject and bodies of the template email.
+func (mm *Merge) parse() (*mergeTemplates, error) {
+	// The subject is stored encoded, so decode it before parsing
+	subject, err := new(mime.WordDecoder).DecodeHeader(mm.template.subject)
+	if err != nil {
+		return nil, err
+	}
+
+	var tmpl mergeTemplates
+
+	tmpl.subject, err = texttemplate.New("subject").Option("missingkey=error").Parse(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl.html, err = htmltemplate.New("html").Option("missingkey=error").Parse(mm.template.html.String())
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl.plain, err = texttemplate.New("plain").Option("missingkey=error").Parse(mm.template.plain.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &tmpl, nil
+}
+
+// bufferAttachments reads the content of each attachment of the template
+// email.
+func (mm *Merge) bufferAttachments() ([][]byte, error) {
+	buffers := make([][]byte, len(mm.template.attachments))
+	for i, a := range mm.template.attachments {
+		b, err := io.ReadAll(a.content)
+		if err != nil {
+			return nil, err
+		}
+		buffers[i] = b
+	}
+
+	return buffers, nil
+}
+
+// send renders and sends the email to r.
+func (mm *Merge) send(ctx context.Context, localHostName string, tmpl *mergeTemplates, attachments [][]byte, r MergeRecipient) error {
+	mail := mm.template.Clone()
+	mail.toGroups = nil
+	mail.To(r.Addr)
+	if mm.rateLimiter != nil {
+		mail.RateLimit(mm.rateLimiter)
+	}
+
+	for i := range mail.attachments {
+		mail.attachments[i].content = bytes.NewReader(attachments[i])
+	}
+
+	var subject bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, r.Data); err != nil {
+		return fmt.Errorf("mailyak: %s: %w", r.Addr, err)
+	}
+	mail.Subject(subject.String())
+
+	if err := mail.HTMLTemplate(tmpl.html, r.Data); err != nil {
+		return fmt.Errorf("mailyak: %s: %w", r.Addr, err)
+	}
+
+	if err := mail.PlainTemplate(tmpl.plain, r.Data); err != nil {
+		return fmt.Errorf("mailyak: %s: %w", r.Addr, err)
+	}
+
+	if _, _, err := mail.SendWithContext(ctx, localHostName); err != nil {
+		return fmt.Errorf("mailyak: %s: %w", r.Addr, err)
+	}
+
+	return nil
+}
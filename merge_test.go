@@ -0,0 +1,232 @@
+package mailyak
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mergeTransport records the emails delivered to each recipient, and is safe
+// for concurrent use.
+type mergeTransport struct {
+	mu   sync.Mutex
+	sent map[string]string
+}
+
+func (t *mergeTransport) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	var buf bytes.Buffer
+	if _, err := msg.WriteTo(&buf); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if rcpts[0] == "reject@example.org" {
+		return errors.New("rejected")
+	}
+	t.sent[strings.Join(rcpts, ",")] = buf.String()
+	return nil
+}
+
+// TestMergeSend ensures each recipient is sent a personalised email, and that
+// failures are reported per recipient.
+func TestMergeSend(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		recipients []MergeRecipient
+		// Want
+		wantSent []string
+		wantErrs []bool
+	}{
+		{
+			"OK",
+			[]MergeRecipient{
+				{Addr: "dom@example.org", Data: map[string]interface{}{"Name": "Dom"}},
+				{Addr: "alice@example.org", Data: map[string]interface{}{"Name": "Alice <3"}},
+				{Addr: "bob@example.org", Data: map[string]interface{}{"Name": "Bob"}},
+			},
+			[]string{"alice@example.org", "bob@example.org", "dom@example.org"},
+			[]bool{false, false, false},
+		},
+		{
+			"Failures",
+			[]MergeRecipient{
+				{Addr: "dom@example.org", Data: map[string]interface{}{"Name": "Dom"}},
+				{Addr: "reject@example.org", Data: map[string]interface{}{"Name": "Reject"}},
+				{Addr: "missing@example.org", Data: map[string]interface{}{}},
+			},
+			[]string{"dom@example.org"},
+			[]bool{false, true, true},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			transport := &mergeTransport{sent: map[string]string{}}
+
+			tmpl := New("", nil)
+			tmpl.From("from@example.org")
+			tmpl.Subject("Hello {{.Name}}")
+			tmpl.HTML().Set("<p>Hi {{.Name}}</p>")
+			tmpl.Plain().Set("Hi {{.Name}}")
+			tmpl.ToGroup("Team", "team@example.org")
+			tmpl.Attach("test.txt", strings.NewReader("attachment"))
+			tmpl.Transport(transport)
+
+			var progress []int
+			merge := NewMerge(tmpl, tt.recipients)
+			merge.Concurrency(2)
+			merge.Progress(func(done, total int) {
+				if total != len(tt.recipients) {
+					t.Errorf("%q. Merge.Send() progress total = %v, want %v", tt.name, total, len(tt.recipients))
+				}
+				progress = append(progress, done)
+			})
+
+			err := merge.Send(context.Background(), "localhost")
+
+			var gotErrs []bool
+			if err != nil {
+				mergeErr, ok := err.(*MergeError)
+				if !ok {
+					t.Fatalf("%q. Merge.Send() error = %v, want *MergeError", tt.name, err)
+				}
+				for _, err := range mergeErr.Errs {
+					gotErrs = append(gotErrs, err != nil)
+				}
+			} else {
+				gotErrs = make([]bool, len(tt.recipients))
+			}
+			for i := range gotErrs {
+				if gotErrs[i] != tt.wantErrs[i] {
+					t.Errorf("%q. Merge.Send() error for %v = %v, want %v", tt.name, tt.recipients[i].Addr, gotErrs[i], tt.wantErrs[i])
+				}
+			}
+
+			if len(progress) != len(tt.recipients) || progress[len(progress)-1] != len(tt.recipients) {
+				t.Errorf("%q. Merge.Send() progress = %v", tt.name, progress)
+			}
+
+			var sent []string
+			for addr := range transport.sent {
+				sent = append(sent, addr)
+			}
+			sort.Strings(sent)
+			if strings.Join(sent, ",") != strings.Join(tt.wantSent, ",") {
+				t.Fatalf("%q. Merge.Send() sent to %v, want %v", tt.name, sent, tt.wantSent)
+			}
+
+			for _, r := range tt.recipients {
+				data, ok := transport.sent[r.Addr]
+				if !ok {
+					continue
+				}
+
+				name := r.Data["Name"].(string)
+				for _, want := range []string{
+					"To: " + r.Addr,
+					"Subject: Hello " + name,
+					"Hi " + name,
+					"<p>Hi " + strings.Replace(name, "<", "&lt;", -1) + "</p>",
+					"YXR0YWNobWVudA==",
+				} {
+					if !strings.Contains(data, want) {
+						t.Errorf("%q. Merge.Send() email to %v missing %q", tt.name, r.Addr, want)
+					}
+				}
+				if strings.Contains(data, "Team:") {
+					t.Errorf("%q. Merge.Send() email to %v includes the template To group", tt.name, r.Addr)
+				}
+			}
+		})
+	}
+}
+
+// TestMergeSend_parseError ensures invalid templates are reported before any
+// email is sent.
+func TestMergeSend_parseError(t *testing.T) {
+	t.Parallel()
+
+	transport := &mergeTransport{sent: map[string]string{}}
+
+	tmpl := New("", nil)
+	tmpl.Subject("Hello {{.Name")
+	tmpl.Transport(transport)
+
+	merge := NewMerge(tmpl, []MergeRecipient{{Addr: "dom@example.org"}})
+	if err := merge.Send(context.Background(), "localhost"); err == nil {
+		t.Fatal("Merge.Send() error = nil, want parse error")
+	}
+	if len(transport.sent) != 0 {
+		t.Errorf("Merge.Send() sent %v emails, want 0", len(transport.sent))
+	}
+}
+
+// cancelTransport cancels the context of a Merge.Send on the first email it
+// delivers.
+type cancelTransport struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	sent   int
+}
+
+func (t *cancelTransport) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sent++
+	t.cancel()
+	return nil
+}
+
+// TestMergeSend_cancel ensures no further emails are sent once ctx is done,
+// and the remaining recipients fail with the error of ctx.
+func TestMergeSend_cancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	transport := &cancelTransport{cancel: cancel}
+
+	tmpl := New("", nil)
+	tmpl.From("from@example.org")
+	tmpl.Transport(transport)
+
+	merge := NewMerge(tmpl, []MergeRecipient{
+		{Addr: "dom@example.org"},
+		{Addr: "alice@example.org"},
+		{Addr: "bob@example.org"},
+	})
+
+	err := merge.Send(ctx, "localhost")
+
+	mergeErr, ok := err.(*MergeError)
+	if !ok {
+		t.Fatalf("Merge.Send() error = %v, want *MergeError", err)
+	}
+	if mergeErr.Errs[0] != nil {
+		t.Errorf("Merge.Send() error for dom@example.org = %v, want nil", mergeErr.Errs[0])
+	}
+	for _, err := range mergeErr.Errs[1:] {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Merge.Send() error = %v, want %v", err, context.Canceled)
+		}
+	}
+
+	if transport.sent != 1 {
+		t.Errorf("Merge.Send() sent %v emails, want 1", transport.sent)
+	}
+}
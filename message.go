@@ -0,0 +1,148 @@
+package mailyak
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// normaliseCRLF replaces any bare LF or CR line endings in msg with CRLF.
+func normaliseCRLF(msg []byte) []byte {
+	var buf bytes.Buffer
+	writeCRLF(&buf, msg)
+	return buf.Bytes()
+}
+
+// writeCRLF writes msg to buf, replacing any bare LF or CR line endings with
+// CRLF.
+func writeCRLF(buf *bytes.Buffer, msg []byte) {
+	buf.Grow(len(msg))
+
+	for i := 0; i < len(msg); i++ {
+		switch c := msg[i]; {
+		case c == '\r' && i+1 < len(msg) && msg[i+1] == '\n':
+			buf.WriteString("\r\n")
+			i++
+		case c == '\r' || c == '\n':
+			buf.WriteString("\r\n")
+		default:
+			buf.WriteByte(c)
+		}
+	}
+}
+
+// maxPooledBufferSize is the capacity above which buffers are not returned to
+// bufferPool, preventing the pool retaining the memory of unusually large
+// emails.
+const maxPooledBufferSize = 4 << 20
+
+// bufferPool holds buffers for reuse when building emails, reducing the
+// allocations made by services sending many emails.
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// getBuffer returns an empty buffer from bufferPool.
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer returns buf to bufferPool. buf, and any slices of its contents,
+// must not be used afterwards.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// splitMessage splits msg into the header section (including the final CRLF of
+// the last header) and the body.
+func splitMessage(msg []byte) ([]byte, []byte) {
+	if bytes.HasPrefix(msg, []byte("\r\n")) {
+		return nil, msg[2:]
+	}
+
+	i := bytes.Index(msg, []byte("\r\n\r\n"))
+	if i < 0 {
+		return msg, nil
+	}
+
+	return msg[:i+2], msg[i+4:]
+}
+
+// splitHeaderFields returns the raw header fields (including folded lines and
+// the terminating CRLF) in headers, in order.
+func splitHeaderFields(headers []byte) []string {
+	var fields []string
+
+	for _, line := range strings.SplitAfter(string(headers), "\r\n") {
+		if line == "" {
+			continue
+		}
+
+		// Continuation of a folded header
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1] += line
+			continue
+		}
+
+		fields = append(fields, line)
+	}
+
+	return fields
+}
+
+// headerFields returns all the raw header fields in headers with the given
+// name, in order.
+func headerFields(headers []byte, name string) []string {
+	var fields []string
+	for _, field := range splitHeaderFields(headers) {
+		if i := strings.IndexByte(field, ':'); i > 0 && strings.EqualFold(strings.TrimRight(field[:i], " \t"), name) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// splitEntity splits msg into the message headers, and the MIME entity formed
+// by the Content-* headers and the body. Line endings are normalised to CRLF.
+func splitEntity(msg []byte) (*bytes.Buffer, []byte) {
+	headers, body := splitMessage(normaliseCRLF(msg))
+
+	var outer, entity bytes.Buffer
+	for _, field := range splitHeaderFields(headers) {
+		if strings.HasPrefix(strings.ToLower(field), "content-") {
+			entity.WriteString(field)
+			continue
+		}
+		outer.WriteString(field)
+	}
+	entity.WriteString("\r\n")
+	entity.Write(body)
+
+	return &outer, entity.Bytes()
+}
+
+// multipartSigned returns a multipart/signed entity (RFC 1847) containing
+// entity, followed by the signature part sigPart (including its headers).
+func multipartSigned(entity []byte, protocol, micalg string, sigPart []byte) ([]byte, error) {
+	boundary, err := randomBoundary(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Content-Type: multipart/signed; protocol=\"%s\"; micalg=%s;\r\n\tboundary=\"%s\"\r\n\r\n", protocol, micalg, boundary)
+	fmt.Fprintf(&buf, "--%s\r\n", boundary)
+	buf.Write(entity)
+	fmt.Fprintf(&buf, "\r\n--%s\r\n", boundary)
+	buf.Write(sigPart)
+	fmt.Fprintf(&buf, "\r\n--%s--\r\n", boundary)
+
+	return buf.Bytes(), nil
+}
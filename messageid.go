@@ -0,0 +1,85 @@
+package mailyak
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// MessageIDDomain sets the domain used in the right hand side of generated
+// Message-ID headers, such as "itsallbroken.com" in:
+//
+//	Message-ID: <1694012345678901234.8c3f5a0e7d21b9a64c05e1f2@itsallbroken.com>
+//
+// If unset, the domain of the From address is used.
+func (m *MailYak) MessageIDDomain(domain string) {
+	m.messageIDDomain = m.trimRegex.ReplaceAllString(domain, "")
+	m.messageID = ""
+}
+
+// GetMessageID returns the Message-ID of the email, including the enclosing
+// angle brackets, generating it if necessary.
+//
+// A new Message-ID is generated each time the email is built, such as by Send
+// or WriteTo, so an email sent again to different recipients is given a unique
+// Message-ID. Once read, the Message-ID is kept for the next build, so it can
+// be recorded before sending, and after sending GetMessageID returns the
+// Message-ID the email was sent with, allowing applications to correlate sent
+// emails with any bounces or replies. The Message-ID of an email read with
+// Parse is kept for every build.
+func (m *MailYak) GetMessageID() string {
+	id := m.messageIDValue()
+	m.messageIDBuilt = false
+	return id
+}
+
+// messageIDValue returns the current Message-ID, generating it if necessary.
+func (m *MailYak) messageIDValue() string {
+	if m.messageID == "" {
+		m.messageID = newMessageID(m.messageIDHost(), m.now(), m.randomSource())
+		m.messageIDBuilt = false
+		m.messageIDFixed = false
+	}
+	return m.messageID
+}
+
+// buildMessageID sets the Message-ID for a new build of the email, generating
+// a new one if the current Message-ID has already been built and not read
+// since.
+func (m *MailYak) buildMessageID() {
+	if m.messageIDBuilt && !m.messageIDFixed {
+		m.messageID = ""
+	}
+	m.messageIDValue()
+	m.messageIDBuilt = true
+}
+
+// messageIDHost returns the domain used to generate the Message-ID.
+func (m *MailYak) messageIDHost() string {
+	domain := m.messageIDDomain
+	if domain == "" {
+		if i := strings.LastIndexByte(m.fromAddr, '@'); i >= 0 {
+			domain = strings.TrimSuffix(m.fromAddr[i+1:], ">")
+		}
+	}
+
+	// the Message-ID must be ASCII
+	domain, err := toASCII(domain)
+	if err != nil || domain == "" {
+		return "localhost"
+	}
+
+	return domain
+}
+
+// newMessageID returns a unique RFC 5322 msg-id for domain, made up of now and
+// 96 random bits read from r.
+func newMessageID(domain string, now time.Time, r io.Reader) string {
+	b := make([]byte, 12)
+
+	// reading from the random sources never returns an error
+	io.ReadFull(r, b)
+
+	return fmt.Sprintf("<%d.%x@%s>", now.UnixNano(), b, domain)
+}
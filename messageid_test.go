@@ -0,0 +1,188 @@
+package mailyak
+
+import (
+	"crypto/rand"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMailYakGetMessageID ensures a valid Message-ID is generated using the
+// configured domain, and is stable once generated
+func TestMailYakGetMessageID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		rfromAddr        string
+		rmessageIDDomain string
+		// Want
+		wantDomain string
+	}{
+		{
+			"From address",
+			"dom@itsallbroken.com",
+			"",
+			"itsallbroken.com",
+		},
+		{
+			"Configured domain",
+			"dom@itsallbroken.com",
+			"mail.itsallbroken.com",
+			"mail.itsallbroken.com",
+		},
+		{
+			"Internationalized domain",
+			"dom@bücher.example",
+			"",
+			"xn--bcher-kva.example",
+		},
+		{
+			"No domain",
+			"",
+			"",
+			"localhost",
+		},
+	}
+
+	valid := regexp.MustCompile(`^<[0-9]+\.[0-9a-f]{24}@([^<>@\s]+)>$`)
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.From(tt.rfromAddr)
+			if tt.rmessageIDDomain != "" {
+				m.MessageIDDomain(tt.rmessageIDDomain)
+			}
+
+			id := m.GetMessageID()
+
+			match := valid.FindStringSubmatch(id)
+			if match == nil {
+				t.Fatalf("%q. MailYak.GetMessageID() = %v, want valid msg-id", tt.name, id)
+			}
+			if match[1] != tt.wantDomain {
+				t.Errorf("%q. MailYak.GetMessageID() domain = %v, want %v", tt.name, match[1], tt.wantDomain)
+			}
+
+			if got := m.GetMessageID(); got != id {
+				t.Errorf("%q. MailYak.GetMessageID() = %v, want %v", tt.name, got, id)
+			}
+
+			buf, err := m.MimeBuf()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(buf.String(), "\r\nMessage-ID: "+id+"\r\n") {
+				t.Errorf("%q. MailYak.MimeBuf() = %v, want Message-ID %v", tt.name, buf, id)
+			}
+		})
+	}
+}
+
+// TestMailYakGetMessageID_rebuild ensures each build of the email has a new
+// Message-ID, unless it was read before building or set by Parse, and that
+// GetMessageID returns the Message-ID built.
+func TestMailYakGetMessageID_rebuild(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		mail func(t *testing.T) *MailYak
+		read bool
+		// Want
+		wantSame bool
+	}{
+		{
+			"Generated",
+			func(t *testing.T) *MailYak { return New("", nil) },
+			false,
+			false,
+		},
+		{
+			"Read before building",
+			func(t *testing.T) *MailYak { return New("", nil) },
+			true,
+			true,
+		},
+		{
+			"Parsed",
+			func(t *testing.T) *MailYak {
+				m, err := Parse(strings.NewReader("Message-ID: <1@example.org>\r\n\r\nHello"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return m
+			},
+			false,
+			true,
+		},
+	}
+
+	header := regexp.MustCompile("\r\nMessage-ID: (<[^>]+>)\r\n")
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := tt.mail(t)
+			m.From("from@example.org")
+
+			var ids []string
+			for i := 0; i < 2; i++ {
+				var read string
+				if tt.read {
+					read = m.GetMessageID()
+				}
+
+				m.To(fmt.Sprintf("to%d@example.org", i))
+
+				buf, err := m.MimeBuf()
+				if err != nil {
+					t.Fatal(err)
+				}
+				match := header.FindStringSubmatch(buf.String())
+				if match == nil {
+					t.Fatalf("%q. MailYak.MimeBuf() = %v, want Message-ID", tt.name, buf)
+				}
+
+				if read != "" && match[1] != read {
+					t.Errorf("%q. MailYak.MimeBuf() Message-ID = %v, want %v read before building", tt.name, match[1], read)
+				}
+				ids = append(ids, match[1])
+			}
+
+			if got := m.GetMessageID(); got != ids[1] {
+				t.Errorf("%q. MailYak.GetMessageID() = %v, want built %v", tt.name, got, ids[1])
+			}
+
+			if got := ids[0] == ids[1]; got != tt.wantSame {
+				t.Errorf("%q. MailYak.MimeBuf() Message-IDs = %v, want same %v", tt.name, ids, tt.wantSame)
+			}
+		})
+	}
+}
+
+// TestNewMessageID ensures generated Message-IDs are unique
+func TestNewMessageID(t *testing.T) {
+	t.Parallel()
+
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		id := newMessageID("itsallbroken.com", time.Now(), rand.Reader)
+		if seen[id] {
+			t.Fatalf("newMessageID() generated duplicate %v", id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,78 @@
+package mailyak
+
+import (
+	"time"
+)
+
+// Metrics records the outcome and performance of sending emails, allowing
+// sends to be monitored without MailYak depending on a metrics library.
+//
+// A Prometheus implementation can be written with a few lines:
+//
+//	type promMetrics struct {
+//		sent, failed, retried prometheus.Counter
+//		build, send           prometheus.Histogram
+//		bytes                 prometheus.Counter
+//	}
+//
+//	func (p *promMetrics) IncSent()                             { p.sent.Inc() }
+//	func (p *promMetrics) IncFailed(err error)                  { p.failed.Inc() }
+//	func (p *promMetrics) IncRetried(err error)                 { p.retried.Inc() }
+//	func (p *promMetrics) ObserveBuildDuration(d time.Duration) { p.build.Observe(d.Seconds()) }
+//	func (p *promMetrics) ObserveSendDuration(d time.Duration)  { p.send.Observe(d.Seconds()) }
+//	func (p *promMetrics) AddBytesWritten(n int64)              { p.bytes.Add(float64(n)) }
+//
+// Implementations are shared between the emails they are set on, and must be
+// safe for concurrent use.
+type Metrics interface {
+	// IncSent is called each time an email is sent successfully.
+	IncSent()
+
+	// IncFailed is called each time sending an email fails, with the error
+	// returned by Send.
+	IncFailed(err error)
+
+	// IncRetried is called each time a failed attempt to send an email is
+	// retried, with the error of the failed attempt.
+	IncRetried(err error)
+
+	// ObserveBuildDuration is called with the time taken to prepare the MIME
+	// message of an email before sending it.
+	ObserveBuildDuration(d time.Duration)
+
+	// ObserveSendDuration is called with the time taken to deliver an email,
+	// including any retries.
+	ObserveSendDuration(d time.Duration)
+
+	// AddBytesWritten is called with the size of the message data written to
+	// the SMTP server for each email.
+	AddBytesWritten(n int64)
+}
+
+// Metrics sets the Metrics used to record the sending of the email. Passing nil
+// disables recording.
+//
+// Clones of the email share the Metrics. When a Pool is used as the Transport,
+// the bytes written are recorded by the Metrics of the Pool configuration, and
+// emails sent with SendAll are recorded by the Metrics of the receiver.
+func (m *MailYak) Metrics(metrics Metrics) {
+	m.metrics = metrics
+}
+
+// recorder returns the Metrics of m, or a no-op Metrics if none is set.
+func (m *MailYak) recorder() Metrics {
+	if m.metrics == nil {
+		return noopMetrics{}
+	}
+	return m.metrics
+}
+
+// noopMetrics is a Metrics discarding everything recorded.
+type noopMetrics struct{}
+
+func (noopMetrics) IncSent()                             {}
+func (noopMetrics) IncFailed(err error)                  {}
+func (noopMetrics) IncRetried(err error)                 {}
+func (noopMetrics) ObserveBuildDuration(d time.Duration) {}
+func (noopMetrics) ObserveSendDuration(d time.Duration)  {}
+func (noopMetrics) AddBytesWritten(n int64)              {}
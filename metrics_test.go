@@ -0,0 +1,170 @@
+package mailyak
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMetrics is a Metrics recording the values observed.
+type recordingMetrics struct {
+	mu      sync.Mutex
+	sent    int
+	failed  int
+	retried int
+	builds  int
+	sends   int
+	bytes   int64
+}
+
+func (r *recordingMetrics) IncSent() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent++
+}
+
+func (r *recordingMetrics) IncFailed(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failed++
+}
+
+func (r *recordingMetrics) IncRetried(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retried++
+}
+
+func (r *recordingMetrics) ObserveBuildDuration(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.builds++
+}
+
+func (r *recordingMetrics) ObserveSendDuration(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sends++
+}
+
+func (r *recordingMetrics) AddBytesWritten(n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bytes += n
+}
+
+// TestMailYakMetrics ensures sends, failures, retries, durations and bytes
+// written are recorded.
+func TestMailYakMetrics(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		replies map[string]string
+		retry   *RetryPolicy
+		veto    bool
+		// Want
+		wantErr     bool
+		wantSent    int
+		wantFailed  int
+		wantRetried int
+		wantBuilds  int
+		wantSends   int
+		wantBytes   bool
+	}{
+		{
+			name:       "Sent",
+			wantSent:   1,
+			wantBuilds: 1,
+			wantSends:  1,
+			wantBytes:  true,
+		},
+		{
+			name:       "Rejected",
+			replies:    map[string]string{".": "554 Rejected"},
+			wantErr:    true,
+			wantFailed: 1,
+			wantBuilds: 1,
+			wantSends:  1,
+			wantBytes:  true,
+		},
+		{
+			name:        "Retried",
+			replies:     map[string]string{"RCPT": "451 Try again later"},
+			retry:       &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			wantErr:     true,
+			wantFailed:  1,
+			wantRetried: 2,
+			wantBuilds:  1,
+			wantSends:   1,
+		},
+		{
+			name:       "Vetoed",
+			veto:       true,
+			wantErr:    true,
+			wantFailed: 1,
+			wantBuilds: 1,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, tt.replies)
+			defer srv.Close()
+
+			metrics := &recordingMetrics{}
+
+			mail := New(srv.Addr(), nil)
+			mail.Metrics(metrics)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+			mail.Subject("Metrics")
+			mail.Plain().Set("Hello")
+			if tt.retry != nil {
+				mail.Retry(*tt.retry)
+			}
+			if tt.veto {
+				mail.OnBeforeSend(func(m *MailYak, envelopeFrom string, rcpts []string) error {
+					return errors.New("vetoed")
+				})
+			}
+
+			if _, _, err := mail.Send("localhost"); (err != nil) != tt.wantErr {
+				t.Fatalf("MailYak.Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			metrics.mu.Lock()
+			defer metrics.mu.Unlock()
+
+			if metrics.sent != tt.wantSent {
+				t.Errorf("sent = %d, want %d", metrics.sent, tt.wantSent)
+			}
+			if metrics.failed != tt.wantFailed {
+				t.Errorf("failed = %d, want %d", metrics.failed, tt.wantFailed)
+			}
+			if metrics.retried != tt.wantRetried {
+				t.Errorf("retried = %d, want %d", metrics.retried, tt.wantRetried)
+			}
+			if metrics.builds != tt.wantBuilds {
+				t.Errorf("build durations = %d, want %d", metrics.builds, tt.wantBuilds)
+			}
+			if metrics.sends != tt.wantSends {
+				t.Errorf("send durations = %d, want %d", metrics.sends, tt.wantSends)
+			}
+			if (metrics.bytes > 0) != tt.wantBytes {
+				t.Errorf("bytes written = %d, want > 0 %v", metrics.bytes, tt.wantBytes)
+			}
+		})
+	}
+}
@@ -2,36 +2,123 @@ package mailyak
 
 import (
 	"bytes"
-	"crypto/rand"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
-	"mime/quotedprintable"
+	"net/mail"
 	"net/textproto"
+	"sort"
+	"strings"
+	"time"
 )
 
+// buildMime returns the generated MIME data as a buffer, signed and/or
+// encrypted as configured.
 func (m *MailYak) buildMime() (*bytes.Buffer, error) {
-	mb, err := randomBoundary()
-	if err != nil {
+	if m.smime != nil && m.pgp != nil {
+		return nil, errors.New("mailyak: S/MIME and PGP cannot be combined")
+	}
+
+	if m.streamable() {
+		var buf bytes.Buffer
+		if err := m.writeMime(&buf); err != nil {
+			return nil, err
+		}
+		return &buf, nil
+	}
+
+	// The unsigned message is only needed until it has been signed or
+	// encrypted into a new buffer, so a pooled buffer is used
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if err := m.writeMime(buf); err != nil {
 		return nil, err
 	}
+	msg := buf.Bytes()
 
-	ab, err := randomBoundary()
-	if err != nil {
+	if m.smime != nil {
+		wrapped, err := m.smime.wrap(msg)
+		if err != nil {
+			return nil, err
+		}
+		msg = wrapped.Bytes()
+	}
+
+	if m.pgp != nil {
+		wrapped, err := m.pgp.wrap(msg)
+		if err != nil {
+			return nil, err
+		}
+		msg = wrapped.Bytes()
+	}
+
+	if m.dkim != nil {
+		signer := m.dkim
+		if m.deterministic {
+			copied := *m.dkim
+			copied.now = m.now
+			signer = &copied
+		}
+
+		signed, err := signer.sign(msg)
+		if err != nil {
+			return nil, err
+		}
+		msg = signed.Bytes()
+	}
+
+	// signing and encryption increase the size of the message
+	if err := m.checkSize(len(msg)); err != nil {
 		return nil, err
 	}
 
-	return m.buildMimeWithBoundaries(mb, ab)
+	return bytes.NewBuffer(msg), nil
+}
+
+// streamable returns true if the MIME data can be written directly to the
+// SMTP connection as it is generated, rather than buffered by buildMime for
+// signing or encryption.
+func (m *MailYak) streamable() bool {
+	return m.smime == nil && m.pgp == nil && m.dkim == nil
+}
+
+// writeMime writes the MIME message to w using randomly generated boundaries.
+//
+// Attachments are read as the message is written, and are not buffered.
+func (m *MailYak) writeMime(w io.Writer) error {
+	mb, ab, err := m.randomBoundaries()
+	if err != nil {
+		return err
+	}
+
+	return m.writeMimeWithBoundaries(m.limitSize(w), mb, ab)
+}
+
+// randomBoundaries returns the boundaries of the multipart/mixed and
+// multipart/alternative parts of the MIME message.
+func (m *MailYak) randomBoundaries() (mb, ab string, err error) {
+	r := m.randomSource()
+
+	if mb, err = randomBoundary(r); err != nil {
+		return "", "", err
+	}
+	if ab, err = randomBoundary(r); err != nil {
+		return "", "", err
+	}
+
+	return mb, ab, nil
 }
 
-// randomBoundary returns a random hexadecimal string used for separating MIME
-// parts.
+// randomBoundary returns a random hexadecimal string read from r used for
+// separating MIME parts.
 //
 // The returned string must be sufficiently random to prevent malicious users
 // from performing content injection attacks.
-func randomBoundary() (string, error) {
+func randomBoundary(r io.Reader) (string, error) {
 	buf := make([]byte, 30)
-	_, err := io.ReadFull(rand.Reader, buf)
+	_, err := io.ReadFull(r, buf)
 	if err != nil {
 		return "", err
 	}
@@ -42,79 +129,228 @@ func randomBoundary() (string, error) {
 // boundaries, and returns the generated MIME data as a buffer.
 func (m *MailYak) buildMimeWithBoundaries(mb, ab string) (*bytes.Buffer, error) {
 	var buf bytes.Buffer
-
-	if err := m.writeHeaders(&buf); err != nil {
+	if err := m.writeMimeWithBoundaries(&buf, mb, ab); err != nil {
 		return nil, err
 	}
+	return &buf, nil
+}
+
+// writeMimeWithBoundaries writes the MIME message to w using mb and ab as MIME
+// boundaries.
+func (m *MailYak) writeMimeWithBoundaries(w io.Writer, mb, ab string) error {
+	if err := m.writeHeaders(w); err != nil {
+		return err
+	}
 
 	// Start our multipart/mixed part
-	mixed := multipart.NewWriter(&buf)
+	mixed := multipart.NewWriter(w)
 	if err := mixed.SetBoundary(mb); err != nil {
-		return nil, err
+		return err
 	}
-	defer mixed.Close()
 
-	fmt.Fprintf(&buf, "Content-Type: multipart/mixed;\r\n\tboundary=\"%s\"; charset=UTF-8\r\n\r\n", mixed.Boundary())
+	if _, err := fmt.Fprintf(w, "Content-Type: multipart/mixed;\r\n\tboundary=\"%s\"; charset=UTF-8\r\n\r\n", mixed.Boundary()); err != nil {
+		return err
+	}
 
 	ctype := fmt.Sprintf("multipart/alternative;\r\n\tboundary=\"%s\"", ab)
 
 	altPart, err := mixed.CreatePart(textproto.MIMEHeader{"Content-Type": {ctype}})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if err := m.writeBody(altPart, ab); err != nil {
-		return nil, err
+		return err
 	}
 
 	if err := m.writeAttachments(mixed, lineSplitterBuilder{}); err != nil {
-		return nil, err
+		return err
 	}
 
-	return &buf, nil
+	return mixed.Close()
 }
 
-// writeHeaders writes the Mime-Version, Date, Reply-To, From, To and Subject headers,
-// plus any custom headers set via AddHeader().
+// writeHeaders writes the From, Sender, Mime-Version, Date, Message-ID,
+// Reply-To, Subject, To, CC, threading, List-Unsubscribe, read receipt and
+// priority headers, plus any custom headers set via AddHeader().
 func (m *MailYak) writeHeaders(buf io.Writer) error {
 
 	if _, err := buf.Write([]byte(m.fromHeader())); err != nil {
 		return err
 	}
 
+	if m.sender != "" {
+		fmt.Fprintf(buf, "Sender: %s\r\n", encodeAddress(m.sender))
+	}
+
 	if _, err := buf.Write([]byte("Mime-Version: 1.0\r\n")); err != nil {
 		return err
 	}
 
-	fmt.Fprintf(buf, "Date: %s\r\n", m.date)
+	fmt.Fprintf(buf, "Date: %s\r\n", m.dateHeader())
+	fmt.Fprintf(buf, "Message-ID: %s\r\n", m.messageIDValue())
 
 	if m.replyTo != "" {
-		fmt.Fprintf(buf, "Reply-To: %s\r\n", m.replyTo)
+		fmt.Fprintf(buf, "Reply-To: %s\r\n", encodeAddress(m.replyTo))
 	}
 
 	fmt.Fprintf(buf, "Subject: %s\r\n", m.subject)
 
-	for _, to := range m.toAddrs {
-		fmt.Fprintf(buf, "To: %s\r\n", to)
+	if m.inReplyTo != "" {
+		fmt.Fprintf(buf, "In-Reply-To: %s\r\n", m.inReplyTo)
+	}
+
+	if len(m.references) > 0 {
+		fmt.Fprintf(buf, "%s\r\n", foldHeader("References:", m.references))
+	}
+
+	if len(m.listUnsubscribe) > 0 {
+		targets := make([]string, len(m.listUnsubscribe))
+		for i, target := range m.listUnsubscribe {
+			if i < len(targets)-1 {
+				target += ","
+			}
+			targets[i] = target
+		}
+		fmt.Fprintf(buf, "%s\r\n", foldHeader("List-Unsubscribe:", targets))
+
+		if m.listUnsubscribePost {
+			fmt.Fprintf(buf, "List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n")
+		}
 	}
 
-	for _, cc := range m.ccAddrs {
-		fmt.Fprintf(buf, "CC: %s\r\n", cc)
+	toAddrs, ccAddrs, bccAddrs := m.headerRecipients()
+
+	// The group syntax is only valid within a single To header, so write the
+	// addresses alongside any groups
+	if len(m.toGroups) > 0 {
+		fmt.Fprintf(buf, "%s\r\n", toHeader(toAddrs, m.toGroups))
+	} else {
+		for _, to := range toAddrs {
+			fmt.Fprintf(buf, "To: %s\r\n", encodeAddress(to))
+		}
+	}
+
+	for _, cc := range ccAddrs {
+		fmt.Fprintf(buf, "CC: %s\r\n", encodeAddress(cc))
 	}
 
 	if m.writeBccHeader {
-		for _, bcc := range m.bccAddrs {
-			fmt.Fprintf(buf, "BCC: %s\r\n", bcc)
+		for _, bcc := range bccAddrs {
+			fmt.Fprintf(buf, "BCC: %s\r\n", encodeAddress(bcc))
 		}
 	}
 
-	for k, v := range m.headers {
-		fmt.Fprintf(buf, "%s: %s\r\n", k, v)
+	if m.readReceipt != "" {
+		addr := encodeAddress(m.readReceipt)
+		fmt.Fprintf(buf, "Disposition-Notification-To: %s\r\nReturn-Receipt-To: %s\r\n", addr, addr)
+	}
+
+	if err := m.writePriorityHeaders(buf); err != nil {
+		return err
+	}
+
+	if h := m.mailerHeader(); h != "" {
+		fmt.Fprint(buf, h)
+	}
+
+	for _, k := range m.customHeaderNames() {
+		for _, v := range m.headerValues(k) {
+			fmt.Fprintf(buf, "%s: %s\r\n", k, v)
+		}
 	}
 
 	return nil
 }
 
+// customHeaderNames returns the names of the headers added with AddHeader and
+// SetHeader in the order they are written - those named by HeaderOrder first,
+// then in the order they were added.
+//
+// Any headers added directly to the map returned by GetHeaders follow in
+// alphabetical order.
+func (m *MailYak) customHeaderNames() []string {
+	names := make([]string, 0, len(m.headers))
+	seen := make(map[string]bool, len(m.headers))
+	add := func(name string) {
+		if _, ok := m.headers[name]; ok && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, want := range m.headerOrder {
+		for _, name := range m.headerNames {
+			if strings.EqualFold(name, want) {
+				add(name)
+			}
+		}
+	}
+
+	for _, name := range m.headerNames {
+		add(name)
+	}
+
+	var rest []string
+	for name := range m.headers {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(names, rest...)
+}
+
+// maxHeaderLineLen is the recommended maximum length of a header line,
+// excluding the CRLF, as defined in RFC 5322 section 2.1.1.
+const maxHeaderLineLen = 78
+
+// foldHeader returns the header name followed by the space separated values,
+// folded onto continuation lines to keep each line within maxHeaderLineLen
+// where possible.
+func foldHeader(name string, values []string) string {
+	var b strings.Builder
+	b.WriteString(name)
+
+	lineLen := len(name)
+	for i, v := range values {
+		if i > 0 && lineLen+1+len(v) > maxHeaderLineLen {
+			b.WriteString("\r\n")
+			lineLen = 0
+		}
+
+		b.WriteString(" ")
+		b.WriteString(v)
+		lineLen += 1 + len(v)
+	}
+
+	return b.String()
+}
+
+// encodeAddress returns addr with any non-ASCII display name encoded as an RFC
+// 2047 encoded-word, such as:
+//
+//	=?utf-8?q?J=C3=BCrgen_M=C3=BCller?= <jm@itsallbroken.com>
+//
+// addr is returned unchanged if it has no display name, or cannot be parsed.
+func encodeAddress(addr string) string {
+	a, err := mail.ParseAddress(addr)
+	if err != nil || isASCII(a.Name) {
+		return addr
+	}
+	return a.String()
+}
+
+// dateHeader returns the value of the Date header - the date set with Date, or
+// the current time.
+func (m *MailYak) dateHeader() string {
+	if m.date != "" {
+		return m.date
+	}
+	return m.now().Format(time.RFC1123Z)
+}
+
 // fromHeader returns a correctly formatted From header, optionally with a name
 // component.
 func (m *MailYak) fromHeader() string {
@@ -122,10 +358,10 @@ func (m *MailYak) fromHeader() string {
 		return fmt.Sprintf("From: %s\r\n", m.fromAddr)
 	}
 
-	return fmt.Sprintf("From: %s <%s>\r\n", m.fromName, m.fromAddr)
+	return fmt.Sprintf("From: %s <%s>\r\n", quoteDisplayName(m.fromName), m.fromAddr)
 }
 
-// writeBody writes the text/plain and text/html mime parts.
+// writeBody writes the text/plain, text/html and text/calendar mime parts.
 func (m *MailYak) writeBody(w io.Writer, boundary string) error {
 	alt := multipart.NewWriter(w)
 	defer alt.Close()
@@ -135,29 +371,36 @@ func (m *MailYak) writeBody(w io.Writer, boundary string) error {
 	}
 
 	var err error
-	writePart := func(ctype string, data []byte) {
+	writePart := func(ctype string, data []byte, enc TransferEncoding) {
 		if len(data) == 0 || err != nil {
 			return
 		}
 
+		if enc == EncodingAuto {
+			enc = EncodingQuotedPrintable
+		}
+
 		c := fmt.Sprintf("%s; charset=UTF-8", ctype)
 
 		var part io.Writer
-		part, err = alt.CreatePart(textproto.MIMEHeader{"Content-Type": {c}, "Content-Transfer-Encoding": {"quoted-printable"}})
+		part, err = alt.CreatePart(textproto.MIMEHeader{"Content-Type": {c}, "Content-Transfer-Encoding": {string(enc)}})
 		if err != nil {
 			return
 		}
 
-		var buf bytes.Buffer
-		qpw := quotedprintable.NewWriter(&buf)
-		_, err = qpw.Write(data)
-		qpw.Close()
-
-		_, err = part.Write(buf.Bytes())
+		var encoder io.WriteCloser
+		if encoder, err = enc.encoder(part, true); err != nil {
+			return
+		}
+		if _, err = encoder.Write(data); err != nil {
+			return
+		}
+		err = encoder.Close()
 	}
 
-	writePart("text/plain", m.plain.Bytes())
-	writePart("text/html", m.html.Bytes())
+	writePart("text/plain", m.plainBody(), m.plain.encoding)
+	writePart("text/html", m.html.Bytes(), m.html.encoding)
+	writePart("text/calendar; method="+m.calendarMethod, m.calendar, EncodingAuto)
 
 	return err
 }
@@ -2,10 +2,13 @@ package mailyak
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"mime/multipart"
+	"reflect"
 	"regexp"
 	"strings"
 	"testing"
@@ -31,6 +34,12 @@ func TestMailYakFromHeader(t *testing.T) {
 			"Dom",
 			"From: Dom <dom@itsallbroken.com>\r\n",
 		},
+		{
+			"With quoted name",
+			"dom@itsallbroken.com",
+			"Smith, Dom",
+			"From: \"Smith, Dom\" <dom@itsallbroken.com>\r\n",
+		},
 		{
 			"Without name",
 			"dom@itsallbroken.com",
@@ -61,6 +70,101 @@ func TestMailYakFromHeader(t *testing.T) {
 	}
 }
 
+// TestEncodeAddress ensures non-ASCII display names are RFC 2047 encoded
+func TestEncodeAddress(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		addr string
+		// Want
+		want string
+	}{
+		{
+			"Address only",
+			"dom@itsallbroken.com",
+			"dom@itsallbroken.com",
+		},
+		{
+			"ASCII name",
+			"Dom <dom@itsallbroken.com>",
+			"Dom <dom@itsallbroken.com>",
+		},
+		{
+			"Non-ASCII name",
+			"Jürgen Müller <jm@itsallbroken.com>",
+			"=?utf-8?q?J=C3=BCrgen_M=C3=BCller?= <jm@itsallbroken.com>",
+		},
+		{
+			"Quoted non-ASCII name",
+			`"Müller, Jürgen" <jm@itsallbroken.com>`,
+			"=?utf-8?b?TcO8bGxlciwgSsO8cmdlbg==?= <jm@itsallbroken.com>",
+		},
+		{
+			"Invalid",
+			"Jürgen Müller",
+			"Jürgen Müller",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := encodeAddress(tt.addr); got != tt.want {
+				t.Errorf("%q. encodeAddress() = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFoldHeader ensures long headers are folded between values
+func TestFoldHeader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		values []string
+		// Want
+		want string
+	}{
+		{
+			"Single",
+			[]string{"<1@itsallbroken.com>"},
+			"References: <1@itsallbroken.com>",
+		},
+		{
+			"Fits on one line",
+			[]string{"<1@itsallbroken.com>", "<2@itsallbroken.com>", "<3@itsallbroken.com>"},
+			"References: <1@itsallbroken.com> <2@itsallbroken.com> <3@itsallbroken.com>",
+		},
+		{
+			"Folded",
+			[]string{"<1@itsallbroken.com>", "<2@itsallbroken.com>", "<3@itsallbroken.com>", "<4@itsallbroken.com>"},
+			"References: <1@itsallbroken.com> <2@itsallbroken.com> <3@itsallbroken.com>\r\n <4@itsallbroken.com>",
+		},
+		{
+			"Long value",
+			[]string{"<" + strings.Repeat("a", 80) + "@itsallbroken.com>", "<2@itsallbroken.com>"},
+			"References: <" + strings.Repeat("a", 80) + "@itsallbroken.com>\r\n <2@itsallbroken.com>",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := foldHeader("References:", tt.values); got != tt.want {
+				t.Errorf("%q. foldHeader() = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
 // TestMailYakWriteHeaders ensures the Mime-Version, Date, Reply-To, From, To and
 // Subject headers are correctly wrote
 func TestMailYakWriteHeaders(t *testing.T) {
@@ -88,7 +192,7 @@ func TestMailYakWriteHeaders(t *testing.T) {
 			"Test",
 			"help@itsallbroken.com",
 			true,
-			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nReply-To: help@itsallbroken.com\r\nSubject: Test\r\nTo: test@itsallbroken.com\r\n",
+			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nReply-To: help@itsallbroken.com\r\nSubject: Test\r\nTo: test@itsallbroken.com\r\n",
 		},
 		{
 			"No reply-to",
@@ -98,7 +202,7 @@ func TestMailYakWriteHeaders(t *testing.T) {
 			"",
 			"",
 			true,
-			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nSubject: \r\nTo: test@itsallbroken.com\r\n",
+			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nSubject: \r\nTo: test@itsallbroken.com\r\n",
 		},
 		{
 			"Multiple To addresses",
@@ -108,7 +212,7 @@ func TestMailYakWriteHeaders(t *testing.T) {
 			"",
 			"",
 			true,
-			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nSubject: \r\nTo: test@itsallbroken.com\r\nTo: repairs@itsallbroken.com\r\n",
+			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nSubject: \r\nTo: test@itsallbroken.com\r\nTo: repairs@itsallbroken.com\r\n",
 		},
 		{
 			"Single Cc address, Multiple To addresses",
@@ -118,7 +222,7 @@ func TestMailYakWriteHeaders(t *testing.T) {
 			"",
 			"",
 			true,
-			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nSubject: \r\nTo: test@itsallbroken.com\r\nTo: repairs@itsallbroken.com\r\nCC: cc@itsallbroken.com\r\n",
+			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nSubject: \r\nTo: test@itsallbroken.com\r\nTo: repairs@itsallbroken.com\r\nCC: cc@itsallbroken.com\r\n",
 		},
 		{
 			"Multiple Cc addresses, Multiple To addresses",
@@ -128,7 +232,7 @@ func TestMailYakWriteHeaders(t *testing.T) {
 			"",
 			"",
 			true,
-			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nSubject: \r\nTo: test@itsallbroken.com\r\nTo: repairs@itsallbroken.com\r\nCC: cc1@itsallbroken.com\r\nCC: cc2@itsallbroken.com\r\n",
+			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nSubject: \r\nTo: test@itsallbroken.com\r\nTo: repairs@itsallbroken.com\r\nCC: cc1@itsallbroken.com\r\nCC: cc2@itsallbroken.com\r\n",
 		},
 		{
 			"Single Bcc address, Multiple To addresses",
@@ -138,7 +242,7 @@ func TestMailYakWriteHeaders(t *testing.T) {
 			"",
 			"",
 			true,
-			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nSubject: \r\nTo: test@itsallbroken.com\r\nTo: repairs@itsallbroken.com\r\nBCC: bcc@itsallbroken.com\r\n",
+			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nSubject: \r\nTo: test@itsallbroken.com\r\nTo: repairs@itsallbroken.com\r\nBCC: bcc@itsallbroken.com\r\n",
 		},
 		{
 			"Multiple Bcc addresses, Multiple To addresses",
@@ -148,7 +252,7 @@ func TestMailYakWriteHeaders(t *testing.T) {
 			"",
 			"",
 			true,
-			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nSubject: \r\nTo: test@itsallbroken.com\r\nTo: repairs@itsallbroken.com\r\nBCC: bcc1@itsallbroken.com\r\nBCC: bcc2@itsallbroken.com\r\n",
+			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nSubject: \r\nTo: test@itsallbroken.com\r\nTo: repairs@itsallbroken.com\r\nBCC: bcc1@itsallbroken.com\r\nBCC: bcc2@itsallbroken.com\r\n",
 		},
 		{
 			"Multiple Bcc addresses, Multiple To addresses",
@@ -158,7 +262,17 @@ func TestMailYakWriteHeaders(t *testing.T) {
 			"",
 			"",
 			false,
-			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nSubject: \r\nTo: test@itsallbroken.com\r\nTo: repairs@itsallbroken.com\r\n",
+			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nSubject: \r\nTo: test@itsallbroken.com\r\nTo: repairs@itsallbroken.com\r\n",
+		},
+		{
+			"Non-ASCII display names",
+			[]string{"Jürgen Müller <jm@itsallbroken.com>"},
+			[]string{"Zoë <zoe@itsallbroken.com>"},
+			[]string{},
+			"",
+			"Hjälp <help@itsallbroken.com>",
+			true,
+			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nReply-To: =?utf-8?q?Hj=C3=A4lp?= <help@itsallbroken.com>\r\nSubject: \r\nTo: =?utf-8?q?J=C3=BCrgen_M=C3=BCller?= <jm@itsallbroken.com>\r\nCC: =?utf-8?q?Zo=C3=AB?= <zoe@itsallbroken.com>\r\n",
 		},
 		{
 			"All together now",
@@ -168,7 +282,7 @@ func TestMailYakWriteHeaders(t *testing.T) {
 			"",
 			"",
 			true,
-			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nSubject: \r\nTo: test@itsallbroken.com\r\nTo: repairs@itsallbroken.com\r\nCC: cc1@itsallbroken.com\r\nCC: cc2@itsallbroken.com\r\nBCC: bcc1@itsallbroken.com\r\nBCC: bcc2@itsallbroken.com\r\n",
+			"From: Dom <dom@itsallbroken.com>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nSubject: \r\nTo: test@itsallbroken.com\r\nTo: repairs@itsallbroken.com\r\nCC: cc1@itsallbroken.com\r\nCC: cc2@itsallbroken.com\r\nBCC: bcc1@itsallbroken.com\r\nBCC: bcc2@itsallbroken.com\r\n",
 		},
 	}
 	for _, tt := range tests {
@@ -186,6 +300,7 @@ func TestMailYakWriteHeaders(t *testing.T) {
 				bccAddrs:       tt.rbccAddrs,
 				writeBccHeader: tt.rwriteBccHeader,
 				date:           now,
+				messageID:      "<id@itsallbroken.com>",
 			}
 
 			buf := &bytes.Buffer{}
@@ -198,6 +313,117 @@ func TestMailYakWriteHeaders(t *testing.T) {
 	}
 }
 
+// TestMailYakWriteHeaders_threading ensures the In-Reply-To and References
+// headers are wrote
+func TestMailYakWriteHeaders_threading(t *testing.T) {
+	t.Parallel()
+
+	m := New("", nil)
+	m.InReplyTo("<3@itsallbroken.com>")
+	m.References("<1@itsallbroken.com>", "<2@itsallbroken.com>", "<3@itsallbroken.com>", "<4@itsallbroken.com>")
+
+	buf := &bytes.Buffer{}
+	if err := m.writeHeaders(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "In-Reply-To: <3@itsallbroken.com>\r\nReferences: <1@itsallbroken.com> <2@itsallbroken.com> <3@itsallbroken.com>\r\n <4@itsallbroken.com>\r\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("MailYak.writeHeaders() = %q, want %q", buf.String(), want)
+	}
+}
+
+// TestMailYakWriteHeaders_listUnsubscribe ensures the List-Unsubscribe and
+// List-Unsubscribe-Post headers are wrote
+func TestMailYakWriteHeaders_listUnsubscribe(t *testing.T) {
+	t.Parallel()
+
+	m := New("", nil)
+	if err := m.ListUnsubscribe("mailto:unsubscribe@itsallbroken.com?subject=unsubscribe", "https://itsallbroken.com/unsubscribe?id=1234"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := m.writeHeaders(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "List-Unsubscribe: <mailto:unsubscribe@itsallbroken.com?subject=unsubscribe>,\r\n <https://itsallbroken.com/unsubscribe?id=1234>\r\nList-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("MailYak.writeHeaders() = %q, want %q", buf.String(), want)
+	}
+}
+
+// TestMailYakWriteHeaders_readReceipt ensures the read receipt headers are
+// wrote
+func TestMailYakWriteHeaders_readReceipt(t *testing.T) {
+	t.Parallel()
+
+	m := New("", nil)
+	if err := m.RequestReadReceipt("Dom <dom@itsallbroken.com>"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := m.writeHeaders(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Disposition-Notification-To: Dom <dom@itsallbroken.com>\r\nReturn-Receipt-To: Dom <dom@itsallbroken.com>\r\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("MailYak.writeHeaders() = %q, want %q", buf.String(), want)
+	}
+}
+
+// TestMailYakWriteHeaders_sender ensures the Sender header is wrote after the
+// From header
+func TestMailYakWriteHeaders_sender(t *testing.T) {
+	t.Parallel()
+
+	m := New("", nil)
+	m.From("customer@example.org")
+	m.Sender("Mailer <mailer@itsallbroken.com>\r\n")
+
+	buf := &bytes.Buffer{}
+	if err := m.writeHeaders(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "From: customer@example.org\r\nSender: Mailer <mailer@itsallbroken.com>\r\n"
+	if !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("MailYak.writeHeaders() = %q, want prefix %q", buf.String(), want)
+	}
+}
+
+// TestMailYakWriteHeaders_date ensures the Date header defaults to the time
+// the email is built
+func TestMailYakWriteHeaders_date(t *testing.T) {
+	t.Parallel()
+
+	m := New("", nil)
+
+	before := time.Now().Truncate(time.Second)
+
+	buf := &bytes.Buffer{}
+	if err := m.writeHeaders(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	match := regexp.MustCompile("\r\nDate: ([^\r]+)\r\n").FindStringSubmatch(buf.String())
+	if match == nil {
+		t.Fatalf("MailYak.writeHeaders() = %q, want Date header", buf.String())
+	}
+
+	date, err := time.Parse(time.RFC1123Z, match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if date.Before(before) || date.After(time.Now()) {
+		t.Errorf("MailYak.writeHeaders() Date = %v, want build time", date)
+	}
+}
+
 // TestMailYakWriteBody ensures the correct MIME parts are wrote for the body
 func TestMailYakWriteBody(t *testing.T) {
 	t.Parallel()
@@ -246,6 +472,14 @@ func TestMailYakWriteBody(t *testing.T) {
 			"--t\r\nContent-Transfer-Encoding: quoted-printable\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\nPlain\r\n--t\r\nContent-Transfer-Encoding: quoted-printable\r\nContent-Type: text/html; charset=UTF-8\r\n\r\nHTML\r\n--t--\r\n",
 			false,
 		},
+		{
+			"8-bit characters",
+			"",
+			"Héllo wörld =",
+			"t",
+			"--t\r\nContent-Transfer-Encoding: quoted-printable\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\nH=C3=A9llo w=C3=B6rld =3D\r\n--t--\r\n",
+			false,
+		},
 		{
 			"Both with long lines",
 			"Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore et dolore magna aliqua. Ut enim ad minim veniam, quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat. Duis aute irure dolor in reprehenderit in voluptate velit esse cillum dolore eu fugiat nulla pariatur. Excepteur sint occaecat cupidatat non proident, sunt in culpa qui officia deserunt mollit anim id est laborum.",
@@ -276,6 +510,97 @@ func TestMailYakWriteBody(t *testing.T) {
 	}
 }
 
+// TestMailYakWriteBody_encoding ensures the body parts are written with the
+// selected transfer encoding.
+func TestMailYakWriteBody_encoding(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		rPlain   string
+		encoding TransferEncoding
+		// Expected results.
+		wantPart string
+		wantErr  error
+	}{
+		{
+			"Auto",
+			"Héllo",
+			EncodingAuto,
+			"Content-Transfer-Encoding: quoted-printable\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\nH=C3=A9llo\r\n",
+			nil,
+		},
+		{
+			"Base64",
+			"Héllo",
+			EncodingBase64,
+			"Content-Transfer-Encoding: base64\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\nSMOpbGxv\r\n",
+			nil,
+		},
+		{
+			"8bit",
+			"Héllo",
+			Encoding8Bit,
+			"Content-Transfer-Encoding: 8bit\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\nHéllo\r\n",
+			nil,
+		},
+		{
+			"7bit",
+			"Hello",
+			Encoding7Bit,
+			"Content-Transfer-Encoding: 7bit\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\nHello\r\n",
+			nil,
+		},
+		{
+			"7bit non-ASCII",
+			"Héllo",
+			Encoding7Bit,
+			"",
+			err7Bit,
+		},
+		{
+			"8bit line endings",
+			"Héllo\nWörld\r\n",
+			Encoding8Bit,
+			"Content-Transfer-Encoding: 8bit\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\nHéllo\r\nWörld\r\n\r\n",
+			nil,
+		},
+		{
+			"7bit line endings",
+			"Hello\nWorld",
+			Encoding7Bit,
+			"Content-Transfer-Encoding: 7bit\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\nHello\r\nWorld\r\n",
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := MailYak{}
+			m.Plain().Set(tt.rPlain)
+			m.Plain().Encoding(tt.encoding)
+
+			w := &bytes.Buffer{}
+			if err := m.writeBody(w, "t"); err != tt.wantErr {
+				t.Fatalf("%q. MailYak.writeBody() error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && !strings.Contains(w.String(), tt.wantPart) {
+				t.Errorf("%q. MailYak.writeBody() = %q, want part %q", tt.name, w.String(), tt.wantPart)
+			}
+
+			// The encoding is retained by clones
+			if got := m.Clone().plain.encoding; got != tt.encoding {
+				t.Errorf("%q. MailYak.Clone() encoding = %v, want %v", tt.name, got, tt.encoding)
+			}
+		})
+	}
+}
+
 // TestMailYakBuildMime tests all the other mime-related bits combine in a sane way
 func TestMailYakBuildMime(t *testing.T) {
 	t.Parallel()
@@ -305,7 +630,7 @@ func TestMailYakBuildMime(t *testing.T) {
 			"",
 			"",
 			"",
-			"From: \r\nMime-Version: 1.0\r\nDate: " + now + "\r\nSubject: \r\nTo: \r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n\r\n--alt--\r\n\r\n--mixed--\r\n",
+			"From: \r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nSubject: \r\nTo: \r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n\r\n--alt--\r\n\r\n--mixed--\r\n",
 			false,
 		},
 		{
@@ -317,7 +642,7 @@ func TestMailYakBuildMime(t *testing.T) {
 			"",
 			"",
 			"",
-			"From: \r\nMime-Version: 1.0\r\nDate: " + now + "\r\nSubject: \r\nTo: \r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n--alt\r\nContent-Transfer-Encoding: quoted-printable\r\nContent-Type: text/html; charset=UTF-8\r\n\r\nHTML\r\n--alt--\r\n\r\n--mixed--\r\n",
+			"From: \r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nSubject: \r\nTo: \r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n--alt\r\nContent-Transfer-Encoding: quoted-printable\r\nContent-Type: text/html; charset=UTF-8\r\n\r\nHTML\r\n--alt--\r\n\r\n--mixed--\r\n",
 			false,
 		},
 		{
@@ -329,7 +654,7 @@ func TestMailYakBuildMime(t *testing.T) {
 			"",
 			"",
 			"",
-			"From: \r\nMime-Version: 1.0\r\nDate: " + now + "\r\nSubject: \r\nTo: \r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n--alt\r\nContent-Transfer-Encoding: quoted-printable\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\nPlain\r\n--alt--\r\n\r\n--mixed--\r\n",
+			"From: \r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nSubject: \r\nTo: \r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n--alt\r\nContent-Transfer-Encoding: quoted-printable\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\nPlain\r\n--alt--\r\n\r\n--mixed--\r\n",
 			false,
 		},
 		{
@@ -341,7 +666,7 @@ func TestMailYakBuildMime(t *testing.T) {
 			"",
 			"",
 			"reply",
-			"From: \r\nMime-Version: 1.0\r\nDate: " + now + "\r\nReply-To: reply\r\nSubject: \r\nTo: \r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n\r\n--alt--\r\n\r\n--mixed--\r\n",
+			"From: \r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nReply-To: reply\r\nSubject: \r\nTo: \r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n\r\n--alt--\r\n\r\n--mixed--\r\n",
 			false,
 		},
 		{
@@ -353,7 +678,7 @@ func TestMailYakBuildMime(t *testing.T) {
 			"",
 			"name",
 			"",
-			"From: name <>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nSubject: \r\nTo: \r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n\r\n--alt--\r\n\r\n--mixed--\r\n",
+			"From: name <>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nSubject: \r\nTo: \r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n\r\n--alt--\r\n\r\n--mixed--\r\n",
 			false,
 		},
 		{
@@ -365,7 +690,7 @@ func TestMailYakBuildMime(t *testing.T) {
 			"addr",
 			"name",
 			"",
-			"From: name <addr>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nSubject: \r\nTo: \r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n\r\n--alt--\r\n\r\n--mixed--\r\n",
+			"From: name <addr>\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nSubject: \r\nTo: \r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n\r\n--alt--\r\n\r\n--mixed--\r\n",
 			false,
 		},
 		{
@@ -377,7 +702,7 @@ func TestMailYakBuildMime(t *testing.T) {
 			"from",
 			"",
 			"",
-			"From: from\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nSubject: \r\nTo: \r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n\r\n--alt--\r\n\r\n--mixed--\r\n",
+			"From: from\r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nSubject: \r\nTo: \r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n\r\n--alt--\r\n\r\n--mixed--\r\n",
 			false,
 		},
 		{
@@ -389,7 +714,7 @@ func TestMailYakBuildMime(t *testing.T) {
 			"",
 			"",
 			"",
-			"From: \r\nMime-Version: 1.0\r\nDate: " + now + "\r\nSubject: subject\r\nTo: \r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n\r\n--alt--\r\n\r\n--mixed--\r\n",
+			"From: \r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nSubject: subject\r\nTo: \r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n\r\n--alt--\r\n\r\n--mixed--\r\n",
 			false,
 		},
 		{
@@ -401,7 +726,7 @@ func TestMailYakBuildMime(t *testing.T) {
 			"",
 			"",
 			"",
-			"From: \r\nMime-Version: 1.0\r\nDate: " + now + "\r\nSubject: \r\nTo: one\r\nTo: two\r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n\r\n--alt--\r\n\r\n--mixed--\r\n",
+			"From: \r\nMime-Version: 1.0\r\nDate: " + now + "\r\nMessage-ID: <id@itsallbroken.com>\r\nSubject: \r\nTo: one\r\nTo: two\r\nContent-Type: multipart/mixed;\r\n\tboundary=\"mixed\"; charset=UTF-8\r\n\r\n--mixed\r\nContent-Type: multipart/alternative;\r\n\tboundary=\"alt\"\r\n\r\n\r\n--alt--\r\n\r\n--mixed--\r\n",
 			false,
 		},
 	}
@@ -421,6 +746,7 @@ func TestMailYakBuildMime(t *testing.T) {
 				replyTo:   tt.rreplyTo,
 				trimRegex: regex,
 				date:      now,
+				messageID: "<id@itsallbroken.com>",
 			}
 			m.HTML().Write(tt.rHTML)
 			m.Plain().Write(tt.rPlain)
@@ -480,7 +806,7 @@ func TestMailYakBuildMime_withAttachments(t *testing.T) {
 			"",
 			"",
 			[]attachment{
-				{"test.txt", strings.NewReader("content"), false, ""},
+				{"test.txt", strings.NewReader("content"), false, "", nil},
 			},
 			[]string{"Y29udGVudA=="},
 			false,
@@ -495,7 +821,7 @@ func TestMailYakBuildMime_withAttachments(t *testing.T) {
 			"",
 			"",
 			[]attachment{
-				{"test.txt", strings.NewReader("content"), true, ""},
+				{"test.txt", strings.NewReader("content"), true, "", nil},
 			},
 			[]string{"Y29udGVudA=="},
 			false,
@@ -510,8 +836,8 @@ func TestMailYakBuildMime_withAttachments(t *testing.T) {
 			"",
 			"",
 			[]attachment{
-				{"test.txt", strings.NewReader("content"), false, ""},
-				{"another.txt", strings.NewReader("another"), false, ""},
+				{"test.txt", strings.NewReader("content"), false, "", nil},
+				{"another.txt", strings.NewReader("another"), false, "", nil},
 			},
 			[]string{"Y29udGVudA==", "YW5vdGhlcg=="},
 			false,
@@ -526,8 +852,8 @@ func TestMailYakBuildMime_withAttachments(t *testing.T) {
 			"",
 			"",
 			[]attachment{
-				{"test.txt", strings.NewReader("content"), true, ""},
-				{"another.txt", strings.NewReader("another"), true, ""},
+				{"test.txt", strings.NewReader("content"), true, "", nil},
+				{"another.txt", strings.NewReader("another"), true, "", nil},
 			},
 			[]string{"Y29udGVudA==", "YW5vdGhlcg=="},
 			false,
@@ -613,3 +939,239 @@ func TestMailYakBuildMime_withAttachments(t *testing.T) {
 		})
 	}
 }
+
+// TestMailYakBuildMime_headerInjection ensures CR and LF characters in values
+// written to headers cannot inject additional headers.
+func TestMailYakBuildMime_headerInjection(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		setup func(m *MailYak)
+	}{
+		{"Subject", func(m *MailYak) { m.Subject("Hello\rBcc: evil@example.com") }},
+		{"FromName", func(m *MailYak) { m.FromName("Dom\r\nBcc: evil@example.com") }},
+		{"ReplyTo", func(m *MailYak) { m.ReplyTo("reply@example.org\nBcc: evil@example.com") }},
+		{"Sender", func(m *MailYak) { m.Sender("sender@example.org\rBcc: evil@example.com") }},
+		{"To", func(m *MailYak) { m.To("to@example.org\rBcc: evil@example.com") }},
+		{"AddHeader value", func(m *MailYak) { m.AddHeader("X-Test", "value\rBcc: evil@example.com") }},
+		{"AddHeader name", func(m *MailYak) { m.AddHeader("X-Test: value\r\nBcc", "evil@example.com") }},
+		{"Attachment filename", func(m *MailYak) {
+			m.Attach("report.pdf\r\nBcc: evil@example.com", strings.NewReader("data"))
+		}},
+		{"Inline attachment filename", func(m *MailYak) {
+			m.AttachInline("logo.png\rBcc: evil@example.com", strings.NewReader("data"))
+		}},
+		{"Attachment MIME type", func(m *MailYak) {
+			m.AttachWithMimeType("report.pdf", strings.NewReader("data"), "application/pdf\r\nBcc: evil@example.com")
+		}},
+		{"Attachment part header", func(m *MailYak) {
+			m.AttachPart("report.pdf", strings.NewReader("data"), map[string][]string{
+				"Content-Description": {"Report\r\nBcc: evil@example.com"},
+				"X-Evil\r\nBcc":       {"evil@example.com"},
+			})
+		}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.From("from@example.org")
+			tt.setup(m)
+
+			buf, err := m.buildMime()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, line := range strings.FieldsFunc(buf.String(), func(r rune) bool { return r == '\r' || r == '\n' }) {
+				if strings.HasPrefix(strings.ToLower(line), "bcc") {
+					t.Fatalf("MailYak.buildMime() injected header %q in:\n%s", line, buf.String())
+				}
+			}
+		})
+	}
+}
+
+// benchmarkMail returns an email with both body parts and an attachment, as
+// typically sent by services sending many similar emails.
+func benchmarkMail() *MailYak {
+	m := New("", nil)
+	m.From("from@example.org")
+	m.FromName("Sender")
+	m.To("to@example.org")
+	m.Subject("Your monthly report")
+	m.HTML().Set(strings.Repeat("<p>Report content for this month.</p>\n", 200))
+	m.Plain().Set(strings.Repeat("Report content for this month.\n", 200))
+	m.Attach("report.csv", bytes.NewReader(bytes.Repeat([]byte("a,b,c,1,2,3\n"), 4096)))
+	return m
+}
+
+func BenchmarkMailYakBuildMime(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		m := benchmarkMail()
+		if _, err := m.buildMime(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMailYakBuildMime_dkim(b *testing.B) {
+	b.ReportAllocs()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < b.N; i++ {
+		m := benchmarkMail()
+		if err := m.DKIM(DKIMOptions{Domain: "example.org", Selector: "s", Signer: key}); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := m.buildMime(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMailYakWriteTo(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		m := benchmarkMail()
+		if _, err := m.WriteTo(ioutil.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestMailYakDeduplicateRecipients ensures a mailbox set on more than one of the
+// To, Cc and Bcc addresses is written to the headers once when enabled.
+func TestMailYakDeduplicateRecipients(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		dedup bool
+		// Want
+		want []string
+	}{
+		{
+			"Disabled",
+			false,
+			[]string{
+				"To: Dom <dom@itsallbroken.com>",
+				"To: DOM@itsallbroken.com",
+				"CC: dom@itsallbroken.com",
+				"CC: cc@itsallbroken.com",
+				"BCC: cc@itsallbroken.com",
+			},
+		},
+		{
+			"Enabled",
+			true,
+			[]string{
+				"To: Dom <dom@itsallbroken.com>",
+				"CC: cc@itsallbroken.com",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.From("from@itsallbroken.com")
+			m.To("Dom <dom@itsallbroken.com>", "DOM@itsallbroken.com")
+			m.Cc("dom@itsallbroken.com", "cc@itsallbroken.com")
+			m.Bcc("cc@itsallbroken.com")
+			m.WriteBccHeader(true)
+			m.DeduplicateRecipients(tt.dedup)
+
+			var buf bytes.Buffer
+			if err := m.writeHeaders(&buf); err != nil {
+				t.Fatalf("MailYak.writeHeaders() error = %v", err)
+			}
+
+			var got []string
+			for _, line := range strings.Split(buf.String(), "\r\n") {
+				if strings.HasPrefix(line, "To: ") || strings.HasPrefix(line, "CC: ") || strings.HasPrefix(line, "BCC: ") {
+					got = append(got, line)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MailYak.writeHeaders() recipients = %q, want %q", got, tt.want)
+			}
+
+			if rcpt := m.recipients(); len(rcpt) != 2 {
+				t.Errorf("MailYak.recipients() = %q, want 2 addresses", rcpt)
+			}
+		})
+	}
+}
+
+// TestMailYakWriteHeaders_order ensures custom headers are written in the order
+// they were added, or the order set with HeaderOrder.
+func TestMailYakWriteHeaders_order(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		order []string
+		// Want
+		want []string
+	}{
+		{
+			"Insertion order",
+			nil,
+			[]string{"X-Zebra: 1", "Comments: one", "Comments: two", "X-Apple: 2", "X-Mango: 3"},
+		},
+		{
+			"Header order",
+			[]string{"x-mango", "Comments", "X-Unset"},
+			[]string{"X-Mango: 3", "Comments: one", "Comments: two", "X-Zebra: 1", "X-Apple: 2"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.AddHeader("X-Zebra", "1")
+			m.AddHeader("Comments", "one")
+			m.AddHeader("X-Apple", "removed")
+			m.AddHeader("X-Mango", "3")
+			m.AddHeader("Comments", "two")
+			m.SetHeader("X-Apple", "2")
+			m.HeaderOrder(tt.order...)
+
+			// Build repeatedly to catch any dependency on map iteration order
+			for i := 0; i < 10; i++ {
+				var buf bytes.Buffer
+				if err := m.writeHeaders(&buf); err != nil {
+					t.Fatalf("MailYak.writeHeaders() error = %v", err)
+				}
+
+				lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+				got := lines[len(lines)-len(tt.want):]
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("MailYak.writeHeaders() custom headers = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package mailyak
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrMTASTSMismatch is returned when connecting to an SMTP server that is not
+// permitted by the enforced MTA-STS policy of a recipient domain.
+var ErrMTASTSMismatch = errors.New("mailyak: server not permitted by MTA-STS policy")
+
+// MTA-STS policy modes (RFC 8461 section 3.2).
+const (
+	MTASTSModeEnforce = "enforce"
+	MTASTSModeTesting = "testing"
+	MTASTSModeNone    = "none"
+)
+
+const (
+	// maxMTASTSPolicySize is the maximum size of a policy file fetched, in
+	// bytes (RFC 8461 section 3.3).
+	maxMTASTSPolicySize = 64 << 10
+
+	// maxMTASTSMaxAge is the maximum permitted policy max_age (RFC 8461
+	// section 3.2).
+	maxMTASTSMaxAge = 31557600 * time.Second
+)
+
+// MTASTSPolicy is the MTA-STS policy of a domain (RFC 8461 section 3.2).
+type MTASTSPolicy struct {
+	// Mode is the policy mode - one of MTASTSModeEnforce, MTASTSModeTesting
+	// or MTASTSModeNone.
+	Mode string
+
+	// MX are the patterns of the MX hosts permitted to receive email for the
+	// domain, such as "mx.itsallbroken.com" or "*.itsallbroken.com".
+	MX []string
+
+	// MaxAge is how long the policy may be cached for.
+	MaxAge time.Duration
+}
+
+// Match returns true if host matches one of the MX patterns of p.
+//
+// A pattern beginning "*." matches hosts with exactly one additional label at
+// the start, so "*.itsallbroken.com" matches "mx.itsallbroken.com" but not
+// "itsallbroken.com" or "a.mx.itsallbroken.com".
+func (p *MTASTSPolicy) Match(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, mx := range p.MX {
+		mx = strings.ToLower(mx)
+		if wildcard := strings.TrimPrefix(mx, "*"); wildcard != mx {
+			if i := strings.IndexByte(host, '.'); i > 0 && host[i:] == wildcard {
+				return true
+			}
+			continue
+		}
+		if host == mx {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TXTResolver looks up DNS TXT records, as implemented by *net.Resolver.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// MTASTSClient fetches and caches the MTA-STS policies of recipient domains
+// (RFC 8461), for use with MailYak.MTASTS:
+//
+//	sts := mailyak.NewMTASTSClient()
+//
+//	mail := mailyak.New("mx.itsallbroken.com:25", nil)
+//	mail.MTASTS(sts)
+//
+// An MTASTSClient is safe for concurrent use, and should be shared between
+// emails so policies are cached.
+type MTASTSClient struct {
+	client   *http.Client
+	resolver TXTResolver
+
+	mu    sync.Mutex
+	cache map[string]cachedMTASTSPolicy
+}
+
+// cachedMTASTSPolicy is a policy cached by an MTASTSClient.
+type cachedMTASTSPolicy struct {
+	policy  *MTASTSPolicy
+	id      string
+	expires time.Time
+}
+
+// NewMTASTSClient returns an MTASTSClient looking up policies using
+// net.DefaultResolver and an HTTP client that does not follow redirects.
+func NewMTASTSClient() *MTASTSClient {
+	return &MTASTSClient{
+		client: &http.Client{
+			Timeout: time.Minute,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		resolver: net.DefaultResolver,
+		cache:    make(map[string]cachedMTASTSPolicy),
+	}
+}
+
+// HTTPClient sets the HTTP client used to fetch policies.
+//
+// RFC 8461 forbids following redirects when fetching a policy, so c should not
+// follow them.
+func (c *MTASTSClient) HTTPClient(client *http.Client) {
+	c.client = client
+}
+
+// Resolver sets the resolver used to look up the "_mta-sts" TXT record of a
+// domain. Defaults to net.DefaultResolver.
+func (c *MTASTSClient) Resolver(r TXTResolver) {
+	c.resolver = r
+}
+
+// Policy returns the MTA-STS policy of domain, or nil if it has no policy.
+//
+// A cached policy is returned until it expires or the policy ID published in
+// the "_mta-sts" TXT record of the domain changes. If the TXT record cannot be
+// found or the policy cannot be fetched, an unexpired cached policy is returned
+// instead.
+func (c *MTASTSClient) Policy(ctx context.Context, domain string) (*MTASTSPolicy, error) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	c.mu.Lock()
+	cached, ok := c.cache[domain]
+	c.mu.Unlock()
+
+	if ok && time.Now().After(cached.expires) {
+		ok = false
+	}
+
+	id, err := c.lookupID(ctx, domain)
+	if err != nil || id == "" {
+		if ok {
+			return cached.policy, nil
+		}
+		return nil, err
+	}
+
+	if ok && cached.id == id {
+		return cached.policy, nil
+	}
+
+	policy, err := c.fetch(ctx, domain)
+	if err != nil {
+		if ok {
+			return cached.policy, nil
+		}
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.cache[domain] = cachedMTASTSPolicy{
+		policy:  policy,
+		id:      id,
+		expires: time.Now().Add(policy.MaxAge),
+	}
+	c.mu.Unlock()
+
+	return policy, nil
+}
+
+// lookupID returns the policy ID published in the "_mta-sts" TXT record of
+// domain, or an empty string if the domain does not publish a policy.
+func (c *MTASTSClient) lookupID(ctx context.Context, domain string) (string, error) {
+	records, err := c.resolver.LookupTXT(ctx, "_mta-sts."+domain)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return "", nil
+		}
+		return "", fmt.Errorf("mailyak: MTA-STS lookup for %s failed: %w", domain, err)
+	}
+
+	// a domain must publish exactly one STSv1 record
+	var id string
+	for _, record := range records {
+		if !strings.HasPrefix(record, "v=STSv1;") {
+			continue
+		}
+		if id != "" {
+			return "", nil
+		}
+		id = parseMTASTSID(record)
+	}
+
+	return id, nil
+}
+
+// parseMTASTSID returns the id field of an MTA-STS TXT record.
+func parseMTASTSID(record string) string {
+	for _, field := range strings.Split(record, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if ok && key == "id" {
+			return value
+		}
+	}
+	return ""
+}
+
+// fetch fetches and parses the policy of domain over HTTPS.
+func (c *MTASTSClient) fetch(ctx context.Context, domain string) (*MTASTSPolicy, error) {
+	u := "https://mta-sts." + domain + "/.well-known/mta-sts.txt"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := c.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("mailyak: fetching MTA-STS policy for %s: %w", domain, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("mailyak: fetching MTA-STS policy for %s: unexpected status %s", domain, resp.Status)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/plain" {
+		return nil, fmt.Errorf("mailyak: fetching MTA-STS policy for %s: unexpected content type %q", domain, mediaType)
+	}
+
+	policy, err := parseMTASTSPolicy(io.LimitReader(resp.Body, maxMTASTSPolicySize))
+	if err != nil {
+		return nil, fmt.Errorf("mailyak: invalid MTA-STS policy for %s: %w", domain, err)
+	}
+	return policy, nil
+}
+
+// parseMTASTSPolicy parses the policy file read from r.
+func parseMTASTSPolicy(r io.Reader) (*MTASTSPolicy, error) {
+	var (
+		policy  MTASTSPolicy
+		version string
+		maxAge  = -1
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(key) {
+		case "version":
+			version = value
+		case "mode":
+			policy.Mode = value
+		case "mx":
+			policy.MX = append(policy.MX, value)
+		case "max_age":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid max_age %q", value)
+			}
+			maxAge = n
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case version != "STSv1":
+		return nil, fmt.Errorf("unsupported version %q", version)
+	case policy.Mode != MTASTSModeEnforce && policy.Mode != MTASTSModeTesting && policy.Mode != MTASTSModeNone:
+		return nil, fmt.Errorf("invalid mode %q", policy.Mode)
+	case maxAge < 0:
+		return nil, errors.New("missing max_age")
+	case len(policy.MX) == 0 && policy.Mode != MTASTSModeNone:
+		return nil, errors.New("missing mx")
+	}
+
+	policy.MaxAge = time.Duration(maxAge) * time.Second
+	if policy.MaxAge > maxMTASTSMaxAge {
+		policy.MaxAge = maxMTASTSMaxAge
+	}
+
+	return &policy, nil
+}
+
+// MTASTS causes the MTA-STS policies of the recipient domains to be fetched
+// using c and enforced when connecting to the SMTP server, as when delivering
+// directly to the MX host of the recipients. A nil c disables MTA-STS, the
+// default.
+//
+// When a recipient domain has a policy in "enforce" mode, the SMTP server must
+// match one of the MX patterns of the policy, or the connection fails with
+// ErrMTASTSMismatch. STARTTLS is then required, and the server certificate
+// must be valid for the server hostname. Policies in "testing" or "none" mode
+// are not enforced.
+//
+// When sending with a Pool, including through a Queue or SendAll, the
+// policies of the recipients of each email are enforced for the pooled
+// connection it is sent over, using the MTASTSClient of the email if set, or
+// that of the Pool configuration otherwise. A pooled connection is only
+// reused for recipients with an enforced policy if it was established with
+// STARTTLS required and the server certificate verified.
+//
+// MTA-STS does not apply to servers with DANE TLSA records (see DANE), or
+// those addressed by IP address or Unix socket.
+func (m *MailYak) MTASTS(c *MTASTSClient) {
+	m.mtaSTS = c
+}
+
+// mtaSTSCheck is the MTASTSClient fetching the policies of the recipients
+// rcpts, which the SMTP server delivered to must satisfy.
+type mtaSTSCheck struct {
+	client *MTASTSClient
+	rcpts  []string
+}
+
+// apply updates policy with the requirements of the enforced MTA-STS policies
+// of the recipient domains, returning ErrMTASTSMismatch if serverName is not
+// permitted by them.
+func (c mtaSTSCheck) apply(ctx context.Context, policy *tlsPolicy, serverName string) error {
+	if net.ParseIP(serverName) != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for _, addr := range c.rcpts {
+		at := strings.LastIndexByte(addr, '@')
+		if at < 0 {
+			continue
+		}
+
+		domain := strings.ToLower(addr[at+1:])
+		if ascii, err := toASCII(domain); err == nil {
+			domain = ascii
+		}
+		if seen[domain] {
+			continue
+		}
+		seen[domain] = true
+
+		sts, err := c.client.Policy(ctx, domain)
+		if err != nil || sts == nil || sts.Mode != MTASTSModeEnforce {
+			continue
+		}
+
+		if !sts.Match(serverName) {
+			return fmt.Errorf("%w: %s is not an MX host of %s", ErrMTASTSMismatch, serverName, domain)
+		}
+
+		policy.required = true
+		policy.config.ServerName = serverName
+		policy.config.InsecureSkipVerify = false
+	}
+
+	return nil
+}
+
+// satisfies returns true if sc may be used to deliver to the recipients of
+// sts - the server must be permitted by their enforced MTA-STS policies, and
+// sc must have been established with STARTTLS required and the server
+// certificate verified if any are enforced.
+func (sc *smtpConn) satisfies(ctx context.Context, sts mtaSTSCheck) bool {
+	network, _, serverName, err := splitHost(sc.host)
+	if err != nil {
+		return false
+	}
+	if sts.client == nil || network != "tcp" || sc.policy.dane {
+		return true
+	}
+
+	policy := tlsPolicy{config: &tls.Config{}}
+	if err := sts.apply(ctx, &policy, serverName); err != nil {
+		return false
+	}
+
+	return !policy.required || (sc.policy.required && !sc.policy.config.InsecureSkipVerify)
+}
@@ -0,0 +1,534 @@
+package mailyak
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testTXTResolver returns the TXT records of records, or a not found error.
+type testTXTResolver struct {
+	mu      sync.Mutex
+	records map[string][]string
+}
+
+func (r *testTXTResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	records, ok := r.records[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func (r *testTXTResolver) set(name string, records ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if records == nil {
+		delete(r.records, name)
+		return
+	}
+	r.records[name] = records
+}
+
+// testMTASTSServer serves MTA-STS policies over HTTP, recording the URLs
+// requested.
+type testMTASTSServer struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	policies map[string]string
+	urls     []string
+}
+
+func newTestMTASTSServer(policies map[string]string) *testMTASTSServer {
+	s := &testMTASTSServer{policies: policies}
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		policy, ok := s.policies[r.Host]
+		s.mu.Unlock()
+
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, policy)
+	}))
+	return s
+}
+
+// Client returns an HTTP client sending requests for any URL to the server.
+func (s *testMTASTSServer) Client() *http.Client {
+	return &http.Client{Transport: s}
+}
+
+func (s *testMTASTSServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	s.urls = append(s.urls, req.URL.String())
+	s.mu.Unlock()
+
+	u, err := url.Parse(s.srv.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Host = req.URL.Host
+	req.URL.Scheme = u.Scheme
+	req.URL.Host = u.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// URLs returns the URLs requested.
+func (s *testMTASTSServer) URLs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.urls...)
+}
+
+func TestParseMTASTSPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		policy string
+		// Want
+		want    *MTASTSPolicy
+		wantErr bool
+	}{
+		{
+			name:   "Enforce",
+			policy: "version: STSv1\r\nmode: enforce\r\nmx: mx1.example.org\r\nmx: *.example.org\r\nmax_age: 86400\r\n",
+			want: &MTASTSPolicy{
+				Mode:   MTASTSModeEnforce,
+				MX:     []string{"mx1.example.org", "*.example.org"},
+				MaxAge: 24 * time.Hour,
+			},
+		},
+		{
+			name:   "LF line endings",
+			policy: "version: STSv1\nmode: testing\nmx: mx.example.org\nmax_age: 60\n",
+			want: &MTASTSPolicy{
+				Mode:   MTASTSModeTesting,
+				MX:     []string{"mx.example.org"},
+				MaxAge: time.Minute,
+			},
+		},
+		{
+			name:   "None without MX",
+			policy: "version: STSv1\nmode: none\nmax_age: 60\n",
+			want:   &MTASTSPolicy{Mode: MTASTSModeNone, MaxAge: time.Minute},
+		},
+		{
+			name:   "Max age limited",
+			policy: "version: STSv1\nmode: none\nmax_age: 99999999999\n",
+			want:   &MTASTSPolicy{Mode: MTASTSModeNone, MaxAge: maxMTASTSMaxAge},
+		},
+		{
+			name:    "Unknown version",
+			policy:  "version: STSv2\nmode: enforce\nmx: mx.example.org\nmax_age: 60\n",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid mode",
+			policy:  "version: STSv1\nmode: strict\nmx: mx.example.org\nmax_age: 60\n",
+			wantErr: true,
+		},
+		{
+			name:    "Missing MX",
+			policy:  "version: STSv1\nmode: enforce\nmax_age: 60\n",
+			wantErr: true,
+		},
+		{
+			name:    "Missing max age",
+			policy:  "version: STSv1\nmode: enforce\nmx: mx.example.org\n",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid max age",
+			policy:  "version: STSv1\nmode: enforce\nmx: mx.example.org\nmax_age: -1\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseMTASTSPolicy(strings.NewReader(tt.policy))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMTASTSPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMTASTSPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMTASTSPolicy_Match(t *testing.T) {
+	t.Parallel()
+
+	policy := &MTASTSPolicy{MX: []string{"mx.example.org", "*.Mail.example.org"}}
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		host string
+		// Want
+		want bool
+	}{
+		{"Exact", "mx.example.org", true},
+		{"Case insensitive", "MX.Example.org", true},
+		{"Trailing dot", "mx.example.org.", true},
+		{"Wildcard", "a.mail.example.org", true},
+		{"Wildcard parent", "mail.example.org", false},
+		{"Wildcard multiple labels", "a.b.mail.example.org", false},
+		{"Other", "mx.example.com", false},
+		{"Suffix", "amx.example.org", false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := policy.Match(tt.host); got != tt.want {
+				t.Errorf("MTASTSPolicy.Match(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMTASTSClient_Policy ensures policies are cached until the policy ID
+// changes, and used when the TXT record or policy cannot be found.
+func TestMTASTSClient_Policy(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestMTASTSServer(map[string]string{
+		"mta-sts.example.org": "version: STSv1\nmode: enforce\nmx: mx.example.org\nmax_age: 3600\n",
+	})
+	defer srv.srv.Close()
+
+	resolver := &testTXTResolver{records: map[string][]string{
+		"_mta-sts.example.org": {"v=STSv1; id=1;"},
+	}}
+
+	c := NewMTASTSClient()
+	c.HTTPClient(srv.Client())
+	c.Resolver(resolver)
+
+	want := &MTASTSPolicy{Mode: MTASTSModeEnforce, MX: []string{"mx.example.org"}, MaxAge: time.Hour}
+	wantURL := "https://mta-sts.example.org/.well-known/mta-sts.txt"
+
+	steps := []struct {
+		name     string
+		txt      []string
+		domain   string
+		want     *MTASTSPolicy
+		wantURLs int
+	}{
+		{"Fetched", []string{"v=STSv1; id=1;"}, "Example.org", want, 1},
+		{"Cached", []string{"v=STSv1; id=1;"}, "example.org", want, 1},
+		{"ID changed", []string{"v=STSv1; id=2;"}, "example.org", want, 2},
+		{"TXT removed", nil, "example.org", want, 2},
+		{"No policy", nil, "example.com", nil, 2},
+		{"Policy not found", []string{"v=STSv1; id=1;"}, "example.net", nil, 3},
+	}
+	for _, s := range steps {
+		resolver.set("_mta-sts."+strings.ToLower(s.domain), s.txt...)
+
+		got, err := c.Policy(context.Background(), s.domain)
+		if s.want != nil && err != nil {
+			t.Fatalf("%s: MTASTSClient.Policy() error = %v", s.name, err)
+		}
+		if !reflect.DeepEqual(got, s.want) {
+			t.Errorf("%s: MTASTSClient.Policy() = %+v, want %+v", s.name, got, s.want)
+		}
+		if urls := srv.URLs(); len(urls) != s.wantURLs || urls[0] != wantURL {
+			t.Errorf("%s: MTASTSClient.Policy() fetched %v, want %d fetches of %s", s.name, urls, s.wantURLs, wantURL)
+		}
+	}
+}
+
+// TestMailYakMTASTS ensures enforced MTA-STS policies of the recipient domains
+// are applied when connecting to the SMTP server.
+func TestMailYakMTASTS(t *testing.T) {
+	t.Parallel()
+
+	chain, _, ca := testDANEChain(t)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	startTLS := map[string]string{
+		"EHLO":     "250-localhost\r\n250 STARTTLS",
+		"STARTTLS": "220 Ready",
+	}
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		policy  string
+		replies map[string]string
+		roots   *x509.CertPool
+		dane    *testTLSAResolver
+		// Want
+		wantErr      error
+		wantAnyErr   bool
+		wantStartTLS bool
+	}{
+		{
+			name:         "Enforce",
+			policy:       "version: STSv1\nmode: enforce\nmx: *.example.org\nmax_age: 60\n",
+			replies:      startTLS,
+			roots:        roots,
+			wantStartTLS: true,
+		},
+		{
+			name:    "Enforce MX mismatch",
+			policy:  "version: STSv1\nmode: enforce\nmx: mx.example.com\nmax_age: 60\n",
+			replies: startTLS,
+			roots:   roots,
+			wantErr: ErrMTASTSMismatch,
+		},
+		{
+			name:    "Enforce STARTTLS unsupported",
+			policy:  "version: STSv1\nmode: enforce\nmx: mx.example.org\nmax_age: 60\n",
+			roots:   roots,
+			wantErr: ErrStartTLSUnsupported,
+		},
+		{
+			name:         "Enforce untrusted certificate",
+			policy:       "version: STSv1\nmode: enforce\nmx: mx.example.org\nmax_age: 60\n",
+			replies:      startTLS,
+			wantAnyErr:   true,
+			wantStartTLS: true,
+		},
+		{
+			name:   "Testing",
+			policy: "version: STSv1\nmode: testing\nmx: mx.example.com\nmax_age: 60\n",
+		},
+		{
+			name:   "DANE takes precedence",
+			policy: "version: STSv1\nmode: enforce\nmx: mx.example.com\nmax_age: 60\n",
+			dane: &testTLSAResolver{
+				records: []TLSARecord{{Usage: 1}},
+				secure:  true,
+			},
+			replies:      startTLS,
+			wantStartTLS: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			policies := newTestMTASTSServer(map[string]string{"mta-sts.example.org": tt.policy})
+			defer policies.srv.Close()
+
+			sts := NewMTASTSClient()
+			sts.HTTPClient(policies.Client())
+			sts.Resolver(&testTXTResolver{records: map[string][]string{
+				"_mta-sts.example.org": {"v=STSv1; id=1;"},
+			}})
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := startTestSMTPServer(&testSMTPServer{
+				l:         l,
+				replies:   tt.replies,
+				tlsConfig: &tls.Config{Certificates: []tls.Certificate{chain}},
+			})
+			defer srv.Close()
+
+			mail := New("mx.example.org:25", nil)
+			mail.Dialer(redirectDialer{addr: srv.Addr()})
+			mail.TLSConfig(&tls.Config{RootCAs: tt.roots})
+			mail.MTASTS(sts)
+			if tt.dane != nil {
+				mail.DANE(tt.dane)
+			}
+			mail.From("from@example.org")
+			mail.To("to@example.org", "to@example.net")
+
+			_, _, err = mail.Send("localhost")
+			switch {
+			case tt.wantAnyErr:
+				if err == nil {
+					t.Fatal("MailYak.Send() error = nil, want error")
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Fatalf("MailYak.Send() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if errors.Is(tt.wantErr, ErrMTASTSMismatch) && len(srv.Commands()) != 0 {
+				t.Errorf("MailYak.Send() sent %v, want no connection", srv.Commands())
+			}
+
+			var gotStartTLS bool
+			for _, cmd := range srv.Commands() {
+				gotStartTLS = gotStartTLS || cmd == "STARTTLS"
+			}
+			if gotStartTLS != tt.wantStartTLS {
+				t.Errorf("MailYak.Send() STARTTLS = %v, want %v", gotStartTLS, tt.wantStartTLS)
+			}
+		})
+	}
+}
+
+// TestPoolMTASTS ensures the enforced MTA-STS policies of the recipients of
+// each email are applied to the pooled connection it is sent over.
+func TestPoolMTASTS(t *testing.T) {
+	t.Parallel()
+
+	chain, _, ca := testDANEChain(t)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	const (
+		enforce  = "version: STSv1\nmode: enforce\nmx: *.example.org\nmax_age: 60\n"
+		mismatch = "version: STSv1\nmode: enforce\nmx: mx.example.com\nmax_age: 60\n"
+	)
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		policy    string
+		configSTS bool
+		// Parameters.
+		rcpts []string
+		// Want
+		wantErrs  []error
+		wantConns int
+	}{
+		{
+			name:      "Enforce",
+			policy:    enforce,
+			configSTS: true,
+			rcpts:     []string{"to@example.org", "to@example.org"},
+			wantErrs:  []error{nil, nil},
+			wantConns: 1,
+		},
+		{
+			name:      "Enforce MX mismatch",
+			policy:    mismatch,
+			configSTS: true,
+			rcpts:     []string{"to@example.org"},
+			wantErrs:  []error{ErrMTASTSMismatch},
+			wantConns: 0,
+		},
+		{
+			name:      "Email MX mismatch",
+			policy:    mismatch,
+			rcpts:     []string{"to@example.org"},
+			wantErrs:  []error{ErrMTASTSMismatch},
+			wantConns: 0,
+		},
+		{
+			name:      "Reused MX mismatch",
+			policy:    mismatch,
+			configSTS: true,
+			rcpts:     []string{"to@example.net", "to@example.org", "to@example.net"},
+			wantErrs:  []error{nil, ErrMTASTSMismatch, nil},
+			wantConns: 1,
+		},
+		{
+			name:      "Reused without required TLS",
+			policy:    enforce,
+			configSTS: true,
+			rcpts:     []string{"to@example.net", "to@example.org"},
+			wantErrs:  []error{nil, nil},
+			wantConns: 2,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			policies := newTestMTASTSServer(map[string]string{"mta-sts.example.org": tt.policy})
+			defer policies.srv.Close()
+
+			sts := NewMTASTSClient()
+			sts.HTTPClient(policies.Client())
+			sts.Resolver(&testTXTResolver{records: map[string][]string{
+				"_mta-sts.example.org": {"v=STSv1; id=1;"},
+			}})
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := startTestSMTPServer(&testSMTPServer{
+				l: l,
+				replies: map[string]string{
+					"EHLO":     "250-localhost\r\n250 STARTTLS",
+					"STARTTLS": "220 Ready",
+				},
+				tlsConfig: &tls.Config{Certificates: []tls.Certificate{chain}},
+			})
+			defer srv.Close()
+
+			config := New("mx.example.org:25", nil)
+			config.Dialer(redirectDialer{addr: srv.Addr()})
+			config.TLSConfig(&tls.Config{RootCAs: roots})
+			if tt.configSTS {
+				config.MTASTS(sts)
+			}
+
+			pool := NewPool(config, "localhost", 1)
+			defer pool.Close()
+
+			for i, rcpt := range tt.rcpts {
+				mail := New("", nil)
+				mail.Transport(pool)
+				if !tt.configSTS {
+					mail.MTASTS(sts)
+				}
+				mail.From("from@example.org")
+				mail.To(rcpt)
+
+				if _, _, err := mail.Send("localhost"); !errors.Is(err, tt.wantErrs[i]) {
+					t.Fatalf("Pool.Send(%s) error = %v, want %v", rcpt, err, tt.wantErrs[i])
+				}
+			}
+
+			// each connection is upgraded with STARTTLS
+			var conns int
+			for _, cmd := range srv.Commands() {
+				if cmd == "STARTTLS" {
+					conns++
+				}
+			}
+			if conns != tt.wantConns {
+				t.Errorf("Pool connections = %d, want %d", conns, tt.wantConns)
+			}
+		})
+	}
+}
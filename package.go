@@ -7,6 +7,8 @@
 //
 // Attachments are fully supported (attach anything that implements io.Reader).
 //
-// The raw MIME content can be retrieved using MimeBuf(), typically used with an
-// API service such as Amazon SES that does not require using an SMTP interface.
+// The raw MIME content can be retrieved using MimeBuf(), streamed to an
+// io.Writer using WriteTo(), or saved to a .eml file using WriteFile(),
+// typically used with an API service such as Amazon SES that does not require
+// using an SMTP interface.
 package mailyak
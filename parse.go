@@ -0,0 +1,318 @@
+package mailyak
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// parsedHeaders are the headers populated into dedicated fields by Parse, or
+// generated when the email is built, and so not added as custom headers.
+var parsedHeaders = map[string]bool{
+	"From":                        true,
+	"Sender":                      true,
+	"To":                          true,
+	"Cc":                          true,
+	"Bcc":                         true,
+	"Reply-To":                    true,
+	"Subject":                     true,
+	"Date":                        true,
+	"Message-Id":                  true,
+	"In-Reply-To":                 true,
+	"References":                  true,
+	"List-Unsubscribe":            true,
+	"List-Unsubscribe-Post":       true,
+	"Disposition-Notification-To": true,
+	"Return-Receipt-To":           true,
+	"Mime-Version":                true,
+	"Content-Type":                true,
+	"Content-Transfer-Encoding":   true,
+	"Dkim-Signature":              true,
+	"X-Mailer":                    true,
+
+	// Trace headers are added by each server the email passes through
+	"Received":    true,
+	"Return-Path": true,
+}
+
+// Parse reads the raw MIME email (such as a .eml file) from r and returns it as
+// a MailYak, allowing an existing email to be modified and re-sent:
+//
+//	mail, err := mailyak.Parse(f)
+//	if err != nil {
+//		return err
+//	}
+//	mail.Host("smtp.itsallbroken.com:587")
+//	mail.Auth(auth)
+//	mail.Send("localhost")
+//
+// The addresses, subject, date, Message-ID, X-Mailer, threading headers,
+// plain-text and HTML bodies, calendar invitation and attachments are
+// populated, and any other headers are added as custom headers. Inline attachments retain their
+// Content-ID, so references from the HTML body continue to resolve.
+//
+// Any DKIM signature is discarded, as it is invalidated by rebuilding the
+// email. Text bodies are assumed to be UTF-8, except ISO-8859-1 which is
+// converted.
+func Parse(r io.Reader) (*MailYak, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewBlank()
+	if err := m.parseHeaders(msg.Header); err != nil {
+		return nil, err
+	}
+
+	header := textproto.MIMEHeader(msg.Header)
+	if err := m.parsePart(header, msg.Body); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// parseHeaders populates m from the top-level headers of a parsed email.
+func (m *MailYak) parseHeaders(h mail.Header) error {
+	dec := new(mime.WordDecoder)
+
+	if v := h.Get("From"); v != "" {
+		addr, err := mail.ParseAddress(v)
+		if err != nil {
+			return fmt.Errorf("mailyak: invalid From header: %v", err)
+		}
+		m.From(addr.Address)
+		m.FromName(addr.Name)
+	}
+
+	for name, set := range map[string]func(...string){"To": m.To, "Cc": m.Cc, "Bcc": m.Bcc} {
+		addrs, err := parseAddressList(h, name)
+		if err != nil {
+			return err
+		}
+		set(addrs...)
+	}
+
+	replyTo, err := parseAddressList(h, "Reply-To")
+	if err != nil {
+		return err
+	}
+	if len(replyTo) > 0 {
+		m.ReplyTo(replyTo[0])
+	}
+
+	sender, err := parseAddressList(h, "Sender")
+	if err != nil {
+		return err
+	}
+	if len(sender) > 0 {
+		m.Sender(sender[0])
+	}
+
+	if v := h.Get("Subject"); v != "" {
+		subject, err := dec.DecodeHeader(v)
+		if err != nil {
+			subject = v
+		}
+		m.Subject(subject)
+	}
+
+	if h.Get("Date") != "" {
+		date, err := h.Date()
+		if err != nil {
+			return fmt.Errorf("mailyak: invalid Date header: %v", err)
+		}
+		m.Date(date)
+	}
+
+	m.messageID = strings.TrimSpace(h.Get("Message-Id"))
+	m.messageIDFixed = m.messageID != ""
+	m.XMailer(h.Get("X-Mailer"))
+
+	if v := h.Get("In-Reply-To"); v != "" {
+		m.InReplyTo(v)
+	}
+	if v := h.Get("References"); v != "" {
+		m.References(strings.Fields(v)...)
+	}
+
+	if v := h.Get("List-Unsubscribe"); v != "" {
+		var targets []string
+		for _, target := range strings.Split(v, ",") {
+			targets = append(targets, strings.Trim(strings.TrimSpace(target), "<>"))
+		}
+		if err := m.ListUnsubscribe(targets...); err != nil {
+			return err
+		}
+	}
+
+	if v := h.Get("Disposition-Notification-To"); v != "" {
+		if err := m.RequestReadReceipt(v); err != nil {
+			return err
+		}
+	}
+
+	// textproto.MIMEHeader does not retain the order of the headers, so they
+	// are added in alphabetical order to be written consistently
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		values := h[name]
+		if parsedHeaders[textproto.CanonicalMIMEHeaderKey(name)] || len(values) == 0 {
+			continue
+		}
+
+		for _, v := range values {
+			value, err := dec.DecodeHeader(v)
+			if err != nil {
+				value = v
+			}
+			m.AddHeader(name, value)
+		}
+	}
+
+	return nil
+}
+
+// parseAddressList returns the addresses in all instances of the named header
+// of h, with any display names.
+func parseAddressList(h mail.Header, name string) ([]string, error) {
+	var addrs []string
+	for _, v := range h[textproto.CanonicalMIMEHeaderKey(name)] {
+		list, err := mail.ParseAddressList(v)
+		if err != nil {
+			return nil, fmt.Errorf("mailyak: invalid %s header: %v", name, err)
+		}
+
+		for _, addr := range list {
+			if addr.Name == "" {
+				addrs = append(addrs, addr.Address)
+				continue
+			}
+			addrs = append(addrs, addr.String())
+		}
+	}
+
+	return addrs, nil
+}
+
+// parsePart populates the bodies and attachments of m from the MIME part with
+// header h and content body, recursing into multipart parts.
+func (m *MailYak) parsePart(h textproto.MIMEHeader, body io.Reader) error {
+	ctype := h.Get("Content-Type")
+	if ctype == "" {
+		ctype = "text/plain; charset=us-ascii"
+	}
+
+	mediaType, params, err := mime.ParseMediaType(ctype)
+	if err != nil {
+		return fmt.Errorf("mailyak: invalid Content-Type %q: %v", ctype, err)
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := m.parsePart(part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := decodeTransferEncoding(h.Get("Content-Transfer-Encoding"), body)
+	if err != nil {
+		return err
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := dparams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(filename); err == nil {
+		filename = decoded
+	}
+
+	if disposition != "attachment" && filename == "" {
+		switch {
+		case mediaType == "text/plain" && m.plain.Len() == 0:
+			m.plain.Set(decodeCharset(data, params["charset"]))
+			return nil
+		case mediaType == "text/html" && m.html.Len() == 0:
+			m.html.Set(decodeCharset(data, params["charset"]))
+			return nil
+		case mediaType == "text/calendar" && params["method"] != "" && m.calendar == nil:
+			return m.Calendar(params["method"], strings.NewReader(decodeCharset(data, params["charset"])))
+		}
+	}
+
+	// The filename parameters are written from the filename when built
+	delete(params, "name")
+	delete(params, "filename")
+
+	a := attachment{
+		filename: filename,
+		content:  bytes.NewReader(data),
+		inline:   disposition == "inline",
+		mimeType: mime.FormatMediaType(mediaType, params),
+	}
+	if cid := h.Get("Content-Id"); cid != "" {
+		a.header = textproto.MIMEHeader{"Content-Id": {cid}}
+	}
+	m.attachments = append(m.attachments, a)
+
+	return nil
+}
+
+// decodeTransferEncoding returns the content read from r, decoded according to
+// the Content-Transfer-Encoding cte.
+func decodeTransferEncoding(cte string, r io.Reader) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(cte)) {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	}
+	return io.ReadAll(r)
+}
+
+// decodeCharset returns data converted from charset to UTF-8.
+//
+// Only ISO-8859-1 is converted, with other character sets assumed to be UTF-8
+// compatible.
+func decodeCharset(data []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1":
+		var b strings.Builder
+		for _, c := range data {
+			b.WriteRune(rune(c))
+		}
+		return b.String()
+	}
+
+	if !utf8.Valid(data) {
+		return strings.ToValidUTF8(string(data), "�")
+	}
+	return string(data)
+}
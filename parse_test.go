@@ -0,0 +1,192 @@
+package mailyak
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParse_roundTrip ensures an email built by MailYak is parsed back into an
+// equivalent email.
+func TestParse_roundTrip(t *testing.T) {
+	t.Parallel()
+
+	date := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	orig := New("", nil)
+	orig.From("from@example.org")
+	orig.FromName("Dom Dwyer")
+	orig.To("to@example.org", "Zoë <zoe@example.org>")
+	orig.Cc("cc@example.org")
+	orig.ReplyTo("reply@example.org")
+	orig.Subject("Héllo")
+	orig.Date(date)
+	orig.InReplyTo("<parent@example.org>")
+	orig.References("<root@example.org>", "<parent@example.org>")
+	orig.AddHeader("X-Campaign", "launch")
+	orig.Plain().Set("Plain body")
+	orig.HTML().Set("<p>HTML body</p>")
+	orig.Attach("report.csv", strings.NewReader("a,b,c\n"))
+	orig.AttachInline("logo.png", strings.NewReader("\x89PNG\r\n\x1a\n"))
+	orig.AttachMessage("original.eml", strings.NewReader("Subject: Original\r\n\r\nOriginal body\r\n"))
+
+	buf, err := orig.MimeBuf()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	for _, check := range []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"from", m.fromAddr, "from@example.org"},
+		{"fromName", m.fromName, "Dom Dwyer"},
+		{"to", m.toAddrs, []string{"to@example.org", "=?utf-8?q?Zo=C3=AB?= <zoe@example.org>"}},
+		{"cc", m.ccAddrs, []string{"cc@example.org"}},
+		{"replyTo", m.replyTo, "reply@example.org"},
+		{"subject", m.subject, orig.subject},
+		{"date", m.date, orig.date},
+		{"messageID", m.messageID, orig.GetMessageID()},
+		{"inReplyTo", m.inReplyTo, "<parent@example.org>"},
+		{"references", m.references, []string{"<root@example.org>", "<parent@example.org>"}},
+		{"headers", m.headers, map[string]string{"X-Campaign": "launch"}},
+		{"xMailer", m.xMailer, DefaultMailer},
+		{"plain", m.plain.String(), "Plain body"},
+		{"html", m.html.String(), "<p>HTML body</p>"},
+	} {
+		if !reflect.DeepEqual(check.got, check.want) {
+			t.Errorf("Parse() %s = %#v, want %#v", check.name, check.got, check.want)
+		}
+	}
+
+	wantAttachments := []struct {
+		filename string
+		mimeType string
+		inline   bool
+		content  string
+	}{
+		{"report.csv", "text/plain; charset=utf-8", false, "a,b,c\n"},
+		{"logo.png", "image/png", true, "\x89PNG\r\n\x1a\n"},
+		{"original.eml", "message/rfc822", false, "Subject: Original\r\n\r\nOriginal body\r\n"},
+	}
+	if len(m.attachments) != len(wantAttachments) {
+		t.Fatalf("Parse() attachments = %d, want %d", len(m.attachments), len(wantAttachments))
+	}
+	for i, want := range wantAttachments {
+		a := m.attachments[i]
+		content, _ := ioutil.ReadAll(a.content)
+		if a.filename != want.filename || a.mimeType != want.mimeType || a.inline != want.inline || string(content) != want.content {
+			t.Errorf("Parse() attachment %d = {%q %q %v %q}, want %+v", i, a.filename, a.mimeType, a.inline, content, want)
+		}
+	}
+}
+
+// TestParse ensures emails from other sources are parsed.
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		eml string
+		// Want
+		wantPlain   string
+		wantHTML    string
+		wantHeaders map[string][]string
+		wantCID     string
+		wantErr     bool
+	}{
+		{
+			"Plain only",
+			"From: from@example.org\r\nTo: to@example.org\r\nReceived: from mx\r\nDKIM-Signature: v=1\r\n\r\nHello\r\n",
+			"Hello\r\n",
+			"",
+			map[string][]string{},
+			"",
+			false,
+		},
+		{
+			"Latin1 quoted-printable",
+			"From: from@example.org\r\nContent-Type: text/plain; charset=ISO-8859-1\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\nH=E9llo=\r\n world",
+			"Héllo world",
+			"",
+			map[string][]string{},
+			"",
+			false,
+		},
+		{
+			"Base64 HTML and inline image",
+			"From: from@example.org\r\nX-Mailer: Test\r\nContent-Type: multipart/related; boundary=b\r\n\r\n" +
+				"--b\r\nContent-Type: text/html; charset=utf-8\r\nContent-Transfer-Encoding: base64\r\n\r\nPHA+SGk8L3A+\r\n" +
+				"--b\r\nContent-Type: image/png; name=\"=?utf-8?q?l=C3=B6go.png?=\"\r\nContent-ID: <logo>\r\nContent-Disposition: inline\r\nContent-Transfer-Encoding: base64\r\n\r\nAAEC\r\n" +
+				"--b--\r\n",
+			"",
+			"<p>Hi</p>",
+			map[string][]string{},
+			"<logo>",
+			false,
+		},
+		{
+			"Repeated headers",
+			"From: from@example.org\r\nComments: one\r\nComments: two\r\n\r\nHello",
+			"Hello",
+			"",
+			map[string][]string{"Comments": {"one", "two"}},
+			"",
+			false,
+		},
+		{
+			"Invalid From",
+			"From: not an address\r\n\r\nHello",
+			"",
+			"",
+			nil,
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m, err := Parse(strings.NewReader(tt.eml))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%q. Parse() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if got := m.plain.String(); got != tt.wantPlain {
+				t.Errorf("%q. Parse() plain = %q, want %q", tt.name, got, tt.wantPlain)
+			}
+			if got := m.html.String(); got != tt.wantHTML {
+				t.Errorf("%q. Parse() html = %q, want %q", tt.name, got, tt.wantHTML)
+			}
+			if got := m.GetHeaderValues(); !reflect.DeepEqual(got, tt.wantHeaders) {
+				t.Errorf("%q. Parse() headers = %v, want %v", tt.name, got, tt.wantHeaders)
+			}
+
+			if tt.wantCID == "" {
+				return
+			}
+			if len(m.attachments) != 1 {
+				t.Fatalf("%q. Parse() attachments = %d, want 1", tt.name, len(m.attachments))
+			}
+			a := m.attachments[0]
+			if a.filename != "lögo.png" || !a.inline || a.header.Get("Content-Id") != tt.wantCID {
+				t.Errorf("%q. Parse() attachment = {%q %v %v}", tt.name, a.filename, a.inline, a.header)
+			}
+		})
+	}
+}
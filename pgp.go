@@ -0,0 +1,133 @@
+package mailyak
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// PGPSigner creates detached OpenPGP signatures.
+//
+// A PGPSigner wrapping a golang.org/x/crypto/openpgp entity can be implemented
+// by calling openpgp.ArmoredDetachSign.
+type PGPSigner interface {
+	// ArmoredDetachSign writes an ASCII-armored detached signature of message
+	// to w.
+	ArmoredDetachSign(w io.Writer, message io.Reader) error
+
+	// Micalg returns the hash algorithm used by the signature, as defined in
+	// RFC 3156 section 5 (i.e. "pgp-sha256").
+	Micalg() string
+}
+
+// PGPEncrypter encrypts messages to a set of OpenPGP recipients.
+//
+// A PGPEncrypter wrapping golang.org/x/crypto/openpgp entities can be
+// implemented by calling openpgp.Encrypt with an armor.Encode writer.
+type PGPEncrypter interface {
+	// ArmoredEncrypt writes the ASCII-armored encryption of plaintext to w.
+	ArmoredEncrypt(w io.Writer, plaintext io.Reader) error
+}
+
+// PGPOptions configures PGP/MIME (RFC 3156) signing and encryption of outgoing
+// emails.
+//
+// Emails are signed when Signer is set, and encrypted when Encrypter is set.
+// When both are configured the email is signed before being encrypted.
+type PGPOptions struct {
+	Signer    PGPSigner
+	Encrypter PGPEncrypter
+}
+
+// pgpWrapper signs and/or encrypts generated MIME messages.
+type pgpWrapper struct {
+	opts PGPOptions
+}
+
+// PGP enables PGP/MIME signing and/or encryption of the email as configured by
+// opts.
+//
+// Signed emails are sent as multipart/signed, and encrypted emails as
+// multipart/encrypted. The HTML and plain-text bodies, and all attachments are
+// signed and encrypted together. The From, To, Subject and other top-level
+// headers are not encrypted.
+//
+// PGP cannot be combined with S/MIME.
+func (m *MailYak) PGP(opts PGPOptions) error {
+	if opts.Signer == nil && opts.Encrypter == nil {
+		return errors.New("mailyak: PGP requires a signer or encrypter")
+	}
+
+	m.pgp = &pgpWrapper{opts: opts}
+	return nil
+}
+
+// wrap returns msg with the MIME content signed and/or encrypted, leaving the
+// top-level headers (From, To, etc) in place.
+func (p *pgpWrapper) wrap(msg []byte) (*bytes.Buffer, error) {
+	outer, content := splitEntity(msg)
+
+	if p.opts.Signer != nil {
+		signed, err := p.sign(content)
+		if err != nil {
+			return nil, err
+		}
+		content = signed
+	}
+
+	if p.opts.Encrypter != nil {
+		encrypted, err := p.encrypt(content)
+		if err != nil {
+			return nil, err
+		}
+		content = encrypted
+	}
+
+	outer.Write(content)
+	return outer, nil
+}
+
+// sign returns a multipart/signed entity containing entity and its detached
+// signature.
+func (p *pgpWrapper) sign(entity []byte) ([]byte, error) {
+	var part bytes.Buffer
+	part.WriteString("Content-Type: application/pgp-signature; name=\"signature.asc\"\r\n")
+	part.WriteString("Content-Description: OpenPGP digital signature\r\n")
+	part.WriteString("Content-Disposition: attachment; filename=\"signature.asc\"\r\n\r\n")
+
+	if err := p.opts.Signer.ArmoredDetachSign(&part, bytes.NewReader(entity)); err != nil {
+		return nil, err
+	}
+
+	return multipartSigned(entity, "application/pgp-signature", p.opts.Signer.Micalg(), normaliseCRLF(part.Bytes()))
+}
+
+// encrypt returns a multipart/encrypted entity containing entity encrypted by
+// the Encrypter.
+func (p *pgpWrapper) encrypt(entity []byte) ([]byte, error) {
+	var ciphertext bytes.Buffer
+	if err := p.opts.Encrypter.ArmoredEncrypt(&ciphertext, bytes.NewReader(entity)); err != nil {
+		return nil, err
+	}
+
+	boundary, err := randomBoundary(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("Content-Type: multipart/encrypted; protocol=\"application/pgp-encrypted\";\r\n\tboundary=\"" + boundary + "\"\r\n\r\n")
+	buf.WriteString("--" + boundary + "\r\n")
+	buf.WriteString("Content-Type: application/pgp-encrypted\r\n")
+	buf.WriteString("Content-Description: PGP/MIME version identification\r\n\r\n")
+	buf.WriteString("Version: 1\r\n")
+	buf.WriteString("\r\n--" + boundary + "\r\n")
+	buf.WriteString("Content-Type: application/octet-stream; name=\"encrypted.asc\"\r\n")
+	buf.WriteString("Content-Description: OpenPGP encrypted message\r\n")
+	buf.WriteString("Content-Disposition: inline; filename=\"encrypted.asc\"\r\n\r\n")
+	buf.Write(normaliseCRLF(ciphertext.Bytes()))
+	buf.WriteString("\r\n--" + boundary + "--\r\n")
+
+	return buf.Bytes(), nil
+}
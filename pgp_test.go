@@ -0,0 +1,144 @@
+package mailyak
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// testPGP is a PGPSigner and PGPEncrypter producing predictable output.
+type testPGP struct{}
+
+func (testPGP) ArmoredDetachSign(w io.Writer, message io.Reader) error {
+	b, err := ioutil.ReadAll(message)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "-----BEGIN PGP SIGNATURE-----\n\n%x\n-----END PGP SIGNATURE-----\n", sha256.Sum256(b))
+	return err
+}
+
+func (testPGP) Micalg() string { return "pgp-sha256" }
+
+func (testPGP) ArmoredEncrypt(w io.Writer, plaintext io.Reader) error {
+	b, err := ioutil.ReadAll(plaintext)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "-----BEGIN PGP MESSAGE-----\n\n%s\n-----END PGP MESSAGE-----\n", base64.StdEncoding.EncodeToString(b))
+	return err
+}
+
+// TestMailYakPGP ensures PGP/MIME messages are structured as described in
+// RFC 3156.
+func TestMailYakPGP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		opts PGPOptions
+		// Want
+		wantSigned    bool
+		wantEncrypted bool
+	}{
+		{"Sign", PGPOptions{Signer: testPGP{}}, true, false},
+		{"Encrypt", PGPOptions{Encrypter: testPGP{}}, false, true},
+		{"Sign and encrypt", PGPOptions{Signer: testPGP{}, Encrypter: testPGP{}}, true, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.From("dom@itsallbroken.com")
+			m.To("to@itsallbroken.com")
+			m.Subject("PGP")
+			m.Plain().Set("Secret")
+			m.Attach("test.txt", strings.NewReader("attachment"))
+
+			if err := m.PGP(tt.opts); err != nil {
+				t.Fatal(err)
+			}
+
+			buf, err := m.MimeBuf()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			headers, body := splitMessage(buf.Bytes())
+			if len(headerFields(headers, "Subject")) != 1 || len(headerFields(headers, "Content-Type")) != 1 {
+				t.Fatalf("%q. unexpected headers %q", tt.name, headers)
+			}
+
+			entity := string(headerFields(headers, "Content-Type")[0]) + "\r\n" + string(body)
+
+			if tt.wantEncrypted {
+				if !strings.HasPrefix(entity, "Content-Type: multipart/encrypted; protocol=\"application/pgp-encrypted\";") {
+					t.Fatalf("%q. entity = %q, want multipart/encrypted", tt.name, entity)
+				}
+				if !strings.Contains(entity, "Content-Type: application/pgp-encrypted\r\nContent-Description: PGP/MIME version identification\r\n\r\nVersion: 1\r\n") {
+					t.Errorf("%q. entity = %q, want version identification part", tt.name, entity)
+				}
+
+				armor := regexp.MustCompile(`-----BEGIN PGP MESSAGE-----\r\n\r\n(.*)\r\n`).FindStringSubmatch(entity)
+				if armor == nil {
+					t.Fatalf("%q. entity = %q, want PGP message", tt.name, entity)
+				}
+
+				plaintext, err := base64.StdEncoding.DecodeString(armor[1])
+				if err != nil {
+					t.Fatal(err)
+				}
+				entity = string(plaintext)
+			}
+
+			if !tt.wantSigned {
+				if !strings.HasPrefix(entity, "Content-Type: multipart/mixed;") {
+					t.Errorf("%q. decrypted entity = %q, want multipart/mixed", tt.name, entity)
+				}
+				return
+			}
+
+			if !strings.HasPrefix(entity, "Content-Type: multipart/signed; protocol=\"application/pgp-signature\"; micalg=pgp-sha256;") {
+				t.Fatalf("%q. entity = %q, want multipart/signed", tt.name, entity)
+			}
+
+			boundary := regexp.MustCompile(`boundary="([^"]+)"`).FindStringSubmatch(entity)[1]
+			start := strings.Index(entity, "--"+boundary+"\r\n") + len(boundary) + 4
+			end := start + strings.Index(entity[start:], "\r\n--"+boundary+"\r\n")
+			signedContent := entity[start:end]
+
+			if !strings.HasPrefix(signedContent, "Content-Type: multipart/mixed;") {
+				t.Errorf("%q. signed content = %q, want multipart/mixed", tt.name, signedContent)
+			}
+
+			wantSig := fmt.Sprintf("-----BEGIN PGP SIGNATURE-----\r\n\r\n%x\r\n", sha256.Sum256([]byte(signedContent)))
+			if !strings.Contains(entity[end:], wantSig) {
+				t.Errorf("%q. entity = %q, want signature %q", tt.name, entity, wantSig)
+			}
+		})
+	}
+}
+
+// TestMailYakPGP_withSMIME ensures PGP and S/MIME cannot be combined.
+func TestMailYakPGP_withSMIME(t *testing.T) {
+	t.Parallel()
+
+	m := New("", nil)
+	if err := m.PGP(PGPOptions{Signer: testPGP{}}); err != nil {
+		t.Fatal(err)
+	}
+	m.smime = &smimeWrapper{}
+
+	if _, err := m.MimeBuf(); err == nil {
+		t.Error("MailYak.MimeBuf() error = nil, want error")
+	}
+}
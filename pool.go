@@ -0,0 +1,290 @@
+package mailyak
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned when sending with a Pool that has been closed.
+var ErrPoolClosed = errors.New("mailyak: pool closed")
+
+// Pool is a Transport delivering emails over persistent SMTP connections,
+// avoiding the cost of connecting, negotiating TLS and authenticating for each
+// email sent.
+//
+// Connections are reused across calls to Send, and validated with a NOOP
+// command before each use - connections closed by the server are replaced
+// transparently. A Pool is safe for concurrent use, with a new connection
+// opened when all existing connections are busy.
+//
+//	pool := mailyak.NewPool(mailyak.New("smtp.itsallbroken.com:587", auth), "localhost", 4)
+//	defer pool.Close()
+//
+//	mail := mailyak.New("", nil)
+//	mail.Transport(pool)
+type Pool struct {
+	config        *MailYak
+	localHostName string
+	size          int
+
+	mu     sync.Mutex
+	idle   []*smtpConn
+	closed bool
+}
+
+// NewPool returns a Pool connecting to the SMTP server configured on config,
+// using its host, authentication, TLS, dialer and timeout configuration, and
+// identifying as localHostName (or the HelloName of config if empty).
+//
+// Up to size idle connections are kept open for reuse. Close must be called to
+// close the idle connections once the Pool is no longer needed.
+func NewPool(config *MailYak, localHostName string, size int) *Pool {
+	return &Pool{
+		config:        config,
+		localHostName: localHostName,
+		size:          size,
+	}
+}
+
+// Send delivers the MIME message written by msg to rcpts using a pooled
+// connection, implementing Transport.
+//
+// The MTA-STS policies of rcpts are enforced as described by MailYak.MTASTS.
+func (p *Pool) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	m := p.config
+
+	ctx, cancel, ctxErr := m.sendContext(ctx)
+	defer cancel()
+
+	sts := mtaSTSCheck{client: m.mtaSTS, rcpts: rcpts}
+	if mm, ok := msg.(*mimeMessage); ok && mm.m.mtaSTS != nil {
+		sts.client = mm.m.mtaSTS
+	}
+
+	pc, err := p.get(ctx, sts)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctxErr()
+		}
+		return err
+	}
+
+	stop := closeOnDone(ctx, pc.conn)
+	_, _, err = m.sendMail(ctx, pc.client, pc.conn, pc.host, envelopeFrom, rcpts, msg)
+	stop()
+
+	if err != nil {
+		// the state of the session is unknown, so the connection is not
+		// reused
+		pc.client.Close()
+
+		if ctx.Err() != nil {
+			return ctxErr()
+		}
+		return err
+	}
+
+	p.put(pc)
+	return nil
+}
+
+// get returns an idle connection that satisfies the MTA-STS policies of the
+// recipients of sts and responds to a NOOP command, or a new connection if
+// there are none.
+func (p *Pool) get(ctx context.Context, sts mtaSTSCheck) (*smtpConn, error) {
+	m := p.config
+
+	// idle connections that cannot be used for these recipients are returned
+	// to the pool for other emails
+	var skipped []*smtpConn
+	defer func() {
+		for _, pc := range skipped {
+			p.put(pc)
+		}
+	}()
+
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrPoolClosed
+		}
+
+		if len(p.idle) == 0 {
+			p.mu.Unlock()
+			break
+		}
+
+		pc := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.mu.Unlock()
+
+		if !pc.satisfies(ctx, sts) {
+			skipped = append(skipped, pc)
+			continue
+		}
+
+		if err := setDeadline(pc.conn, m.timeouts.Hello); err == nil && pc.client.Noop() == nil {
+			return pc, nil
+		}
+
+		// the connection is broken or has been closed by the server
+		pc.client.Close()
+	}
+
+	return m.connect(ctx, p.localHostName, sts)
+}
+
+// put returns pc to the pool for reuse, or closes it if the pool is full or
+// closed.
+func (p *Pool) put(pc *smtpConn) {
+	p.mu.Lock()
+	if !p.closed && len(p.idle) < p.size && setDeadline(pc.conn, 0) == nil {
+		p.idle = append(p.idle, pc)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+
+	pc.client.Quit()
+}
+
+// Close closes all idle connections. Subsequent calls to Send return
+// ErrPoolClosed.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+
+	for _, pc := range idle {
+		pc.client.Quit()
+	}
+
+	return nil
+}
+
+// SendAllError is returned by SendAll when one or more emails could not be
+// delivered.
+type SendAllError struct {
+	// Errs holds the error for each email passed to SendAll, in the same
+	// order, with a nil entry for each email delivered successfully.
+	Errs []error
+}
+
+// Error implements the error interface.
+func (e *SendAllError) Error() string {
+	failed := failedErrs(e.Errs)
+	if len(failed) == 0 {
+		return "mailyak: no emails failed"
+	}
+	return fmt.Sprintf("mailyak: %d of %d emails failed: %v", len(failed), len(e.Errs), failed[0])
+}
+
+// Unwrap returns the errors of the emails that could not be delivered.
+func (e *SendAllError) Unwrap() []error {
+	return failedErrs(e.Errs)
+}
+
+// failedErrs returns the non-nil errors in errs.
+func failedErrs(errs []error) []error {
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return failed
+}
+
+// SendAll delivers each of mails sequentially over a single SMTP session to the
+// server configured on m, greeting and authenticating only once rather than
+// for each email:
+//
+//	server := mailyak.New("smtp.itsallbroken.com:587", auth)
+//	err := server.SendAll(ctx, "localhost", reports...)
+//
+// The host, authentication, TLS, dialer, timeout, rate limit and metrics
+// configuration of m is used, while any of these set on mails is ignored. The
+// hooks registered on each of mails are called as when sending it with Send.
+// The MTA-STS policies of the recipients of each of mails are enforced as
+// described by MTASTS, which may require a new connection.
+//
+// A failure to deliver one email does not prevent delivery of the others - if
+// the session is left in an unknown state by the failure, a new connection is
+// opened for the remaining emails. If any email fails, a *SendAllError is
+// returned holding the error for each email.
+func (m *MailYak) SendAll(ctx context.Context, localHostName string, mails ...*MailYak) error {
+	pool := NewPool(m, localHostName, 1)
+	defer pool.Close()
+
+	var (
+		errs   = make([]error, len(mails))
+		failed bool
+	)
+	for i, mail := range mails {
+		if m.rateLimiter != nil {
+			if err := m.rateLimiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+
+		start := time.Now()
+		err := mail.sendAllOne(ctx, pool)
+		m.recorder().ObserveSendDuration(time.Since(start))
+
+		if err != nil {
+			m.recorder().IncFailed(err)
+			mail.runAfterSend(-1, "", err)
+		} else {
+			m.recorder().IncSent()
+			mail.runAfterSend(0, "", nil)
+		}
+
+		if err != nil {
+			errs[i] = err
+			failed = true
+		}
+	}
+
+	if failed {
+		return &SendAllError{Errs: errs}
+	}
+
+	return nil
+}
+
+// sendAllOne delivers m using pool, calling its OnBeforeBuild and OnBeforeSend
+// hooks.
+func (m *MailYak) sendAllOne(ctx context.Context, pool *Pool) error {
+	m.sendResult = nil
+
+	if err := m.runBeforeBuild(); err != nil {
+		return err
+	}
+
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	msg, err := m.mimeMessage()
+	if err != nil {
+		return err
+	}
+
+	envelopeFrom, rcpts := m.envelopeSender(), m.recipients()
+	if err := m.runBeforeSend(envelopeFrom, rcpts); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = pool.Send(ctx, envelopeFrom, rcpts, msg)
+	m.recordResult(msg, start)
+
+	return err
+}
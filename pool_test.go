@@ -0,0 +1,181 @@
+package mailyak
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestPool ensures emails are delivered over a reused connection, validated
+// with NOOP, and that broken connections are replaced.
+func TestPool(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		replies map[string]string
+		// Want
+		wantCmds []string
+	}{
+		{
+			"Reused",
+			nil,
+			[]string{
+				"EHLO localhost",
+				"MAIL FROM:<from@example.org>", "RCPT TO:<to@example.org>", "DATA",
+				"NOOP",
+				"MAIL FROM:<from@example.org>", "RCPT TO:<to@example.org>", "DATA",
+				"NOOP",
+				"MAIL FROM:<from@example.org>", "RCPT TO:<to@example.org>", "DATA",
+				"QUIT",
+			},
+		},
+		{
+			"Reconnect",
+			map[string]string{"NOOP": "421 Closing connection"},
+			[]string{
+				"EHLO localhost",
+				"MAIL FROM:<from@example.org>", "RCPT TO:<to@example.org>", "DATA",
+				"NOOP",
+				"EHLO localhost",
+				"MAIL FROM:<from@example.org>", "RCPT TO:<to@example.org>", "DATA",
+				"NOOP",
+				"EHLO localhost",
+				"MAIL FROM:<from@example.org>", "RCPT TO:<to@example.org>", "DATA",
+				"QUIT",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, tt.replies)
+			defer srv.Close()
+
+			pool := NewPool(New(srv.Addr(), nil), "localhost", 1)
+
+			for i := 0; i < 3; i++ {
+				mail := New("", nil)
+				mail.From("from@example.org")
+				mail.To("to@example.org")
+				mail.Transport(pool)
+
+				if _, _, err := mail.Send("localhost"); err != nil {
+					t.Fatalf("%q. MailYak.Send() error = %v", tt.name, err)
+				}
+			}
+
+			if err := pool.Close(); err != nil {
+				t.Fatalf("%q. Pool.Close() error = %v", tt.name, err)
+			}
+
+			if got := srv.Commands(); !reflect.DeepEqual(got, tt.wantCmds) {
+				t.Errorf("%q. Pool.Send() commands = %q, want %q", tt.name, got, tt.wantCmds)
+			}
+		})
+	}
+}
+
+// TestPool_closed ensures a closed Pool cannot be used.
+func TestPool_closed(t *testing.T) {
+	t.Parallel()
+
+	pool := NewPool(New("127.0.0.1:0", nil), "localhost", 1)
+	pool.Close()
+
+	if err := pool.Send(context.Background(), "from@example.org", []string{"to@example.org"}, &mimeMessage{m: New("", nil)}); err != ErrPoolClosed {
+		t.Errorf("Pool.Send() error = %v, want %v", err, ErrPoolClosed)
+	}
+}
+
+// TestMailYakSendAll ensures SendAll delivers all emails over a single session,
+// reporting the error for each email that fails.
+func TestMailYakSendAll(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		replies map[string]string
+		// Want
+		wantEHLO int
+		wantErrs int
+	}{
+		{
+			"OK",
+			nil,
+			1,
+			0,
+		},
+		{
+			"Rejected",
+			map[string]string{"RCPT": "550 No such user"},
+			3,
+			3,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, tt.replies)
+			defer srv.Close()
+
+			var mails []*MailYak
+			for i := 0; i < 3; i++ {
+				mail := New("", nil)
+				mail.From("from@example.org")
+				mail.To("to@example.org")
+				mails = append(mails, mail)
+			}
+
+			err = New(srv.Addr(), nil).SendAll(context.Background(), "localhost", mails...)
+			if tt.wantErrs == 0 && err != nil {
+				t.Fatalf("%q. MailYak.SendAll() error = %v", tt.name, err)
+			}
+			if tt.wantErrs > 0 {
+				sendErr, ok := err.(*SendAllError)
+				if !ok {
+					t.Fatalf("%q. MailYak.SendAll() error = %v, want *SendAllError", tt.name, err)
+				}
+				if got := len(sendErr.Unwrap()); got != tt.wantErrs {
+					t.Errorf("%q. MailYak.SendAll() failed = %v, want %v", tt.name, got, tt.wantErrs)
+				}
+			}
+
+			var ehlo, data int
+			for _, cmd := range srv.Commands() {
+				switch strings.SplitN(cmd, " ", 2)[0] {
+				case "EHLO":
+					ehlo++
+				case "DATA":
+					data++
+				}
+			}
+			if ehlo != tt.wantEHLO {
+				t.Errorf("%q. MailYak.SendAll() EHLO count = %v, want %v", tt.name, ehlo, tt.wantEHLO)
+			}
+			if want := len(mails) - tt.wantErrs; data != want {
+				t.Errorf("%q. MailYak.SendAll() DATA count = %v, want %v", tt.name, data, want)
+			}
+		})
+	}
+}
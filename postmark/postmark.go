@@ -0,0 +1,179 @@
+// Package postmark provides a mailyak.Transport sending emails through the
+// Postmark email API.
+package postmark
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/domodwyer/mailyak/v3/internal/apimail"
+)
+
+const (
+	// TagHeader is the custom header holding the Postmark tag of an email
+	// sent by Transport, as used by the Postmark SMTP service:
+	//
+	//	mail.AddHeader(postmark.TagHeader, "welcome")
+	TagHeader = "X-PM-Tag"
+
+	// MetadataHeaderPrefix is the prefix of custom headers holding Postmark
+	// metadata of an email sent by Transport, as used by the Postmark SMTP
+	// service:
+	//
+	//	mail.AddHeader(postmark.MetadataHeaderPrefix+"user-id", "42")
+	MetadataHeaderPrefix = "X-PM-Metadata-"
+)
+
+// Transport is a mailyak.Transport sending emails through the Postmark email
+// API:
+//
+//	mail.Transport(postmark.New(os.Getenv("POSTMARK_SERVER_TOKEN")))
+//
+// The bodies, addresses, attachments and headers of the email are converted
+// into a Postmark request, with the TagHeader and MetadataHeaderPrefix custom
+// headers sent as the tag and metadata of the email. Signed or encrypted
+// emails and calendar invitations cannot be sent.
+//
+// Errors returned by Postmark are returned as a *mailyak.APIError.
+type Transport struct {
+	serverToken   string
+	endpoint      string
+	client        *http.Client
+	messageStream string
+}
+
+// New returns a Transport authenticating with the Postmark serverToken.
+func New(serverToken string) *Transport {
+	return &Transport{
+		serverToken: serverToken,
+		endpoint:    "https://api.postmarkapp.com",
+	}
+}
+
+// Endpoint sets the base URL of the Postmark API. Defaults to
+// "https://api.postmarkapp.com".
+func (t *Transport) Endpoint(u string) {
+	t.endpoint = strings.TrimSuffix(u, "/")
+}
+
+// HTTPClient sets the HTTP client used to call Postmark. Defaults to
+// http.DefaultClient.
+func (t *Transport) HTTPClient(c *http.Client) {
+	t.client = c
+}
+
+// MessageStream sets the ID of the Postmark message stream used to send emails,
+// such as "broadcast". Defaults to the "outbound" transactional stream.
+func (t *Transport) MessageStream(id string) {
+	t.messageStream = id
+}
+
+// request is the body of a Postmark email request.
+type request struct {
+	From          string            `json:"From"`
+	To            string            `json:"To"`
+	Cc            string            `json:"Cc,omitempty"`
+	Bcc           string            `json:"Bcc,omitempty"`
+	Subject       string            `json:"Subject"`
+	Tag           string            `json:"Tag,omitempty"`
+	HTMLBody      string            `json:"HtmlBody,omitempty"`
+	TextBody      string            `json:"TextBody,omitempty"`
+	ReplyTo       string            `json:"ReplyTo,omitempty"`
+	Headers       []header          `json:"Headers,omitempty"`
+	Metadata      map[string]string `json:"Metadata,omitempty"`
+	Attachments   []attachment      `json:"Attachments,omitempty"`
+	MessageStream string            `json:"MessageStream,omitempty"`
+}
+
+type header struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+type attachment struct {
+	Name        string `json:"Name"`
+	Content     []byte `json:"Content"`
+	ContentType string `json:"ContentType"`
+	ContentID   string `json:"ContentID,omitempty"`
+}
+
+// Send sends the email to the Postmark API.
+//
+// The recipients are taken from the To and Cc addresses of the email, with any
+// other recipients in rcpts sent as Bcc recipients.
+func (t *Transport) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	e, err := apimail.NewEmail(msg, rcpts, "Postmark")
+	if err != nil {
+		return err
+	}
+
+	req := request{
+		From:          e.From.String(),
+		To:            joinAddresses(e.To),
+		Cc:            joinAddresses(e.Cc),
+		Bcc:           joinAddresses(e.Bcc),
+		Subject:       e.Subject,
+		Tag:           e.PopHeader(TagHeader),
+		HTMLBody:      e.HTML,
+		TextBody:      e.Plain,
+		MessageStream: t.messageStream,
+	}
+	if e.ReplyTo != nil {
+		req.ReplyTo = e.ReplyTo.String()
+	}
+
+	prefix := strings.ToLower(MetadataHeaderPrefix)
+	for _, k := range apimail.SortedKeys(e.Headers) {
+		if strings.HasPrefix(strings.ToLower(k), prefix) {
+			if req.Metadata == nil {
+				req.Metadata = map[string]string{}
+			}
+			req.Metadata[strings.ToLower(k[len(prefix):])] = e.PopHeader(k)
+			continue
+		}
+		for _, v := range e.HeaderValues[k] {
+			req.Headers = append(req.Headers, header{Name: k, Value: v})
+		}
+	}
+
+	for _, a := range e.Attachments {
+		att := attachment{
+			Name:        a.Filename,
+			Content:     a.Data,
+			ContentType: a.ContentType,
+		}
+		if a.Inline {
+			att.ContentID = "cid:" + a.ContentID
+		}
+		req.Attachments = append(req.Attachments, att)
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint+"/email", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Accept", "application/json")
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Postmark-Server-Token", t.serverToken)
+
+	return apimail.Do(t.client, r, "Postmark", nil)
+}
+
+// joinAddresses returns addrs as a comma separated address list.
+func joinAddresses(addrs []*mail.Address) string {
+	s := make([]string, len(addrs))
+	for i, a := range addrs {
+		s[i] = a.String()
+	}
+	return strings.Join(s, ", ")
+}
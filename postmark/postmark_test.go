@@ -0,0 +1,120 @@
+package postmark
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/domodwyer/mailyak/v3"
+)
+
+// TestTransport ensures emails are converted into Postmark email
+// requests.
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	var (
+		got     request
+		headers http.Header
+		path    string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		headers = r.Header
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		w.Write([]byte(`{"ErrorCode": 0, "Message": "OK", "MessageID": "abc"}`))
+	}))
+	defer srv.Close()
+
+	transport := New("token")
+	transport.Endpoint(srv.URL)
+	transport.MessageStream("broadcast")
+
+	mail := mailyak.New("", nil)
+	mail.Transport(transport)
+	mail.From("from@example.org")
+	mail.FromName("Dom")
+	mail.To("to@example.org", "Zoë <zoe@example.org>")
+	mail.Cc("cc@example.org")
+	mail.ReplyTo("reply@example.org")
+	mail.Subject("Hello")
+	mail.AddHeader("X-Campaign", "launch")
+	mail.AddHeader("X-Campaign", "spring")
+	mail.AddHeader(TagHeader, "welcome")
+	mail.AddHeader(MetadataHeaderPrefix+"User-ID", "42")
+	mail.Plain().Set("plain")
+	mail.HTML().Set("<p>html</p>")
+	mail.Attach("report.csv", strings.NewReader("a,b"))
+	mail.AttachInlineWithMimeType("logo.png", strings.NewReader("png"), "image/png")
+
+	if _, _, err := mail.Send("localhost"); err != nil {
+		t.Fatalf("Transport.Send() error = %v", err)
+	}
+
+	if path != "/email" {
+		t.Errorf("Transport.Send() path = %q", path)
+	}
+	if got := headers.Get("X-Postmark-Server-Token"); got != "token" {
+		t.Errorf("Transport.Send() X-Postmark-Server-Token = %q", got)
+	}
+
+	want := request{
+		From:     `"Dom" <from@example.org>`,
+		To:       "<to@example.org>, =?utf-8?q?Zo=C3=AB?= <zoe@example.org>",
+		Cc:       "<cc@example.org>",
+		Subject:  "Hello",
+		Tag:      "welcome",
+		HTMLBody: "<p>html</p>",
+		TextBody: "plain",
+		ReplyTo:  "<reply@example.org>",
+		Headers: []header{
+			{Name: "Message-Id", Value: mail.GetMessageID()},
+			{Name: "X-Campaign", Value: "launch"},
+			{Name: "X-Campaign", Value: "spring"},
+			{Name: "X-Mailer", Value: mailyak.DefaultMailer},
+		},
+		Metadata: map[string]string{"user-id": "42"},
+		Attachments: []attachment{
+			{Name: "report.csv", Content: []byte("a,b"), ContentType: "text/plain; charset=utf-8"},
+			{Name: "logo.png", Content: []byte("png"), ContentType: "image/png", ContentID: "cid:logo.png"},
+		},
+		MessageStream: "broadcast",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Transport.Send() request =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+// TestTransport_error ensures errors returned by Postmark are returned
+// as a *mailyak.APIError.
+func TestTransport_error(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"ErrorCode": 300, "Message": "Invalid 'To' address."}`))
+	}))
+	defer srv.Close()
+
+	transport := New("token")
+	transport.Endpoint(srv.URL)
+
+	mail := mailyak.New("", nil)
+	mail.Transport(transport)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+
+	_, _, err := mail.Send("localhost")
+
+	want := &mailyak.APIError{Service: "Postmark", StatusCode: http.StatusUnprocessableEntity, Message: "Invalid 'To' address."}
+	var apiErr *mailyak.APIError
+	if !errors.As(err, &apiErr) || *apiErr != *want {
+		t.Errorf("Transport.Send() error = %v, want %v", err, want)
+	}
+}
@@ -0,0 +1,56 @@
+package mailyak
+
+import (
+	"fmt"
+	"io"
+)
+
+// PriorityLevel is the priority of an email, as used in the X-Priority header.
+type PriorityLevel int
+
+const (
+	// PriorityHigh marks an email as urgent.
+	PriorityHigh PriorityLevel = 1
+
+	// PriorityNormal is the default priority of an email.
+	PriorityNormal PriorityLevel = 3
+
+	// PriorityLow marks an email as non-urgent.
+	PriorityLow PriorityLevel = 5
+)
+
+// Priority sets the X-Priority, Importance and Priority headers consistently
+// for level, so urgent emails are flagged by Outlook and other clients:
+//
+//	mail.Priority(mailyak.PriorityHigh)
+//
+// The intermediate X-Priority levels 2 and 4 are also accepted. Any other
+// level removes the headers.
+func (m *MailYak) Priority(level PriorityLevel) {
+	if level < PriorityHigh || level > PriorityLow {
+		level = 0
+	}
+	m.priority = level
+}
+
+// writePriorityHeaders writes the headers for the priority level, if set.
+func (m *MailYak) writePriorityHeaders(w io.Writer) error {
+	var xPriority, importance, priority string
+	switch m.priority {
+	case 0:
+		return nil
+	case PriorityHigh:
+		xPriority, importance, priority = "1 (Highest)", "high", "urgent"
+	case 2:
+		xPriority, importance, priority = "2 (High)", "high", "urgent"
+	case PriorityNormal:
+		xPriority, importance, priority = "3 (Normal)", "normal", "normal"
+	case 4:
+		xPriority, importance, priority = "4 (Low)", "low", "non-urgent"
+	default:
+		xPriority, importance, priority = "5 (Lowest)", "low", "non-urgent"
+	}
+
+	_, err := fmt.Fprintf(w, "X-Priority: %s\r\nImportance: %s\r\nPriority: %s\r\n", xPriority, importance, priority)
+	return err
+}
@@ -0,0 +1,69 @@
+package mailyak
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestMailYakPriority ensures the priority headers are set consistently
+func TestMailYakPriority(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		level PriorityLevel
+		// Want
+		want string
+	}{
+		{
+			"High",
+			PriorityHigh,
+			"X-Priority: 1 (Highest)\r\nImportance: high\r\nPriority: urgent\r\n",
+		},
+		{
+			"Normal",
+			PriorityNormal,
+			"X-Priority: 3 (Normal)\r\nImportance: normal\r\nPriority: normal\r\n",
+		},
+		{
+			"Low",
+			PriorityLow,
+			"X-Priority: 5 (Lowest)\r\nImportance: low\r\nPriority: non-urgent\r\n",
+		},
+		{
+			"Intermediate",
+			2,
+			"X-Priority: 2 (High)\r\nImportance: high\r\nPriority: urgent\r\n",
+		},
+		{
+			"Unset",
+			0,
+			"",
+		},
+		{
+			"Out of range",
+			6,
+			"",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := MailYak{}
+			m.Priority(tt.level)
+
+			buf := &bytes.Buffer{}
+			if err := m.writePriorityHeaders(buf); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("%q. MailYak.writePriorityHeaders() = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
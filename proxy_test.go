@@ -0,0 +1,290 @@
+package mailyak
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// pipe copies data between a and b until either is closed.
+func pipe(a, b net.Conn) {
+	defer a.Close()
+	defer b.Close()
+
+	go io.Copy(a, b)
+	io.Copy(b, a)
+}
+
+// newTestHTTPProxy starts a HTTP CONNECT proxy, requiring the Proxy-Authorization
+// header to equal auth if non-empty.
+func newTestHTTPProxy(t *testing.T, auth string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil || req.Method != http.MethodConnect {
+					conn.Close()
+					return
+				}
+
+				if auth != "" && req.Header.Get("Proxy-Authorization") != auth {
+					io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+					conn.Close()
+					return
+				}
+
+				target, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+					conn.Close()
+					return
+				}
+
+				io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+				pipe(conn, target)
+			}()
+		}
+	}()
+
+	return l
+}
+
+// newTestSOCKS5Proxy starts a SOCKS5 proxy, requiring username/password
+// authentication if user is non-empty.
+func newTestSOCKS5Proxy(t *testing.T, user, pass string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				target, err := socks5Accept(conn, user, pass)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				pipe(conn, target)
+			}()
+		}
+	}()
+
+	return l
+}
+
+// socks5Accept performs the server side of the SOCKS5 handshake, returning a
+// connection to the requested target.
+func socks5Accept(conn net.Conn, user, pass string) (net.Conn, error) {
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+
+	methods := make([]byte, buf[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return nil, err
+	}
+
+	if user == "" {
+		conn.Write([]byte{socks5Version, socks5AuthNone})
+	} else {
+		conn.Write([]byte{socks5Version, socks5AuthPassword})
+
+		// Version and username
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return nil, err
+		}
+		gotUser := make([]byte, buf[1])
+		if _, err := io.ReadFull(conn, gotUser); err != nil {
+			return nil, err
+		}
+
+		// Password
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return nil, err
+		}
+		gotPass := make([]byte, buf[0])
+		if _, err := io.ReadFull(conn, gotPass); err != nil {
+			return nil, err
+		}
+
+		if string(gotUser) != user || string(gotPass) != pass {
+			conn.Write([]byte{0x01, 0x01})
+			return nil, io.EOF
+		}
+		conn.Write([]byte{0x01, socks5Succeeded})
+	}
+
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return nil, err
+	}
+
+	var host string
+	switch req[3] {
+	case socks5AddrIPv4:
+		ip := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return nil, err
+		}
+		host = net.IP(ip).String()
+	case socks5AddrDomain:
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return nil, err
+		}
+		name := make([]byte, buf[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return nil, err
+		}
+		host = string(name)
+	default:
+		return nil, io.EOF
+	}
+
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+	port := int(buf[0])<<8 | int(buf[1])
+
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		conn.Write([]byte{socks5Version, 0x05, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+		return nil, err
+	}
+
+	conn.Write([]byte{socks5Version, socks5Succeeded, 0x00, socks5AddrIPv4, 127, 0, 0, 1, 0, 0})
+	return target, nil
+}
+
+// TestMailYakProxy ensures the SMTP conversation is performed through the
+// configured proxy.
+func TestMailYakProxy(t *testing.T) {
+	t.Parallel()
+
+	httpProxy := newTestHTTPProxy(t, "")
+	defer httpProxy.Close()
+
+	httpAuthProxy := newTestHTTPProxy(t, "Basic dXNlcjpwYXNz") // user:pass
+	defer httpAuthProxy.Close()
+
+	socksProxy := newTestSOCKS5Proxy(t, "", "")
+	defer socksProxy.Close()
+
+	socksAuthProxy := newTestSOCKS5Proxy(t, "user", "pass")
+	defer socksAuthProxy.Close()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		proxyURL string
+		// Want
+		wantErr     bool
+		wantProxied bool
+	}{
+		{"HTTP", "http://" + httpProxy.Addr().String(), false, true},
+		{"HTTP with auth", "http://user:pass@" + httpAuthProxy.Addr().String(), false, true},
+		{"HTTP bad auth", "http://user:wrong@" + httpAuthProxy.Addr().String(), false, false},
+		{"SOCKS5", "socks5://" + socksProxy.Addr().String(), false, true},
+		{"SOCKS5h", "socks5h://" + socksProxy.Addr().String(), false, true},
+		{"SOCKS5 with auth", "socks5://user:pass@" + socksAuthProxy.Addr().String(), false, true},
+		{"SOCKS5 bad auth", "socks5://user:wrong@" + socksAuthProxy.Addr().String(), false, false},
+		{"Unsupported scheme", "ftp://" + httpProxy.Addr().String(), true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, map[string]string{"MAIL": "550 Go away"})
+			defer srv.Close()
+
+			mail := New(srv.Addr(), nil)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+
+			if err := mail.Proxy(tt.proxyURL); (err != nil) != tt.wantErr {
+				t.Fatalf("%q. MailYak.Proxy() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if _, _, err := mail.Send("localhost"); err == nil {
+				t.Fatalf("%q. MailYak.Send() error = nil, want error", tt.name)
+			}
+
+			var want []string
+			if tt.wantProxied {
+				want = []string{"EHLO localhost", "MAIL FROM:<from@example.org>"}
+			}
+
+			if got := srv.Commands(); !reflect.DeepEqual(got, want) {
+				t.Errorf("%q. MailYak.Send() commands = %q, want %q", tt.name, got, want)
+			}
+		})
+	}
+}
+
+// TestMailYakProxy_dialer ensures the proxy is connected to using the dialer
+// set with Dialer, even when Dialer is called after Proxy.
+func TestMailYakProxy_dialer(t *testing.T) {
+	t.Parallel()
+
+	proxy := newTestHTTPProxy(t, "")
+	defer proxy.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTestSMTPServer(l, map[string]string{"MAIL": "550 Go away"})
+	defer srv.Close()
+
+	dialer := &testDialer{}
+
+	mail := New(srv.Addr(), nil)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+
+	if err := mail.Proxy("http://" + proxy.Addr().String()); err != nil {
+		t.Fatalf("MailYak.Proxy() error = %v", err)
+	}
+	mail.Dialer(dialer)
+
+	if _, _, err := mail.Send("localhost"); err == nil {
+		t.Fatal("MailYak.Send() error = nil, want MAIL rejection")
+	}
+
+	if want := []string{"tcp://" + proxy.Addr().String()}; !reflect.DeepEqual(dialer.addrs, want) {
+		t.Errorf("MailYak.Send() dialed = %v, want %v", dialer.addrs, want)
+	}
+
+	want := []string{"EHLO localhost", "MAIL FROM:<from@example.org>"}
+	if got := srv.Commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MailYak.Send() commands = %q, want %q", got, want)
+	}
+}
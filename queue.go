@@ -0,0 +1,178 @@
+package mailyak
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrQueueFull is returned by Queue.Enqueue when the queue is at capacity.
+	ErrQueueFull = errors.New("mailyak: queue full")
+
+	// ErrQueueClosed is returned by Queue.Enqueue once the queue has been
+	// closed.
+	ErrQueueClosed = errors.New("mailyak: queue closed")
+)
+
+// Queue sends emails asynchronously, with a fixed number of workers delivering
+// queued emails over pooled SMTP connections:
+//
+//	queue := mailyak.NewQueue(mailyak.New("smtp.itsallbroken.com:587", auth), "localhost", 4, 1000)
+//	defer queue.Close(context.Background())
+//
+//	err := queue.Enqueue(mail, func(err error) {
+//		if err != nil {
+//			log.Printf("sending failed: %v", err)
+//		}
+//	})
+//
+// Emails without a Transport are delivered over a Pool connecting to the SMTP
+// server configured on config, while emails with a Transport are delivered
+// using it. The retry and rate limit configuration of each email is applied.
+//
+// A Queue is safe for concurrent use.
+type Queue struct {
+	pool          *Pool
+	localHostName string
+
+	// ctx is cancelled to abort in-flight sends when Close gives up waiting
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.RWMutex
+	jobs   chan queuedEmail
+	closed bool
+
+	wg sync.WaitGroup
+}
+
+// queuedEmail is an email waiting to be sent, and the function to call once it
+// has been handled.
+type queuedEmail struct {
+	mail *MailYak
+	done func(err error)
+}
+
+// NewQueue returns a Queue delivering emails with workers concurrent senders,
+// holding up to capacity emails waiting to be sent.
+//
+// The SMTP server, authentication, TLS, dialer and timeout configuration of
+// config is used to connect, identifying as localHostName (or the HelloName of
+// config if empty), with up to workers connections kept open for reuse. Close
+// must be called to stop the workers and close the connections once the Queue
+// is no longer needed.
+func NewQueue(config *MailYak, localHostName string, workers, capacity int) *Queue {
+	if workers < 1 {
+		workers = 1
+	}
+	if capacity < 0 {
+		capacity = 0
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	q := &Queue{
+		pool:          NewPool(config, localHostName, workers),
+		localHostName: localHostName,
+		ctx:           ctx,
+		cancel:        cancel,
+		jobs:          make(chan queuedEmail, capacity),
+	}
+
+	q.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+
+	return q
+}
+
+// Enqueue queues mail to be sent, returning immediately. mail must not be
+// modified once enqueued.
+//
+// done, if not nil, is called from a worker goroutine once mail has been sent
+// or has failed, with the result of sending it.
+//
+// ErrQueueFull is returned if the queue is at capacity and no worker is
+// available, and ErrQueueClosed if Close has been called.
+func (q *Queue) Enqueue(mail *MailYak, done func(err error)) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	select {
+	case q.jobs <- queuedEmail{mail: mail, done: done}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting new emails and waits for the queued emails to be sent,
+// then closes the pooled connections.
+//
+// If ctx is done before the queue has drained, in-flight sends are aborted and
+// any remaining emails fail with ErrQueueClosed, and ctx.Err() is returned
+// once the workers have stopped.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		q.cancel()
+		<-drained
+		err = ctx.Err()
+	}
+
+	q.cancel()
+	q.pool.Close()
+
+	return err
+}
+
+// work sends queued emails until the queue is closed and drained.
+func (q *Queue) work() {
+	defer q.wg.Done()
+
+	for job := range q.jobs {
+		err := ErrQueueClosed
+		if q.ctx.Err() == nil {
+			err = q.send(job.mail)
+		}
+
+		if job.done != nil {
+			job.done(err)
+		}
+	}
+}
+
+// send delivers mail using its Transport, or the pool if it has none.
+//
+// The pool is passed for this send only, leaving the Transport of mail unset
+// so it can be sent again once the queue is closed.
+func (q *Queue) send(mail *MailYak) error {
+	transport := mail.transport
+	if transport == nil {
+		transport = q.pool
+	}
+
+	_, _, err := mail.send(q.ctx, q.localHostName, transport)
+	return err
+}
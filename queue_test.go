@@ -0,0 +1,188 @@
+package mailyak
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// blockingTransport blocks each send until released or the context is done.
+type blockingTransport struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (t *blockingTransport) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	if _, err := msg.WriteTo(ioutil.Discard); err != nil {
+		return err
+	}
+
+	t.started <- struct{}{}
+
+	select {
+	case <-t.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TestQueue ensures queued emails are sent over pooled connections, calling
+// the completion function for each.
+func TestQueue(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTestSMTPServer(l, nil)
+	defer srv.Close()
+
+	queue := NewQueue(New(srv.Addr(), nil), "localhost", 2, 10)
+
+	var (
+		mu    sync.Mutex
+		done  = map[string]error{}
+		mails []*MailYak
+	)
+	for i := 0; i < 5; i++ {
+		addr := fmt.Sprintf("user%d@example.org", i)
+
+		mail := New("", nil)
+		mail.From("from@example.org")
+		mail.To(addr)
+		mails = append(mails, mail)
+
+		err := queue.Enqueue(mail, func(err error) {
+			mu.Lock()
+			done[addr] = err
+			mu.Unlock()
+		})
+		if err != nil {
+			t.Fatalf("Queue.Enqueue() error = %v", err)
+		}
+	}
+
+	if err := queue.Close(context.Background()); err != nil {
+		t.Fatalf("Queue.Close() error = %v", err)
+	}
+
+	// the pool is used for each send without becoming the Transport of the
+	// queued emails, which would leave them tied to the closed pool
+	for _, mail := range mails {
+		if mail.transport != nil {
+			t.Errorf("Queue set the Transport of a queued email to %T, want nil", mail.transport)
+		}
+	}
+
+	if len(done) != 5 {
+		t.Errorf("Queue.Close() completed %d emails, want 5", len(done))
+	}
+	for addr, err := range done {
+		if err != nil {
+			t.Errorf("Queue sending to %s error = %v", addr, err)
+		}
+	}
+
+	var ehlo, data int
+	for _, cmd := range srv.Commands() {
+		switch strings.SplitN(cmd, " ", 2)[0] {
+		case "EHLO":
+			ehlo++
+		case "DATA":
+			data++
+		}
+	}
+	if data != 5 {
+		t.Errorf("Queue DATA count = %v, want 5", data)
+	}
+	if ehlo > 2 {
+		t.Errorf("Queue EHLO count = %v, want no more than 2", ehlo)
+	}
+
+	if err := queue.Enqueue(New("", nil), nil); err != ErrQueueClosed {
+		t.Errorf("Queue.Enqueue() after Close error = %v, want %v", err, ErrQueueClosed)
+	}
+}
+
+// TestQueue_full ensures emails are rejected once the queue is at capacity.
+func TestQueue_full(t *testing.T) {
+	t.Parallel()
+
+	transport := &blockingTransport{
+		started: make(chan struct{}, 2),
+		release: make(chan struct{}),
+	}
+
+	queue := NewQueue(New("", nil), "localhost", 1, 1)
+
+	enqueue := func() error {
+		mail := New("", nil)
+		mail.Transport(transport)
+		return queue.Enqueue(mail, nil)
+	}
+
+	// the first email is taken by the worker, and the second fills the queue
+	if err := enqueue(); err != nil {
+		t.Fatalf("Queue.Enqueue() error = %v", err)
+	}
+	<-transport.started
+
+	if err := enqueue(); err != nil {
+		t.Fatalf("Queue.Enqueue() error = %v", err)
+	}
+	if err := enqueue(); err != ErrQueueFull {
+		t.Errorf("Queue.Enqueue() error = %v, want %v", err, ErrQueueFull)
+	}
+
+	close(transport.release)
+	if err := queue.Close(context.Background()); err != nil {
+		t.Fatalf("Queue.Close() error = %v", err)
+	}
+}
+
+// TestQueue_closeTimeout ensures Close aborts in-flight sends and fails the
+// remaining emails when ctx is done before the queue drains.
+func TestQueue_closeTimeout(t *testing.T) {
+	t.Parallel()
+
+	transport := &blockingTransport{
+		started: make(chan struct{}, 2),
+		release: make(chan struct{}),
+	}
+
+	queue := NewQueue(New("", nil), "localhost", 1, 1)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		mail := New("", nil)
+		mail.Transport(transport)
+		if err := queue.Enqueue(mail, func(err error) { errs <- err }); err != nil {
+			t.Fatalf("Queue.Enqueue() error = %v", err)
+		}
+		if i == 0 {
+			<-transport.started
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := queue.Close(ctx); err != context.Canceled {
+		t.Fatalf("Queue.Close() error = %v, want %v", err, context.Canceled)
+	}
+
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("in-flight email error = %v, want %v", err, context.Canceled)
+	}
+	if err := <-errs; err != ErrQueueClosed {
+		t.Errorf("queued email error = %v, want %v", err, ErrQueueClosed)
+	}
+}
@@ -0,0 +1,110 @@
+package mailyak
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the rate at which emails are sent, allowing bulk sends to
+// stay within the throttling limits of an email provider.
+//
+// A RateLimiter is shared between the emails it limits, and is safe for
+// concurrent use:
+//
+//	// allow 10 emails per second, with bursts of up to 20
+//	limiter := mailyak.NewRateLimiter(10, time.Second, 20)
+//
+//	for _, user := range users {
+//		mail := base.Clone()
+//		mail.RateLimit(limiter)
+//		...
+//	}
+type RateLimiter struct {
+	interval time.Duration
+	burst    float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing n emails to be sent per
+// period, such as 100 per time.Minute, with bursts of up to burst emails sent
+// without waiting.
+//
+// A burst less than 1 is treated as 1, spacing emails evenly over the period.
+func NewRateLimiter(n int, per time.Duration, burst int) *RateLimiter {
+	if n < 1 {
+		n = 1
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		interval: per / time.Duration(n),
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until an email may be sent, or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	delay := l.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve takes a token from the bucket, returning how long to wait until it
+// becomes available.
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.interval > 0 {
+		l.tokens += float64(now.Sub(l.last)) / float64(l.interval)
+	} else {
+		l.tokens = l.burst
+	}
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens * float64(l.interval))
+}
+
+// cancel returns a token reserved by a call to Wait that was abandoned.
+func (l *RateLimiter) cancel() {
+	l.mu.Lock()
+	l.tokens++
+	l.mu.Unlock()
+}
+
+// RateLimit sets the RateLimiter that Send and SendWithContext wait on before
+// sending the email. Passing nil disables rate limiting.
+//
+// Clones of the email share the RateLimiter, limiting the rate of the emails
+// sent by a Merge when set on its template email.
+func (m *MailYak) RateLimit(l *RateLimiter) {
+	m.rateLimiter = l
+}
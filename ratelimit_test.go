@@ -0,0 +1,142 @@
+package mailyak
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestRateLimiterReserve ensures bursts are allowed without waiting, with
+// subsequent emails spaced by the rate.
+func TestRateLimiterReserve(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		n     int
+		per   time.Duration
+		burst int
+		// Want
+		wantImmediate int
+		wantInterval  time.Duration
+	}{
+		{"Burst", 10, time.Second, 3, 3, 100 * time.Millisecond},
+		{"No burst", 60, time.Minute, 0, 1, time.Second},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := NewRateLimiter(tt.n, tt.per, tt.burst)
+
+			for i := 0; i < tt.wantImmediate; i++ {
+				if got := l.reserve(); got != 0 {
+					t.Fatalf("RateLimiter.reserve() #%d = %v, want 0", i+1, got)
+				}
+			}
+
+			// allow for the time elapsed since the limiter was created
+			got := l.reserve()
+			if got <= tt.wantInterval/2 || got > tt.wantInterval {
+				t.Errorf("RateLimiter.reserve() = %v, want %v", got, tt.wantInterval)
+			}
+		})
+	}
+}
+
+// TestRateLimiterWait_cancel ensures waiting stops when the context is done,
+// returning the reserved token.
+func TestRateLimiterWait_cancel(t *testing.T) {
+	t.Parallel()
+
+	l := NewRateLimiter(1, time.Hour, 1)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("RateLimiter.Wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Fatalf("RateLimiter.Wait() error = %v, want %v", err, context.Canceled)
+	}
+
+	if got := l.reserve(); got > time.Hour {
+		t.Errorf("RateLimiter.reserve() = %v, want no more than 1h", got)
+	}
+}
+
+// TestMergeRateLimit ensures emails sent by a Merge are rate limited.
+func TestMergeRateLimit(t *testing.T) {
+	t.Parallel()
+
+	transport := &mergeTransport{sent: map[string]string{}}
+
+	tmpl := New("", nil)
+	tmpl.Transport(transport)
+	tmpl.From("from@example.org")
+	tmpl.Subject("Hello {{.Name}}")
+
+	var recipients []MergeRecipient
+	for i := 0; i < 3; i++ {
+		recipients = append(recipients, MergeRecipient{
+			Addr: fmt.Sprintf("user%d@example.org", i),
+			Data: map[string]interface{}{"Name": i},
+		})
+	}
+
+	merge := NewMerge(tmpl, recipients)
+	merge.Concurrency(3)
+	merge.RateLimit(NewRateLimiter(1, 50*time.Millisecond, 1))
+
+	start := time.Now()
+	if err := merge.Send(context.Background(), "localhost"); err != nil {
+		t.Fatalf("Merge.Send() error = %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Merge.Send() took %v, want at least 100ms", elapsed)
+	}
+	if len(transport.sent) != 3 {
+		t.Errorf("Merge.Send() sent %d emails, want 3", len(transport.sent))
+	}
+}
+
+// TestMailYakSendAll_rateLimit ensures emails sent by SendAll are rate limited
+// by the server configuration.
+func TestMailYakSendAll_rateLimit(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTestSMTPServer(l, nil)
+	defer srv.Close()
+
+	var mails []*MailYak
+	for i := 0; i < 3; i++ {
+		mail := New("", nil)
+		mail.From("from@example.org")
+		mail.To("to@example.org")
+		mails = append(mails, mail)
+	}
+
+	server := New(srv.Addr(), nil)
+	server.RateLimit(NewRateLimiter(1, 50*time.Millisecond, 1))
+
+	start := time.Now()
+	if err := server.SendAll(context.Background(), "localhost", mails...); err != nil {
+		t.Fatalf("MailYak.SendAll() error = %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("MailYak.SendAll() took %v, want at least 100ms", elapsed)
+	}
+}
@@ -0,0 +1,168 @@
+package mailyak
+
+import (
+	"fmt"
+	"html"
+	"mime"
+	"net/mail"
+	"strings"
+)
+
+// ReplyOptions configures the reply built by Reply.
+type ReplyOptions struct {
+	// All also addresses the reply to the To and Cc recipients of the original
+	// email, as "reply all".
+	All bool
+
+	// Quote includes the body of the original email in the reply, following an
+	// attribution line such as "On Mon, 2 Jan 2006 15:04:05 -0700, Dom
+	// <dom@itsallbroken.com> wrote:".
+	Quote bool
+}
+
+// Reply returns a new email replying to m, typically an inbound email loaded
+// with Parse:
+//
+//	orig, err := mailyak.Parse(r)
+//	if err != nil {
+//		return err
+//	}
+//
+//	reply := orig.Reply(mailyak.ReplyOptions{Quote: true})
+//	reply.From("support@itsallbroken.com")
+//	reply.Plain().Set("Thanks, we're looking into it." + "\n\n" + reply.Plain().String())
+//
+// The reply is addressed to the Reply-To address of m, or the From address if
+// unset, with the subject prefixed with "Re:" and the In-Reply-To and
+// References headers set to thread the reply into the same conversation.
+//
+// The SMTP server, authentication, TLS, dialer, timeout and signing
+// configuration of m is retained, but the From address must be set.
+func (m *MailYak) Reply(opts ReplyOptions) *MailYak {
+	r := m.Clone()
+	r.Reset()
+	r.From("")
+	r.FromName("")
+	r.EnvelopeFrom("")
+	r.Sender("")
+	r.ReplyTo("")
+
+	to := m.replyTo
+	if to == "" {
+		to = m.fromHeaderAddress()
+	}
+	if to != "" {
+		r.To(to)
+	}
+
+	if opts.All {
+		seen := map[string]bool{strings.ToLower(envelopeAddress(to)): true}
+
+		var cc []string
+		for _, addr := range append(cloneStrings(m.toAddrs), m.ccAddrs...) {
+			key := strings.ToLower(envelopeAddress(addr))
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			cc = append(cc, addr)
+		}
+		r.Cc(cc...)
+	}
+
+	subject := decodeHeader(m.subject)
+	if !hasSubjectPrefix(subject, "re:") {
+		subject = "Re: " + subject
+	}
+	r.Subject(subject)
+
+	// The References of the reply are those of the original, followed by its
+	// Message-ID (RFC 5322 section 3.6.4)
+	refs := cloneStrings(m.references)
+	if len(refs) == 0 && m.inReplyTo != "" {
+		refs = []string{m.inReplyTo}
+	}
+	r.InReplyTo(m.GetMessageID())
+	r.References(append(refs, m.GetMessageID())...)
+
+	if opts.Quote {
+		attribution := m.fromHeaderAddress() + " wrote:"
+		if m.date != "" {
+			attribution = "On " + m.date + ", " + attribution
+		}
+
+		if m.plain.Len() > 0 {
+			r.plain.Set(attribution + "\n" + quoteText(m.plain.String()))
+		}
+		if m.html.Len() > 0 {
+			r.html.Set(fmt.Sprintf(
+				"<p>%s</p>\n<blockquote type=\"cite\">\n%s\n</blockquote>\n",
+				html.EscapeString(attribution),
+				htmlBodyContent(m.html.String()),
+			))
+		}
+	}
+
+	return r
+}
+
+// fromHeaderAddress returns the From address of m, including the display name
+// if set.
+func (m *MailYak) fromHeaderAddress() string {
+	if m.fromName == "" {
+		return m.fromAddr
+	}
+	return (&mail.Address{Name: decodeHeader(m.fromName), Address: m.fromAddr}).String()
+}
+
+// decodeHeader returns s with any RFC 2047 encoded words decoded.
+func decodeHeader(s string) string {
+	decoded, err := new(mime.WordDecoder).DecodeHeader(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+// hasSubjectPrefix returns true if subject starts with the lowercase prefix,
+// ignoring case.
+func hasSubjectPrefix(subject, prefix string) bool {
+	subject = strings.TrimSpace(subject)
+	return len(subject) >= len(prefix) && strings.EqualFold(subject[:len(prefix)], prefix)
+}
+
+// quoteText returns text with each line prefixed with "> ".
+func quoteText(text string) string {
+	lines := strings.Split(strings.TrimRight(strings.Replace(text, "\r\n", "\n", -1), "\n"), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ">") {
+			lines[i] = ">" + line
+			continue
+		}
+		lines[i] = "> " + line
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// htmlBodyContent returns the content of the body element of the HTML document
+// doc, or doc itself if it has no body element.
+func htmlBodyContent(doc string) string {
+	lower := strings.ToLower(doc)
+
+	start := strings.Index(lower, "<body")
+	if start < 0 {
+		return doc
+	}
+	open := strings.IndexByte(lower[start:], '>')
+	if open < 0 {
+		return doc
+	}
+	start += open + 1
+
+	end := strings.LastIndex(lower, "</body>")
+	if end < start {
+		end = len(doc)
+	}
+
+	return strings.TrimSpace(doc[start:end])
+}
@@ -0,0 +1,148 @@
+package mailyak
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestMailYakReply ensures replies are addressed, threaded and quoted.
+func TestMailYakReply(t *testing.T) {
+	t.Parallel()
+
+	orig := func() *MailYak {
+		m := New("mail.host.com:25", nil)
+		m.From("dom@itsallbroken.com")
+		m.FromName("Dom")
+		m.To("support@itsallbroken.com", "Zoë <zoe@itsallbroken.com>")
+		m.Cc("ops@itsallbroken.com", "dom@itsallbroken.com")
+		m.Subject("Broken build")
+		m.Date(time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC))
+		m.messageID = "<child@itsallbroken.com>"
+		m.InReplyTo("<parent@itsallbroken.com>")
+		m.References("<root@itsallbroken.com>", "<parent@itsallbroken.com>")
+		m.Plain().Set("It's broken.\n> Is it?\n")
+		m.HTML().Set("<html><body><p>It's broken.</p></body></html>")
+		return m
+	}
+
+	tests := []struct {
+		// Test description.
+		name string
+
+		// Receiver fields.
+		rOrig func() *MailYak
+
+		// Parameters.
+		opts ReplyOptions
+
+		// Want
+		wantTo      []string
+		wantCc      []string
+		wantSubject string
+		wantRefs    []string
+		wantPlain   string
+		wantHTML    string
+	}{
+		{
+			name:        "Reply",
+			rOrig:       orig,
+			wantTo:      []string{`"Dom" <dom@itsallbroken.com>`},
+			wantSubject: "Re: Broken build",
+			wantRefs:    []string{"<root@itsallbroken.com>", "<parent@itsallbroken.com>", "<child@itsallbroken.com>"},
+		},
+		{
+			name: "Reply-To",
+			rOrig: func() *MailYak {
+				m := orig()
+				m.ReplyTo("tickets@itsallbroken.com")
+				return m
+			},
+			wantTo:      []string{"tickets@itsallbroken.com"},
+			wantSubject: "Re: Broken build",
+			wantRefs:    []string{"<root@itsallbroken.com>", "<parent@itsallbroken.com>", "<child@itsallbroken.com>"},
+		},
+		{
+			name:        "Reply all",
+			rOrig:       orig,
+			opts:        ReplyOptions{All: true},
+			wantTo:      []string{`"Dom" <dom@itsallbroken.com>`},
+			wantCc:      []string{"support@itsallbroken.com", "Zoë <zoe@itsallbroken.com>", "ops@itsallbroken.com"},
+			wantSubject: "Re: Broken build",
+			wantRefs:    []string{"<root@itsallbroken.com>", "<parent@itsallbroken.com>", "<child@itsallbroken.com>"},
+		},
+		{
+			name: "Existing prefix",
+			rOrig: func() *MailYak {
+				m := orig()
+				m.Subject("RE: Broken build")
+				return m
+			},
+			wantTo:      []string{`"Dom" <dom@itsallbroken.com>`},
+			wantSubject: "RE: Broken build",
+			wantRefs:    []string{"<root@itsallbroken.com>", "<parent@itsallbroken.com>", "<child@itsallbroken.com>"},
+		},
+		{
+			name: "No References",
+			rOrig: func() *MailYak {
+				m := orig()
+				m.References()
+				return m
+			},
+			wantTo:      []string{`"Dom" <dom@itsallbroken.com>`},
+			wantSubject: "Re: Broken build",
+			wantRefs:    []string{"<parent@itsallbroken.com>", "<child@itsallbroken.com>"},
+		},
+		{
+			name:        "Quote",
+			rOrig:       orig,
+			opts:        ReplyOptions{Quote: true},
+			wantTo:      []string{`"Dom" <dom@itsallbroken.com>`},
+			wantSubject: "Re: Broken build",
+			wantRefs:    []string{"<root@itsallbroken.com>", "<parent@itsallbroken.com>", "<child@itsallbroken.com>"},
+			wantPlain:   "On Thu, 04 Mar 2021 05:06:07 +0000, \"Dom\" <dom@itsallbroken.com> wrote:\n> It's broken.\n>> Is it?\n",
+			wantHTML:    "<p>On Thu, 04 Mar 2021 05:06:07 +0000, &#34;Dom&#34; &lt;dom@itsallbroken.com&gt; wrote:</p>\n<blockquote type=\"cite\">\n<p>It's broken.</p>\n</blockquote>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := tt.rOrig()
+			r := m.Reply(tt.opts)
+
+			if r.fromAddr != "" || r.fromName != "" || r.replyTo != "" {
+				t.Errorf("Reply() from = %q, fromName = %q, replyTo = %q, want empty", r.fromAddr, r.fromName, r.replyTo)
+			}
+			if r.host != "mail.host.com:25" {
+				t.Errorf("Reply() host = %q, want %q", r.host, "mail.host.com:25")
+			}
+			if !reflect.DeepEqual(r.toAddrs, tt.wantTo) {
+				t.Errorf("Reply() to = %v, want %v", r.toAddrs, tt.wantTo)
+			}
+			if (len(r.ccAddrs) > 0 || len(tt.wantCc) > 0) && !reflect.DeepEqual(r.ccAddrs, tt.wantCc) {
+				t.Errorf("Reply() cc = %v, want %v", r.ccAddrs, tt.wantCc)
+			}
+			if got := decodeHeader(r.subject); got != tt.wantSubject {
+				t.Errorf("Reply() subject = %q, want %q", got, tt.wantSubject)
+			}
+			if r.inReplyTo != "<child@itsallbroken.com>" {
+				t.Errorf("Reply() inReplyTo = %q, want %q", r.inReplyTo, "<child@itsallbroken.com>")
+			}
+			if !reflect.DeepEqual(r.references, tt.wantRefs) {
+				t.Errorf("Reply() references = %v, want %v", r.references, tt.wantRefs)
+			}
+			if got := r.plain.String(); got != tt.wantPlain {
+				t.Errorf("Reply() plain = %q, want %q", got, tt.wantPlain)
+			}
+			if got := r.html.String(); got != tt.wantHTML {
+				t.Errorf("Reply() html = %q, want %q", got, tt.wantHTML)
+			}
+			if r.GetMessageID() == m.GetMessageID() {
+				t.Errorf("Reply() reused the Message-ID %q", m.GetMessageID())
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package mailyak
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SendResult describes how the SMTP server responded to an email, and the
+// connection it was sent over.
+type SendResult struct {
+	// Accepted holds the recipients accepted by the server.
+	Accepted []RecipientStatus
+
+	// Rejected holds the recipients rejected by the server.
+	Rejected []RecipientStatus
+
+	// Code and Msg are the final response of the server to the message
+	// data, or zero if the message data was not accepted.
+	Code int
+	Msg  string
+
+	// EnhancedCode is the enhanced status code (RFC 3463) at the start of
+	// Msg, such as "2.0.0", or empty if the server did not include one.
+	EnhancedCode string
+
+	// BytesWritten is the size of the message data written to the server.
+	BytesWritten int64
+
+	// Host is the SMTP host the email was sent to, as passed to New or
+	// FallbackHosts.
+	Host string
+
+	// TLS is the state of the TLS connection to the server, or nil if the
+	// connection was not encrypted.
+	TLS *tls.ConnectionState
+
+	// Start is when sending began, and Duration how long it took, including
+	// any retries.
+	Start    time.Time
+	Duration time.Duration
+}
+
+// RecipientStatus is the response of the SMTP server to the RCPT command for a
+// recipient.
+type RecipientStatus struct {
+	// Addr is the recipient address.
+	Addr string
+
+	// Code and Msg are the response of the server.
+	Code int
+	Msg  string
+}
+
+// PartialDelivery causes Send to continue delivering the email to the accepted
+// recipients when the SMTP server rejects some of them, rather than failing on
+// the first rejected recipient. Defaults to false.
+//
+// The email is sent if at least one recipient is accepted, with the rejected
+// recipients listed by GetSendResult:
+//
+//	mail.PartialDelivery(true)
+//	if _, _, err := mail.Send("localhost"); err != nil {
+//		return err
+//	}
+//	for _, r := range mail.GetSendResult().Rejected {
+//		log.Printf("%s rejected: %d %s", r.Addr, r.Code, r.Msg)
+//	}
+//
+// If every recipient is rejected, Send returns the *RecipientRejectedError of
+// the first.
+func (m *MailYak) PartialDelivery(enabled bool) {
+	m.partialDelivery = enabled
+}
+
+// GetSendResult returns the result of the last call to Send or
+// SendWithContext - the server response, the recipients accepted and rejected
+// by the SMTP server and details of the connection - or nil if no recipients
+// were sent to a server:
+//
+//	if r := mail.GetSendResult(); r != nil {
+//		log.Printf("sent to %s in %v: %d %s", r.Host, r.Duration, r.Code, r.Msg)
+//	}
+//
+// When the email fails to send, the result records how far the attempt got,
+// such as the rejected recipients.
+func (m *MailYak) GetSendResult() *SendResult {
+	return m.sendResult
+}
+
+// recordResult sets the result of the last send, which began at start, to the
+// result of sending msg, if any recipients were sent to a server.
+func (m *MailYak) recordResult(msg *mimeMessage, start time.Time) {
+	m.sendResult = nil
+	if r := msg.result; r != nil && len(r.Accepted)+len(r.Rejected) > 0 {
+		r.Start = start
+		r.Duration = time.Since(start)
+		m.sendResult = r
+	}
+}
+
+// recordSession records the details of sending size bytes of message data to
+// host over the session smtpClient, and the response of the server if err is
+// nil.
+func (r *SendResult) recordSession(smtpClient *smtp.Client, conn net.Conn, host string, size int64, code int, msg string, err error) {
+	r.Host = host
+	r.BytesWritten = size
+	r.TLS = tlsState(smtpClient, conn)
+
+	if err == nil {
+		r.Code = code
+		r.Msg = msg
+		r.EnhancedCode = enhancedCode(code, msg)
+	}
+}
+
+// tlsState returns the state of the TLS connection of the SMTP session over
+// conn, or nil if it is not encrypted.
+func tlsState(smtpClient *smtp.Client, conn net.Conn) *tls.ConnectionState {
+	if state, ok := smtpClient.TLSConnectionState(); ok {
+		return &state
+	}
+
+	for {
+		switch c := conn.(type) {
+		case *tls.Conn:
+			state := c.ConnectionState()
+			return &state
+		case *debugConn:
+			if c.tlsConn != nil {
+				state := c.tlsConn.ConnectionState()
+				return &state
+			}
+			conn = c.Conn
+		default:
+			return nil
+		}
+	}
+}
+
+// enhancedCode returns the enhanced status code (RFC 3463 section 2) at the
+// start of the response msg, if its class matches the reply code.
+func enhancedCode(code int, msg string) string {
+	status := msg
+	if i := strings.IndexAny(msg, " \n"); i >= 0 {
+		status = msg[:i]
+	}
+
+	parts := strings.Split(status, ".")
+	if len(parts) != 3 || parts[0] != strconv.Itoa(code/100) {
+		return ""
+	}
+
+	for _, p := range parts[1:] {
+		if p == "" || len(p) > 3 || strings.Trim(p, "0123456789") != "" {
+			return ""
+		}
+	}
+
+	return status
+}
+
+// envelopeResult returns the SendResult recording the response to each
+// recipient of msg, and true if the send continues when recipients are
+// rejected.
+func envelopeResult(msg io.WriterTo) (*SendResult, bool) {
+	mm, ok := msg.(*mimeMessage)
+	if !ok || mm.result == nil {
+		return &SendResult{}, false
+	}
+
+	// discard the result of any previous attempt
+	*mm.result = SendResult{}
+
+	return mm.result, mm.m.partialDelivery
+}
@@ -0,0 +1,262 @@
+package mailyak
+
+import (
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestMailYakPartialDelivery ensures rejected recipients are skipped when
+// partial delivery is enabled, and reported by GetSendResult.
+func TestMailYakPartialDelivery(t *testing.T) {
+	t.Parallel()
+
+	accepted := func(addr string) RecipientStatus {
+		return RecipientStatus{Addr: addr, Code: 250, Msg: "OK"}
+	}
+	rejected := func(addr string) RecipientStatus {
+		return RecipientStatus{Addr: addr, Code: 550, Msg: "No such user"}
+	}
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		partial  bool
+		pipeline bool
+		rejected []string
+		// Want
+		wantErr      bool
+		wantSent     bool
+		wantAccepted []RecipientStatus
+		wantRejected []RecipientStatus
+	}{
+		{
+			name:         "All accepted",
+			partial:      true,
+			wantSent:     true,
+			wantAccepted: []RecipientStatus{accepted("a@example.org"), accepted("b@example.org"), accepted("c@example.org")},
+		},
+		{
+			name:         "Disabled",
+			rejected:     []string{"b@example.org"},
+			wantErr:      true,
+			wantAccepted: []RecipientStatus{accepted("a@example.org")},
+			wantRejected: []RecipientStatus{rejected("b@example.org")},
+		},
+		{
+			name:         "Disabled pipelined",
+			pipeline:     true,
+			rejected:     []string{"b@example.org"},
+			wantErr:      true,
+			wantAccepted: []RecipientStatus{accepted("a@example.org"), accepted("c@example.org")},
+			wantRejected: []RecipientStatus{rejected("b@example.org")},
+		},
+		{
+			name:         "Some rejected",
+			partial:      true,
+			rejected:     []string{"b@example.org"},
+			wantSent:     true,
+			wantAccepted: []RecipientStatus{accepted("a@example.org"), accepted("c@example.org")},
+			wantRejected: []RecipientStatus{rejected("b@example.org")},
+		},
+		{
+			name:         "Some rejected pipelined",
+			partial:      true,
+			pipeline:     true,
+			rejected:     []string{"a@example.org", "c@example.org"},
+			wantSent:     true,
+			wantAccepted: []RecipientStatus{accepted("b@example.org")},
+			wantRejected: []RecipientStatus{rejected("a@example.org"), rejected("c@example.org")},
+		},
+		{
+			name:         "All rejected",
+			partial:      true,
+			rejected:     []string{"a@example.org", "b@example.org", "c@example.org"},
+			wantErr:      true,
+			wantRejected: []RecipientStatus{rejected("a@example.org"), rejected("b@example.org"), rejected("c@example.org")},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			replies := map[string]string{}
+			if tt.pipeline {
+				replies["EHLO"] = "250-localhost\r\n250 PIPELINING"
+			}
+			for _, addr := range tt.rejected {
+				replies["RCPT TO:<"+addr+">"] = "550 No such user"
+			}
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, replies)
+			defer srv.Close()
+
+			mail := New(srv.Addr(), nil)
+			mail.PartialDelivery(tt.partial)
+			mail.From("from@example.org")
+			mail.To("a@example.org", "b@example.org", "c@example.org")
+			mail.Subject("Partial")
+			mail.Plain().Set("Hello")
+
+			_, _, err = mail.Send("localhost")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MailYak.Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrRecipientRejected) {
+				t.Errorf("MailYak.Send() error = %v, want ErrRecipientRejected", err)
+			}
+
+			if got := srv.Data() != nil; got != tt.wantSent {
+				t.Errorf("MailYak.Send() sent data = %v, want %v", got, tt.wantSent)
+			}
+
+			got := mail.GetSendResult()
+			if got == nil {
+				t.Fatal("MailYak.GetSendResult() = nil")
+			}
+			if !reflect.DeepEqual(got.Accepted, tt.wantAccepted) {
+				t.Errorf("MailYak.GetSendResult().Accepted = %v, want %v", got.Accepted, tt.wantAccepted)
+			}
+			if !reflect.DeepEqual(got.Rejected, tt.wantRejected) {
+				t.Errorf("MailYak.GetSendResult().Rejected = %v, want %v", got.Rejected, tt.wantRejected)
+			}
+		})
+	}
+}
+
+// TestMailYakGetSendResult ensures the server response and connection details
+// are recorded in the result.
+func TestMailYakGetSendResult(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		tls   string
+		debug bool
+		// Want
+		wantTLS bool
+	}{
+		{name: "Plaintext"},
+		{name: "Implicit TLS", tls: "implicit", wantTLS: true},
+		{name: "STARTTLS", tls: "starttls", wantTLS: true},
+		{name: "STARTTLS debug", tls: "starttls", debug: true, wantTLS: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			replies := map[string]string{".": "250 2.0.0 Ok: queued as ABC123"}
+
+			var (
+				srv  *testSMTPServer
+				mail *MailYak
+			)
+			switch tt.tls {
+			case "implicit":
+				var config *tls.Config
+				srv, config = newTLSTestSMTPServer(t, replies)
+				mail = NewWithTLS(srv.Addr(), nil, config)
+
+			case "starttls":
+				replies["EHLO"] = "250-localhost\r\n250 STARTTLS"
+				replies["STARTTLS"] = "220 Go ahead"
+
+				var config *tls.Config
+				srv, config = newStartTLSTestSMTPServer(t, replies)
+				mail = New(srv.Addr(), nil)
+				mail.TLSConfig(config)
+
+			default:
+				l, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatal(err)
+				}
+				srv = newTestSMTPServer(l, replies)
+				mail = New(srv.Addr(), nil)
+			}
+			defer srv.Close()
+
+			if tt.debug {
+				mail.DebugWriter(ioutil.Discard)
+			}
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+			mail.Plain().Set("Hello")
+
+			start := time.Now()
+			if _, _, err := mail.Send("localhost"); err != nil {
+				t.Fatalf("MailYak.Send() error = %v", err)
+			}
+
+			got := mail.GetSendResult()
+			if got == nil {
+				t.Fatal("MailYak.GetSendResult() = nil")
+			}
+			if got.Code != 250 || got.Msg != "2.0.0 Ok: queued as ABC123" || got.EnhancedCode != "2.0.0" {
+				t.Errorf("MailYak.GetSendResult() response = %d %q (%q), want 250 2.0.0 Ok: queued as ABC123", got.Code, got.Msg, got.EnhancedCode)
+			}
+			if got.Host != srv.Addr() {
+				t.Errorf("MailYak.GetSendResult().Host = %q, want %q", got.Host, srv.Addr())
+			}
+			if got.BytesWritten <= 0 {
+				t.Errorf("MailYak.GetSendResult().BytesWritten = %d, want > 0", got.BytesWritten)
+			}
+			if (got.TLS != nil) != tt.wantTLS {
+				t.Errorf("MailYak.GetSendResult().TLS = %v, want TLS %v", got.TLS, tt.wantTLS)
+			}
+			if got.Start.Before(start) || got.Duration <= 0 {
+				t.Errorf("MailYak.GetSendResult() Start = %v, Duration = %v, want start after %v", got.Start, got.Duration, start)
+			}
+		})
+	}
+}
+
+func TestEnhancedCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		code int
+		msg  string
+		// Want
+		want string
+	}{
+		{"Success", 250, "2.0.0 Ok: queued as ABC123", "2.0.0"},
+		{"Failure", 550, "5.1.1 No such user", "5.1.1"},
+		{"Multi-digit", 452, "4.3.120 Try again", "4.3.120"},
+		{"Status only", 250, "2.6.0", "2.6.0"},
+		{"Multiline", 250, "2.0.0\nOk", "2.0.0"},
+		{"Missing", 250, "Ok: queued as ABC123", ""},
+		{"Class mismatch", 250, "5.0.0 Ok", ""},
+		{"Too long", 250, "2.0.1234 Ok", ""},
+		{"Not numeric", 250, "2.a.0 Ok", ""},
+		{"Too many parts", 250, "2.0.0.0 Ok", ""},
+		{"Empty", 250, "", ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := enhancedCode(tt.code, tt.msg); got != tt.want {
+				t.Errorf("enhancedCode(%d, %q) = %q, want %q", tt.code, tt.msg, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package mailyak
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/textproto"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures how sending an email is retried after a transient
+// failure.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts to send the email,
+	// including the first. Values less than 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry, doubling for each
+	// subsequent retry. If zero, one second is used.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts. If zero, the delay is
+	// capped at one hour.
+	MaxBackoff time.Duration
+
+	// Jitter randomly adjusts each delay by up to the given fraction (between
+	// 0 and 1) in either direction, spreading out retries from concurrent
+	// senders.
+	Jitter float64
+
+	// OnRetry, if set, is called after each failed attempt that will be
+	// retried, with the number of the failed attempt (starting from 1), the
+	// error it failed with, and the delay before the next attempt.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// defaultMaxBackoff caps the delay between attempts when
+// RetryPolicy.MaxBackoff is not set, preventing the doubling delay from
+// overflowing.
+const defaultMaxBackoff = time.Hour
+
+// Retry configures Send and SendWithContext to retry sending the email after a
+// transient failure, waiting with exponential backoff between attempts:
+//
+//	mail.Retry(mailyak.RetryPolicy{
+//		MaxAttempts:    3,
+//		InitialBackoff: time.Second,
+//		Jitter:         0.2,
+//	})
+//
+// Connections that are refused or reset, timeouts (other than the Overall
+// timeout, which applies to each attempt), SMTP 4xx responses and API responses
+// with a 429 or 5xx status code are considered transient. Sending is not
+// retried once ctx is done.
+//
+// As attachments are read when the email is sent, emails with attachments are
+// built in memory before the first attempt so they can be resent.
+func (m *MailYak) Retry(p RetryPolicy) {
+	m.retry = &p
+}
+
+// withRetry calls send, retrying transient failures according to the retry
+// policy of m.
+func (m *MailYak) withRetry(ctx context.Context, send func() error) error {
+	p := m.retry
+	if p == nil {
+		return send()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := send()
+		if err == nil || attempt >= p.MaxAttempts || !isTransient(err) || ctx.Err() != nil {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, delay)
+		}
+		m.recorder().IncRetried(err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay before retrying the given failed attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	if delay <= 0 {
+		delay = time.Second
+	}
+
+	limit := p.MaxBackoff
+	if limit <= 0 {
+		limit = defaultMaxBackoff
+	}
+
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+
+	if p.Jitter > 0 {
+		delay += time.Duration(float64(delay) * p.Jitter * (2*rand.Float64() - 1))
+	}
+
+	return delay
+}
+
+// isTransient returns true if err is a failure that may succeed if retried.
+func isTransient(err error) bool {
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) {
+		return tpErr.Code >= 400 && tpErr.Code < 500
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == 429 || apiErr.StatusCode >= 500
+	}
+
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return timeoutErr.Stage != "overall"
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
@@ -0,0 +1,280 @@
+package mailyak
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyTransport fails with each of errs in turn, recording the message
+// written for each attempt.
+type flakyTransport struct {
+	errs []error
+	msgs []string
+}
+
+func (t *flakyTransport) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	var buf bytes.Buffer
+	if _, err := msg.WriteTo(&buf); err != nil {
+		return err
+	}
+	t.msgs = append(t.msgs, buf.String())
+
+	if len(t.errs) == 0 {
+		return nil
+	}
+	err := t.errs[0]
+	t.errs = t.errs[1:]
+	return err
+}
+
+// TestMailYakRetry ensures transient failures are retried up to the maximum
+// number of attempts.
+func TestMailYakRetry(t *testing.T) {
+	t.Parallel()
+
+	transient := &textproto.Error{Code: 451, Msg: "Try again later"}
+	permanent := &textproto.Error{Code: 550, Msg: "No such user"}
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		maxAttempts int
+		errs        []error
+		// Want
+		wantAttempts int
+		wantRetries  []int
+		wantErr      error
+	}{
+		{
+			name:         "Success",
+			maxAttempts:  3,
+			wantAttempts: 1,
+		},
+		{
+			name:         "Transient failure",
+			maxAttempts:  3,
+			errs:         []error{transient},
+			wantAttempts: 2,
+			wantRetries:  []int{1},
+		},
+		{
+			name:         "Permanent failure",
+			maxAttempts:  3,
+			errs:         []error{permanent},
+			wantAttempts: 1,
+			wantErr:      permanent,
+		},
+		{
+			name:         "Attempts exhausted",
+			maxAttempts:  3,
+			errs:         []error{transient, transient, transient},
+			wantAttempts: 3,
+			wantRetries:  []int{1, 2},
+			wantErr:      transient,
+		},
+		{
+			name:         "Retries disabled",
+			maxAttempts:  1,
+			errs:         []error{transient},
+			wantAttempts: 1,
+			wantErr:      transient,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			transport := &flakyTransport{errs: tt.errs}
+
+			var retries []int
+			mail := New("", nil)
+			mail.Transport(transport)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+			content := strings.NewReader("attachment")
+			mail.Attach("test.txt", content)
+			mail.Retry(RetryPolicy{
+				MaxAttempts:    tt.maxAttempts,
+				InitialBackoff: time.Millisecond,
+				OnRetry: func(attempt int, err error, delay time.Duration) {
+					retries = append(retries, attempt)
+				},
+			})
+
+			_, _, err := mail.Send("localhost")
+			if err != tt.wantErr {
+				t.Fatalf("MailYak.Send() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if len(transport.msgs) != tt.wantAttempts {
+				t.Fatalf("MailYak.Send() attempts = %v, want %v", len(transport.msgs), tt.wantAttempts)
+			}
+			for _, msg := range transport.msgs {
+				if msg != transport.msgs[0] || !strings.Contains(msg, "YXR0YWNobWVudA==") {
+					t.Errorf("MailYak.Send() resent message = %q, want %q", msg, transport.msgs[0])
+				}
+			}
+
+			if !reflect.DeepEqual(retries, tt.wantRetries) {
+				t.Errorf("MailYak.Send() retries = %v, want %v", retries, tt.wantRetries)
+			}
+
+			// the buffered content is kept by the message, not the email
+			if got := mail.attachments[0].content; got != io.Reader(content) {
+				t.Errorf("MailYak.Send() replaced attachment content with %T", got)
+			}
+		})
+	}
+}
+
+// TestMailYakRetry_smtp ensures SMTP temporary failures are retried.
+func TestMailYakRetry_smtp(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTestSMTPServer(l, map[string]string{"MAIL": "421 Too busy"})
+	defer srv.Close()
+
+	mail := New(srv.Addr(), nil)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+	mail.Retry(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+
+	if _, _, err := mail.Send("localhost"); err == nil || !strings.Contains(err.Error(), "Too busy") {
+		t.Fatalf("MailYak.Send() error = %v, want MAIL rejection", err)
+	}
+
+	var attempts int
+	for _, cmd := range srv.Commands() {
+		if strings.HasPrefix(cmd, "MAIL ") {
+			attempts++
+		}
+	}
+	if attempts != 2 {
+		t.Errorf("MailYak.Send() attempts = %v, want 2", attempts)
+	}
+}
+
+// TestMailYakRetry_context ensures retries stop when the context is done.
+func TestMailYakRetry_context(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	transport := &flakyTransport{errs: []error{&textproto.Error{Code: 451}}}
+
+	mail := New("", nil)
+	mail.Transport(transport)
+	mail.Retry(RetryPolicy{
+		MaxAttempts:    2,
+		InitialBackoff: time.Hour,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			cancel()
+		},
+	})
+
+	if _, _, err := mail.SendWithContext(ctx, "localhost"); err != context.Canceled {
+		t.Errorf("MailYak.SendWithContext() error = %v, want %v", err, context.Canceled)
+	}
+	if len(transport.msgs) != 1 {
+		t.Errorf("MailYak.SendWithContext() attempts = %v, want 1", len(transport.msgs))
+	}
+}
+
+// TestRetryPolicyBackoff ensures the delay doubles for each attempt, up to the
+// maximum.
+func TestRetryPolicyBackoff(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		policy RetryPolicy
+		// Parameters.
+		attempt int
+		// Want
+		want time.Duration
+	}{
+		{"Default", RetryPolicy{}, 1, time.Second},
+		{"First retry", RetryPolicy{InitialBackoff: 100 * time.Millisecond}, 1, 100 * time.Millisecond},
+		{"Third retry", RetryPolicy{InitialBackoff: 100 * time.Millisecond}, 3, 400 * time.Millisecond},
+		{"Capped", RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, 10, 5 * time.Second},
+		{"Default cap", RetryPolicy{InitialBackoff: time.Second}, 100, time.Hour},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.policy.backoff(tt.attempt); got != tt.want {
+				t.Errorf("RetryPolicy.backoff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRetryPolicyBackoff_jitter ensures jitter keeps the delay within the
+// configured fraction.
+func TestRetryPolicyBackoff_jitter(t *testing.T) {
+	t.Parallel()
+
+	p := RetryPolicy{InitialBackoff: time.Second, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if got := p.backoff(1); got < 500*time.Millisecond || got > 1500*time.Millisecond {
+			t.Fatalf("RetryPolicy.backoff() = %v, want 500ms-1.5s", got)
+		}
+	}
+}
+
+// TestIsTransient ensures temporary failures are distinguished from permanent
+// failures.
+func TestIsTransient(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		err error
+		// Want
+		want bool
+	}{
+		{"SMTP temporary failure", &textproto.Error{Code: 451}, true},
+		{"SMTP permanent failure", &textproto.Error{Code: 550}, false},
+		{"API rate limited", &APIError{StatusCode: 429}, true},
+		{"API server error", &APIError{StatusCode: 503}, true},
+		{"API bad request", &APIError{StatusCode: 400}, false},
+		{"Dial timeout", &TimeoutError{Stage: "dial"}, true},
+		{"Overall timeout", &TimeoutError{Stage: "overall"}, false},
+		{"Connection refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{"Connection reset", fmt.Errorf("write: %w", syscall.ECONNRESET), true},
+		{"Other", errors.New("broken"), false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := isTransient(tt.err); got != tt.want {
+				t.Errorf("isTransient() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
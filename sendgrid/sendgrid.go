@@ -0,0 +1,171 @@
+// Package sendgrid provides a mailyak.Transport sending emails through the
+// SendGrid v3 API.
+package sendgrid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/domodwyer/mailyak/v3/internal/apimail"
+)
+
+// CategoriesHeader is the custom header holding the comma separated SendGrid
+// categories of an email sent by Transport:
+//
+//	mail.AddHeader(sendgrid.CategoriesHeader, "welcome, onboarding")
+//
+// The header is removed from the email and sent as its categories.
+const CategoriesHeader = "X-Sendgrid-Categories"
+
+// Transport is a mailyak.Transport sending emails through the SendGrid v3
+// mail/send API:
+//
+//	mail.Transport(sendgrid.New(os.Getenv("SENDGRID_API_KEY")))
+//
+// The bodies, addresses, attachments and headers of the email are converted
+// into a SendGrid request, so emails can be moved between SMTP and SendGrid
+// without changing how they are built. Signed or encrypted emails and
+// calendar invitations cannot be sent.
+//
+// Errors returned by SendGrid are returned as a *mailyak.APIError.
+type Transport struct {
+	apiKey   string
+	endpoint string
+	client   *http.Client
+}
+
+// New returns a Transport authenticating with apiKey.
+func New(apiKey string) *Transport {
+	return &Transport{
+		apiKey:   apiKey,
+		endpoint: "https://api.sendgrid.com",
+	}
+}
+
+// Endpoint sets the base URL of the SendGrid API, such as
+// "https://api.eu.sendgrid.com" for EU regional subusers. Defaults to
+// "https://api.sendgrid.com".
+func (t *Transport) Endpoint(u string) {
+	t.endpoint = strings.TrimSuffix(u, "/")
+}
+
+// HTTPClient sets the HTTP client used to call SendGrid. Defaults to
+// http.DefaultClient.
+func (t *Transport) HTTPClient(c *http.Client) {
+	t.client = c
+}
+
+// request is the body of a SendGrid v3 mail/send request.
+type request struct {
+	Personalizations []personalization `json:"personalizations"`
+	From             address           `json:"from"`
+	ReplyTo          *address          `json:"reply_to,omitempty"`
+	Subject          string            `json:"subject"`
+	Content          []content         `json:"content,omitempty"`
+	Attachments      []attachment      `json:"attachments,omitempty"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	Categories       []string          `json:"categories,omitempty"`
+}
+
+type personalization struct {
+	To  []address `json:"to"`
+	Cc  []address `json:"cc,omitempty"`
+	Bcc []address `json:"bcc,omitempty"`
+}
+
+type address struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type content struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type attachment struct {
+	Content     []byte `json:"content"`
+	Type        string `json:"type"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition"`
+	ContentID   string `json:"content_id,omitempty"`
+}
+
+// Send sends the email to the SendGrid API.
+//
+// The recipients are taken from the To and Cc addresses of the email, with any
+// other recipients in rcpts sent as Bcc recipients.
+func (t *Transport) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	e, err := apimail.NewEmail(msg, rcpts, "SendGrid")
+	if err != nil {
+		return err
+	}
+
+	req := request{
+		Personalizations: []personalization{{
+			To:  addresses(e.To),
+			Cc:  addresses(e.Cc),
+			Bcc: addresses(e.Bcc),
+		}},
+		From:       address{Email: e.From.Address, Name: e.From.Name},
+		Subject:    e.Subject,
+		Categories: apimail.SplitHeaderList(e.PopHeader(CategoriesHeader)),
+	}
+	if e.ReplyTo != nil {
+		req.ReplyTo = &address{Email: e.ReplyTo.Address, Name: e.ReplyTo.Name}
+	}
+
+	// SendGrid requires the plain-text content before the HTML content
+	if e.Plain != "" {
+		req.Content = append(req.Content, content{Type: "text/plain", Value: e.Plain})
+	}
+	if e.HTML != "" {
+		req.Content = append(req.Content, content{Type: "text/html", Value: e.HTML})
+	}
+
+	for _, a := range e.Attachments {
+		att := attachment{
+			Content:     a.Data,
+			Type:        a.ContentType,
+			Filename:    a.Filename,
+			Disposition: "attachment",
+		}
+		if a.Inline {
+			att.Disposition = "inline"
+			att.ContentID = a.ContentID
+		}
+		req.Attachments = append(req.Attachments, att)
+	}
+
+	if len(e.Headers) > 0 {
+		req.Headers = e.Headers
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint+"/v3/mail/send", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Authorization", "Bearer "+t.apiKey)
+	r.Header.Set("Content-Type", "application/json")
+
+	return apimail.Do(t.client, r, "SendGrid", nil)
+}
+
+// addresses returns addrs as SendGrid addresses.
+func addresses(addrs []*mail.Address) []address {
+	var out []address
+	for _, a := range addrs {
+		out = append(out, address{Email: a.Address, Name: a.Name})
+	}
+	return out
+}
@@ -0,0 +1,172 @@
+package sendgrid
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/domodwyer/mailyak/v3"
+)
+
+// testSigner is a mailyak.PGPSigner producing a fixed signature.
+type testSigner struct{}
+
+func (testSigner) ArmoredDetachSign(w io.Writer, message io.Reader) error {
+	if _, err := io.Copy(io.Discard, message); err != nil {
+		return err
+	}
+	_, err := fmt.Fprint(w, "-----BEGIN PGP SIGNATURE-----\n\nsignature\n-----END PGP SIGNATURE-----\n")
+	return err
+}
+
+func (testSigner) Micalg() string { return "pgp-sha256" }
+
+// TestTransport ensures emails are converted into SendGrid mail/send
+// requests.
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	var (
+		got     request
+		headers http.Header
+		path    string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		headers = r.Header
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	transport := New("key")
+	transport.Endpoint(srv.URL)
+
+	mail := mailyak.New("", nil)
+	mail.Transport(transport)
+	mail.From("from@example.org")
+	mail.FromName("Zoë")
+	mail.To("Dom <to@example.org>")
+	mail.Cc("cc@example.org")
+	mail.Bcc("bcc@example.org")
+	mail.ReplyTo("reply@example.org")
+	mail.Subject("Héllo")
+	mail.InReplyTo("<parent@example.org>")
+	mail.AddHeader("X-Campaign", "launch")
+	mail.AddHeader(CategoriesHeader, "welcome, onboarding")
+	mail.Plain().Set("plain")
+	mail.HTML().Set("<p>html</p>")
+	mail.Attach("report.csv", strings.NewReader("a,b"))
+	mail.AttachInlineWithMimeType("logo.png", strings.NewReader("png"), "image/png")
+
+	if _, _, err := mail.Send("localhost"); err != nil {
+		t.Fatalf("Transport.Send() error = %v", err)
+	}
+
+	if path != "/v3/mail/send" {
+		t.Errorf("Transport.Send() path = %q", path)
+	}
+	if got := headers.Get("Authorization"); got != "Bearer key" {
+		t.Errorf("Transport.Send() Authorization = %q", got)
+	}
+
+	want := request{
+		Personalizations: []personalization{{
+			To:  []address{{Email: "to@example.org", Name: "Dom"}},
+			Cc:  []address{{Email: "cc@example.org"}},
+			Bcc: []address{{Email: "bcc@example.org"}},
+		}},
+		From:    address{Email: "from@example.org", Name: "Zoë"},
+		ReplyTo: &address{Email: "reply@example.org"},
+		Subject: "Héllo",
+		Content: []content{
+			{Type: "text/plain", Value: "plain"},
+			{Type: "text/html", Value: "<p>html</p>"},
+		},
+		Attachments: []attachment{
+			{Content: []byte("a,b"), Type: "text/plain; charset=utf-8", Filename: "report.csv", Disposition: "attachment"},
+			{Content: []byte("png"), Type: "image/png", Filename: "logo.png", Disposition: "inline", ContentID: "logo.png"},
+		},
+		Headers: map[string]string{
+			"Message-Id":  mail.GetMessageID(),
+			"In-Reply-To": "<parent@example.org>",
+			"X-Campaign":  "launch",
+			"X-Mailer":    mailyak.DefaultMailer,
+		},
+		Categories: []string{"welcome", "onboarding"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Transport.Send() request =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+// TestTransport_signed ensures signed emails, which cannot be
+// represented in a SendGrid request, are rejected.
+func TestTransport_signed(t *testing.T) {
+	t.Parallel()
+
+	mail := mailyak.New("", nil)
+	mail.Transport(New("key"))
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+	if err := mail.PGP(mailyak.PGPOptions{Signer: testSigner{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := mail.Send("localhost"); err == nil || !strings.Contains(err.Error(), "signed or encrypted") {
+		t.Errorf("Transport.Send() error = %v, want signed email error", err)
+	}
+}
+
+// TestTransport_retry ensures emails with attachments are resent with the
+// attachment content on each attempt.
+func TestTransport_retry(t *testing.T) {
+	t.Parallel()
+
+	var attachments [][]attachment
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		attachments = append(attachments, req.Attachments)
+
+		if len(attachments) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	transport := New("key")
+	transport.Endpoint(srv.URL)
+
+	mail := mailyak.New("", nil)
+	mail.Transport(transport)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+	mail.Attach("report.csv", strings.NewReader("a,b"))
+	mail.Retry(mailyak.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+
+	if _, _, err := mail.Send("localhost"); err != nil {
+		t.Fatalf("MailYak.Send() error = %v", err)
+	}
+
+	if len(attachments) != 2 {
+		t.Fatalf("MailYak.Send() attempts = %d, want 2", len(attachments))
+	}
+	for i, a := range attachments {
+		if len(a) != 1 || string(a[0].Content) != "a,b" {
+			t.Errorf("MailYak.Send() attempt %d attachments = %+v, want report.csv with content", i+1, a)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package mailyak
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// SendmailTransport is a Transport piping emails into a local sendmail binary,
+// for systems delivering through a local MTA such as Postfix or Exim rather
+// than connecting to an SMTP server:
+//
+//	mail.Transport(mailyak.NewSendmailTransport())
+//
+// By default "/usr/sbin/sendmail -t -i" is run for each email, reading the
+// recipients from the To, Cc and Bcc headers. The envelope sender is passed
+// with the -f flag.
+type SendmailTransport struct {
+	path string
+	args []string
+}
+
+// NewSendmailTransport returns a SendmailTransport running
+// "/usr/sbin/sendmail -t -i".
+func NewSendmailTransport() *SendmailTransport {
+	return &SendmailTransport{
+		path: "/usr/sbin/sendmail",
+		args: []string{"-t", "-i"},
+	}
+}
+
+// Path sets the path of the sendmail binary. Defaults to "/usr/sbin/sendmail".
+func (t *SendmailTransport) Path(path string) {
+	t.path = path
+}
+
+// Args sets the arguments passed to the sendmail binary. Defaults to "-t" and
+// "-i".
+//
+// If the arguments do not include -t, the recipients are passed as arguments
+// after those set, rather than read from the headers of the email.
+func (t *SendmailTransport) Args(args ...string) {
+	t.args = cloneStrings(args)
+}
+
+// Send pipes msg into the sendmail binary, returning an error if it exits with
+// a non-zero status or ctx is cancelled before it exits.
+//
+// When the recipients are read from the headers, the Bcc header is included in
+// the MIME data for sendmail to remove. Signed or encrypted emails with Bcc
+// recipients cannot then be sent, as the Bcc header cannot be added after
+// signing.
+//
+// The lines of the message are terminated by LF rather than CRLF, as expected
+// by local mail submission.
+func (t *SendmailTransport) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	args := cloneStrings(t.args)
+	if envelopeFrom != "" {
+		if strings.HasPrefix(envelopeFrom, "-") {
+			return fmt.Errorf("mailyak: invalid sendmail sender %q", envelopeFrom)
+		}
+		args = append(args, "-f", envelopeFrom)
+	}
+
+	if readsHeaders(t.args) {
+		var err error
+		if msg, err = withBccHeader(msg, "sendmail"); err != nil {
+			return err
+		}
+	} else {
+		for _, rcpt := range rcpts {
+			if strings.HasPrefix(rcpt, "-") {
+				return fmt.Errorf("mailyak: invalid sendmail recipient %q", rcpt)
+			}
+		}
+		args = append(args, rcpts...)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.path, args...)
+	cmd.Stderr = &stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("mailyak: starting sendmail: %w", err)
+	}
+
+	w := bufio.NewWriter(stdin)
+	lw := &lfWriter{w: w}
+	_, writeErr := msg.WriteTo(lw)
+	if writeErr == nil && lw.cr {
+		writeErr = w.WriteByte('\r')
+	}
+	if writeErr == nil {
+		writeErr = w.Flush()
+	}
+	stdin.Close()
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if out := strings.TrimSpace(stderr.String()); out != "" {
+			return fmt.Errorf("mailyak: sendmail: %w: %s", err, out)
+		}
+		return fmt.Errorf("mailyak: sendmail: %w", err)
+	}
+
+	return writeErr
+}
+
+// withBccHeader returns msg with the Bcc header included, for transports that
+// deliver raw MIME data to the recipients in its headers rather than an
+// envelope.
+//
+// Signed or encrypted emails with Bcc recipients return an error, as the Bcc
+// header cannot be added after signing.
+func withBccHeader(msg io.WriterTo, service string) (io.WriterTo, error) {
+	mm, ok := msg.(*mimeMessage)
+	if !ok || len(mm.m.bccAddrs) == 0 || mm.m.writeBccHeader {
+		return msg, nil
+	}
+
+	if mm.buf != nil {
+		return nil, fmt.Errorf("mailyak: %s transport cannot send signed or encrypted emails with Bcc recipients", service)
+	}
+
+	// The Message-ID is generated before copying, so it is shared with the
+	// original email
+	mm.m.messageIDValue()
+
+	c := *mm.m
+	c.writeBccHeader = true
+	return &mimeMessage{m: &c, attachments: mm.attachments, mb: mm.mb, ab: mm.ab, result: mm.result}, nil
+}
+
+// readsHeaders returns true if args include the -t flag, causing sendmail to
+// read the recipients from the headers of the email.
+func readsHeaders(args []string) bool {
+	for _, arg := range args {
+		if arg == "-t" {
+			return true
+		}
+	}
+	return false
+}
+
+// lfWriter is an io.Writer converting CRLF line endings to LF.
+type lfWriter struct {
+	w io.Writer
+
+	// cr is true if the last byte written was a CR, not yet written to w
+	cr bool
+}
+
+func (l *lfWriter) Write(p []byte) (int, error) {
+	out := make([]byte, 0, len(p)+1)
+	for _, b := range p {
+		if l.cr && b != '\n' {
+			out = append(out, '\r')
+		}
+		l.cr = b == '\r'
+		if !l.cr {
+			out = append(out, b)
+		}
+	}
+
+	if _, err := l.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,152 @@
+package mailyak
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeSendmail writes a shell script to dir recording its arguments and input
+// into dir, exiting with status.
+func fakeSendmail(t *testing.T, dir string, status int) string {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("sendmail tests require a POSIX shell")
+	}
+
+	path := filepath.Join(dir, "sendmail")
+	script := "#!/bin/sh\n" +
+		"printf '%s\\n' \"$@\" > " + filepath.Join(dir, "args") + "\n" +
+		"cat > " + filepath.Join(dir, "input") + "\n" +
+		"echo 'fake sendmail failed' >&2\n" +
+		"exit " + strconv.Itoa(status) + "\n"
+	if err := ioutil.WriteFile(path, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestSendmailTransport ensures the email is piped into sendmail with the
+// configured arguments.
+func TestSendmailTransport(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		args []string
+		// Want
+		wantArgs string
+		wantBcc  bool
+	}{
+		{
+			"Default",
+			nil,
+			"-t\n-i\n-f\nbounce@example.org\n",
+			true,
+		},
+		{
+			"Recipient arguments",
+			[]string{"-i", "-oi"},
+			"-i\n-oi\n-f\nbounce@example.org\nto@example.org\nbcc@example.org\n",
+			false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+
+			transport := NewSendmailTransport()
+			transport.Path(fakeSendmail(t, dir, 0))
+			if tt.args != nil {
+				transport.Args(tt.args...)
+			}
+
+			mail := New("", nil)
+			mail.Transport(transport)
+			mail.From("from@example.org")
+			mail.EnvelopeFrom("bounce@example.org")
+			mail.To("to@example.org")
+			mail.Bcc("bcc@example.org")
+			mail.Plain().Set("Hello")
+
+			if _, _, err := mail.Send("localhost"); err != nil {
+				t.Fatalf("SendmailTransport.Send() error = %v", err)
+			}
+
+			args, err := ioutil.ReadFile(filepath.Join(dir, "args"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(args) != tt.wantArgs {
+				t.Errorf("SendmailTransport.Send() args = %q, want %q", args, tt.wantArgs)
+			}
+
+			input, err := ioutil.ReadFile(filepath.Join(dir, "input"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(input, []byte("\r")) || !bytes.HasPrefix(input, []byte("From: from@example.org\n")) {
+				t.Errorf("SendmailTransport.Send() input = %q, want LF line endings", input)
+			}
+			if got := bytes.Contains(input, []byte("\nBCC: bcc@example.org\n")); got != tt.wantBcc {
+				t.Errorf("SendmailTransport.Send() input contains Bcc = %v, want %v", got, tt.wantBcc)
+			}
+		})
+	}
+}
+
+// TestSendmailTransport_error ensures a non-zero exit status is returned as an
+// error including the output of sendmail.
+func TestSendmailTransport_error(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	transport := NewSendmailTransport()
+	transport.Path(fakeSendmail(t, dir, 1))
+
+	mail := New("", nil)
+	mail.Transport(transport)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+
+	_, _, err := mail.Send("localhost")
+	if err == nil || !strings.Contains(err.Error(), "fake sendmail failed") {
+		t.Fatalf("SendmailTransport.Send() error = %v, want sendmail output", err)
+	}
+
+	transport.Path(filepath.Join(dir, "missing"))
+	if _, _, err := mail.Send("localhost"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("SendmailTransport.Send() error = %v, want not exist", err)
+	}
+}
+
+// TestLFWriter ensures CRLF line endings are converted to LF, including when
+// split across writes.
+func TestLFWriter(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	w := &lfWriter{w: &buf}
+	for _, p := range []string{"a\r\nb\r", "\nc\rd\r", "\r\n"} {
+		if n, err := w.Write([]byte(p)); err != nil || n != len(p) {
+			t.Fatalf("lfWriter.Write(%q) = %d, %v", p, n, err)
+		}
+	}
+
+	if want := "a\nb\nc\rd\r\n"; buf.String() != want {
+		t.Errorf("lfWriter.Write() = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,245 @@
+// Package ses provides a mailyak.Transport sending emails through the Amazon
+// SES v2 API, for environments where SMTP is unavailable.
+package ses
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/domodwyer/mailyak/v3/internal/apimail"
+)
+
+const (
+	// maxMessageSize is the maximum size of an email accepted by the SES v2
+	// API, including attachments.
+	maxMessageSize = 40 << 20
+
+	// maxRecipients is the maximum number of recipients of an email sent by
+	// SES.
+	maxRecipients = 50
+)
+
+// ErrMessageTooLarge is returned when sending an email larger than the 40MB
+// accepted by the SES v2 API.
+var ErrMessageTooLarge = errors.New("mailyak: email exceeds the 40MB SES limit")
+
+// ErrTooManyRecipients is returned when sending an email to more than the 50
+// recipients accepted by SES.
+var ErrTooManyRecipients = errors.New("mailyak: email exceeds the 50 recipient SES limit")
+
+// Credentials are the AWS credentials used to sign requests to SES.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// SessionToken is required when using temporary credentials, such as
+	// those of an assumed IAM role.
+	SessionToken string
+}
+
+// Transport is a mailyak.Transport sending emails through the Amazon SES v2
+// SendEmail API:
+//
+//	mail.Transport(ses.New("eu-west-1", ses.Credentials{
+//		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+//		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+//	}))
+//
+// The email is sent as raw MIME data, so all MailYak features (attachments,
+// signing, custom headers, etc) are supported. Each email is limited to 40MB
+// and 50 recipients by SES - larger emails return ErrMessageTooLarge, and
+// emails to more recipients return ErrTooManyRecipients without being sent.
+// Errors returned by SES are returned as a *mailyak.APIError.
+type Transport struct {
+	region           string
+	creds            Credentials
+	endpoint         string
+	client           *http.Client
+	configurationSet string
+	now              func() time.Time
+}
+
+// New returns a Transport sending emails through SES in region, such as
+// "eu-west-1", signing requests with creds.
+func New(region string, creds Credentials) *Transport {
+	return &Transport{
+		region:   region,
+		creds:    creds,
+		endpoint: "https://email." + region + ".amazonaws.com",
+		now:      time.Now,
+	}
+}
+
+// Endpoint sets the base URL of the SES API, such as a VPC endpoint. Defaults
+// to the public endpoint of the region.
+func (t *Transport) Endpoint(u string) {
+	t.endpoint = strings.TrimSuffix(u, "/")
+}
+
+// HTTPClient sets the HTTP client used to call SES. Defaults to
+// http.DefaultClient.
+func (t *Transport) HTTPClient(c *http.Client) {
+	t.client = c
+}
+
+// ConfigurationSet sets the name of the SES configuration set used to send
+// emails, enabling event publishing, dedicated IP pools, etc.
+func (t *Transport) ConfigurationSet(name string) {
+	t.configurationSet = name
+}
+
+// sendEmailRequest is the body of an SES v2 SendEmail request.
+type sendEmailRequest struct {
+	Destination          destination `json:"Destination"`
+	Content              content     `json:"Content"`
+	ConfigurationSetName string      `json:"ConfigurationSetName,omitempty"`
+}
+
+type destination struct {
+	ToAddresses []string `json:"ToAddresses"`
+}
+
+type content struct {
+	Raw rawMessage `json:"Raw"`
+}
+
+type rawMessage struct {
+	Data []byte `json:"Data"`
+}
+
+// Send sends msg to rcpts using the SES SendEmail API.
+//
+// The sender is taken from the From header of msg. The envelope sender, used
+// for bounces, is determined by SES rather than envelopeFrom.
+func (t *Transport) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	if len(rcpts) > maxRecipients {
+		return fmt.Errorf("%w: %d recipients", ErrTooManyRecipients, len(rcpts))
+	}
+
+	data, err := apimail.Buffer(msg)
+	if err != nil {
+		return err
+	}
+
+	// The limit applies to the base64 encoded message
+	if n := base64Len(len(data)); n > maxMessageSize {
+		return fmt.Errorf("%w: %d bytes encoded", ErrMessageTooLarge, n)
+	}
+
+	// All recipients are listed in ToAddresses, as the To, Cc and Bcc
+	// headers of the raw message are sent unchanged
+	body, err := json.Marshal(sendEmailRequest{
+		Destination:          destination{ToAddresses: rcpts},
+		Content:              content{Raw: rawMessage{Data: data}},
+		ConfigurationSetName: t.configurationSet,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint+"/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	t.sign(req, body)
+
+	return apimail.Do(t.client, req, "SES", nil)
+}
+
+// base64Len returns the length of n bytes when base64 encoded.
+func base64Len(n int) int {
+	return (n + 2) / 3 * 4
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req, which has
+// the given body.
+func (t *Transport) sign(req *http.Request, body []byte) {
+	now := t.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if t.creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", t.creds.SessionToken)
+	}
+
+	payloadHash := sha256.Sum256(body)
+
+	// The canonical headers are the lowercase header names in sorted order,
+	// including the host
+	headers := map[string]string{"host": req.URL.Host}
+	for k := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(req.Header.Get(k))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + t.region + "/ses/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signature := hmacSHA256(sigV4Key(t.creds.SecretAccessKey, date, t.region, "ses"), stringToSign)
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		t.creds.AccessKeyID, scope, signedHeaders, hex.EncodeToString(signature),
+	))
+}
+
+// canonicalQuery returns the query parameters in q sorted and encoded as
+// required by AWS Signature Version 4.
+func canonicalQuery(q url.Values) string {
+	return strings.Replace(q.Encode(), "+", "%20", -1)
+}
+
+// sigV4Key returns the AWS Signature Version 4 signing key for the given
+// secret, date (as YYYYMMDD), region and service.
+func sigV4Key(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data using key.
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,163 @@
+package ses
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/domodwyer/mailyak/v3"
+)
+
+// TestSigV4Key ensures the signing key is derived as in the example in the AWS
+// Signature Version 4 documentation.
+func TestSigV4Key(t *testing.T) {
+	t.Parallel()
+
+	got := hex.EncodeToString(sigV4Key("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam"))
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got != want {
+		t.Errorf("sigV4Key() = %v, want %v", got, want)
+	}
+}
+
+// TestTransport ensures emails are sent to the SES SendEmail API.
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		rcpts      int
+		attachment int
+		status     int
+		response   string
+		// Want
+		wantRequest bool
+		wantErr     error
+	}{
+		{
+			name:        "OK",
+			rcpts:       2,
+			status:      http.StatusOK,
+			response:    `{"MessageId": "abc"}`,
+			wantRequest: true,
+		},
+		{
+			name:        "API error",
+			rcpts:       1,
+			status:      http.StatusBadRequest,
+			response:    `{"message": "Email address is not verified."}`,
+			wantRequest: true,
+			wantErr:     &mailyak.APIError{Service: "SES", StatusCode: http.StatusBadRequest, Message: "Email address is not verified."},
+		},
+		{
+			name:    "Too many recipients",
+			rcpts:   51,
+			wantErr: ErrTooManyRecipients,
+		},
+		{
+			name:       "Too large",
+			rcpts:      1,
+			attachment: 31 << 20,
+			wantErr:    ErrMessageTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				gotRequest bool
+				body       sendEmailRequest
+				headers    http.Header
+				path       string
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRequest = true
+				path = r.URL.Path
+				headers = r.Header
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("decoding request: %v", err)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.response))
+			}))
+			defer srv.Close()
+
+			transport := New("eu-west-1", Credentials{
+				AccessKeyID:     "AKIDEXAMPLE",
+				SecretAccessKey: "secret",
+				SessionToken:    "token",
+			})
+			transport.Endpoint(srv.URL + "/")
+			transport.ConfigurationSet("tracking")
+			transport.now = func() time.Time { return time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC) }
+
+			mail := mailyak.New("", nil)
+			mail.Transport(transport)
+			mail.From("from@example.org")
+			mail.Subject("SES")
+			mail.Plain().Set("Hello")
+			var rcpts []string
+			for i := 0; i < tt.rcpts; i++ {
+				rcpts = append(rcpts, fmt.Sprintf("to%d@example.org", i))
+			}
+			mail.Bcc(rcpts...)
+			if tt.attachment > 0 {
+				mail.Attach("big.bin", strings.NewReader(strings.Repeat("a", tt.attachment)))
+			}
+
+			_, _, err := mail.Send("localhost")
+
+			var apiErr *mailyak.APIError
+			if want, ok := tt.wantErr.(*mailyak.APIError); ok {
+				if !errors.As(err, &apiErr) || *apiErr != *want {
+					t.Fatalf("Transport.Send() error = %v, want %v", err, want)
+				}
+			} else if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Transport.Send() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if gotRequest != tt.wantRequest {
+				t.Fatalf("Transport.Send() sent request = %v, want %v", gotRequest, tt.wantRequest)
+			}
+			if !gotRequest {
+				return
+			}
+
+			if path != "/v2/email/outbound-emails" {
+				t.Errorf("Transport.Send() path = %q", path)
+			}
+
+			auth := regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20210304/eu-west-1/ses/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-security-token, Signature=[0-9a-f]{64}$`)
+			if got := headers.Get("Authorization"); !auth.MatchString(got) {
+				t.Errorf("Transport.Send() Authorization = %q", got)
+			}
+			if got := headers.Get("X-Amz-Date"); got != "20210304T050607Z" {
+				t.Errorf("Transport.Send() X-Amz-Date = %q", got)
+			}
+			if got := headers.Get("X-Amz-Security-Token"); got != "token" {
+				t.Errorf("Transport.Send() X-Amz-Security-Token = %q", got)
+			}
+
+			if len(body.Destination.ToAddresses) != tt.rcpts {
+				t.Errorf("Transport.Send() ToAddresses = %v, want %d addresses", body.Destination.ToAddresses, tt.rcpts)
+			}
+			if body.ConfigurationSetName != "tracking" {
+				t.Errorf("Transport.Send() ConfigurationSetName = %q", body.ConfigurationSetName)
+			}
+			if raw := string(body.Content.Raw.Data); !strings.Contains(raw, "Subject: SES\r\n") || strings.Contains(raw, "Bcc:") {
+				t.Errorf("Transport.Send() raw message = %q", raw)
+			}
+		})
+	}
+}
@@ -1,6 +1,13 @@
 package mailyak
 
-import "mime"
+import (
+	"fmt"
+	"mime"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+)
 
 // To sets a list of recipient addresses.
 //
@@ -16,17 +23,25 @@ import "mime"
 //	}
 //
 //	mail.To(tos...)
+//
+// Addresses may include a display name, such as "Dom <dom@itsallbroken.com>".
+// Non-ASCII display names are encoded according to RFC 2047 when the email is
+// built.
 func (m *MailYak) To(addrs ...string) {
-	m.toAddrs = []string{}
-
-	for _, addr := range addrs {
-		trimmed := m.trimRegex.ReplaceAllString(addr, "")
-		if trimmed == "" {
-			continue
-		}
+	m.toAddrs = m.appendAddrs([]string{}, addrs)
+}
 
-		m.toAddrs = append(m.toAddrs, trimmed)
-	}
+// AddTo appends addrs to the list of recipient addresses, keeping any already
+// set.
+//
+// This is useful when collecting recipients incrementally:
+//
+//	for rows.Next() {
+//		// ...
+//		mail.AddTo(addr)
+//	}
+func (m *MailYak) AddTo(addrs ...string) {
+	m.toAddrs = m.appendAddrs(m.toAddrs, addrs)
 }
 
 // Bcc sets a list of blind carbon copy (BCC) addresses.
@@ -44,16 +59,70 @@ func (m *MailYak) To(addrs ...string) {
 //
 // 	mail.Bcc(bccs...)
 func (m *MailYak) Bcc(addrs ...string) {
-	m.bccAddrs = []string{}
+	m.bccAddrs = m.appendAddrs([]string{}, addrs)
+}
 
+// AddBcc appends addrs to the list of blind carbon copy (BCC) addresses,
+// keeping any already set.
+func (m *MailYak) AddBcc(addrs ...string) {
+	m.bccAddrs = m.appendAddrs(m.bccAddrs, addrs)
+}
+
+// appendAddrs appends each non-empty address of addrs to list, removing any
+// line breaks.
+func (m *MailYak) appendAddrs(list, addrs []string) []string {
 	for _, addr := range addrs {
 		trimmed := m.trimRegex.ReplaceAllString(addr, "")
 		if trimmed == "" {
 			continue
 		}
 
-		m.bccAddrs = append(m.bccAddrs, trimmed)
+		list = append(list, trimmed)
 	}
+	return list
+}
+
+// ClearRecipients removes all To, Cc and Bcc addresses, and any groups added
+// with ToGroup.
+func (m *MailYak) ClearRecipients() {
+	m.toAddrs = []string{}
+	m.toGroups = nil
+	m.ccAddrs = []string{}
+	m.bccAddrs = []string{}
+}
+
+// RemoveRecipient removes addr from the To, Cc and Bcc addresses, and from any
+// groups added with ToGroup.
+//
+// Addresses are compared case-insensitively and ignoring any display name, so
+// RemoveRecipient("dom@itsallbroken.com") removes "Dom <DOM@itsallbroken.com>".
+func (m *MailYak) RemoveRecipient(addr string) {
+	key := strings.ToLower(envelopeAddress(addr))
+
+	lists := []*[]string{&m.toAddrs, &m.ccAddrs, &m.bccAddrs}
+	for i := range m.toGroups {
+		lists = append(lists, &m.toGroups[i].addrs)
+	}
+
+	for _, list := range lists {
+		kept := []string{}
+		for _, a := range *list {
+			if strings.ToLower(envelopeAddress(a)) != key {
+				kept = append(kept, a)
+			}
+		}
+		*list = kept
+	}
+}
+
+// DeduplicateRecipients removes duplicate addresses from the To, Cc and Bcc
+// headers when true, so a mailbox set more than once appears only in the first
+// of the To, Cc and Bcc headers it was set on. Defaults to false.
+//
+// Addresses are compared case-insensitively and ignoring any display name.
+// Each mailbox is always delivered to once, regardless of this setting.
+func (m *MailYak) DeduplicateRecipients(enabled bool) {
+	m.dedupRecipients = enabled
 }
 
 // WriteBccHeader writes the BCC header to the MIME body when true. Defaults to
@@ -92,16 +161,13 @@ func (m *MailYak) WriteBccHeader(shouldWrite bool) {
 //
 // 	mail.Cc(ccs...)
 func (m *MailYak) Cc(addrs ...string) {
-	m.ccAddrs = []string{}
-
-	for _, addr := range addrs {
-		trimmed := m.trimRegex.ReplaceAllString(addr, "")
-		if trimmed == "" {
-			continue
-		}
+	m.ccAddrs = m.appendAddrs([]string{}, addrs)
+}
 
-		m.ccAddrs = append(m.ccAddrs, trimmed)
-	}
+// AddCc appends addrs to the list of carbon copy (CC) addresses, keeping any
+// already set.
+func (m *MailYak) AddCc(addrs ...string) {
+	m.ccAddrs = m.appendAddrs(m.ccAddrs, addrs)
 }
 
 // From sets the sender email address.
@@ -111,15 +177,29 @@ func (m *MailYak) From(addr string) {
 	m.fromAddr = m.trimRegex.ReplaceAllString(addr, "")
 }
 
+// EnvelopeFrom sets the envelope sender address used in the SMTP MAIL command,
+// to which bounces and delivery status notifications are sent. This allows
+// bounce handling and VERP addresses distinct from the From address:
+//
+//	mail.From("news@itsallbroken.com")
+//	mail.EnvelopeFrom("bounces+dom=example.org@itsallbroken.com")
+//
+// If unset, the From address is used. The From and FromName headers are not
+// affected.
+func (m *MailYak) EnvelopeFrom(addr string) {
+	m.envelopeFrom = m.trimRegex.ReplaceAllString(addr, "")
+}
+
 // FromName sets the sender name.
 //
 // If set, emails typically display as being from:
 //
 // 		From Name <sender@example.com>
 //
-// If name contains non-ASCII characters, it is Q-encoded according to RFC1342.
+// If name contains special characters it is quoted, and if it contains non-ASCII
+// characters it is encoded according to RFC 2047.
 func (m *MailYak) FromName(name string) {
-	m.fromName = mime.QEncoding.Encode("UTF-8", m.trimRegex.ReplaceAllString(name, ""))
+	m.fromName = encodeDisplayName(m.trimRegex.ReplaceAllString(name, ""))
 }
 
 // ReplyTo sets the Reply-To email address.
@@ -129,6 +209,114 @@ func (m *MailYak) ReplyTo(addr string) {
 	m.replyTo = m.trimRegex.ReplaceAllString(addr, "")
 }
 
+// InReplyTo sets the In-Reply-To header to the Message-ID of the email being
+// replied to, threading the reply into the same conversation.
+//
+// Angle brackets are added to msgID if missing.
+func (m *MailYak) InReplyTo(msgID string) {
+	m.inReplyTo = formatMsgID(m.trimRegex.ReplaceAllString(msgID, ""))
+}
+
+// References sets the References header to the Message-IDs of the emails in
+// the conversation being replied to, typically the References of the parent
+// email followed by its own Message-ID.
+//
+// Angle brackets are added to each ID if missing.
+//
+//	mail.InReplyTo(parent.GetMessageID())
+//	mail.References(append(parentRefs, parent.GetMessageID())...)
+func (m *MailYak) References(ids ...string) {
+	m.references = []string{}
+
+	for _, id := range ids {
+		trimmed := m.trimRegex.ReplaceAllString(id, "")
+		if strings.TrimSpace(trimmed) == "" {
+			continue
+		}
+
+		m.references = append(m.references, formatMsgID(trimmed))
+	}
+}
+
+// formatMsgID returns id enclosed in angle brackets.
+func formatMsgID(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" || strings.HasPrefix(id, "<") && strings.HasSuffix(id, ">") {
+		return id
+	}
+	return "<" + strings.Trim(id, "<>") + ">"
+}
+
+// ListUnsubscribe sets the List-Unsubscribe header (RFC 2369) to the mailto:
+// and/or https: unsubscribe targets, as required of bulk senders by many
+// mailbox providers:
+//
+//	err := mail.ListUnsubscribe(
+//		"mailto:unsubscribe@itsallbroken.com?subject=unsubscribe",
+//		"https://itsallbroken.com/unsubscribe?id=1234",
+//	)
+//
+// If an https: target is given, the List-Unsubscribe-Post header is also set to
+// enable one-click unsubscription (RFC 8058). The https: target must then
+// unsubscribe the recipient when receiving a POST request, and should be
+// unique to the recipient.
+//
+// An error is returned if any target is not a valid mailto: or https: URI.
+func (m *MailYak) ListUnsubscribe(targets ...string) error {
+	var (
+		list     []string
+		oneClick bool
+	)
+	for _, target := range targets {
+		target = m.trimRegex.ReplaceAllString(target, "")
+
+		u, err := url.Parse(target)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case u.Scheme == "mailto" && u.Opaque != "":
+		case u.Scheme == "https" && u.Host != "":
+			oneClick = true
+		default:
+			return fmt.Errorf("mailyak: invalid List-Unsubscribe target %q", target)
+		}
+
+		list = append(list, "<"+target+">")
+	}
+
+	m.listUnsubscribe = list
+	m.listUnsubscribePost = oneClick
+	return nil
+}
+
+// RequestReadReceipt asks the recipient's email client to send a read receipt
+// (message disposition notification) to addr, by setting the
+// Disposition-Notification-To (RFC 8098) and Return-Receipt-To headers.
+//
+// Many clients ask the recipient before sending a receipt, or ignore the
+// request entirely. An error is returned if addr is not a valid address.
+func (m *MailYak) RequestReadReceipt(addr string) error {
+	addr = m.trimRegex.ReplaceAllString(addr, "")
+	if _, err := mail.ParseAddress(addr); err != nil {
+		return fmt.Errorf("mailyak: invalid read receipt address %q: %v", addr, err)
+	}
+
+	m.readReceipt = addr
+	return nil
+}
+
+// Sender sets the Sender header to the address of the agent actually sending
+// the email, when it differs from the author in the From header, such as when
+// sending on behalf of a user (RFC 5322 section 3.6.2).
+//
+// Many clients display such emails as sent by the Sender "on behalf of" the
+// From address. Setting a Sender is optional.
+func (m *MailYak) Sender(addr string) {
+	m.sender = m.trimRegex.ReplaceAllString(addr, "")
+}
+
 // Subject sets the email subject line.
 //
 // If sub contains non-ASCII characters, it is Q-encoded according to RFC1342.
@@ -136,12 +324,117 @@ func (m *MailYak) Subject(sub string) {
 	m.subject = mime.QEncoding.Encode("UTF-8", m.trimRegex.ReplaceAllString(sub, ""))
 }
 
+// Date sets the Date header to t, rather than the time the email is built.
+//
+// Passing the zero time restores the default behaviour of using the current
+// time each time the email is built or sent.
+func (m *MailYak) Date(t time.Time) {
+	if t.IsZero() {
+		m.date = ""
+		return
+	}
+	m.date = t.Format(time.RFC1123Z)
+}
+
 // AddHeader adds an arbitrary email header.
 //
+// Calling AddHeader more than once with the same name adds the header once for
+// each value, such as for multiple Comments headers. Use SetHeader to replace
+// any existing values instead.
+//
 // If value contains non-ASCII characters, it is Q-encoded according to RFC1342.
 // As always, validate any user input before adding it to a message, as this
 // method may enable an attacker to override the standard headers and, for
 // example, BCC themselves in a password reset email to a different user.
+//
+// Any characters not permitted in a header name, such as a colon or
+// whitespace, are removed from name.
 func (m *MailYak) AddHeader(name, value string) {
-	m.headers[m.trimRegex.ReplaceAllString(name, "")] = mime.QEncoding.Encode("UTF-8", m.trimRegex.ReplaceAllString(value, ""))
+	name = headerName(name)
+	m.addHeaderName(name)
+
+	if _, ok := m.headers[name]; !ok {
+		m.headers[name] = m.headerValue(value)
+		delete(m.extraHeaders, name)
+		return
+	}
+
+	if m.extraHeaders == nil {
+		m.extraHeaders = map[string][]string{}
+	}
+	m.extraHeaders[name] = append(m.extraHeaders[name], m.headerValue(value))
+}
+
+// SetHeader sets an arbitrary email header to value, replacing any values
+// previously added for name.
+//
+// The same encoding and sanitisation as AddHeader is applied.
+func (m *MailYak) SetHeader(name, value string) {
+	name = headerName(name)
+	m.addHeaderName(name)
+	m.headers[name] = m.headerValue(value)
+	delete(m.extraHeaders, name)
+}
+
+// deleteHeader removes all values of the named custom header.
+func (m *MailYak) deleteHeader(name string) {
+	delete(m.headers, name)
+	delete(m.extraHeaders, name)
+}
+
+// headerValues returns the values of the named custom header, or nil if it is
+// not set.
+func (m *MailYak) headerValues(name string) []string {
+	v, ok := m.headers[name]
+	if !ok {
+		return nil
+	}
+	return append([]string{v}, m.extraHeaders[name]...)
+}
+
+// addHeaderName records name in the order headers were first added, if not
+// already set.
+func (m *MailYak) addHeaderName(name string) {
+	if _, ok := m.headers[name]; !ok {
+		m.headerNames = append(m.headerNames, name)
+	}
+}
+
+// headerValue returns value with any line breaks removed, Q-encoded if it
+// contains non-ASCII characters.
+func (m *MailYak) headerValue(value string) string {
+	return mime.QEncoding.Encode("UTF-8", m.trimRegex.ReplaceAllString(value, ""))
+}
+
+// headerName returns name with any characters not permitted in a header field
+// name removed, leaving the printable ASCII characters other than the colon
+// (RFC 5322 section 2.2).
+func headerName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < '!' || r > '~' || r == ':' {
+			return -1
+		}
+		return r
+	}, name)
+}
+
+// ClearHeaders removes all headers set via AddHeader or SetHeader.
+func (m *MailYak) ClearHeaders() {
+	m.headers = map[string]string{}
+	m.extraHeaders = nil
+	m.headerNames = nil
+}
+
+// HeaderOrder sets the order headers added with AddHeader and SetHeader are
+// written in, for gateways expecting headers in a particular order.
+//
+// Headers named in names are written first, in the order given and matched
+// case-insensitively, followed by the remaining headers in the order they were
+// first added. By default all headers are written in the order they were first
+// added.
+//
+// Custom headers are always written after the standard headers, which are
+// written in a fixed order.
+func (m *MailYak) HeaderOrder(names ...string) {
+	m.headerOrder = cloneStrings(names)
 }
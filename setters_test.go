@@ -3,7 +3,9 @@ package mailyak
 import (
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestMailYakTo(t *testing.T) {
@@ -105,6 +107,57 @@ func TestMailYakBcc(t *testing.T) {
 		})
 	}
 }
+
+func TestMailYakCc(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		addrs []string
+		// Want
+		want []string
+	}{
+		{
+			"Single email",
+			[]string{"dom@itsallbroken.com"},
+			[]string{"dom@itsallbroken.com"},
+		},
+		{
+			"Multiple email",
+			[]string{"dom@itsallbroken.com", "ohnoes@itsallbroken.com"},
+			[]string{"dom@itsallbroken.com", "ohnoes@itsallbroken.com"},
+		},
+		{
+			"Empty last",
+			[]string{"dom@itsallbroken.com", "ohnoes@itsallbroken.com", ""},
+			[]string{"dom@itsallbroken.com", "ohnoes@itsallbroken.com"},
+		},
+		{
+			"Empty Middle",
+			[]string{"dom@itsallbroken.com", "", "ohnoes@itsallbroken.com"},
+			[]string{"dom@itsallbroken.com", "ohnoes@itsallbroken.com"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := &MailYak{
+				ccAddrs:   []string{},
+				trimRegex: regexp.MustCompile("\r?\n"),
+			}
+			m.Cc(tt.addrs...)
+
+			if !reflect.DeepEqual(m.ccAddrs, tt.want) {
+				t.Errorf("%q. MailYak.Cc() = %v, want %v", tt.name, m.ccAddrs, tt.want)
+			}
+		})
+	}
+}
+
 func TestMailYakSubject(t *testing.T) {
 	t.Parallel()
 
@@ -190,28 +243,49 @@ func TestMailYakAddHeader(t *testing.T) {
 		// Test description.
 		name string
 		// Parameters.
-		from map[string]string
+		from [][2]string
 		// Want
-		want map[string]string
+		want map[string][]string
 	}{
 		{
 			"ASCII",
-			map[string]string{
-				"List-Unsubscribe": "http://example.com",
-				"X-NASTY":          "true\r\nBcc: badguy@example.com",
+			[][2]string{
+				{"List-Unsubscribe", "http://example.com"},
+				{"X-NASTY", "true\r\nBcc: badguy@example.com"},
 			},
-			map[string]string{
-				"List-Unsubscribe": "http://example.com",
-				"X-NASTY":          "trueBcc: badguy@example.com",
+			map[string][]string{
+				"List-Unsubscribe": {"http://example.com"},
+				"X-NASTY":          {"trueBcc: badguy@example.com"},
+			},
+		},
+		{
+			"Name injection",
+			[][2]string{
+				{"X-Evil\r\nBcc: badguy@example.com\r\nX-Other", "true"},
+			},
+			map[string][]string{
+				"X-EvilBccbadguy@example.comX-Other": {"true"},
 			},
 		},
 		{
 			"Q-encoded",
-			map[string]string{
-				"X-BEETHOVEN": "für Elise",
+			[][2]string{
+				{"X-BEETHOVEN", "für Elise"},
 			},
-			map[string]string{
-				"X-BEETHOVEN": "=?UTF-8?q?f=C3=BCr_Elise?=",
+			map[string][]string{
+				"X-BEETHOVEN": {"=?UTF-8?q?f=C3=BCr_Elise?="},
+			},
+		},
+		{
+			"Multiple values",
+			[][2]string{
+				{"Comments", "one"},
+				{"X-Tag", "a"},
+				{"Comments", "two"},
+			},
+			map[string][]string{
+				"Comments": {"one", "two"},
+				"X-Tag":    {"a"},
 			},
 		},
 	}
@@ -225,13 +299,459 @@ func TestMailYakAddHeader(t *testing.T) {
 				trimRegex: regexp.MustCompile("\r?\n"),
 			}
 
-			for k, v := range tt.from {
-				m.AddHeader(k, v)
+			for _, h := range tt.from {
+				m.AddHeader(h[0], h[1])
+			}
+
+			if got := m.GetHeaderValues(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%q. MailYak.AddHeader() = %v, want %v", tt.name, got, tt.want)
+			}
+
+			// GetHeaders returns the first value of each header
+			for k, v := range tt.want {
+				if got := m.GetHeaders()[k]; got != v[0] {
+					t.Errorf("%q. MailYak.GetHeaders()[%q] = %q, want %q", tt.name, k, got, v[0])
+				}
+			}
+		})
+	}
+}
+
+func TestMailYakSetHeader(t *testing.T) {
+	t.Parallel()
+
+	m := New("", nil)
+	m.AddHeader("Comments", "one")
+	m.AddHeader("Comments", "two")
+	m.SetHeader("Comments", "three\r\n")
+	m.SetHeader("X-Tag", "a")
+
+	want := map[string][]string{
+		"Comments": {"three"},
+		"X-Tag":    {"a"},
+	}
+	if got := m.GetHeaderValues(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MailYak.SetHeader() = %v, want %v", got, want)
+	}
+}
+
+func TestMailYakInReplyTo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		msgID string
+		// Want
+		want string
+	}{
+		{
+			"With brackets",
+			"<1234@itsallbroken.com>",
+			"<1234@itsallbroken.com>",
+		},
+		{
+			"Without brackets",
+			"1234@itsallbroken.com\r\n",
+			"<1234@itsallbroken.com>",
+		},
+		{
+			"Empty",
+			"",
+			"",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := &MailYak{
+				trimRegex: regexp.MustCompile("\r?\n"),
+			}
+			m.InReplyTo(tt.msgID)
+
+			if m.inReplyTo != tt.want {
+				t.Errorf("%q. MailYak.InReplyTo() = %v, want %v", tt.name, m.inReplyTo, tt.want)
+			}
+		})
+	}
+}
+
+func TestMailYakReferences(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		ids []string
+		// Want
+		want []string
+	}{
+		{
+			"Single",
+			[]string{"<1@itsallbroken.com>"},
+			[]string{"<1@itsallbroken.com>"},
+		},
+		{
+			"Multiple",
+			[]string{"<1@itsallbroken.com>", "2@itsallbroken.com"},
+			[]string{"<1@itsallbroken.com>", "<2@itsallbroken.com>"},
+		},
+		{
+			"Ignore empty",
+			[]string{"", "<1@itsallbroken.com>", "\r\n"},
+			[]string{"<1@itsallbroken.com>"},
+		},
+		{
+			"None",
+			[]string{},
+			[]string{},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := &MailYak{
+				trimRegex: regexp.MustCompile("\r?\n"),
+			}
+			m.References(tt.ids...)
+
+			if !reflect.DeepEqual(m.references, tt.want) {
+				t.Errorf("%q. MailYak.References() = %v, want %v", tt.name, m.references, tt.want)
+			}
+		})
+	}
+}
+
+func TestMailYakListUnsubscribe(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		targets []string
+		// Want
+		want     []string
+		wantPost bool
+		wantErr  bool
+	}{
+		{
+			"mailto",
+			[]string{"mailto:unsubscribe@itsallbroken.com?subject=unsubscribe"},
+			[]string{"<mailto:unsubscribe@itsallbroken.com?subject=unsubscribe>"},
+			false,
+			false,
+		},
+		{
+			"https",
+			[]string{"https://itsallbroken.com/unsubscribe?id=1234"},
+			[]string{"<https://itsallbroken.com/unsubscribe?id=1234>"},
+			true,
+			false,
+		},
+		{
+			"Both",
+			[]string{"mailto:unsubscribe@itsallbroken.com", "https://itsallbroken.com/unsubscribe?id=1234"},
+			[]string{"<mailto:unsubscribe@itsallbroken.com>", "<https://itsallbroken.com/unsubscribe?id=1234>"},
+			true,
+			false,
+		},
+		{
+			"http",
+			[]string{"http://itsallbroken.com/unsubscribe"},
+			nil,
+			false,
+			true,
+		},
+		{
+			"Empty mailto",
+			[]string{"mailto:"},
+			nil,
+			false,
+			true,
+		},
+		{
+			"Invalid",
+			[]string{"unsubscribe@itsallbroken.com"},
+			nil,
+			false,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := &MailYak{
+				trimRegex: regexp.MustCompile("\r?\n"),
+			}
+
+			err := m.ListUnsubscribe(tt.targets...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%q. MailYak.ListUnsubscribe() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(m.listUnsubscribe, tt.want) {
+				t.Errorf("%q. MailYak.ListUnsubscribe() = %v, want %v", tt.name, m.listUnsubscribe, tt.want)
+			}
+			if m.listUnsubscribePost != tt.wantPost {
+				t.Errorf("%q. MailYak.ListUnsubscribe() post = %v, want %v", tt.name, m.listUnsubscribePost, tt.wantPost)
+			}
+		})
+	}
+}
+
+func TestMailYakRequestReadReceipt(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		addr string
+		// Want
+		want    string
+		wantErr bool
+	}{
+		{
+			"Address",
+			"dom@itsallbroken.com",
+			"dom@itsallbroken.com",
+			false,
+		},
+		{
+			"Named address",
+			"Dom <dom@itsallbroken.com>\r\n",
+			"Dom <dom@itsallbroken.com>",
+			false,
+		},
+		{
+			"Invalid",
+			"dom",
+			"",
+			true,
+		},
+		{
+			"Empty",
+			"",
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := &MailYak{
+				trimRegex: regexp.MustCompile("\r?\n"),
+			}
+
+			if err := m.RequestReadReceipt(tt.addr); (err != nil) != tt.wantErr {
+				t.Fatalf("%q. MailYak.RequestReadReceipt() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+
+			if m.readReceipt != tt.want {
+				t.Errorf("%q. MailYak.RequestReadReceipt() = %v, want %v", tt.name, m.readReceipt, tt.want)
+			}
+		})
+	}
+}
+
+func TestMailYakWriteBccHeader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		shouldWrite bool
+		// Want
+		want bool
+	}{
+		{
+			"Write",
+			true,
+			true,
+		},
+		{
+			"Omit",
+			false,
+			false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.To("to@itsallbroken.com")
+			m.Bcc("bcc@itsallbroken.com")
+			m.WriteBccHeader(tt.shouldWrite)
+
+			buf, err := m.MimeBuf()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := strings.Contains(buf.String(), "\r\nBCC: bcc@itsallbroken.com\r\n"); got != tt.want {
+				t.Errorf("%q. MailYak.MimeBuf() BCC header = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMailYakDate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		date time.Time
+		// Want
+		want string
+	}{
+		{
+			"Set",
+			time.Date(2016, 4, 10, 20, 45, 0, 0, time.FixedZone("BST", 3600)),
+			"Sun, 10 Apr 2016 20:45:00 +0100",
+		},
+		{
+			"Zero",
+			time.Time{},
+			"",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := &MailYak{date: "a date"}
+			m.Date(tt.date)
+
+			if m.date != tt.want {
+				t.Errorf("%q. MailYak.Date() = %v, want %v", tt.name, m.date, tt.want)
 			}
+		})
+	}
+}
+
+// TestMailYakAddTo ensures the append variants of the recipient setters keep
+// the addresses already set.
+func TestMailYakAddTo(t *testing.T) {
+	t.Parallel()
 
-			if !reflect.DeepEqual(m.headers, tt.want) {
-				t.Errorf("%q. MailYak.AddHeader() = %v, want %v", tt.name, m.headers, tt.want)
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		set func(m *MailYak)
+		get func(m *MailYak) []string
+		// Want
+		want []string
+	}{
+		{
+			"To",
+			func(m *MailYak) {
+				m.To("one@itsallbroken.com")
+				m.AddTo("two@itsallbroken.com", "", "three@itsallbroken.com")
+				m.AddTo()
+			},
+			func(m *MailYak) []string { return m.toAddrs },
+			[]string{"one@itsallbroken.com", "two@itsallbroken.com", "three@itsallbroken.com"},
+		},
+		{
+			"Cc",
+			func(m *MailYak) {
+				m.AddCc("one@itsallbroken.com")
+				m.AddCc("two@itsallbroken.com\r\n")
+			},
+			func(m *MailYak) []string { return m.ccAddrs },
+			[]string{"one@itsallbroken.com", "two@itsallbroken.com"},
+		},
+		{
+			"Bcc",
+			func(m *MailYak) {
+				m.AddBcc("one@itsallbroken.com")
+				m.Bcc("two@itsallbroken.com")
+				m.AddBcc("three@itsallbroken.com")
+			},
+			func(m *MailYak) []string { return m.bccAddrs },
+			[]string{"two@itsallbroken.com", "three@itsallbroken.com"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			tt.set(m)
+
+			if got := tt.get(m); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%q. MailYak.Add%s() = %v, want %v", tt.name, tt.name, got, tt.want)
 			}
 		})
 	}
 }
+
+func TestMailYakClearRecipients(t *testing.T) {
+	t.Parallel()
+
+	m := New("", nil)
+	m.To("to@itsallbroken.com")
+	m.Cc("cc@itsallbroken.com")
+	m.Bcc("bcc@itsallbroken.com")
+
+	m.ClearRecipients()
+
+	if len(m.toAddrs) != 0 || len(m.ccAddrs) != 0 || len(m.bccAddrs) != 0 {
+		t.Errorf("MailYak.ClearRecipients() to = %v, cc = %v, bcc = %v, want none", m.toAddrs, m.ccAddrs, m.bccAddrs)
+	}
+}
+
+func TestMailYakRemoveRecipient(t *testing.T) {
+	t.Parallel()
+
+	m := New("", nil)
+	m.To("Dom <DOM@itsallbroken.com>", "to@itsallbroken.com")
+	m.Cc("dom@itsallbroken.com")
+	m.Bcc("bcc@itsallbroken.com", "dom@itsallbroken.com")
+
+	m.RemoveRecipient("Dom@itsallbroken.com")
+
+	want := [][]string{{"to@itsallbroken.com"}, {}, {"bcc@itsallbroken.com"}}
+	got := [][]string{m.toAddrs, m.ccAddrs, m.bccAddrs}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MailYak.RemoveRecipient() = %v, want %v", got, want)
+	}
+}
+
+func TestMailYakClearHeaders(t *testing.T) {
+	t.Parallel()
+
+	m := New("", nil)
+	m.AddHeader("Precedence", "bulk")
+
+	m.ClearHeaders()
+
+	if len(m.headers) != 0 {
+		t.Errorf("MailYak.ClearHeaders() = %v, want none", m.headers)
+	}
+
+	// headers can still be added
+	m.AddHeader("Precedence", "bulk")
+	if len(m.headers) != 1 {
+		t.Errorf("MailYak.AddHeader() = %v, want 1 header", m.headers)
+	}
+}
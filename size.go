@@ -0,0 +1,121 @@
+package mailyak
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/smtp"
+	"strconv"
+)
+
+// ErrMessageTooLarge is returned when sending an email larger than the maximum
+// message size advertised by the SMTP server with the SIZE extension
+// (RFC 1870), or when building an email larger than the limit set with
+// MaxMessageSize.
+//
+// The returned error wraps ErrMessageTooLarge with the size of the email and
+// the server limit, and should be checked using errors.Is.
+var ErrMessageTooLarge = errors.New("mailyak: message too large")
+
+// MaxMessageSize limits the size of the MIME message built for the email to
+// bytes, causing Send, WriteTo and the other methods building the message to
+// fail with an error wrapping ErrMessageTooLarge once exceeded. A limit of 0
+// or less disables the check, which is the default.
+//
+// The message is checked as it is generated, so an email streamed to the SMTP
+// server is aborted as soon as it exceeds the limit, and an email built before
+// connecting (such as when it is signed or encrypted) fails without connecting
+// to the server. The email API transports check the size of the equivalent
+// MIME message before sending the email.
+func (m *MailYak) MaxMessageSize(bytes int64) {
+	m.maxMessageSize = bytes
+}
+
+// limitSize returns w limited to the maximum message size of m, if set.
+func (m *MailYak) limitSize(w io.Writer) io.Writer {
+	if m.maxMessageSize <= 0 {
+		return w
+	}
+
+	return &limitedWriter{
+		w:         w,
+		remaining: m.maxMessageSize,
+		err:       fmt.Errorf("%w: message exceeds maximum size of %d bytes", ErrMessageTooLarge, m.maxMessageSize),
+	}
+}
+
+// checkSize returns an error if a message of size bytes exceeds the maximum
+// message size of m.
+func (m *MailYak) checkSize(size int) error {
+	if m.maxMessageSize > 0 && int64(size) > m.maxMessageSize {
+		return fmt.Errorf("%w: message is %d bytes, maximum size is %d bytes", ErrMessageTooLarge, size, m.maxMessageSize)
+	}
+	return nil
+}
+
+// sizeLimit applies the maximum message size advertised by the server to msg.
+//
+// If the size of msg is known (such as when it is signed or encrypted), it is
+// checked before any envelope commands are sent and returned as the SIZE
+// parameter of the MAIL command. Streamed messages are instead checked as they
+// are written, failing as soon as the limit is exceeded rather than after the
+// entire message has been sent - they are not sized in advance, as that would
+// read and encode every attachment twice.
+func sizeLimit(smtpClient *smtp.Client, msg io.WriterTo) (io.WriterTo, []string, error) {
+	ok, param := smtpClient.Extension("SIZE")
+	if !ok {
+		return msg, nil, nil
+	}
+
+	// a missing or zero limit indicates no fixed maximum
+	limit, _ := strconv.ParseInt(param, 10, 64)
+
+	mm, ok := msg.(*mimeMessage)
+	if !ok || mm.buf == nil {
+		if limit <= 0 {
+			return msg, nil, nil
+		}
+		return &limitedMessage{msg: msg, limit: limit}, nil, nil
+	}
+
+	size := int64(len(mm.buf))
+	if limit > 0 && size > limit {
+		return nil, nil, fmt.Errorf("%w: message is %d bytes, server limit is %d bytes", ErrMessageTooLarge, size, limit)
+	}
+
+	return msg, []string{"SIZE=" + strconv.FormatInt(size, 10)}, nil
+}
+
+// limitedMessage is an io.WriterTo failing with ErrMessageTooLarge once more
+// than limit bytes of msg have been written.
+type limitedMessage struct {
+	msg   io.WriterTo
+	limit int64
+}
+
+// WriteTo writes msg to w, up to the limit.
+func (l *limitedMessage) WriteTo(w io.Writer) (int64, error) {
+	return l.msg.WriteTo(&limitedWriter{
+		w:         w,
+		remaining: l.limit,
+		err:       fmt.Errorf("%w: message exceeds server limit of %d bytes", ErrMessageTooLarge, l.limit),
+	})
+}
+
+// limitedWriter is an io.Writer failing with err once more than remaining
+// bytes have been written to w.
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64
+	err       error
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.remaining {
+		return 0, l.err
+	}
+
+	n, err := l.w.Write(p)
+	l.remaining -= int64(n)
+	return n, err
+}
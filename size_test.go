@@ -0,0 +1,294 @@
+package mailyak
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestMailYakSizeLimit ensures emails larger than the SIZE limit advertised by
+// the server are rejected.
+func TestMailYakSizeLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		ehlo       string
+		signed     bool
+		body       int
+		attachment io.Reader
+		// Want
+		wantMail string
+		wantSize bool
+		wantData bool
+		wantErr  error
+	}{
+		{
+			name:     "Unsupported",
+			ehlo:     "250 localhost",
+			body:     10000,
+			wantMail: "MAIL FROM:<from@example.org>",
+			wantData: true,
+		},
+		{
+			name:     "No limit",
+			ehlo:     "250-localhost\r\n250 SIZE",
+			body:     10000,
+			wantMail: "MAIL FROM:<from@example.org>",
+			wantData: true,
+		},
+		{
+			name:     "Streamed within limit",
+			ehlo:     "250-localhost\r\n250 SIZE 100000",
+			body:     10000,
+			wantMail: "MAIL FROM:<from@example.org>",
+			wantData: true,
+		},
+		{
+			name:     "Streamed over limit",
+			ehlo:     "250-localhost\r\n250 SIZE 1000",
+			body:     10000,
+			wantMail: "MAIL FROM:<from@example.org>",
+			wantErr:  ErrMessageTooLarge,
+		},
+		{
+			name:       "Seekable attachment within limit",
+			ehlo:       "250-localhost\r\n250 SIZE 100000",
+			body:       100,
+			attachment: strings.NewReader(strings.Repeat("a", 10000)),
+			wantMail:   "MAIL FROM:<from@example.org>",
+			wantData:   true,
+		},
+		{
+			name:       "Seekable attachment over limit",
+			ehlo:       "250-localhost\r\n250 SIZE 1000",
+			body:       100,
+			attachment: strings.NewReader(strings.Repeat("a", 10000)),
+			wantMail:   "MAIL FROM:<from@example.org>",
+			wantErr:    ErrMessageTooLarge,
+		},
+		{
+			name:       "Unseekable attachment within limit",
+			ehlo:       "250-localhost\r\n250 SIZE 100000",
+			body:       100,
+			attachment: io.MultiReader(strings.NewReader(strings.Repeat("a", 10000))),
+			wantMail:   "MAIL FROM:<from@example.org>",
+			wantData:   true,
+		},
+		{
+			name:       "Unseekable attachment over limit",
+			ehlo:       "250-localhost\r\n250 SIZE 1000",
+			body:       100,
+			attachment: io.MultiReader(strings.NewReader(strings.Repeat("a", 10000))),
+			wantMail:   "MAIL FROM:<from@example.org>",
+			wantErr:    ErrMessageTooLarge,
+		},
+		{
+			name:     "Built within limit",
+			ehlo:     "250-localhost\r\n250 SIZE 100000",
+			signed:   true,
+			body:     10000,
+			wantMail: "MAIL FROM:<from@example.org> SIZE=",
+			wantSize: true,
+			wantData: true,
+		},
+		{
+			name:    "Built over limit",
+			ehlo:    "250-localhost\r\n250 SIZE 1000",
+			signed:  true,
+			body:    10000,
+			wantErr: ErrMessageTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, map[string]string{"EHLO": tt.ehlo})
+			defer srv.Close()
+
+			mail := New(srv.Addr(), nil)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+			mail.Plain().Set(strings.Repeat("a", tt.body))
+			if tt.attachment != nil {
+				mail.Attach("a.txt", tt.attachment)
+			}
+			if tt.signed {
+				if err := mail.PGP(PGPOptions{Signer: testPGP{}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, _, err = mail.Send("localhost")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MailYak.Send() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var gotMail string
+			for _, cmd := range srv.Commands() {
+				if strings.HasPrefix(cmd, "MAIL ") {
+					gotMail = cmd
+				}
+			}
+			if !strings.HasPrefix(gotMail, tt.wantMail) || (tt.wantMail == "" && gotMail != "") {
+				t.Errorf("MailYak.Send() MAIL command = %q, want prefix %q", gotMail, tt.wantMail)
+			}
+			if got := strings.Contains(gotMail, " SIZE="); got != tt.wantSize {
+				t.Errorf("MailYak.Send() MAIL command = %q, want SIZE parameter %v", gotMail, tt.wantSize)
+			}
+
+			if got := len(srv.Data()) > 0; got != tt.wantData {
+				t.Errorf("MailYak.Send() delivered data = %v, want %v", got, tt.wantData)
+			}
+
+			// the declared size is that of the message data sent, which is
+			// read with the CRLF line endings converted to LF
+			if i := strings.Index(gotMail, " SIZE="); i >= 0 && tt.wantData {
+				data := srv.Data()
+				if got, want := gotMail[i+len(" SIZE="):], strconv.Itoa(len(data)+bytes.Count(data, []byte("\n"))); got != want {
+					t.Errorf("MailYak.Send() SIZE = %s, want %s", got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestMailYakMaxMessageSize ensures emails larger than the configured maximum
+// size fail as they are built.
+func TestMailYakMaxMessageSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		max    int64
+		signed bool
+		body   int
+		// Want
+		wantMail bool
+		wantData bool
+		wantErr  error
+	}{
+		{
+			name:     "Disabled",
+			body:     10000,
+			wantMail: true,
+			wantData: true,
+		},
+		{
+			name:     "Streamed within limit",
+			max:      100000,
+			body:     10000,
+			wantMail: true,
+			wantData: true,
+		},
+		{
+			name:     "Streamed over limit",
+			max:      1000,
+			body:     10000,
+			wantMail: true,
+			wantErr:  ErrMessageTooLarge,
+		},
+		{
+			name:     "Built within limit",
+			max:      100000,
+			signed:   true,
+			body:     10000,
+			wantMail: true,
+			wantData: true,
+		},
+		{
+			name:    "Built over limit",
+			max:     1000,
+			signed:  true,
+			body:    10000,
+			wantErr: ErrMessageTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, nil)
+			defer srv.Close()
+
+			mail := New(srv.Addr(), nil)
+			mail.MaxMessageSize(tt.max)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+			mail.Plain().Set(strings.Repeat("a", tt.body))
+			if tt.signed {
+				if err := mail.PGP(PGPOptions{Signer: testPGP{}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, _, err = mail.Send("localhost")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MailYak.Send() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var gotMail bool
+			for _, cmd := range srv.Commands() {
+				gotMail = gotMail || strings.HasPrefix(cmd, "MAIL ")
+			}
+			if gotMail != tt.wantMail {
+				t.Errorf("MailYak.Send() sent MAIL = %v, want %v", gotMail, tt.wantMail)
+			}
+
+			if got := len(srv.Data()) > 0; got != tt.wantData {
+				t.Errorf("MailYak.Send() delivered data = %v, want %v", got, tt.wantData)
+			}
+		})
+	}
+}
+
+// TestMailYakMaxMessageSize_signed ensures the size limit applies to the
+// message once signed.
+func TestMailYakMaxMessageSize_signed(t *testing.T) {
+	t.Parallel()
+
+	mail := New("", nil)
+	mail.DeterministicOutput(42)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+	mail.Plain().Set("Hello")
+	if err := mail.PGP(PGPOptions{Signer: testPGP{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := mail.buildMime()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mail.MaxMessageSize(int64(buf.Len()))
+	if _, err := mail.buildMime(); err != nil {
+		t.Fatalf("MailYak.buildMime() error = %v, want nil at the limit", err)
+	}
+
+	mail.MaxMessageSize(int64(buf.Len() - 1))
+	if _, err := mail.buildMime(); !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("MailYak.buildMime() error = %v, want %v", err, ErrMessageTooLarge)
+	}
+}
@@ -0,0 +1,420 @@
+package mailyak
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// SMIMEOptions configures S/MIME (RFC 8551) signing and encryption of outgoing
+// emails.
+//
+// Emails are signed when both Certificate and Signer are set, and encrypted
+// when Recipients is non-empty. When both are configured the email is signed
+// before being encrypted.
+type SMIMEOptions struct {
+	// Certificate is the signer's certificate, included in the signature.
+	Certificate *x509.Certificate
+
+	// Signer is the private key of Certificate, either an *rsa.PrivateKey or
+	// an *ecdsa.PrivateKey.
+	Signer crypto.Signer
+
+	// Intermediates are additional certificates included in the signature
+	// to help recipients build a chain to a trusted root.
+	Intermediates []*x509.Certificate
+
+	// Recipients are the certificates of the recipients able to decrypt the
+	// email. Only RSA certificates are supported.
+	//
+	// The sender's own certificate should typically be included to allow
+	// the sent message to be read.
+	Recipients []*x509.Certificate
+}
+
+// Object identifiers used in the CMS (RFC 5652) structures.
+var (
+	oidData                   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidEnvelopedData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidAttributeContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttributeMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttributeSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidSHA256                 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidRSAESOAEP              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 7}
+	oidECDSAWithSHA256        = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidAES256CBC              = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type encapsulatedContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+}
+
+type signerInfo struct {
+	Version            int
+	SID                issuerAndSerialNumber
+	DigestAlgorithm    algorithmIdentifier
+	SignedAttrs        asn1.RawValue
+	SignatureAlgorithm algorithmIdentifier
+	Signature          []byte
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []algorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type keyTransRecipientInfo struct {
+	Version                int
+	RID                    issuerAndSerialNumber
+	KeyEncryptionAlgorithm algorithmIdentifier
+	EncryptedKey           []byte
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm algorithmIdentifier
+	EncryptedContent           asn1.RawValue
+}
+
+type envelopedData struct {
+	Version              int
+	RecipientInfos       []keyTransRecipientInfo `asn1:"set"`
+	EncryptedContentInfo encryptedContentInfo
+}
+
+// smimeWrapper signs and/or encrypts generated MIME messages.
+type smimeWrapper struct {
+	opts SMIMEOptions
+	now  func() time.Time
+}
+
+// SMIME enables S/MIME signing and/or encryption of the email as configured
+// by opts.
+//
+// Signed emails are sent as multipart/signed with a detached signature, and
+// encrypted emails as application/pkcs7-mime enveloped data, encrypted with
+// AES-256-CBC. The From, To, Subject and other top-level headers are not
+// encrypted.
+func (m *MailYak) SMIME(opts SMIMEOptions) error {
+	signing := opts.Certificate != nil || opts.Signer != nil
+	if signing && (opts.Certificate == nil || opts.Signer == nil) {
+		return errors.New("mailyak: S/MIME signing requires both a certificate and signer")
+	}
+
+	if !signing && len(opts.Recipients) == 0 {
+		return errors.New("mailyak: S/MIME requires a signer or recipients")
+	}
+
+	if signing {
+		switch opts.Signer.(type) {
+		case *rsa.PrivateKey, *ecdsa.PrivateKey:
+		default:
+			return fmt.Errorf("mailyak: unsupported S/MIME signer %T", opts.Signer)
+		}
+	}
+
+	for _, cert := range opts.Recipients {
+		if _, ok := cert.PublicKey.(*rsa.PublicKey); !ok {
+			return fmt.Errorf("mailyak: unsupported S/MIME recipient key %T", cert.PublicKey)
+		}
+	}
+
+	m.smime = &smimeWrapper{opts: opts, now: time.Now}
+	return nil
+}
+
+// wrap returns msg with the MIME content signed and/or encrypted, leaving the
+// top-level headers (From, To, etc) in place.
+func (s *smimeWrapper) wrap(msg []byte) (*bytes.Buffer, error) {
+	outer, content := splitEntity(msg)
+
+	if s.opts.Signer != nil {
+		signed, err := s.sign(content)
+		if err != nil {
+			return nil, err
+		}
+		content = signed
+	}
+
+	if len(s.opts.Recipients) > 0 {
+		encrypted, err := s.encrypt(content)
+		if err != nil {
+			return nil, err
+		}
+		content = encrypted
+	}
+
+	outer.Write(content)
+	return outer, nil
+}
+
+// sign returns a multipart/signed entity containing entity and its detached
+// signature.
+func (s *smimeWrapper) sign(entity []byte) ([]byte, error) {
+	sig, err := s.signature(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	var part bytes.Buffer
+	part.WriteString("Content-Type: application/pkcs7-signature; name=\"smime.p7s\"\r\n")
+	part.WriteString("Content-Transfer-Encoding: base64\r\n")
+	part.WriteString("Content-Disposition: attachment; filename=\"smime.p7s\"\r\n\r\n")
+	if err := writeBase64Lines(&part, sig); err != nil {
+		return nil, err
+	}
+
+	return multipartSigned(entity, "application/pkcs7-signature", "sha-256", part.Bytes())
+}
+
+// encrypt returns an application/pkcs7-mime entity containing entity encrypted
+// to the recipients.
+func (s *smimeWrapper) encrypt(entity []byte) ([]byte, error) {
+	data, err := s.envelope(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("Content-Type: application/pkcs7-mime; smime-type=enveloped-data; name=\"smime.p7m\"\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+	buf.WriteString("Content-Disposition: attachment; filename=\"smime.p7m\"\r\n\r\n")
+	if err := writeBase64Lines(&buf, data); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\r\n")
+
+	return buf.Bytes(), nil
+}
+
+// writeBase64Lines writes data to w as base64, broken into 76 character lines.
+func writeBase64Lines(w io.Writer, data []byte) error {
+	encoder := base64.NewEncoder(base64.StdEncoding, &lineSplitter{w: w, maxLen: 76})
+	if _, err := encoder.Write(data); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// signature returns the DER encoded CMS SignedData structure containing a
+// detached signature of content.
+func (s *smimeWrapper) signature(content []byte) ([]byte, error) {
+	digest := sha256.Sum256(content)
+
+	attrs, err := marshalAttributes(
+		attribute(oidAttributeContentType, oidData),
+		attribute(oidAttributeMessageDigest, digest[:]),
+		attribute(oidAttributeSigningTime, s.now().UTC()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The signature is calculated over the DER encoding of the attributes as
+	// a SET OF
+	setOf, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: attrs})
+	if err != nil {
+		return nil, err
+	}
+	attrsDigest := sha256.Sum256(setOf)
+
+	var sigAlg algorithmIdentifier
+	switch s.opts.Signer.(type) {
+	case *ecdsa.PrivateKey:
+		sigAlg = algorithmIdentifier{Algorithm: oidECDSAWithSHA256}
+	default:
+		sigAlg = algorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue}
+	}
+
+	sig, err := s.opts.Signer.Sign(rand.Reader, attrsDigest[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []byte
+	certs = append(certs, s.opts.Certificate.Raw...)
+	for _, cert := range s.opts.Intermediates {
+		certs = append(certs, cert.Raw...)
+	}
+
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []algorithmIdentifier{{Algorithm: oidSHA256}},
+		EncapContentInfo: encapsulatedContentInfo{ContentType: oidData},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certs},
+		SignerInfos: []signerInfo{{
+			Version:            1,
+			SID:                issuerAndSerial(s.opts.Certificate),
+			DigestAlgorithm:    algorithmIdentifier{Algorithm: oidSHA256},
+			SignedAttrs:        asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: attrs},
+			SignatureAlgorithm: sigAlg,
+			Signature:          sig,
+		}},
+	}
+
+	return marshalContentInfo(oidSignedData, sd)
+}
+
+// envelope returns the DER encoded CMS EnvelopedData structure containing
+// content encrypted with AES-256-CBC, using a random key encrypted to each
+// recipient with RSAES-OAEP.
+func (s *smimeWrapper) envelope(content []byte) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// PKCS#7 padding
+	pad := aes.BlockSize - len(content)%aes.BlockSize
+	plaintext := make([]byte, len(content), len(content)+pad)
+	copy(plaintext, content)
+	plaintext = append(plaintext, bytes.Repeat([]byte{byte(pad)}, pad)...)
+
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+	// RSAES-OAEP with the default (SHA-1) parameters, as defined in RFC 3560
+	oaepParams, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var recipients []keyTransRecipientInfo
+	for _, cert := range s.opts.Recipients {
+		encryptedKey, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, cert.PublicKey.(*rsa.PublicKey), key, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		recipients = append(recipients, keyTransRecipientInfo{
+			Version:                0,
+			RID:                    issuerAndSerial(cert),
+			KeyEncryptionAlgorithm: algorithmIdentifier{Algorithm: oidRSAESOAEP, Parameters: asn1.RawValue{FullBytes: oaepParams}},
+			EncryptedKey:           encryptedKey,
+		})
+	}
+
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+
+	ed := envelopedData{
+		Version:        0,
+		RecipientInfos: recipients,
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType:                oidData,
+			ContentEncryptionAlgorithm: algorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParam}},
+			EncryptedContent:           asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: ciphertext},
+		},
+	}
+
+	return marshalContentInfo(oidEnvelopedData, ed)
+}
+
+// issuerAndSerial returns the IssuerAndSerialNumber identifying cert.
+func issuerAndSerial(cert *x509.Certificate) issuerAndSerialNumber {
+	return issuerAndSerialNumber{
+		Issuer:       asn1.RawValue{FullBytes: cert.RawIssuer},
+		SerialNumber: cert.SerialNumber,
+	}
+}
+
+// marshalContentInfo returns the DER encoded ContentInfo wrapping content.
+func marshalContentInfo(contentType asn1.ObjectIdentifier, content interface{}) ([]byte, error) {
+	inner, err := asn1.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(contentInfo{
+		ContentType: contentType,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: inner},
+	})
+}
+
+// attributeValue is a CMS attribute with a single value.
+type attributeValue struct {
+	oid   asn1.ObjectIdentifier
+	value interface{}
+}
+
+func attribute(oid asn1.ObjectIdentifier, value interface{}) attributeValue {
+	return attributeValue{oid: oid, value: value}
+}
+
+// marshalAttributes returns the concatenated DER encoding of attrs, sorted as
+// required for a DER SET OF.
+func marshalAttributes(attrs ...attributeValue) ([]byte, error) {
+	encoded := make([][]byte, 0, len(attrs))
+	for _, a := range attrs {
+		value, err := asn1.Marshal(a.value)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := asn1.Marshal(struct {
+			Type   asn1.ObjectIdentifier
+			Values asn1.RawValue
+		}{
+			Type:   a.oid,
+			Values: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: value},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		encoded = append(encoded, b)
+	}
+
+	sort.Slice(encoded, func(i, j int) bool {
+		return bytes.Compare(encoded[i], encoded[j]) < 0
+	})
+
+	return bytes.Join(encoded, nil), nil
+}
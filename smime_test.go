@@ -0,0 +1,283 @@
+package mailyak
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"math/big"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestCertificate returns a self-signed certificate for key.
+func newTestCertificate(t *testing.T, key crypto.Signer) *x509.Certificate {
+	tmpl := &x509.Certificate{
+		SerialNumber:   big.NewInt(42),
+		Subject:        pkix.Name{CommonName: "dom@itsallbroken.com"},
+		EmailAddresses: []string{"dom@itsallbroken.com"},
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+		KeyUsage:       x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+// base64Part returns the decoded base64 content following the Content-Type
+// header with ctype in msg.
+func base64Part(t *testing.T, msg, ctype string) []byte {
+	re := regexp.MustCompile(`(?s)Content-Type: ` + regexp.QuoteMeta(ctype) + `.*?\r\n\r\n([A-Za-z0-9+/=\r\n]+)`)
+	match := re.FindStringSubmatch(msg)
+	if match == nil {
+		t.Fatalf("no %s part in %q", ctype, msg)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.Replace(match[1], "\r\n", "", -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// TestMailYakSMIME_options ensures invalid S/MIME options are rejected.
+func TestMailYakSMIME_options(t *testing.T) {
+	t.Parallel()
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecCert := newTestCertificate(t, ecKey)
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		opts SMIMEOptions
+		// Want
+		wantErr bool
+	}{
+		{"Sign", SMIMEOptions{Certificate: ecCert, Signer: ecKey}, false},
+		{"Empty", SMIMEOptions{}, true},
+		{"No certificate", SMIMEOptions{Signer: ecKey}, true},
+		{"No signer", SMIMEOptions{Certificate: ecCert}, true},
+		{"ECDSA recipient", SMIMEOptions{Recipients: []*x509.Certificate{ecCert}}, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := NewBlank()
+			if err := m.SMIME(tt.opts); (err != nil) != tt.wantErr {
+				t.Errorf("%q. MailYak.SMIME() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestMailYakSMIME ensures signed emails carry a valid detached signature, and
+// encrypted emails can be decrypted by the recipient.
+func TestMailYakSMIME(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaCert := newTestCertificate(t, rsaKey)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecCert := newTestCertificate(t, ecKey)
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		opts SMIMEOptions
+		// Want
+		wantSigned    bool
+		wantEncrypted bool
+	}{
+		{"RSA sign", SMIMEOptions{Certificate: rsaCert, Signer: rsaKey}, true, false},
+		{"ECDSA sign", SMIMEOptions{Certificate: ecCert, Signer: ecKey}, true, false},
+		{"Encrypt", SMIMEOptions{Recipients: []*x509.Certificate{rsaCert}}, false, true},
+		{
+			"Sign and encrypt",
+			SMIMEOptions{Certificate: ecCert, Signer: ecKey, Recipients: []*x509.Certificate{rsaCert}},
+			true,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.From("dom@itsallbroken.com")
+			m.To("to@itsallbroken.com")
+			m.Subject("S/MIME")
+			m.Plain().Set("Secret")
+			m.Attach("test.txt", strings.NewReader("attachment"))
+
+			if err := m.SMIME(tt.opts); err != nil {
+				t.Fatal(err)
+			}
+
+			buf, err := m.MimeBuf()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			headers, body := splitMessage(buf.Bytes())
+			if len(headerFields(headers, "Subject")) != 1 || len(headerFields(headers, "Content-Type")) != 1 {
+				t.Fatalf("%q. unexpected headers %q", tt.name, headers)
+			}
+
+			entity := string(headers[bytes.Index(headers, []byte("Content-Type:")):]) + "\r\n" + string(body)
+
+			if tt.wantEncrypted {
+				entity = decryptTestEnvelope(t, base64Part(t, entity, "application/pkcs7-mime"), rsaKey)
+			}
+
+			if !tt.wantSigned {
+				if !strings.HasPrefix(entity, "Content-Type: multipart/mixed;") {
+					t.Errorf("%q. decrypted entity = %q, want multipart/mixed", tt.name, entity)
+				}
+				return
+			}
+
+			if !strings.HasPrefix(entity, "Content-Type: multipart/signed;") {
+				t.Fatalf("%q. entity = %q, want multipart/signed", tt.name, entity)
+			}
+
+			boundary := regexp.MustCompile(`boundary="([^"]+)"`).FindStringSubmatch(entity)[1]
+			start := strings.Index(entity, "--"+boundary+"\r\n") + len(boundary) + 4
+			end := start + strings.Index(entity[start:], "\r\n--"+boundary+"\r\n")
+			signedContent := entity[start:end]
+
+			if !strings.HasPrefix(signedContent, "Content-Type: multipart/mixed;") {
+				t.Errorf("%q. signed content = %q, want multipart/mixed", tt.name, signedContent)
+			}
+
+			verifyTestSignature(t, base64Part(t, entity, "application/pkcs7-signature"), []byte(signedContent), tt.opts.Certificate)
+		})
+	}
+}
+
+// verifyTestSignature ensures p7s contains a valid signature of content by
+// cert.
+func verifyTestSignature(t *testing.T, p7s, content []byte, cert *x509.Certificate) {
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(p7s, &ci); err != nil {
+		t.Fatal(err)
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		t.Fatalf("content type = %v, want %v", ci.ContentType, oidSignedData)
+	}
+
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(sd.Certificates.Bytes, cert.Raw) {
+		t.Error("signed data does not contain the signer certificate")
+	}
+
+	si := sd.SignerInfos[0]
+	if si.SID.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+		t.Errorf("signer serial = %v, want %v", si.SID.SerialNumber, cert.SerialNumber)
+	}
+
+	// Ensure the message digest attribute matches the content
+	digest := sha256.Sum256(content)
+	if !bytes.Contains(si.SignedAttrs.Bytes, digest[:]) {
+		t.Error("signed attributes do not contain the content digest")
+	}
+
+	setOf, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: si.SignedAttrs.Bytes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrsDigest := sha256.Sum256(setOf)
+
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, attrsDigest[:], si.Signature); err != nil {
+			t.Errorf("signature does not verify: %v", err)
+		}
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, attrsDigest[:], si.Signature) {
+			t.Error("signature does not verify")
+		}
+	}
+}
+
+// decryptTestEnvelope returns the content of the enveloped data in p7m,
+// decrypted with key.
+func decryptTestEnvelope(t *testing.T, p7m []byte, key *rsa.PrivateKey) string {
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(p7m, &ci); err != nil {
+		t.Fatal(err)
+	}
+	if !ci.ContentType.Equal(oidEnvelopedData) {
+		t.Fatalf("content type = %v, want %v", ci.ContentType, oidEnvelopedData)
+	}
+
+	var ed envelopedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+		t.Fatal(err)
+	}
+
+	cek, err := rsa.DecryptOAEP(sha1.New(), rand.Reader, key, ed.RecipientInfos[0].EncryptedKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(ed.EncryptedContentInfo.ContentEncryptionAlgorithm.Parameters.FullBytes, &iv); err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext := ed.EncryptedContentInfo.EncryptedContent.Bytes
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	pad := int(plaintext[len(plaintext)-1])
+	return string(plaintext[:len(plaintext)-pad])
+}
@@ -4,7 +4,13 @@ import (
 	"io"
 )
 
-const maxLineLen = 60
+// maxLineLen is the length of the lines of base64 encoded attachments, the
+// maximum permitted by RFC 2045 section 6.8.
+const maxLineLen = 76
+
+// crlf is the line break written by lineSplitter, allocated once as it is
+// written for every line.
+var crlf = []byte("\r\n")
 
 // lineSplitter breaks the given input into lines of maxLineLen characters
 // before writing a "\r\n" newline
@@ -53,7 +59,7 @@ func (w *lineSplitter) Write(p []byte) (int, error) {
 
 		// If this finishes a line, add linebreaks
 		if end == i+lineSize {
-			if _, err := w.w.Write([]byte("\r\n")); err != nil {
+			if _, err := w.w.Write(crlf); err != nil {
 				// If this errors, return the bytes wrote so far from the
 				// caller's perspective (it is unaware newlines are being added)
 				return i + len(chunk), err
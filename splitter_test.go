@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/base64"
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -186,7 +188,7 @@ func TestLineSplitterWrite(t *testing.T) {
 
 			var buf bytes.Buffer
 
-			w := &lineSplitter{w: &buf, maxLen: maxLineLen}
+			w := &lineSplitter{w: &buf, maxLen: 60}
 
 			encoder := base64.NewEncoder(base64.StdEncoding, w)
 			_, err := encoder.Write(tt.p)
@@ -222,3 +224,38 @@ func TestChunkedWrites(t *testing.T) {
 		}
 	}
 }
+
+// TestMailYakWriteMime_attachmentLines ensures attachments are encoded as base64
+// lines of 76 characters, as permitted by RFC 2045.
+func TestMailYakWriteMime_attachmentLines(t *testing.T) {
+	t.Parallel()
+
+	m := New("", nil)
+	m.Attach("large.bin", io.LimitReader(zeroReader{}, 1<<20))
+
+	var buf bytes.Buffer
+	if err := m.writeMimeWithBoundaries(&buf, "mixed", "alt"); err != nil {
+		t.Fatal(err)
+	}
+
+	body := buf.String()[strings.Index(buf.String(), "filename=\"large.bin\"\r\n\r\n"):]
+	lines := strings.Split(body, "\r\n")[2:]
+
+	// base64 of 1MiB, excluding the final partial line
+	wantLines := (1 << 20) * 4 / 3 / maxLineLen
+	for i, line := range lines[:wantLines] {
+		if len(line) != maxLineLen {
+			t.Fatalf("line %d length = %d, want %d", i, len(line), maxLineLen)
+		}
+	}
+}
+
+// zeroReader is an infinite source of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
@@ -0,0 +1,43 @@
+package mailyak
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+// HTMLTemplate executes tpl with data, replacing the HTML body of the email
+// with the result:
+//
+//	if err := mail.HTMLTemplate(tpl, data); err != nil {
+//		return err
+//	}
+//
+// If tpl fails to execute, the error is returned and the HTML body is left
+// unchanged.
+func (m *MailYak) HTMLTemplate(tpl *htmltemplate.Template, data interface{}) error {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	m.html.Reset()
+	_, err := buf.WriteTo(&m.html)
+	return err
+}
+
+// PlainTemplate executes tpl with data, replacing the plain-text body of the
+// email with the result.
+//
+// If tpl fails to execute, the error is returned and the plain-text body is
+// left unchanged.
+func (m *MailYak) PlainTemplate(tpl *texttemplate.Template, data interface{}) error {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	m.plain.Reset()
+	_, err := buf.WriteTo(&m.plain)
+	return err
+}
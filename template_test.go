@@ -0,0 +1,107 @@
+package mailyak
+
+import (
+	htmltemplate "html/template"
+	"testing"
+	texttemplate "text/template"
+)
+
+// TestMailYakHTMLTemplate ensures the HTML body is set to the executed
+// template, and left unchanged on error.
+func TestMailYakHTMLTemplate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		tpl  string
+		data interface{}
+		// Want
+		want    string
+		wantErr bool
+	}{
+		{
+			"OK",
+			"<p>Hello {{.}}</p>",
+			"<Dom>",
+			"<p>Hello &lt;Dom&gt;</p>",
+			false,
+		},
+		{
+			"Error",
+			"<p>Hello {{.Name}}</p>",
+			42,
+			"existing",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.HTML().Set("existing")
+
+			tpl := htmltemplate.Must(htmltemplate.New("").Parse(tt.tpl))
+			if err := m.HTMLTemplate(tpl, tt.data); (err != nil) != tt.wantErr {
+				t.Errorf("%q. MailYak.HTMLTemplate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+
+			if got := m.HTML().String(); got != tt.want {
+				t.Errorf("%q. MailYak.HTMLTemplate() = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMailYakPlainTemplate ensures the plain-text body is set to the executed
+// template, and left unchanged on error.
+func TestMailYakPlainTemplate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		tpl  string
+		data interface{}
+		// Want
+		want    string
+		wantErr bool
+	}{
+		{
+			"OK",
+			"Hello {{.}}",
+			"<Dom>",
+			"Hello <Dom>",
+			false,
+		},
+		{
+			"Error",
+			"Hello {{.Name}}",
+			42,
+			"existing",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.Plain().Set("existing")
+
+			tpl := texttemplate.Must(texttemplate.New("").Parse(tt.tpl))
+			if err := m.PlainTemplate(tpl, tt.data); (err != nil) != tt.wantErr {
+				t.Errorf("%q. MailYak.PlainTemplate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+
+			if got := m.Plain().String(); got != tt.want {
+				t.Errorf("%q. MailYak.PlainTemplate() = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
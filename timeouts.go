@@ -0,0 +1,128 @@
+package mailyak
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Timeouts bounds the duration of each stage of the SMTP conversation when
+// sending an email.
+//
+// A zero duration disables the respective timeout.
+type Timeouts struct {
+	// Dial bounds establishing the connection to the SMTP server.
+	Dial time.Duration
+
+	// Hello bounds reading the server greeting, sending EHLO and negotiating
+	// TLS.
+	Hello time.Duration
+
+	// Auth bounds authenticating with the server.
+	Auth time.Duration
+
+	// Data bounds the MAIL, RCPT and DATA commands, including writing the
+	// message content.
+	Data time.Duration
+
+	// Overall bounds the entire SMTP conversation.
+	Overall time.Duration
+}
+
+// Timeouts configures the maximum duration of each stage of the SMTP
+// conversation, causing Send to return a *TimeoutError when exceeded.
+//
+// By default no timeouts are applied.
+func (m *MailYak) Timeouts(t Timeouts) {
+	m.timeouts = t
+}
+
+// TimeoutError is returned when a stage of the SMTP conversation exceeds the
+// duration configured in Timeouts.
+type TimeoutError struct {
+	// Stage is the stage of the SMTP conversation that timed out - one of
+	// "dial", "hello", "auth", "data" or "overall".
+	Stage string
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("mailyak: %s timed out: %v", e.Stage, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+// Timeout always returns true, satisfying the net.Error interface.
+func (e *TimeoutError) Timeout() bool { return true }
+
+// Temporary always returns true, satisfying the net.Error interface.
+func (e *TimeoutError) Temporary() bool { return true }
+
+// setDeadline bounds subsequent reads and writes on conn to d from now, or
+// removes the deadline if d is zero.
+func setDeadline(conn net.Conn, d time.Duration) error {
+	if d == 0 {
+		return conn.SetDeadline(time.Time{})
+	}
+	return conn.SetDeadline(time.Now().Add(d))
+}
+
+// stageError wraps err in a *TimeoutError for stage if err is a timeout.
+func stageError(stage string, err error) error {
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return &TimeoutError{Stage: stage, Err: err}
+	}
+	return err
+}
+
+// maxWriteSize is the maximum number of bytes of message data written to the
+// server at once, bounding how long a write continues after the send is
+// cancelled.
+const maxWriteSize = 32 << 10
+
+// contextMessage is an io.WriterTo writing msg, failing with ctx.Err() once
+// ctx is done.
+type contextMessage struct {
+	ctx context.Context
+	msg io.WriterTo
+}
+
+// WriteTo writes msg to w in writes of at most maxWriteSize bytes.
+func (c *contextMessage) WriteTo(w io.Writer) (int64, error) {
+	return c.msg.WriteTo(&contextWriter{ctx: c.ctx, w: w})
+}
+
+// contextWriter is an io.Writer splitting writes to w into chunks of at most
+// maxWriteSize bytes, failing with ctx.Err() once ctx is done.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (c *contextWriter) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		if err := c.ctx.Err(); err != nil {
+			return n, err
+		}
+
+		chunk := p
+		if len(chunk) > maxWriteSize {
+			chunk = chunk[:maxWriteSize]
+		}
+
+		written, err := c.w.Write(chunk)
+		n += written
+		if err != nil {
+			return n, err
+		}
+		p = p[written:]
+	}
+
+	return n, nil
+}
@@ -0,0 +1,230 @@
+package mailyak
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestMailYakTimeouts ensures a stage of the SMTP conversation exceeding its
+// timeout returns a *TimeoutError for that stage.
+func TestMailYakTimeouts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		rtimeouts Timeouts
+		// Server replies.
+		replies map[string]string
+		// Want
+		wantStage string
+	}{
+		{
+			"Greeting",
+			Timeouts{Hello: 50 * time.Millisecond},
+			map[string]string{"220": ""},
+			"hello",
+		},
+		{
+			"EHLO",
+			Timeouts{Hello: 50 * time.Millisecond},
+			map[string]string{"EHLO": ""},
+			"hello",
+		},
+		{
+			"MAIL",
+			Timeouts{Data: 50 * time.Millisecond},
+			map[string]string{"MAIL": ""},
+			"data",
+		},
+		{
+			"End of data",
+			Timeouts{Hello: time.Second, Data: 50 * time.Millisecond},
+			map[string]string{".": ""},
+			"data",
+		},
+		{
+			"Overall",
+			Timeouts{Overall: 50 * time.Millisecond},
+			map[string]string{"RCPT": ""},
+			"overall",
+		},
+		{
+			"Overall shorter than stage",
+			Timeouts{Data: time.Minute, Overall: 50 * time.Millisecond},
+			map[string]string{"DATA": ""},
+			"overall",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, tt.replies)
+			defer srv.Close()
+
+			mail := New(srv.Addr(), nil)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+			mail.Timeouts(tt.rtimeouts)
+
+			_, _, err = mail.Send("localhost")
+
+			var te *TimeoutError
+			if !errors.As(err, &te) {
+				t.Fatalf("%q. MailYak.Send() error = %v, want *TimeoutError", tt.name, err)
+			}
+			if te.Stage != tt.wantStage {
+				t.Errorf("%q. MailYak.Send() timeout stage = %q, want %q", tt.name, te.Stage, tt.wantStage)
+			}
+		})
+	}
+}
+
+// TestMailYakTimeouts_cancelled ensures cancelling the caller's context is not
+// reported as a timeout.
+func TestMailYakTimeouts_cancelled(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTestSMTPServer(l, map[string]string{"EHLO": ""})
+	defer srv.Close()
+
+	mail := New(srv.Addr(), nil)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+	mail.Timeouts(Timeouts{Overall: time.Minute})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, _, err := mail.SendWithContext(ctx, "localhost"); err != context.DeadlineExceeded {
+		t.Errorf("MailYak.SendWithContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// cancellingReader is an endless io.Reader cancelling a context once more than
+// after bytes have been read.
+type cancellingReader struct {
+	cancel context.CancelFunc
+	after  int64
+	n      int64
+}
+
+func (r *cancellingReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'a'
+	}
+	r.n += int64(len(p))
+	if r.n > r.after {
+		r.cancel()
+	}
+	return len(p), nil
+}
+
+// TestMailYakSendWithContext_cancelledData ensures cancelling the context while
+// writing the message data stops the send promptly, without completing the
+// message.
+func TestMailYakSendWithContext_cancelledData(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTestSMTPServer(l, nil)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := &cancellingReader{cancel: cancel, after: 1 << 20}
+
+	mail := New(srv.Addr(), nil)
+	mail.From("from@example.org")
+	mail.To("to@example.org")
+	mail.Attach("large.bin", r)
+
+	if _, _, err := mail.SendWithContext(ctx, "localhost"); err != context.Canceled {
+		t.Fatalf("MailYak.SendWithContext() error = %v, want %v", err, context.Canceled)
+	}
+
+	if r.n > 2<<20 {
+		t.Errorf("MailYak.SendWithContext() read %d bytes after cancellation at %d", r.n, r.after)
+	}
+	if srv.Data() != nil {
+		t.Errorf("MailYak.SendWithContext() completed the message data")
+	}
+}
+
+// chunkRecorder is an io.Writer recording the size of each write, calling
+// onWrite after each.
+type chunkRecorder struct {
+	sizes   []int
+	onWrite func()
+}
+
+func (w *chunkRecorder) Write(p []byte) (int, error) {
+	w.sizes = append(w.sizes, len(p))
+	if w.onWrite != nil {
+		w.onWrite()
+	}
+	return len(p), nil
+}
+
+func TestContextWriter(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("a"), 2*maxWriteSize+1)
+
+	t.Run("Chunked", func(t *testing.T) {
+		t.Parallel()
+
+		rec := &chunkRecorder{}
+		w := &contextWriter{ctx: context.Background(), w: rec}
+
+		n, err := w.Write(data)
+		if n != len(data) || err != nil {
+			t.Fatalf("contextWriter.Write() = %d, %v, want %d, nil", n, err, len(data))
+		}
+
+		want := []int{maxWriteSize, maxWriteSize, 1}
+		if len(rec.sizes) != len(want) || rec.sizes[0] != want[0] || rec.sizes[1] != want[1] || rec.sizes[2] != want[2] {
+			t.Errorf("contextWriter.Write() wrote %v, want %v", rec.sizes, want)
+		}
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		rec := &chunkRecorder{onWrite: cancel}
+		w := &contextWriter{ctx: ctx, w: rec}
+
+		n, err := w.Write(data)
+		if n != maxWriteSize || err != context.Canceled {
+			t.Fatalf("contextWriter.Write() = %d, %v, want %d, %v", n, err, maxWriteSize, context.Canceled)
+		}
+		if len(rec.sizes) != 1 {
+			t.Errorf("contextWriter.Write() wrote %v, want a single chunk", rec.sizes)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+package mailyak
+
+import "context"
+
+// TokenSource supplies OAuth2 access tokens to the gmail and graph transports,
+// and XOAUTH2Auth.
+//
+// A golang.org/x/oauth2.TokenSource, which refreshes expired tokens, can be
+// adapted with TokenSourceFunc:
+//
+//	ts := config.TokenSource(ctx, token)
+//	mailyak.TokenSourceFunc(func(ctx context.Context) (string, error) {
+//		t, err := ts.Token()
+//		if err != nil {
+//			return "", err
+//		}
+//		return t.AccessToken, nil
+//	})
+type TokenSource interface {
+	// AccessToken returns a valid access token, refreshing it if necessary.
+	AccessToken(ctx context.Context) (string, error)
+}
+
+// TokenSourceFunc is a function implementing TokenSource.
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+// AccessToken calls f(ctx).
+func (f TokenSourceFunc) AccessToken(ctx context.Context) (string, error) {
+	return f(ctx)
+}
@@ -0,0 +1,118 @@
+package mailyak
+
+import (
+	"context"
+	"io"
+)
+
+// Tracer starts the spans recording the phases of sending an email, allowing
+// sends to be traced without MailYak depending on a tracing library.
+//
+// An OpenTelemetry TracerProvider can be adapted with a few lines:
+//
+//	type otelTracer struct{ trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string) (context.Context, mailyak.Span) {
+//		ctx, span := t.Tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//		return ctx, otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ span trace.Span }
+//
+//	func (s otelSpan) SetAttribute(key string, value interface{}) {
+//		s.span.SetAttributes(attribute.String(key, fmt.Sprint(value)))
+//	}
+//
+//	func (s otelSpan) End(err error) {
+//		if err != nil {
+//			s.span.RecordError(err)
+//			s.span.SetStatus(codes.Error, err.Error())
+//		}
+//		s.span.End()
+//	}
+//
+//	mail.Tracer(otelTracer{provider.Tracer("github.com/domodwyer/mailyak")})
+type Tracer interface {
+	// Start starts a span named name as a child of any span in ctx,
+	// returning a context containing the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a phase of sending an email started by a Tracer.
+type Span interface {
+	// SetAttribute records an attribute of the span, with a value of type
+	// string, int or int64.
+	SetAttribute(key string, value interface{})
+
+	// End completes the span, recording err if the phase failed.
+	End(err error)
+}
+
+// Span names and attributes recorded when a Tracer is configured.
+//
+// The "mailyak.send" span covers the entire send, with child spans for
+// building the message, and for each phase of the SMTP conversation.
+const (
+	SpanSend     = "mailyak.send"
+	SpanBuild    = "mailyak.build"
+	SpanDial     = "mailyak.dial"
+	SpanStartTLS = "mailyak.starttls"
+	SpanAuth     = "mailyak.auth"
+	SpanData     = "mailyak.data"
+
+	// AttrServerAddress is the host of the SMTP server, recorded on
+	// SpanDial.
+	AttrServerAddress = "server.address"
+
+	// AttrRecipients is the number of envelope recipients, recorded on
+	// SpanSend and SpanData.
+	AttrRecipients = "mailyak.recipients"
+
+	// AttrMessageSize is the size of the message in bytes, recorded on
+	// SpanBuild when the message is built before sending, and on SpanData.
+	AttrMessageSize = "mailyak.message.size"
+
+	// AttrResponseCode and AttrResponseMessage are the server response to
+	// the message data, recorded on SpanData.
+	AttrResponseCode    = "smtp.response.code"
+	AttrResponseMessage = "smtp.response.message"
+)
+
+// Tracer sets the Tracer used to record spans when sending the email. Passing
+// nil disables tracing.
+//
+// When a Pool is used as the Transport, the spans of the SMTP conversation are
+// recorded by the Tracer of the Pool configuration.
+func (m *MailYak) Tracer(t Tracer) {
+	m.tracer = t
+}
+
+// startSpan starts a span named name if a Tracer is configured, or returns ctx
+// and a no-op span otherwise.
+func (m *MailYak) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if m.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return m.tracer.Start(ctx, name)
+}
+
+// noopSpan is a Span discarding everything recorded.
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) End(err error)                              {}
+
+// countingMessage is an io.WriterTo recording the number of bytes written by
+// msg.
+type countingMessage struct {
+	msg io.WriterTo
+	n   int64
+}
+
+// WriteTo writes msg to w.
+func (c *countingMessage) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	_, err := c.msg.WriteTo(cw)
+	c.n += cw.n
+	return cw.n, err
+}
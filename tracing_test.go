@@ -0,0 +1,207 @@
+package mailyak
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// recordingTracer is a Tracer recording the spans started.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordingTracerKey struct{}
+
+func (r *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	s := &recordedSpan{name: name, attrs: map[string]interface{}{}}
+	if parent, ok := ctx.Value(recordingTracerKey{}).(*recordedSpan); ok {
+		s.parent = parent.name
+	}
+
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+
+	return context.WithValue(ctx, recordingTracerKey{}, s), s
+}
+
+// Span returns the first span named name, or nil.
+func (r *recordingTracer) Span(name string) *recordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.spans {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Names returns the names of the spans started, in order.
+func (r *recordingTracer) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.spans))
+	for _, s := range r.spans {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+type recordedSpan struct {
+	name   string
+	parent string
+	attrs  map[string]interface{}
+	ended  bool
+	err    error
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) {
+	s.attrs[key] = value
+}
+
+func (s *recordedSpan) End(err error) {
+	s.ended = true
+	s.err = err
+}
+
+// TestMailYakTracer ensures a span is recorded for each phase of sending.
+func TestMailYakTracer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		tls     bool
+		auth    smtp.Auth
+		replies map[string]string
+		// Want
+		wantSpans  []string
+		wantCode   int
+		wantErrEnd string
+	}{
+		{
+			name:      "Plain",
+			wantSpans: []string{SpanSend, SpanBuild, SpanDial, SpanData},
+			wantCode:  250,
+		},
+		{
+			name: "STARTTLS and auth",
+			tls:  true,
+			auth: smtp.PlainAuth("", "user", "secret", "127.0.0.1"),
+			replies: map[string]string{
+				"EHLO":     "250-localhost\r\n250-STARTTLS\r\n250 AUTH PLAIN",
+				"STARTTLS": "220 Go ahead",
+				"AUTH":     "235 OK",
+			},
+			wantSpans: []string{SpanSend, SpanBuild, SpanDial, SpanStartTLS, SpanAuth, SpanData},
+			wantCode:  250,
+		},
+		{
+			name: "Recipient rejected",
+			replies: map[string]string{
+				"RCPT": "550 No such user",
+			},
+			wantSpans:  []string{SpanSend, SpanBuild, SpanDial, SpanData},
+			wantErrEnd: SpanData,
+		},
+		{
+			name: "Auth rejected",
+			auth: smtp.PlainAuth("", "user", "secret", "127.0.0.1"),
+			replies: map[string]string{
+				"EHLO": "250-localhost\r\n250 AUTH PLAIN",
+				"AUTH": "535 Authentication failed",
+			},
+			wantSpans:  []string{SpanSend, SpanBuild, SpanDial, SpanAuth},
+			wantErrEnd: SpanAuth,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mail *MailYak
+			if tt.tls {
+				srv, config := newStartTLSTestSMTPServer(t, tt.replies)
+				defer srv.Close()
+
+				mail = New(srv.Addr(), tt.auth)
+				mail.TLSConfig(config)
+			} else {
+				l, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				srv := newTestSMTPServer(l, tt.replies)
+				defer srv.Close()
+
+				mail = New(srv.Addr(), tt.auth)
+			}
+
+			tracer := &recordingTracer{}
+			mail.Tracer(tracer)
+			mail.From("from@example.org")
+			mail.To("to@example.org", "other@example.org")
+			mail.Subject("Tracing")
+			mail.Plain().Set("Hello")
+
+			_, _, err := mail.Send("localhost")
+			if (err != nil) != (tt.wantErrEnd != "") {
+				t.Fatalf("MailYak.Send() error = %v, wantErr %v", err, tt.wantErrEnd != "")
+			}
+
+			if got := tracer.Names(); !reflect.DeepEqual(got, tt.wantSpans) {
+				t.Fatalf("MailYak.Send() spans = %v, want %v", got, tt.wantSpans)
+			}
+
+			for _, name := range tt.wantSpans {
+				s := tracer.Span(name)
+				if !s.ended {
+					t.Errorf("span %q not ended", name)
+				}
+				if name != SpanSend && s.parent != SpanSend {
+					t.Errorf("span %q parent = %q, want %q", name, s.parent, SpanSend)
+				}
+				if wantErr := name == SpanSend || name == tt.wantErrEnd; (s.err != nil) != (wantErr && tt.wantErrEnd != "") {
+					t.Errorf("span %q error = %v", name, s.err)
+				}
+			}
+
+			send := tracer.Span(SpanSend)
+			if got := send.attrs[AttrRecipients]; got != 2 {
+				t.Errorf("span %q %s = %v, want 2", SpanSend, AttrRecipients, got)
+			}
+
+			dial := tracer.Span(SpanDial)
+			if got := dial.attrs[AttrServerAddress]; got != mail.host {
+				t.Errorf("span %q %s = %v, want %v", SpanDial, AttrServerAddress, got, mail.host)
+			}
+
+			if tt.wantCode == 0 {
+				return
+			}
+
+			data := tracer.Span(SpanData)
+			if got := data.attrs[AttrRecipients]; got != 2 {
+				t.Errorf("span %q %s = %v, want 2", SpanData, AttrRecipients, got)
+			}
+			if got := data.attrs[AttrResponseCode]; got != tt.wantCode {
+				t.Errorf("span %q %s = %v, want %v", SpanData, AttrResponseCode, got, tt.wantCode)
+			}
+			if got := data.attrs[AttrResponseMessage]; got != "OK" {
+				t.Errorf("span %q %s = %v, want %q", SpanData, AttrResponseMessage, got, "OK")
+			}
+			if got, ok := data.attrs[AttrMessageSize].(int64); !ok || got <= 0 {
+				t.Errorf("span %q %s = %v, want > 0", SpanData, AttrMessageSize, data.attrs[AttrMessageSize])
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package mailyak
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// Transport delivers emails, allowing them to be sent by a mechanism other
+// than the built-in SMTP client, such as an email API, a local spool or a test
+// double.
+type Transport interface {
+	// Send delivers the MIME message written by msg to rcpts, using
+	// envelopeFrom as the envelope sender (the SMTP MAIL FROM address).
+	//
+	// Attachments are read as msg is written, so msg can be written only once.
+	Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error
+}
+
+// Transport sets the Transport used by Send and SendWithContext to deliver the
+// email, replacing the built-in SMTP client and its configuration. Passing nil
+// restores the built-in SMTP client.
+//
+// When a Transport is set, Send returns a zero status code and empty message
+// on success.
+func (m *MailYak) Transport(t Transport) {
+	m.transport = t
+}
+
+// mimeMessage is an io.WriterTo writing the MIME message of an email.
+type mimeMessage struct {
+	m *MailYak
+
+	// buf holds the built message if the email cannot be streamed
+	buf []byte
+
+	// attachments holds the content of each attachment of m, and mb and ab
+	// the MIME boundaries, if set in advance so the message can be written
+	// more than once
+	attachments [][]byte
+	mb, ab      string
+
+	// result records the response of the server to each recipient
+	result *SendResult
+}
+
+// mimeMessage returns the MIME message for m, building it immediately if it
+// cannot be streamed (such as when it is signed or encrypted).
+func (m *MailYak) mimeMessage() (*mimeMessage, error) {
+	m.buildMessageID()
+
+	if m.streamable() {
+		return &mimeMessage{m: m, result: &SendResult{}}, nil
+	}
+
+	buf, err := m.buildMime()
+	if err != nil {
+		return nil, err
+	}
+
+	return &mimeMessage{m: m, buf: buf.Bytes(), result: &SendResult{}}, nil
+}
+
+// WriteTo writes the MIME message to w.
+func (msg *mimeMessage) WriteTo(w io.Writer) (int64, error) {
+	if msg.buf != nil {
+		return bytes.NewReader(msg.buf).WriteTo(w)
+	}
+
+	cw := &countingWriter{w: w}
+	if msg.attachments == nil {
+		err := msg.m.writeMime(cw)
+		return cw.n, err
+	}
+
+	// the buffered content is written in place of the attachments of the
+	// email, which are left unread
+	c := *msg.m
+	c.attachments = msg.bufferedAttachments()
+	err := c.writeMimeWithBoundaries(c.limitSize(cw), msg.mb, msg.ab)
+	return cw.n, err
+}
+
+// withASCIIDomains returns the message with the domains of the addresses in
+// its headers converted to their ASCII form.
+//
+// ok is false if the message has already been built, or any address has a
+// non-ASCII local part.
+func (msg *mimeMessage) withASCIIDomains() (*mimeMessage, bool) {
+	if msg.buf != nil {
+		return nil, false
+	}
+
+	c, ok := msg.m.withASCIIDomains()
+	if !ok {
+		return nil, false
+	}
+
+	return &mimeMessage{m: c, attachments: msg.attachments, mb: msg.mb, ab: msg.ab, result: msg.result}, true
+}
+
+// bufferAttachments reads the content of each attachment of the email, within
+// its attachment limits, so the message can be written more than once. The MIME
+// boundaries are fixed, so each write is identical.
+func (msg *mimeMessage) bufferAttachments() error {
+	m := msg.m
+	if err := m.checkAttachments(m.attachments); err != nil {
+		return err
+	}
+
+	mb, ab, err := m.randomBoundaries()
+	if err != nil {
+		return err
+	}
+
+	// total is the number of bytes read from all attachments
+	var total int64
+
+	attachments := make([][]byte, len(m.attachments))
+	for i, a := range m.attachments {
+		b, err := io.ReadAll(m.limitAttachment(a, &total))
+		if err != nil {
+			return err
+		}
+		attachments[i] = b
+	}
+
+	msg.attachments = attachments
+	msg.mb, msg.ab = mb, ab
+	return nil
+}
+
+// bufferedAttachments returns a copy of the attachments of the email reading
+// the buffered content from the start.
+func (msg *mimeMessage) bufferedAttachments() []attachment {
+	attachments := make([]attachment, len(msg.m.attachments))
+	for i, a := range msg.m.attachments {
+		a.content = bytes.NewReader(msg.attachments[i])
+		attachments[i] = a
+	}
+	return attachments
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
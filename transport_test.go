@@ -0,0 +1,140 @@
+package mailyak
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testTransport records the emails it is asked to deliver.
+type testTransport struct {
+	envelopeFrom string
+	rcpts        []string
+	data         bytes.Buffer
+	err          error
+}
+
+func (t *testTransport) Send(ctx context.Context, envelopeFrom string, rcpts []string, msg io.WriterTo) error {
+	t.envelopeFrom = envelopeFrom
+	t.rcpts = rcpts
+	if _, err := msg.WriteTo(&t.data); err != nil {
+		return err
+	}
+	return t.err
+}
+
+// TestMailYakTransport ensures emails are delivered using the configured
+// Transport.
+func TestMailYakTransport(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		rerr error
+		// Want
+		wantCode int
+		wantErr  error
+	}{
+		{
+			"Delivered",
+			nil,
+			0,
+			nil,
+		},
+		{
+			"Error",
+			errors.New("delivery failed"),
+			-1,
+			errors.New("delivery failed"),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			transport := &testTransport{err: tt.rerr}
+
+			mail := New("", nil)
+			mail.From("from@example.org")
+			mail.EnvelopeFrom("bounces@example.org")
+			mail.To("To <to@example.org>")
+			mail.Bcc("bcc@example.org")
+			mail.Subject("Transport")
+			mail.Attach("test.txt", strings.NewReader("attachment"))
+			mail.Transport(transport)
+
+			code, _, err := mail.Send("localhost")
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Errorf("%q. MailYak.Send() error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			if code != tt.wantCode {
+				t.Errorf("%q. MailYak.Send() code = %v, want %v", tt.name, code, tt.wantCode)
+			}
+
+			if transport.envelopeFrom != "bounces@example.org" {
+				t.Errorf("%q. Transport.Send() envelopeFrom = %v, want %v", tt.name, transport.envelopeFrom, "bounces@example.org")
+			}
+
+			wantRcpts := []string{"to@example.org", "bcc@example.org"}
+			if !reflect.DeepEqual(transport.rcpts, wantRcpts) {
+				t.Errorf("%q. Transport.Send() rcpts = %v, want %v", tt.name, transport.rcpts, wantRcpts)
+			}
+
+			if !strings.Contains(transport.data.String(), "Subject: Transport\r\n") {
+				t.Errorf("%q. Transport.Send() msg = %q, want Subject header", tt.name, transport.data.String())
+			}
+		})
+	}
+}
+
+// TestMimeMessageWriteTo ensures streamed and built messages are written in
+// full, returning the number of bytes written.
+func TestMimeMessageWriteTo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		buf []byte
+	}{
+		{
+			"Streamed",
+			nil,
+		},
+		{
+			"Built",
+			[]byte("From: dom@itsallbroken.com\r\n\r\nbody\r\n"),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.From("dom@itsallbroken.com")
+
+			msg := &mimeMessage{m: m, buf: tt.buf}
+
+			var w bytes.Buffer
+			n, err := msg.WriteTo(&w)
+			if err != nil {
+				t.Fatalf("%q. mimeMessage.WriteTo() error = %v", tt.name, err)
+			}
+			if n != int64(w.Len()) || n == 0 {
+				t.Errorf("%q. mimeMessage.WriteTo() = %v, want %v", tt.name, n, w.Len())
+			}
+			if !strings.HasPrefix(w.String(), "From: dom@itsallbroken.com\r\n") {
+				t.Errorf("%q. mimeMessage.WriteTo() wrote %q", tt.name, w.String())
+			}
+		})
+	}
+}
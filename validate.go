@@ -0,0 +1,168 @@
+package mailyak
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// RFC 5321 section 4.5.3.1 limits on the length of an address, in octets.
+const (
+	maxLocalPartLen = 64
+	maxDomainLen    = 255
+	maxPathLen      = 254
+)
+
+// AddressError is returned by Validate, Send and SendWithContext when an
+// address of the email is invalid.
+type AddressError struct {
+	// Field is the field the address was set on - one of "From",
+	// "EnvelopeFrom", "Sender", "Reply-To", "To", "Cc" or "Bcc".
+	Field string
+
+	// Addr is the invalid address.
+	Addr string
+
+	// Err describes why the address is invalid.
+	Err error
+}
+
+func (e *AddressError) Error() string {
+	return fmt.Sprintf("mailyak: invalid %s address %q: %v", e.Field, e.Addr, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *AddressError) Unwrap() error { return e.Err }
+
+// StrictAddresses causes addresses to additionally be validated against the
+// length and domain syntax limits of RFC 5321 when validated, rejecting
+// addresses that parse as valid RFC 5322 addresses but would be refused by
+// most SMTP servers, such as "dom@localhost" or "dom@-invalid.example".
+// Defaults to false.
+func (m *MailYak) StrictAddresses(strict bool) {
+	m.strictAddresses = strict
+}
+
+// Validate checks the From, EnvelopeFrom, Sender, Reply-To, To, Cc and Bcc
+// addresses of the email, including those of any groups, are valid RFC 5322
+// addresses, optionally including a display name, returning an *AddressError
+// for the first invalid address. Unset addresses are not checked.
+//
+// The attachments of the email are then checked against its AttachmentLimits,
+// returning an *AttachmentLimitError if exceeded.
+//
+// Validate is called by Send and SendWithContext before the email is built, so
+// an invalid email fails without connecting to the server.
+func (m *MailYak) Validate() error {
+	single := []struct {
+		field string
+		addr  string
+	}{
+		{"From", m.fromAddr},
+		{"EnvelopeFrom", m.envelopeFrom},
+		{"Sender", m.sender},
+		{"Reply-To", m.replyTo},
+	}
+	for _, s := range single {
+		if s.addr == "" {
+			continue
+		}
+		if err := m.validateAddress(s.field, s.addr); err != nil {
+			return err
+		}
+	}
+
+	lists := []struct {
+		field string
+		addrs []string
+	}{
+		{"To", m.toAddrs},
+		{"To", m.groupAddrs()},
+		{"Cc", m.ccAddrs},
+		{"Bcc", m.bccAddrs},
+	}
+	for _, l := range lists {
+		for _, addr := range l.addrs {
+			if err := m.validateAddress(l.field, addr); err != nil {
+				return err
+			}
+		}
+	}
+
+	return m.checkAttachments(m.attachments)
+}
+
+// validateAddress returns an *AddressError if addr is not a valid address for
+// field.
+func (m *MailYak) validateAddress(field, addr string) error {
+	a, err := mail.ParseAddress(addr)
+	if err == nil && m.strictAddresses {
+		err = validateRFC5321(a.Address)
+	}
+	if err != nil {
+		return &AddressError{Field: field, Addr: addr, Err: err}
+	}
+	return nil
+}
+
+// validateRFC5321 returns an error if the bare address addr exceeds the length
+// limits of RFC 5321, or its domain is not a fully qualified domain name or
+// address literal.
+func validateRFC5321(addr string) error {
+	at := strings.LastIndexByte(addr, '@')
+	local, domain := addr[:at], addr[at+1:]
+
+	if len(local) > maxLocalPartLen {
+		return fmt.Errorf("local part exceeds %d octets", maxLocalPartLen)
+	}
+	if len(addr) > maxPathLen {
+		return fmt.Errorf("address exceeds %d octets", maxPathLen)
+	}
+
+	// address literals such as [192.0.2.1] are validated by net/mail
+	if strings.HasPrefix(domain, "[") {
+		return nil
+	}
+
+	ascii, err := toASCII(domain)
+	if err != nil {
+		return err
+	}
+	if len(ascii) > maxDomainLen {
+		return fmt.Errorf("domain exceeds %d octets", maxDomainLen)
+	}
+
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		return errors.New("domain is not fully qualified")
+	}
+
+	for _, label := range labels {
+		if err := validateLabel(label); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateLabel returns an error if label is not a valid DNS label of letters,
+// digits and hyphens, as required by RFC 5321 section 4.1.2.
+func validateLabel(label string) error {
+	if label == "" || len(label) > maxLabelLen {
+		return fmt.Errorf("invalid domain label %q", label)
+	}
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return fmt.Errorf("domain label %q must not begin or end with a hyphen", label)
+	}
+
+	for _, c := range label {
+		isAlnum := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isAlnum && c != '-' {
+			return fmt.Errorf("invalid character %q in domain label %q", c, label)
+		}
+	}
+
+	return nil
+}
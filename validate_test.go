@@ -0,0 +1,169 @@
+package mailyak
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestMailYakValidate ensures invalid addresses are reported with the field
+// they were set on.
+func TestMailYakValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		from    string
+		replyTo string
+		to      []string
+		bcc     []string
+		strict  bool
+		// Want
+		wantField string
+		wantAddr  string
+	}{
+		{
+			name: "Valid",
+			from: "from@example.org",
+			to:   []string{"to@example.org", "Dom <dom@itsallbroken.com>"},
+		},
+		{
+			name: "Unset",
+		},
+		{
+			name:      "From",
+			from:      "not an address",
+			wantField: "From",
+			wantAddr:  "not an address",
+		},
+		{
+			name:      "Reply-To",
+			from:      "from@example.org",
+			replyTo:   "reply@",
+			wantField: "Reply-To",
+			wantAddr:  "reply@",
+		},
+		{
+			name:      "To",
+			to:        []string{"to@example.org", "to.example.org"},
+			wantField: "To",
+			wantAddr:  "to.example.org",
+		},
+		{
+			name:      "Bcc",
+			bcc:       []string{"<bcc@example.org"},
+			wantField: "Bcc",
+			wantAddr:  "<bcc@example.org",
+		},
+		{
+			name: "Unqualified domain",
+			to:   []string{"dom@localhost"},
+		},
+		{
+			name:      "Unqualified domain strict",
+			to:        []string{"dom@localhost"},
+			strict:    true,
+			wantField: "To",
+			wantAddr:  "dom@localhost",
+		},
+		{
+			name:   "Internationalized domain strict",
+			to:     []string{"dom@bücher.example"},
+			strict: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New("", nil)
+			m.From(tt.from)
+			m.ReplyTo(tt.replyTo)
+			m.To(tt.to...)
+			m.Bcc(tt.bcc...)
+			m.StrictAddresses(tt.strict)
+
+			err := m.Validate()
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("MailYak.Validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var addrErr *AddressError
+			if !errors.As(err, &addrErr) {
+				t.Fatalf("MailYak.Validate() error = %v, want *AddressError", err)
+			}
+			if addrErr.Field != tt.wantField || addrErr.Addr != tt.wantAddr {
+				t.Errorf("MailYak.Validate() error = %v, want field %q addr %q", err, tt.wantField, tt.wantAddr)
+			}
+		})
+	}
+}
+
+// TestValidateRFC5321 ensures the length and domain syntax limits of RFC 5321
+// are enforced.
+func TestValidateRFC5321(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Parameters.
+		addr string
+		// Want
+		wantErr bool
+	}{
+		{"Valid", "dom@itsallbroken.com", false},
+		{"Subdomain", "dom@mail.itsallbroken.com", false},
+		{"Hyphen", "dom@its-all-broken.com", false},
+		{"Address literal", "dom@[192.0.2.1]", false},
+		{"Local part limit", strings.Repeat("a", 64) + "@example.org", false},
+		{"Local part too long", strings.Repeat("a", 65) + "@example.org", true},
+		{"Address too long", "dom@" + strings.Repeat(strings.Repeat("a", 62)+".", 4) + "org", true},
+		{"Label too long", "dom@" + strings.Repeat("a", 64) + ".org", true},
+		{"Unqualified", "dom@localhost", true},
+		{"Leading hyphen", "dom@-example.org", true},
+		{"Trailing hyphen", "dom@example-.org", true},
+		{"Empty label", "dom@example..org", true},
+		{"Underscore", "dom@ex_ample.org", true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := validateRFC5321(tt.addr); (err != nil) != tt.wantErr {
+				t.Errorf("validateRFC5321(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestMailYakSend_invalidAddress ensures an invalid address fails the send
+// without connecting to the server.
+func TestMailYakSend_invalidAddress(t *testing.T) {
+	t.Parallel()
+
+	dialer := &testDialer{}
+
+	m := New("mail.invalid:25", nil)
+	m.Dialer(dialer)
+	m.From("from@example.org")
+	m.To("garbage")
+
+	_, _, err := m.Send("localhost")
+
+	var addrErr *AddressError
+	if !errors.As(err, &addrErr) || addrErr.Field != "To" {
+		t.Fatalf("MailYak.Send() error = %v, want *AddressError for To", err)
+	}
+
+	if len(dialer.addrs) != 0 {
+		t.Errorf("MailYak.Send() dialed %v, want no connection", dialer.addrs)
+	}
+}
@@ -0,0 +1,287 @@
+package mailyak
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+)
+
+// VerifyStatus is the deliverability of an address determined by
+// VerifyAddress.
+type VerifyStatus string
+
+const (
+	// VerifyDeliverable indicates the MX host of the domain accepted the
+	// address, and rejected a random address at the same domain.
+	VerifyDeliverable VerifyStatus = "deliverable"
+
+	// VerifyUndeliverable indicates the MX host permanently rejected the
+	// address, or the domain does not accept email.
+	VerifyUndeliverable VerifyStatus = "undeliverable"
+
+	// VerifyCatchAll indicates the MX host accepts any address at the domain,
+	// so whether the address exists cannot be determined.
+	VerifyCatchAll VerifyStatus = "catch-all"
+
+	// VerifyUnknown indicates the MX host temporarily rejected the address,
+	// such as when greylisting, or rejected the probe sender. The probe may
+	// succeed if retried later.
+	VerifyUnknown VerifyStatus = "unknown"
+)
+
+// VerifyResult is the result of VerifyAddress.
+type VerifyResult struct {
+	// Status is the deliverability of the address.
+	Status VerifyStatus
+
+	// Host is the MX host that was probed, or empty if the domain does not
+	// accept email.
+	Host string
+
+	// Code and Msg are the response of the MX host to the probe, or zero if
+	// the domain does not accept email.
+	Code int
+	Msg  string
+}
+
+// MXResolver looks up the MX records of a domain, as implemented by
+// *net.Resolver.
+type MXResolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// MXResolver sets the resolver used by VerifyAddress to look up the MX hosts
+// of a domain. Defaults to net.DefaultResolver.
+func (m *MailYak) MXResolver(r MXResolver) {
+	m.mxResolver = r
+}
+
+// VerifyAddress checks whether addr is deliverable without sending an email,
+// by connecting to the MX host of its domain and issuing the MAIL and RCPT
+// commands before abandoning the transaction with RSET:
+//
+//	result, err := mail.VerifyAddress(ctx, "dom@itsallbroken.com")
+//	if err == nil && result.Status == mailyak.VerifyUndeliverable {
+//		return errors.New("email address does not exist")
+//	}
+//
+// The MX hosts are tried in order of preference, as for FallbackHosts. The
+// dialer, HelloName, TLS, DANE, MTA-STS and timeout configuration of m is
+// used, with the envelope sender of m (or the null sender if unset) as the
+// probe sender. The host, authentication and recipients of m are ignored.
+//
+// When addr is accepted, a random address at the same domain is probed to
+// detect domains accepting any address, returning VerifyCatchAll if also
+// accepted. Temporary rejections, such as by greylisting, return VerifyUnknown
+// rather than VerifyUndeliverable, and may succeed if retried later.
+//
+// An error is returned if addr is invalid, the MX hosts cannot be looked up,
+// or none of the MX hosts can be connected to.
+//
+// Many servers accept every address during the SMTP conversation and reject
+// undeliverable email later, and some block hosts that probe addresses, so the
+// result is a best effort.
+func (m *MailYak) VerifyAddress(ctx context.Context, addr string) (*VerifyResult, error) {
+	a, err := mail.ParseAddress(addr)
+	if err != nil {
+		return nil, &AddressError{Field: "To", Addr: addr, Err: err}
+	}
+	addr = a.Address
+
+	at := strings.LastIndexByte(addr, '@')
+	domain := addr[at+1:]
+	if ascii, err := toASCII(domain); err == nil {
+		domain = ascii
+	}
+
+	hosts, err := m.lookupMX(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	// a null MX record indicates the domain does not accept email (RFC 7505)
+	if len(hosts) == 0 {
+		return &VerifyResult{Status: VerifyUndeliverable}, nil
+	}
+
+	// connect as when sending an email to addr through the MX hosts
+	probe := *m
+	probe.host = hosts[0]
+	probe.fallbackHosts = hosts[1:]
+	probe.auth = nil
+	probe.implicitTLS = false
+	probe.lmtp = false
+	probe.toAddrs = []string{addr}
+	probe.toGroups = nil
+	probe.ccAddrs = nil
+	probe.bccAddrs = nil
+
+	ctx, cancel, ctxErr := probe.sendContext(ctx)
+	defer cancel()
+
+	sc, err := probe.connect(ctx, "", mtaSTSCheck{client: probe.mtaSTS, rcpts: []string{addr}})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctxErr()
+		}
+		return nil, err
+	}
+	conn, smtpClient, host := sc.conn, sc.client, sc.host
+
+	defer closeOnDone(ctx, conn)()
+	defer smtpClient.Close()
+
+	if err := setDeadline(conn, m.timeouts.Data); err != nil {
+		return nil, err
+	}
+
+	result, err := probeAddress(smtpClient, m.envelopeSender(), addr, domain)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctxErr()
+		}
+		return nil, stageError("data", err)
+	}
+
+	result.Host = host
+	return result, nil
+}
+
+// lookupMX returns the MX hosts of domain in order of preference, with the SMTP
+// port, or no hosts if the domain has a null MX record.
+//
+// If the domain has no MX records, the domain itself is used (RFC 5321 section
+// 5.1).
+func (m *MailYak) lookupMX(ctx context.Context, domain string) ([]string, error) {
+	r := m.mxResolver
+	if r == nil {
+		r = net.DefaultResolver
+	}
+
+	records, err := r.LookupMX(ctx, domain)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return []string{net.JoinHostPort(domain, "25")}, nil
+		}
+		return nil, fmt.Errorf("mailyak: MX lookup for %s failed: %w", domain, err)
+	}
+
+	if len(records) == 0 {
+		return []string{net.JoinHostPort(domain, "25")}, nil
+	}
+
+	hosts := make([]string, 0, len(records))
+	for _, mx := range records {
+		host := strings.TrimSuffix(mx.Host, ".")
+		if host == "" {
+			continue
+		}
+		hosts = append(hosts, net.JoinHostPort(host, "25"))
+	}
+
+	return hosts, nil
+}
+
+// probeAddress issues the MAIL and RCPT commands for addr over smtpClient,
+// probing a random address at domain if addr is accepted, and ends the
+// session.
+func probeAddress(smtpClient *smtp.Client, envelopeFrom, addr, domain string) (*VerifyResult, error) {
+	mailCmd, err := mailCommand(smtpClient, envelopeFrom, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the probe sender being rejected says nothing about addr
+	if code, msg, err := probeCommand(smtpClient, mailCmd); err != nil {
+		return endProbe(smtpClient, &VerifyResult{Status: VerifyUnknown, Code: code, Msg: msg}, err)
+	}
+
+	result, err := probeRecipient(smtpClient, addr)
+	if err != nil || result.Status != VerifyDeliverable {
+		return endProbe(smtpClient, result, err)
+	}
+
+	// a server accepting a random address accepts any address
+	random, err := randomLocalPart()
+	if err != nil {
+		return nil, err
+	}
+
+	catchAll, err := probeRecipient(smtpClient, random+"@"+domain)
+	if err == nil && catchAll.Status == VerifyDeliverable {
+		result.Status = VerifyCatchAll
+	}
+
+	return endProbe(smtpClient, result, err)
+}
+
+// probeRecipient issues the RCPT command for addr, returning the status
+// indicated by the response.
+func probeRecipient(smtpClient *smtp.Client, addr string) (*VerifyResult, error) {
+	cmd, err := rcptCommand(addr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	code, msg, err := probeCommand(smtpClient, cmd)
+	result := &VerifyResult{Status: VerifyDeliverable, Code: code, Msg: msg}
+
+	var tpErr *textproto.Error
+	switch {
+	case err == nil:
+		return result, nil
+	case !errors.As(err, &tpErr):
+		return nil, err
+	case code >= 500:
+		result.Status = VerifyUndeliverable
+	default:
+		result.Status = VerifyUnknown
+	}
+
+	return result, nil
+}
+
+// probeCommand sends cmd, returning the response of the server.
+func probeCommand(smtpClient *smtp.Client, cmd smtpCommand) (int, string, error) {
+	id, err := smtpClient.Text.Cmd("%s", cmd.line)
+	if err != nil {
+		return -1, "", err
+	}
+	return readResponse(smtpClient, id, cmd.expectCode)
+}
+
+// endProbe abandons the transaction with RSET and ends the session with QUIT
+// if the session is usable, returning result unless err is a failure other
+// than a rejection by the server.
+func endProbe(smtpClient *smtp.Client, result *VerifyResult, err error) (*VerifyResult, error) {
+	var tpErr *textproto.Error
+	if err != nil && !errors.As(err, &tpErr) {
+		return nil, err
+	}
+
+	if err := smtpClient.Reset(); err != nil {
+		return nil, err
+	}
+	if err := smtpClient.Quit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// randomLocalPart returns a random local part unlikely to exist.
+func randomLocalPart() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "mailyak-probe-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,203 @@
+package mailyak
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+)
+
+// testMXResolver returns fixed MX lookup results.
+type testMXResolver struct {
+	records []*net.MX
+	err     error
+}
+
+func (r testMXResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	return r.records, r.err
+}
+
+// recordingRedirectDialer records the addresses dialed before connecting to
+// addr.
+type recordingRedirectDialer struct {
+	addr string
+
+	mu    sync.Mutex
+	addrs []string
+}
+
+func (d *recordingRedirectDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.mu.Lock()
+	d.addrs = append(d.addrs, address)
+	d.mu.Unlock()
+
+	var nd net.Dialer
+	return nd.DialContext(ctx, "tcp", d.addr)
+}
+
+// TestMailYakVerifyAddress ensures the deliverability of an address is
+// determined from the response of its MX host.
+func TestMailYakVerifyAddress(t *testing.T) {
+	t.Parallel()
+
+	mx := []*net.MX{{Host: "mx.example.org.", Pref: 10}}
+
+	tests := []struct {
+		// Test description.
+		name string
+		// Receiver fields.
+		resolver testMXResolver
+		replies  map[string]string
+		// Parameters.
+		addr string
+		// Want
+		want       *VerifyResult
+		wantDialed string
+		wantErr    bool
+	}{
+		{
+			name:     "Deliverable",
+			resolver: testMXResolver{records: mx},
+			replies: map[string]string{
+				"RCPT":                      "550 5.1.1 No such user",
+				"RCPT TO:<dom@example.org>": "250 OK",
+			},
+			addr:       "Dom <dom@example.org>",
+			want:       &VerifyResult{Status: VerifyDeliverable, Host: "mx.example.org:25", Code: 250, Msg: "OK"},
+			wantDialed: "mx.example.org:25",
+		},
+		{
+			name:       "Catch-all",
+			resolver:   testMXResolver{records: mx},
+			addr:       "dom@example.org",
+			want:       &VerifyResult{Status: VerifyCatchAll, Host: "mx.example.org:25", Code: 250, Msg: "OK"},
+			wantDialed: "mx.example.org:25",
+		},
+		{
+			name:       "Undeliverable",
+			resolver:   testMXResolver{records: mx},
+			replies:    map[string]string{"RCPT": "550 5.1.1 No such user"},
+			addr:       "dom@example.org",
+			want:       &VerifyResult{Status: VerifyUndeliverable, Host: "mx.example.org:25", Code: 550, Msg: "5.1.1 No such user"},
+			wantDialed: "mx.example.org:25",
+		},
+		{
+			name:       "Greylisted",
+			resolver:   testMXResolver{records: mx},
+			replies:    map[string]string{"RCPT": "451 4.7.1 Greylisted, try again later"},
+			addr:       "dom@example.org",
+			want:       &VerifyResult{Status: VerifyUnknown, Host: "mx.example.org:25", Code: 451, Msg: "4.7.1 Greylisted, try again later"},
+			wantDialed: "mx.example.org:25",
+		},
+		{
+			name:       "Sender rejected",
+			resolver:   testMXResolver{records: mx},
+			replies:    map[string]string{"MAIL": "550 Go away"},
+			addr:       "dom@example.org",
+			want:       &VerifyResult{Status: VerifyUnknown, Host: "mx.example.org:25", Code: 550, Msg: "Go away"},
+			wantDialed: "mx.example.org:25",
+		},
+		{
+			name:       "No MX records",
+			resolver:   testMXResolver{err: &net.DNSError{Err: "no such host", Name: "example.org", IsNotFound: true}},
+			addr:       "dom@example.org",
+			want:       &VerifyResult{Status: VerifyCatchAll, Host: "example.org:25", Code: 250, Msg: "OK"},
+			wantDialed: "example.org:25",
+		},
+		{
+			name:     "Null MX",
+			resolver: testMXResolver{records: []*net.MX{{Host: ".", Pref: 0}}},
+			addr:     "dom@example.org",
+			want:     &VerifyResult{Status: VerifyUndeliverable},
+		},
+		{
+			name:     "Lookup failed",
+			resolver: testMXResolver{err: &net.DNSError{Err: "server misbehaving", Name: "example.org"}},
+			addr:     "dom@example.org",
+			wantErr:  true,
+		},
+		{
+			name:     "Invalid address",
+			resolver: testMXResolver{records: mx},
+			addr:     "garbage",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := newTestSMTPServer(l, tt.replies)
+			defer srv.Close()
+
+			dialer := &recordingRedirectDialer{addr: srv.Addr()}
+
+			mail := New("smtp.invalid:587", nil)
+			mail.Dialer(dialer)
+			mail.MXResolver(tt.resolver)
+			mail.From("from@example.org")
+			mail.To("to@example.org")
+
+			got, err := mail.VerifyAddress(context.Background(), tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MailYak.VerifyAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if *got != *tt.want {
+				t.Errorf("MailYak.VerifyAddress() = %+v, want %+v", got, tt.want)
+			}
+
+			if tt.wantDialed == "" {
+				if len(dialer.addrs) != 0 {
+					t.Errorf("MailYak.VerifyAddress() dialed %v, want no connection", dialer.addrs)
+				}
+				return
+			}
+			if len(dialer.addrs) != 1 || dialer.addrs[0] != tt.wantDialed {
+				t.Errorf("MailYak.VerifyAddress() dialed %v, want %s", dialer.addrs, tt.wantDialed)
+			}
+
+			cmds := srv.Commands()
+			if n := len(cmds); n < 2 || cmds[n-2] != "RSET" || cmds[n-1] != "QUIT" {
+				t.Errorf("MailYak.VerifyAddress() commands = %v, want RSET and QUIT last", cmds)
+			}
+			if cmds[1] != "MAIL FROM:<from@example.org>" {
+				t.Errorf("MailYak.VerifyAddress() commands = %v, want MAIL FROM:<from@example.org>", cmds)
+			}
+		})
+	}
+}
+
+// TestMailYakVerifyAddress_unreachable ensures an error is returned when none
+// of the MX hosts can be connected to.
+func TestMailYakVerifyAddress_unreachable(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	mail := New("", nil)
+	mail.Dialer(&recordingRedirectDialer{addr: addr})
+	mail.MXResolver(testMXResolver{records: []*net.MX{
+		{Host: "mx1.example.org.", Pref: 10},
+		{Host: "mx2.example.org.", Pref: 20},
+	}})
+
+	if _, err := mail.VerifyAddress(context.Background(), "dom@example.org"); !errors.Is(err, ErrDial) {
+		t.Errorf("MailYak.VerifyAddress() error = %v, want %v", err, ErrDial)
+	}
+}
@@ -3,7 +3,10 @@ package mailyak
 import "bytes"
 
 // BodyPart is a buffer holding the contents of an email MIME part.
-type BodyPart struct{ bytes.Buffer }
+type BodyPart struct {
+	bytes.Buffer
+	encoding TransferEncoding
+}
 
 // Set accepts a string s as the contents of a BodyPart, replacing any existing
 // data.
@@ -11,3 +14,14 @@ func (w *BodyPart) Set(s string) {
 	w.Reset()
 	w.WriteString(s)
 }
+
+// Encoding sets the Content-Transfer-Encoding of the MIME part. Defaults to
+// EncodingAuto, which uses quoted-printable.
+//
+// Some gateways are known to mangle specific encodings, so selecting another
+// encoding may improve deliverability:
+//
+//	mail.HTML().Encoding(mailyak.EncodingBase64)
+func (w *BodyPart) Encoding(e TransferEncoding) {
+	w.encoding = e
+}